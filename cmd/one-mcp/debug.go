@@ -0,0 +1,55 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// debugAddr returns the address the debug listener binds to. A bare port
+// binds to localhost; other hosts must be loopback addresses, as profiles
+// expose memory contents and command lines.
+func debugAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid debug address %q: %w", addr, err)
+	}
+	if host == "" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if host == "localhost" {
+		return addr, nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return "", fmt.Errorf("debug address %q is not a loopback address", addr)
+	}
+	return addr, nil
+}
+
+// serveDebug serves pprof profiles under /debug/pprof/ and expvar variables
+// at /debug/vars on a localhost-only address, in the background
+func serveDebug(addr string, logger *slog.Logger) {
+	listenAddr, err := debugAddr(addr)
+	if err != nil {
+		logger.Error("Debug listener disabled", "error", err)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	logger.Info("Serving debug endpoints", "addr", listenAddr)
+	go func() {
+		if err := http.ListenAndServe(listenAddr, mux); err != nil {
+			logger.Error("Debug listener failed", "error", err)
+		}
+	}()
+}
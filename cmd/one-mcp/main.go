@@ -2,36 +2,55 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"time"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/logfile"
 	"github.com/radutopala/onemcp/internal/mcp"
+	"github.com/radutopala/onemcp/internal/telemetry"
 )
 
 func main() {
-	// Create log file
+	// one-mcp self-update replaces the binary with the latest release
+	if len(os.Args) > 1 && os.Args[1] == "self-update" {
+		if err := selfUpdate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "one-mcp self-update:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Create log file, or log to stderr only with MCP_LOG_FILE=stderr
 	logPath := os.Getenv("MCP_LOG_FILE")
 	if logPath == "" {
 		logPath = "/tmp/one-mcp.log"
 	}
 
-	// Open log file
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
+	// Open log file; it is rotated once the aggregator has read logMaxSizeMB
+	var logOutput io.Writer = os.Stderr
+	var logFile *logfile.File
+	if logPath != "stderr" {
+		file, err := logfile.Open(logPath)
+		if err == nil {
+			defer file.Close()
+			logFile, logOutput = file, file
+		}
 		// Fallback to stderr if we can't open the log file
-		logFile = os.Stderr
-	} else {
-		defer logFile.Close()
 	}
 
 	// Set log level from environment or default to Info
-	logLevel := slog.LevelInfo
+	// (the logLevel setting and config_set may change it later)
+	logLevel := new(slog.LevelVar)
 	if os.Getenv("MCP_LOG_LEVEL") == "debug" {
-		logLevel = slog.LevelDebug
+		logLevel.Set(slog.LevelDebug)
 	}
 
-	logger := slog.New(slog.NewTextHandler(logFile, &slog.HandlerOptions{
+	logger := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 
@@ -45,7 +64,21 @@ func main() {
 
 	serverVersion := os.Getenv("MCP_SERVER_VERSION")
 	if serverVersion == "" {
-		serverVersion = "0.2.0"
+		serverVersion = version
+	}
+
+	// Trace searches and tool calls when an OpenTelemetry exporter is configured
+	shutdownTracing, err := telemetry.SetupTracing(ctx, serverName, serverVersion)
+	if err != nil {
+		logger.Warn("Tracing disabled", "error", err)
+	} else {
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(shutdownCtx); err != nil {
+				logger.Warn("Failed to flush traces", "error", err)
+			}
+		}()
 	}
 
 	// Get config path from environment or use default
@@ -61,6 +94,40 @@ func main() {
 		os.Exit(1)
 	}
 	defer mcpServer.Close()
+	mcpServer.UseLogLevel(logLevel)
+	if logFile != nil {
+		if err := logFile.SetLimits(mcpServer.LogRotation()); err != nil {
+			logger.Warn("Failed to rotate log file", "path", logPath, "error", err)
+		}
+	}
+
+	// Serve Prometheus metrics on their own address, in any mode
+	if metricsAddr := os.Getenv("ONEMCP_METRICS_ADDR"); metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", mcpServer.MetricsHandler())
+		logger.Info("Serving metrics", "addr", metricsAddr)
+		go func() {
+			if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+				logger.Error("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	// Profile memory and goroutines in place when a debug address is given
+	if debugAddr := os.Getenv("ONEMCP_DEBUG_ADDR"); debugAddr != "" {
+		serveDebug(debugAddr, logger)
+	}
+
+	// Serve many clients over Streamable HTTP when an address is given
+	if httpAddr := os.Getenv("ONEMCP_HTTP_ADDR"); httpAddr != "" {
+		// Metrics stay on ONEMCP_METRICS_ADDR, so they are never exposed to MCP clients
+		logger.Info("Starting OneMCP aggregator server over Streamable HTTP...", "name", serverName, "version", serverVersion, "addr", httpAddr)
+		if err := http.ListenAndServe(httpAddr, mcpServer.HTTPHandler()); err != nil {
+			logger.Error("OneMCP aggregator server failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Start serving over stdio
 	logger.Info("Starting OneMCP aggregator server over stdio...", "name", serverName, "version", serverVersion)
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is the running release, set at build time with
+// -ldflags "-X main.version=1.2.3"
+var version = "0.2.0"
+
+// latestReleaseURL is the GitHub API endpoint of the latest release
+const latestReleaseURL = "https://api.github.com/repos/radutopala/onemcp/releases/latest"
+
+// checksumsAsset lists the SHA-256 of every binary of a release, one
+// "<hex>  <name>" line each
+const checksumsAsset = "checksums.txt"
+
+// updateTimeout bounds each request, downloads included
+const updateTimeout = 5 * time.Minute
+
+// release is the part of a GitHub release self-update reads
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file attached to a release
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// selfUpdateUsage is the self-update help. The checksums come from the same
+// release as the binary and are not signed, so they only catch a corrupted
+// download, not a tampered release; the help says so.
+const selfUpdateUsage = `Usage: one-mcp self-update [-check]
+
+Replaces the running binary with the latest release built for this platform.
+
+The download is checked against the SHA-256 listed in the release's
+checksums.txt. This verifies integrity only, not authenticity: checksums.txt
+is not signed, so anyone able to change the release (or the mirror set with
+ONEMCP_UPDATE_URL) can change both. Install from a source you trust, or
+verify the binary yourself, if that matters to you.
+
+`
+
+// selfUpdate replaces the running binary with the latest release built for
+// this platform, after checking it against the release's checksums
+func selfUpdate(args []string) error {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release exists")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), selfUpdateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	client := &http.Client{Timeout: updateTimeout}
+	latest, err := latestRelease(client, cmp.Or(os.Getenv("ONEMCP_UPDATE_URL"), latestReleaseURL))
+	if err != nil {
+		return err
+	}
+	if compareVersions(latest.TagName, version) <= 0 {
+		fmt.Printf("one-mcp %s is up to date\n", version)
+		return nil
+	}
+	if *check {
+		fmt.Printf("one-mcp %s is available (running %s); run one-mcp self-update to install it\n", latest.TagName, version)
+		return nil
+	}
+
+	binary, ok := platformAsset(latest.Assets)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", latest.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums, ok := findAsset(latest.Assets, checksumsAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s to verify the binary with", latest.TagName, checksumsAsset)
+	}
+	want, err := expectedChecksum(client, checksums.URL, binary.Name)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if err := replaceBinary(client, binary.URL, want, exe); err != nil {
+		return err
+	}
+	fmt.Printf("Updated one-mcp %s -> %s (%s)\n", version, latest.TagName, exe)
+	fmt.Printf("Checked the download against %s (integrity only; the release is not signed)\n", checksumsAsset)
+	return nil
+}
+
+// latestRelease fetches the latest release
+func latestRelease(client *http.Client, url string) (*release, error) {
+	body, err := download(client, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for releases: %w", err)
+	}
+	defer body.Close()
+	var latest release
+	if err := json.NewDecoder(body).Decode(&latest); err != nil {
+		return nil, fmt.Errorf("failed to read the latest release: %w", err)
+	}
+	return &latest, nil
+}
+
+// platformAsset finds the binary for this OS and architecture, named
+// one-mcp-<os>-<arch>, or one-mcp-<os> for amd64 builds
+func platformAsset(assets []releaseAsset) (releaseAsset, bool) {
+	if asset, ok := findAsset(assets, "one-mcp-"+runtime.GOOS+"-"+runtime.GOARCH); ok {
+		return asset, true
+	}
+	if runtime.GOARCH == "amd64" {
+		return findAsset(assets, "one-mcp-"+runtime.GOOS)
+	}
+	return releaseAsset{}, false
+}
+
+// findAsset returns the asset with the given name
+func findAsset(assets []releaseAsset, name string) (releaseAsset, bool) {
+	for _, asset := range assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// expectedChecksum reads the SHA-256 listed for name in the checksums file
+func expectedChecksum(client *http.Client, url, name string) (string, error) {
+	body, err := download(client, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+	defer body.Close()
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", checksumsAsset, err)
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", checksumsAsset, name)
+}
+
+// replaceBinary downloads the new binary next to exe and, if its SHA-256
+// matches, renames it over exe. A mismatching download is deleted.
+func replaceBinary(client *http.Client, url, checksum, exe string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".one-mcp-update-*")
+	if err != nil {
+		return fmt.Errorf("can't write next to %s: %w", exe, err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	defer tmp.Close()
+
+	body, err := download(client, url)
+	if err != nil {
+		return fmt.Errorf("failed to download the binary: %w", err)
+	}
+	defer body.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		return fmt.Errorf("failed to download the binary: %w", err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != checksum {
+		return fmt.Errorf("checksum mismatch: downloaded binary has SHA-256 %s, release lists %s", got, checksum)
+	}
+
+	if err := tmp.Chmod(0755); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// download starts a GET of url, failing on non-2xx answers
+func download(client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "one-mcp/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// compareVersions compares dotted versions such as "v1.2.0" and "1.10",
+// numerically part by part, ignoring a leading "v" and any "-suffix"
+func compareVersions(a, b string) int {
+	parse := func(v string) []int {
+		v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+		var parts []int
+		for _, part := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(part)
+			parts = append(parts, n)
+		}
+		return parts
+	}
+	pa, pb := parse(a), parse(b)
+	for i := range max(len(pa), len(pb)) {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	return 0
+}
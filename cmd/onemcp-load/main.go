@@ -0,0 +1,249 @@
+// Command onemcp-load drives concurrent tool_search and tool_execute load
+// against an aggregator in front of a synthetic catalog, and reports
+// throughput and latency.
+//
+// It starts -servers fake external servers with -tools tools each over
+// Streamable HTTP, an aggregator connected to all of them, and -clients MCP
+// clients calling the aggregator for -duration:
+//
+//	go run ./cmd/onemcp-load -servers 20 -tools 50 -clients 16 -duration 30s
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcp"
+)
+
+// Words fake tools are named and described with
+var (
+	verbs   = []string{"read", "write", "list", "create", "delete", "update", "search", "query", "send", "take"}
+	objects = []string{"file", "page", "screenshot", "issue", "pull_request", "table", "message", "channel", "container", "pod", "branch", "order"}
+)
+
+// options are the command-line flags
+type options struct {
+	servers     int
+	tools       int
+	clients     int
+	duration    time.Duration
+	searchRatio float64
+	toolLatency time.Duration
+	provider    string
+	logFile     string
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.servers, "servers", 10, "Fake external servers")
+	flag.IntVar(&opts.tools, "tools", 50, "Tools per fake server")
+	flag.IntVar(&opts.clients, "clients", 8, "Concurrent MCP clients")
+	flag.DurationVar(&opts.duration, "duration", 20*time.Second, "How long to drive load")
+	flag.Float64Var(&opts.searchRatio, "search-ratio", 0.5, "Share of calls that are tool_search, 0-1; the rest are tool_execute")
+	flag.DurationVar(&opts.toolLatency, "tool-latency", 0, "Time each fake tool takes to answer")
+	flag.StringVar(&opts.provider, "provider", "tfidf", "Search provider of the aggregator")
+	flag.StringVar(&opts.logFile, "log", "", "Aggregator log file (default: discarded)")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "onemcp-load:", err)
+		os.Exit(1)
+	}
+}
+
+// run starts the catalog and the aggregator, drives the load and prints the report
+func run(opts options) error {
+	if opts.servers < 1 || opts.tools < 1 || opts.clients < 1 {
+		return fmt.Errorf("-servers, -tools and -clients must be positive")
+	}
+
+	logOutput := io.Discard
+	if opts.logFile != "" {
+		file, err := os.Create(opts.logFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		logOutput = file
+	}
+	logger := slog.New(slog.NewTextHandler(logOutput, nil))
+
+	dir, err := os.MkdirTemp("", "onemcp-load")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// Fake external servers
+	servers := map[string]any{}
+	var toolNames []string
+	for i := range opts.servers {
+		name := fmt.Sprintf("load%02d", i)
+		downstream := httptest.NewServer(fakeServerHandler(name, opts.tools, opts.toolLatency))
+		defer downstream.Close()
+		servers[name] = map[string]any{"url": downstream.URL, "enabled": true, "pingInterval": -1}
+		for j := range opts.tools {
+			toolNames = append(toolNames, name+"_"+fakeToolName(j))
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{
+		"mcpServers": servers,
+		"settings":   map[string]any{"searchProvider": opts.provider, "cacheDir": filepath.Join(dir, "cache")},
+	})
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(dir, ".onemcp.json")
+	if err := os.WriteFile(configPath, config, 0644); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	aggregator, err := mcp.NewAggregatorServer("onemcp-load", "0.0.0", configPath, logger)
+	if err != nil {
+		return err
+	}
+	defer aggregator.Close()
+	startup := time.Since(start)
+	endpoint := httptest.NewServer(aggregator.HTTPHandler())
+	defer endpoint.Close()
+
+	fmt.Printf("Catalog: %d servers x %d tools = %d tools, aggregator started in %s\n",
+		opts.servers, opts.tools, len(toolNames), startup.Round(time.Millisecond))
+	fmt.Printf("Load: %d clients for %s, %.0f%% tool_search\n\n", opts.clients, opts.duration, opts.searchRatio*100)
+
+	stats := drive(endpoint.URL, toolNames, opts)
+	stats.print(opts.duration)
+	return nil
+}
+
+// fakeServerHandler serves a fake external server whose tools echo their arguments
+func fakeServerHandler(name string, tools int, latency time.Duration) http.Handler {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: name, Version: "1.0.0"}, nil)
+	for i := range tools {
+		tool := &mcpsdk.Tool{
+			Name:        fakeToolName(i),
+			Description: fmt.Sprintf("%s a %s on %s", verbs[i%len(verbs)], objects[(i/len(verbs))%len(objects)], name),
+		}
+		mcpsdk.AddTool(server, tool, func(ctx context.Context, req *mcpsdk.CallToolRequest, input map[string]any) (*mcpsdk.CallToolResult, any, error) {
+			if latency > 0 {
+				select {
+				case <-time.After(latency):
+				case <-ctx.Done():
+					return nil, nil, ctx.Err()
+				}
+			}
+			text, _ := json.Marshal(input)
+			return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: string(text)}}}, nil, nil
+		})
+	}
+	return mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return server }, nil)
+}
+
+// fakeToolName names the i-th tool of a fake server
+func fakeToolName(i int) string {
+	return fmt.Sprintf("%s_%s_%d", verbs[i%len(verbs)], objects[(i/len(verbs))%len(objects)], i)
+}
+
+// drive runs the clients until the duration is up and collects their latencies
+func drive(url string, toolNames []string, opts options) *loadStats {
+	stats := &loadStats{latencies: map[string][]time.Duration{}, errors: map[string]int{}}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range opts.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: fmt.Sprintf("load-client-%d", i), Version: "1.0.0"}, nil)
+			session, err := client.Connect(context.Background(), &mcpsdk.StreamableClientTransport{Endpoint: url}, nil)
+			if err != nil {
+				stats.record("connect", 0, err)
+				return
+			}
+			defer session.Close()
+
+			random := rand.New(rand.NewPCG(uint64(i), 0))
+			for ctx.Err() == nil {
+				params := &mcpsdk.CallToolParams{Name: "tool_execute"}
+				target := toolNames[random.IntN(len(toolNames))]
+				if random.Float64() < opts.searchRatio {
+					params.Name = "tool_search"
+					params.Arguments = map[string]any{"query": fmt.Sprintf("%s %s", verbs[random.IntN(len(verbs))], objects[random.IntN(len(objects))])}
+				} else {
+					params.Arguments = map[string]any{"tool_name": target, "arguments": map[string]any{"client": i}}
+				}
+
+				callStart := time.Now()
+				result, err := session.CallTool(ctx, params)
+				if ctx.Err() != nil {
+					return // Calls cut short by the deadline aren't counted
+				}
+				if err == nil && result.IsError {
+					err = fmt.Errorf("tool error")
+				}
+				stats.record(params.Name, time.Since(callStart), err)
+			}
+		}()
+	}
+	wg.Wait()
+	return stats
+}
+
+// loadStats collects call latencies and errors by operation
+type loadStats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+// record adds one call
+func (s *loadStats) record(op string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors[op]++
+		return
+	}
+	s.latencies[op] = append(s.latencies[op], latency)
+}
+
+// print reports throughput and latency percentiles per operation
+func (s *loadStats) print(duration time.Duration) {
+	fmt.Printf("%-14s %8s %7s %9s %9s %9s %9s %9s\n", "operation", "calls", "errors", "calls/s", "p50", "p90", "p99", "max")
+	for _, op := range []string{"tool_search", "tool_execute", "connect"} {
+		latencies := s.latencies[op]
+		if len(latencies) == 0 && s.errors[op] == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+		fmt.Printf("%-14s %8d %7d %9.1f %9s %9s %9s %9s\n", op, len(latencies), s.errors[op],
+			float64(len(latencies))/duration.Seconds(),
+			percentile(latencies, 0.50), percentile(latencies, 0.90), percentile(latencies, 0.99), percentile(latencies, 1))
+	}
+}
+
+// percentile returns the latency below which the given share of sorted latencies fall
+func percentile(sorted []time.Duration, share float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := min(len(sorted)-1, int(share*float64(len(sorted))))
+	return sorted[index].Round(10 * time.Microsecond)
+}
@@ -167,10 +167,11 @@ func (s *HTTPComponentTestSuite) TestStreamableHTTPCallTool() {
 	require.NotNil(s.T(), result)
 
 	// Verify the result
-	resultMap, ok := result.(map[string]any)
-	require.True(s.T(), ok, "Result should be a map")
-	require.Contains(s.T(), resultMap, "content", "Result should have content")
-	require.Contains(s.T(), resultMap["content"], "Hello, World!")
+	require.False(s.T(), result.IsError)
+	require.Len(s.T(), result.Content, 1, "Result should have content")
+	text, ok := result.Content[0].(*mcp.TextContent)
+	require.True(s.T(), ok, "Content should be text")
+	require.Contains(s.T(), text.Text, "Hello, World!")
 }
 
 // TestStreamableHTTPSchemaCache tests that schemas are cached
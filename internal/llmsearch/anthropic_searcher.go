@@ -0,0 +1,167 @@
+package llmsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// anthropicVersion is the Messages API version sent with every request
+const anthropicVersion = "2023-06-01"
+
+// anthropicModelAliases maps the claude CLI's short model names to API model IDs
+var anthropicModelAliases = map[string]string{
+	"haiku":  "claude-haiku-4-5",
+	"sonnet": "claude-sonnet-4-5",
+	"opus":   "claude-opus-4-1",
+}
+
+// AnthropicSearcher calls the Anthropic Messages API directly to semantically match queries against tools
+type AnthropicSearcher struct {
+	model   string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewAnthropicSearcher creates a new Anthropic API-based searcher.
+// The API key falls back to the ANTHROPIC_API_KEY environment variable.
+// model accepts the claude CLI aliases (haiku, sonnet, opus) or a full model ID.
+// Each call is abandoned after timeout (DefaultSearchTimeout if zero).
+func NewAnthropicSearcher(apiKey, baseURL, model string, timeout time.Duration, logger *slog.Logger) (*AnthropicSearcher, error) {
+	// Default to haiku if not specified
+	if model == "" {
+		model = claudeDefaultModel
+	}
+	if id, ok := anthropicModelAliases[model]; ok {
+		model = id
+	}
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("anthropic API key not configured: set anthropicAPIKey or ANTHROPIC_API_KEY")
+	}
+
+	logger.Info("Created Anthropic searcher", "model", model, "url", baseURL)
+
+	return &AnthropicSearcher{
+		model:   model,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{},
+		timeout: timeout,
+		logger:  logger,
+	}, nil
+}
+
+// SearchTools uses the Anthropic API to find relevant tools for a query
+// Returns tool names ranked by relevance
+func (s *AnthropicSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	prompt := buildSearchPrompt(query, toolSchemas, topK)
+
+	s.logger.Debug("Calling Anthropic API", "query", query, "topK", topK)
+
+	responseText, err := s.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	rankings, err := parseRankings(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
+
+	s.logger.Info("Anthropic search completed", "query", query, "found", len(rankings))
+
+	return rankings, nil
+}
+
+// SearchToolsBatch uses the Anthropic API to rank tools for several queries in one call
+func (s *AnthropicSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	s.logger.Debug("Calling Anthropic API for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, s, "anthropic", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to the Anthropic Messages API and returns the response text
+func (s *AnthropicSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":      s.model,
+		"max_tokens": 1024,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal anthropic request: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", s.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", callError(ctx, "anthropic", fmt.Errorf("anthropic request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+
+	// Log raw response for debugging
+	s.logger.Debug("Anthropic raw response", "status", resp.StatusCode, "body", string(data))
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("anthropic returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	// The API returns: {"content":[{"type":"text","text":"..."}],"usage":{...}, ...}
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int64 `json:"input_tokens"`
+			OutputTokens int64 `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("failed to parse anthropic response: %w, output: %s", err, string(data))
+	}
+	reportUsage(ctx, Usage{InputTokens: response.Usage.InputTokens, OutputTokens: response.Usage.OutputTokens})
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text content in anthropic response")
+	}
+
+	return text.String(), nil
+}
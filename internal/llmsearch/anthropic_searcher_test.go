@@ -0,0 +1,56 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newMockAnthropic starts a fake Anthropic API answering /v1/messages with the given text
+func newMockAnthropic(t *testing.T, text string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		require.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		require.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+
+		var request map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "claude-haiku-4-5", request["model"], "CLI alias should map to an API model ID")
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"content": []map[string]any{{"type": "text", "text": text}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestAnthropicSearcher_Search tests searching through the Anthropic API
+func TestAnthropicSearcher_Search(t *testing.T) {
+	server := newMockAnthropic(t, "```json\n[\"browser_screenshot\"]\n```")
+
+	searcher, err := NewAnthropicSearcher("test-key", server.URL, "haiku", 0, testLogger())
+	require.NoError(t, err)
+
+	store := indexTestTools(t, NewLLMSearchStore("anthropic", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "capture the page", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+}
+
+// TestNewAnthropicSearcher_MissingKey tests that an API key is required
+func TestNewAnthropicSearcher_MissingKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	_, err := NewAnthropicSearcher("", "", "", 0, testLogger())
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package llmsearch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// maxCacheEntries bounds the number of cached search responses
+const maxCacheEntries = 1000
+
+// cacheEntry is a cached search response
+type cacheEntry struct {
+	results  []ScoredTool
+	storedAt time.Time
+}
+
+// CacheUsage summarizes the lookups of search result caches
+type CacheUsage struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // Hits per lookup, 0 before the first one
+}
+
+// CacheStats counts the hits and misses of search result caches. One
+// CacheStats can be shared by several caches, and outlives the caches
+// replaced when the index is rebuilt.
+type CacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCacheStats creates an empty cache counter
+func NewCacheStats() *CacheStats {
+	return &CacheStats{}
+}
+
+// Snapshot returns the counts so far
+func (c *CacheStats) Snapshot() CacheUsage {
+	usage := CacheUsage{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if lookups := usage.Hits + usage.Misses; lookups > 0 {
+		usage.HitRate = float64(usage.Hits) / float64(lookups)
+	}
+	return usage
+}
+
+// CachedSearchStore caches search results of a wrapped store keyed by
+// (normalized query, tool catalog hash, topK) so repeated queries don't
+// trigger another multi-second LLM round-trip.
+type CachedSearchStore struct {
+	store       SearchStore
+	ttl         time.Duration
+	catalogHash string
+	mu          sync.Mutex
+	entries     map[string]cacheEntry
+	stats       *CacheStats // Hit and miss counts (nil if not counted)
+	now         func() time.Time
+	logger      *slog.Logger
+}
+
+// NewCachedSearchStore wraps a search store with a TTL cache
+func NewCachedSearchStore(store SearchStore, ttl time.Duration, logger *slog.Logger) *CachedSearchStore {
+	return &CachedSearchStore{
+		store:   store,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+		logger:  logger,
+	}
+}
+
+// SetStats counts the cache's hits and misses in stats
+func (s *CachedSearchStore) SetStats(stats *CacheStats) {
+	s.stats = stats
+}
+
+// BuildFromTools builds the wrapped store and recomputes the catalog hash.
+// Entries cached for a different catalog no longer match and age out.
+func (s *CachedSearchStore) BuildFromTools(allTools []*tools.Tool) error {
+	if err := s.store.BuildFromTools(allTools); err != nil {
+		return err
+	}
+
+	hash, err := catalogHash(allTools)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.catalogHash = hash
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Search returns cached results when available, otherwise queries the wrapped store
+func (s *CachedSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	s.mu.Lock()
+	key := s.cacheKey(query, topK)
+	entry, ok := s.entries[key]
+	if ok && s.now().Sub(entry.storedAt) < s.ttl {
+		s.mu.Unlock()
+		s.logger.Debug("Search cache hit", "query", query, "topK", topK)
+		if s.stats != nil {
+			s.stats.hits.Add(1)
+		}
+		return entry.results, nil
+	}
+	s.mu.Unlock()
+	if s.stats != nil {
+		s.stats.misses.Add(1)
+	}
+
+	results, err := s.store.Search(ctx, query, topK)
+	if err != nil {
+		return nil, err // Never cache failures
+	}
+
+	s.mu.Lock()
+	s.evictLocked()
+	s.entries[key] = cacheEntry{results: results, storedAt: s.now()}
+	s.mu.Unlock()
+
+	return results, nil
+}
+
+// GetToolCount returns the number of tools indexed
+func (s *CachedSearchStore) GetToolCount() int {
+	return s.store.GetToolCount()
+}
+
+// cacheKey builds the cache key. Callers must hold mu.
+func (s *CachedSearchStore) cacheKey(query string, topK int) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	return fmt.Sprintf("%s|%d|%s", s.catalogHash, topK, normalized)
+}
+
+// evictLocked drops expired entries and, if still full, the oldest one.
+// Callers must hold mu.
+func (s *CachedSearchStore) evictLocked() {
+	if len(s.entries) < maxCacheEntries {
+		return
+	}
+
+	now := s.now()
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range s.entries {
+		if now.Sub(entry.storedAt) >= s.ttl {
+			delete(s.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+
+	if len(s.entries) >= maxCacheEntries && oldestKey != "" {
+		delete(s.entries, oldestKey)
+	}
+}
+
+// catalogHash hashes tool names, categories, descriptions and schemas.
+// Tools are sorted by name so the hash doesn't depend on registry order.
+func catalogHash(allTools []*tools.Tool) (string, error) {
+	sorted := make([]*tools.Tool, len(allTools))
+	copy(sorted, allTools)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	metadata := make([]tools.ToolMetadata, len(sorted))
+	for i, tool := range sorted {
+		metadata[i] = tools.ToolMetadata{
+			Name:        tool.Name,
+			Category:    tool.Category,
+			Description: tool.Description,
+		}
+		if schemaMap, ok := tool.InputSchema.(map[string]any); ok {
+			metadata[i].Parameters = schemaMap
+		}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash tool catalog: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -2,25 +2,59 @@ package llmsearch
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os/exec"
-	"strings"
+	"sync"
+	"time"
 )
 
+// claudeDefaultModel is the model used when none is configured
+const claudeDefaultModel = "haiku"
+
+func init() {
+	RegisterProvider(Provider{
+		Name:         "claude",
+		DefaultModel: claudeDefaultModel,
+		New: func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+			searcher, err := NewClaudeSearcher(cfg.Model, cfg.Timeout, logger)
+			if err == nil {
+				return searcher, nil
+			}
+
+			// Without the CLI, call the Messages API directly if a key is available
+			apiSearcher, apiErr := NewAnthropicSearcher(cfg.APIKey, cfg.BaseURL, cfg.Model, cfg.Timeout, logger)
+			if apiErr != nil {
+				return nil, fmt.Errorf("%w (API fallback: %v)", err, apiErr)
+			}
+			logger.Info("Claude CLI unavailable, using Anthropic API", "model", cfg.Model)
+			return apiSearcher, nil
+		},
+	})
+}
+
 // ClaudeSearcher uses Claude CLI to semantically match queries against tools
 type ClaudeSearcher struct {
 	model        string
 	claudeBinary string
+	timeout      time.Duration
 	logger       *slog.Logger
+
+	sessionMu sync.Mutex
+	sessions  map[string]string // Catalog key -> CLI session that has seen it
 }
 
 // NewClaudeSearcher creates a new Claude-based searcher
-func NewClaudeSearcher(model string, logger *slog.Logger) (*ClaudeSearcher, error) {
+// Each CLI call is killed after timeout (DefaultSearchTimeout if zero).
+func NewClaudeSearcher(model string, timeout time.Duration, logger *slog.Logger) (*ClaudeSearcher, error) {
 	// Default to haiku if not specified
 	if model == "" {
-		model = "haiku"
+		model = claudeDefaultModel
 	}
 
 	// Find claude binary
@@ -34,101 +68,164 @@ func NewClaudeSearcher(model string, logger *slog.Logger) (*ClaudeSearcher, erro
 	return &ClaudeSearcher{
 		model:        model,
 		claudeBinary: claudePath,
+		timeout:      timeout,
 		logger:       logger,
 	}, nil
 }
 
-// Generate is not used for Claude searcher (we use direct LLM search instead)
-// This exists for interface compatibility but shouldn't be called
-func (e *ClaudeSearcher) Generate(text string) ([]float32, error) {
-	return nil, fmt.Errorf("Claude searcher doesn't support Generate() - use SearchTools() directly")
-}
+// SearchTools uses Claude to find relevant tools for a query
+// Returns tool names ranked by relevance. The first search of a catalog sends
+// the full schemas; later searches of the same catalog resume (a fork of)
+// that CLI session and only send the query.
+func (e *ClaudeSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	key := catalogKey(toolSchemas)
+
+	var response claudeResponse
+	var err error
+	resumed := false
+	if sessionID := e.session(key); sessionID != "" {
+		e.logger.Debug("Calling Claude CLI with cached catalog session", "query", query, "topK", topK, "session", sessionID)
+		response, err = e.run(ctx, buildSessionSearchPrompt(query, topK), sessionID)
+		resumed = err == nil || ctx.Err() != nil || errors.Is(err, ErrSearchTimeout)
+		if !resumed {
+			e.logger.Warn("Claude session resume failed, sending the full catalog", "session", sessionID, "error", err)
+			e.forgetSession(key)
+		}
+	}
+	if !resumed {
+		// Build prompt for Claude
+		prompt := buildSearchPrompt(query, toolSchemas, topK)
 
-// Dimension returns 0 (Claude uses LLM-based search, not vector embeddings)
-func (e *ClaudeSearcher) Dimension() int {
-	return 0 // No vector embeddings
-}
+		e.logger.Debug("Calling Claude CLI", "query", query, "topK", topK)
 
-// SearchTools uses Claude to find relevant tools for a query
-// Returns tool names ranked by relevance
-func (e *ClaudeSearcher) SearchTools(query string, toolSchemas []byte, topK int) ([]string, error) {
-	// Build prompt for Claude
-	prompt := fmt.Sprintf(`You are helping match a user query to the most relevant tools.
+		response, err = e.run(ctx, prompt, "")
+		if err == nil && response.SessionID != "" {
+			e.rememberSession(key, response.SessionID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-Given this query: "%s"
+	rankings, err := parseRankings(response.Result)
+	if err != nil {
+		return nil, fmt.Errorf("claude: %w", err)
+	}
 
-And these available tools (JSON array with name, description, category, parameters):
-%s
+	e.logger.Info("Claude search completed", "query", query, "found", len(rankings))
 
-Return ONLY a JSON array of EXACTLY %d tool names, ranked by relevance.
-Format: ["tool_name_1", "tool_name_2", ...]
-IMPORTANT: Return no more and no less than %d tools.
+	return rankings, nil
+}
+
+// SearchToolsBatch uses Claude to rank tools for several queries in one call
+func (e *ClaudeSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	e.logger.Debug("Calling Claude CLI for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, e, "claude", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to the Claude CLI and returns the response text
+func (e *ClaudeSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	response, err := e.run(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+	return response.Result, nil
+}
 
-Consider:
-- Semantic similarity between query and tool description
-- Tool category and parameters
-- Likely user intent
+// claudeResponse is the CLI's JSON output:
+// {"type":"result","result":"...","session_id":"...","usage":{...},"total_cost_usd":0.001, ...}
+type claudeResponse struct {
+	Type      string `json:"type"`
+	Result    string `json:"result"`
+	SessionID string `json:"session_id"`
+	Usage     struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+}
 
-Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK, topK)
+// run sends a prompt to the Claude CLI. A non-empty resumeID continues a fork
+// of that session, so the session itself stays as it was.
+func (e *ClaudeSearcher) run(ctx context.Context, prompt, resumeID string) (claudeResponse, error) {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
 
-	// Call claude CLI with prompt as last argument
-	cmd := exec.Command(
-		e.claudeBinary,
+	args := []string{
 		"--print",
 		"--output-format", "json",
 		"--model", e.model,
 		"--dangerously-skip-permissions",
 		"--tools", "", // Disable all tools
-		"--", // End of options
-		prompt,
-	)
+	}
+	if resumeID != "" {
+		args = append(args, "--resume", resumeID, "--fork-session")
+	}
+	// Prompt goes last, after the end of options
+	args = append(args, "--", prompt)
+
+	cmd := exec.CommandContext(ctx, e.claudeBinary, args...)
+
+	// Don't wait on grandchildren holding the pipes once the CLI is killed
+	cmd.WaitDelay = time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	e.logger.Debug("Calling Claude CLI", "query", query, "topK", topK)
-
+	var response claudeResponse
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("claude CLI failed: %w, stderr: %s", err, stderr.String())
+		return response, callError(ctx, "claude CLI", fmt.Errorf("claude CLI failed: %w, stderr: %s", err, stderr.String()))
 	}
 
 	// Log raw response for debugging
 	e.logger.Debug("Claude raw response", "stdout", stdout.String())
 
-	// Parse Claude's JSON response
-	// The CLI returns: {"type":"result","result":"...", ...}
-	var response struct {
-		Type   string `json:"type"`
-		Result string `json:"result"`
-	}
-
 	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse claude response: %w, output: %s", err, stdout.String())
+		return response, fmt.Errorf("failed to parse claude response: %w, output: %s", err, stdout.String())
 	}
+	reportUsage(ctx, Usage{InputTokens: response.Usage.InputTokens, OutputTokens: response.Usage.OutputTokens, CostUSD: response.TotalCostUSD})
 
-	e.logger.Debug("Parsed Claude response", "type", response.Type, "result", response.Result)
+	e.logger.Debug("Parsed Claude response", "type", response.Type, "result", response.Result, "session", response.SessionID)
 
 	if response.Result == "" {
-		return nil, fmt.Errorf("no result in claude response")
+		return response, fmt.Errorf("no result in claude response")
 	}
 
-	responseText := response.Result
+	return response, nil
+}
 
-	// Parse the JSON array of tool names from Claude's response
-	// Claude might wrap it in markdown code blocks, so clean that up
-	responseText = strings.TrimSpace(responseText)
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimPrefix(responseText, "```")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
+// maxClaudeSessions bounds the catalog sessions kept; chunk finalists make
+// one-off catalogs, so the cache is simply reset when it fills up
+const maxClaudeSessions = 32
 
-	var toolNames []string
-	if err := json.Unmarshal([]byte(responseText), &toolNames); err != nil {
-		return nil, fmt.Errorf("failed to parse tool names from claude: %w, text: %s", err, responseText)
-	}
+// catalogKey identifies a tool schema payload
+func catalogKey(toolSchemas []byte) string {
+	sum := sha256.Sum256(toolSchemas)
+	return hex.EncodeToString(sum[:])
+}
 
-	e.logger.Info("Claude search completed", "query", query, "found", len(toolNames))
+// session returns the CLI session seeded with a catalog, if any
+func (e *ClaudeSearcher) session(key string) string {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+	return e.sessions[key]
+}
+
+// rememberSession records the CLI session that has seen a catalog
+func (e *ClaudeSearcher) rememberSession(key, sessionID string) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	if e.sessions == nil || len(e.sessions) >= maxClaudeSessions {
+		e.sessions = make(map[string]string)
+	}
+	e.sessions[key] = sessionID
+}
 
-	return toolNames, nil
+// forgetSession drops a session that could not be resumed
+func (e *ClaudeSearcher) forgetSession(key string) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+	delete(e.sessions, key)
 }
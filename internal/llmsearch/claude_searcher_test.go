@@ -0,0 +1,105 @@
+package llmsearch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// installCLI puts a fake CLI named name running the shell script on PATH
+func installCLI(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// installHangingCLI puts a fake CLI named name on PATH that never answers
+func installHangingCLI(t *testing.T, name string) {
+	installCLI(t, name, "sleep 30\n")
+}
+
+// TestClaudeSearcher_Timeout tests that a hung CLI is killed at the deadline
+func TestClaudeSearcher_Timeout(t *testing.T) {
+	installHangingCLI(t, "claude")
+
+	searcher, err := NewClaudeSearcher("haiku", 100*time.Millisecond, testLogger())
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = searcher.SearchTools(context.Background(), "open a page", []byte("[]"), 2)
+	require.ErrorIs(t, err, ErrSearchTimeout)
+	require.Less(t, time.Since(start), 5*time.Second, "Hung CLI should be killed at the deadline")
+}
+
+// TestClaudeSearcher_Cancelled tests that cancellation is not reported as a timeout
+func TestClaudeSearcher_Cancelled(t *testing.T) {
+	installHangingCLI(t, "claude")
+
+	searcher, err := NewClaudeSearcher("haiku", time.Minute, testLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = searcher.Complete(ctx, "hello")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrSearchTimeout)
+}
+
+// installRecordingCLI puts a fake claude CLI on PATH that logs its arguments
+// and answers with session-1. With failResume, resuming a session fails.
+func installRecordingCLI(t *testing.T, failResume bool) string {
+	logPath := filepath.Join(t.TempDir(), "calls.log")
+	script := "echo \"CALL $*\" >> " + logPath + "\n"
+	if failResume {
+		script += "case \"$*\" in *--resume*) echo 'No conversation found' >&2; exit 1;; esac\n"
+	}
+	script += `echo '{"type":"result","result":"[\"browser_navigate\"]","session_id":"session-1"}'` + "\n"
+	installCLI(t, "claude", script)
+	return logPath
+}
+
+// TestClaudeSearcher_ReusesCatalogSession tests that later searches resume the session holding the catalog
+func TestClaudeSearcher_ReusesCatalogSession(t *testing.T) {
+	logPath := installRecordingCLI(t, false)
+
+	searcher, err := NewClaudeSearcher("haiku", time.Minute, testLogger())
+	require.NoError(t, err)
+
+	schemas := []byte(`[{"name":"browser_navigate","description":"Open a URL"}]`)
+	for _, query := range []string{"open a page", "go to a website"} {
+		rankings, err := searcher.SearchTools(context.Background(), query, schemas, 1)
+		require.NoError(t, err)
+		require.Equal(t, "browser_navigate", rankings[0].Name)
+	}
+
+	calls, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(calls), "Open a URL"), "Only the first search should send the catalog")
+	require.Contains(t, string(calls), "--resume session-1 --fork-session")
+}
+
+// TestClaudeSearcher_ResumeFailureSendsCatalog tests that a failed resume falls back to the full prompt
+func TestClaudeSearcher_ResumeFailureSendsCatalog(t *testing.T) {
+	logPath := installRecordingCLI(t, true)
+
+	searcher, err := NewClaudeSearcher("haiku", time.Minute, testLogger())
+	require.NoError(t, err)
+
+	schemas := []byte(`[{"name":"browser_navigate","description":"Open a URL"}]`)
+	for range 2 {
+		_, err := searcher.SearchTools(context.Background(), "open a page", schemas, 1)
+		require.NoError(t, err)
+	}
+
+	calls, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, 3, strings.Count(string(calls), "CALL "), "A failed resume should fall back to the full prompt")
+	require.Equal(t, 2, strings.Count(string(calls), "Open a URL"))
+}
@@ -2,25 +2,42 @@ package llmsearch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os/exec"
 	"strings"
+	"time"
 )
 
+// codexDefaultModel is the model used when none is configured
+const codexDefaultModel = "gpt-5-codex-mini"
+
+func init() {
+	RegisterProvider(Provider{
+		Name:         "codex",
+		DefaultModel: codexDefaultModel,
+		New: func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+			return NewCodexSearcher(cfg.Model, cfg.Timeout, logger)
+		},
+	})
+}
+
 // CodexSearcher uses Codex CLI to semantically match queries against tools
 type CodexSearcher struct {
 	model       string
 	codexBinary string
+	timeout     time.Duration
 	logger      *slog.Logger
 }
 
 // NewCodexSearcher creates a new Codex-based searcher
-func NewCodexSearcher(model string, logger *slog.Logger) (*CodexSearcher, error) {
+// Each CLI call is killed after timeout (DefaultSearchTimeout if zero).
+func NewCodexSearcher(model string, timeout time.Duration, logger *slog.Logger) (*CodexSearcher, error) {
 	// Default to gpt-5-codex-mini if not specified
 	if model == "" {
-		model = "gpt-5-codex-mini"
+		model = codexDefaultModel
 	}
 
 	// Find codex binary
@@ -34,45 +51,48 @@ func NewCodexSearcher(model string, logger *slog.Logger) (*CodexSearcher, error)
 	return &CodexSearcher{
 		model:       model,
 		codexBinary: codexPath,
+		timeout:     timeout,
 		logger:      logger,
 	}, nil
 }
 
-// Generate is not used for Codex searcher (we use direct LLM search instead)
-// This exists for interface compatibility but shouldn't be called
-func (e *CodexSearcher) Generate(text string) ([]float32, error) {
-	return nil, fmt.Errorf("Codex searcher doesn't support Generate() - use SearchTools() directly")
-}
-
-// Dimension returns 0 (Codex uses LLM-based search, not vector embeddings)
-func (e *CodexSearcher) Dimension() int {
-	return 0 // No vector embeddings
-}
-
 // SearchTools uses Codex to find relevant tools for a query
 // Returns tool names ranked by relevance
-func (e *CodexSearcher) SearchTools(query string, toolSchemas []byte, topK int) ([]string, error) {
+func (e *CodexSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
 	// Build prompt for Codex
-	prompt := fmt.Sprintf(`You are helping match a user query to the most relevant tools.
+	prompt := buildSearchPrompt(query, toolSchemas, topK)
 
-Given this query: "%s"
+	e.logger.Debug("Calling Codex CLI", "query", query, "topK", topK)
 
-And these available tools (JSON array with name, description, category, parameters):
-%s
+	responseText, err := e.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
 
-Return ONLY a JSON array of EXACTLY %d tool names, ranked by relevance.
-Format: ["tool_name_1", "tool_name_2", ...]
-IMPORTANT: Return no more and no less than %d tools.
+	rankings, err := parseRankings(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("codex: %w", err)
+	}
 
-Consider:
-- Semantic similarity between query and tool description
-- Tool category and parameters
-- Likely user intent
+	e.logger.Info("Codex search completed", "query", query, "found", len(rankings))
 
-Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK, topK)
+	return rankings, nil
+}
+
+// SearchToolsBatch uses Codex to rank tools for several queries in one call
+func (e *CodexSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	e.logger.Debug("Calling Codex CLI for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, e, "codex", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to the Codex CLI and returns the agent's message text
+func (e *CodexSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
 
 	// Call codex CLI with exec subcommand
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		e.codexBinary,
 		"exec",
 		"--json",
@@ -81,14 +101,15 @@ Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK,
 		prompt,
 	)
 
+	// Don't wait on grandchildren holding the pipes once the CLI is killed
+	cmd.WaitDelay = time.Second
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	e.logger.Debug("Calling Codex CLI", "query", query, "topK", topK)
-
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("codex CLI failed: %w, stderr: %s", err, stderr.String())
+		return "", callError(ctx, "codex CLI", fmt.Errorf("codex CLI failed: %w, stderr: %s", err, stderr.String()))
 	}
 
 	// Log raw response for debugging
@@ -110,37 +131,29 @@ Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK,
 				Type string `json:"type"`
 				Text string `json:"text"`
 			} `json:"item"`
+			Usage struct {
+				InputTokens  int64 `json:"input_tokens"`
+				OutputTokens int64 `json:"output_tokens"`
+			} `json:"usage"`
 		}
 
 		if err := json.Unmarshal([]byte(line), &event); err != nil {
 			continue // Skip lines that don't parse
 		}
 
-		if event.Type == "item.completed" && event.Item.Type == "agent_message" {
+		switch {
+		case event.Type == "item.completed" && event.Item.Type == "agent_message" && responseText == "":
 			responseText = event.Item.Text
 			e.logger.Debug("Parsed Codex response", "text", responseText)
-			break
+		case event.Type == "turn.completed":
+			// Token usage is reported once the turn ends, after the message
+			reportUsage(ctx, Usage{InputTokens: event.Usage.InputTokens, OutputTokens: event.Usage.OutputTokens})
 		}
 	}
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no agent_message in codex response: %s", stdout.String())
+		return "", fmt.Errorf("no agent_message in codex response: %s", stdout.String())
 	}
 
-	// Parse the JSON array of tool names from Codex's response
-	// Codex might wrap it in markdown code blocks, so clean that up
-	responseText = strings.TrimSpace(responseText)
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimPrefix(responseText, "```")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
-
-	var toolNames []string
-	if err := json.Unmarshal([]byte(responseText), &toolNames); err != nil {
-		return nil, fmt.Errorf("failed to parse tool names from codex: %w, text: %s", err, responseText)
-	}
-
-	e.logger.Info("Codex search completed", "query", query, "found", len(toolNames))
-
-	return toolNames, nil
+	return responseText, nil
 }
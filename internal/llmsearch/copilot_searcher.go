@@ -2,25 +2,40 @@ package llmsearch
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log/slog"
 	"os/exec"
-	"strings"
+	"time"
 )
 
+// copilotDefaultModel is the model used when none is configured
+const copilotDefaultModel = "claude-haiku-4.5"
+
+func init() {
+	RegisterProvider(Provider{
+		Name:         "copilot",
+		DefaultModel: copilotDefaultModel,
+		New: func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+			return NewCopilotSearcher(cfg.Model, cfg.Timeout, logger)
+		},
+	})
+}
+
 // CopilotSearcher uses GitHub Copilot CLI to semantically match queries against tools
 type CopilotSearcher struct {
 	model         string
 	copilotBinary string
+	timeout       time.Duration
 	logger        *slog.Logger
 }
 
 // NewCopilotSearcher creates a new Copilot-based searcher
-func NewCopilotSearcher(model string, logger *slog.Logger) (*CopilotSearcher, error) {
+// Each CLI call is killed after timeout (DefaultSearchTimeout if zero).
+func NewCopilotSearcher(model string, timeout time.Duration, logger *slog.Logger) (*CopilotSearcher, error) {
 	// Default to claude-haiku-4.5
 	if model == "" {
-		model = "claude-haiku-4.5"
+		model = copilotDefaultModel
 	}
 
 	// Find copilot binary
@@ -34,70 +49,68 @@ func NewCopilotSearcher(model string, logger *slog.Logger) (*CopilotSearcher, er
 	return &CopilotSearcher{
 		model:         model,
 		copilotBinary: copilotPath,
+		timeout:       timeout,
 		logger:        logger,
 	}, nil
 }
 
 // SearchTools uses GitHub Copilot to find relevant tools for a query
 // Returns tool names ranked by relevance
-func (s *CopilotSearcher) SearchTools(query string, toolSchemas []byte, topK int) ([]string, error) {
+func (s *CopilotSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
 	// Build prompt for Copilot
-	prompt := fmt.Sprintf(`You are helping match a user query to the most relevant tools.
+	prompt := buildSearchPrompt(query, toolSchemas, topK)
 
-Given this query: "%s"
+	s.logger.Debug("Calling Copilot CLI", "query", query, "topK", topK)
 
-And these available tools (JSON array with name, description, category, parameters):
-%s
+	responseText, err := s.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
 
-Return ONLY a JSON array of EXACTLY %d tool names, ranked by relevance.
-Format: ["tool_name_1", "tool_name_2", ...]
-IMPORTANT: Return no more and no less than %d tools.
+	rankings, err := parseRankings(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("copilot: %w", err)
+	}
 
-Consider:
-- Semantic similarity between query and tool description
-- Tool category and parameters
-- Likely user intent
+	s.logger.Info("Copilot search completed", "query", query, "found", len(rankings))
 
-Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK, topK)
+	return rankings, nil
+}
+
+// SearchToolsBatch uses Copilot to rank tools for several queries in one call
+func (s *CopilotSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	s.logger.Debug("Calling Copilot CLI for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, s, "copilot", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to the Copilot CLI and returns the response text
+func (s *CopilotSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
 
 	// Call copilot CLI in non-interactive mode
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		s.copilotBinary,
 		"--model", s.model,
 		"--allow-all-tools",
 		"--prompt", prompt,
 	)
 
+	// Don't wait on grandchildren holding the pipes once the CLI is killed
+	cmd.WaitDelay = time.Second
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	s.logger.Debug("Calling Copilot CLI", "query", query, "topK", topK)
-
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("copilot CLI failed: %w, stderr: %s", err, stderr.String())
+		return "", callError(ctx, "copilot CLI", fmt.Errorf("copilot CLI failed: %w, stderr: %s", err, stderr.String()))
 	}
 
 	// Log raw response for debugging
 	s.logger.Debug("Copilot raw response", "stdout", stdout.String())
 
 	// Copilot returns the response directly in stdout (not wrapped in JSON)
-	responseText := stdout.String()
-
-	// Parse the JSON array of tool names from Copilot's response
-	// Clean up markdown code blocks if present
-	responseText = strings.TrimSpace(responseText)
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimPrefix(responseText, "```")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
-
-	var toolNames []string
-	if err := json.Unmarshal([]byte(responseText), &toolNames); err != nil {
-		return nil, fmt.Errorf("failed to parse tool names from copilot: %w, text: %s", err, responseText)
-	}
-
-	s.logger.Info("Copilot search completed", "query", query, "found", len(toolNames))
-
-	return toolNames, nil
+	return stdout.String(), nil
 }
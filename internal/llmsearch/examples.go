@@ -0,0 +1,62 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// GenerateExamples asks an LLM for up to n example argument objects for a
+// tool, valid against its input schema
+func GenerateExamples(ctx context.Context, c Completer, tool *tools.Tool, n int) ([]map[string]any, error) {
+	schema, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input schema: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`Write %d realistic example invocations of this tool.
+
+Tool: %s
+Description: %s
+Input schema: %s
+
+Each example is a JSON object of arguments that is valid against the input schema: include every required property and use plausible values. Vary the examples, showing optional properties in some of them.
+
+Return ONLY a JSON array of the argument objects, no explanation.`, n, tool.Name, tool.Description, schema)
+
+	response, err := c.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	examples, err := parseExamples(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(examples) > n {
+		examples = examples[:n]
+	}
+	return examples, nil
+}
+
+// parseExamples extracts a JSON array of argument objects from an LLM
+// response, tolerating code fences and stray prose around the array
+func parseExamples(text string) ([]map[string]any, error) {
+	cleaned := stripCodeFence(text)
+
+	var examples []map[string]any
+	if err := json.Unmarshal([]byte(cleaned), &examples); err == nil {
+		return examples, nil
+	}
+	if start := strings.Index(cleaned, "["); start >= 0 {
+		if end := strings.LastIndex(cleaned, "]"); end > start {
+			if err := json.Unmarshal([]byte(cleaned[start:end+1]), &examples); err == nil {
+				return examples, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMalformedResponse, text)
+}
@@ -0,0 +1,102 @@
+package llmsearch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// NamedSearchStore pairs a search store with the provider name used in logs
+type NamedSearchStore struct {
+	Name  string
+	Store SearchStore
+}
+
+// searchReportKey is the context key of the *SearchReport a search fills in
+type searchReportKey struct{}
+
+// SearchReport describes how a fallback chain answered one search
+type SearchReport struct {
+	TimedOut []string // Providers that missed their deadline, in chain order
+}
+
+// WithSearchReport returns a context whose search records into the returned report
+func WithSearchReport(ctx context.Context) (context.Context, *SearchReport) {
+	report := &SearchReport{}
+	return context.WithValue(ctx, searchReportKey{}, report), report
+}
+
+// FallbackSearchStore queries a chain of stores in order, moving on to the next
+// when a store fails or returns nothing for a non-empty catalog.
+type FallbackSearchStore struct {
+	stores []NamedSearchStore
+	logger *slog.Logger
+}
+
+// NewFallbackSearchStore creates a search store that tries the given stores in order
+func NewFallbackSearchStore(stores []NamedSearchStore, logger *slog.Logger) *FallbackSearchStore {
+	return &FallbackSearchStore{
+		stores: stores,
+		logger: logger,
+	}
+}
+
+// BuildFromTools builds every store in the chain
+func (s *FallbackSearchStore) BuildFromTools(allTools []*tools.Tool) error {
+	for _, named := range s.stores {
+		if err := named.Store.BuildFromTools(allTools); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search queries each store in turn until one answers. A timed-out store
+// falls through to the next, and is noted in the context's SearchReport if
+// there is one, but a cancelled caller stops the chain.
+// The last store's results (or error) are returned if none answers.
+func (s *FallbackSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	var results []ScoredTool
+	var err error
+	for i, named := range s.stores {
+		results, err = named.Store.Search(ctx, query, topK)
+		last := i == len(s.stores)-1
+		if err == nil && (len(results) > 0 || named.Store.GetToolCount() == 0 || last) {
+			// A fallback answering means the preferred provider is failing, which should show in the logs
+			level := slog.LevelDebug
+			if i > 0 {
+				level = slog.LevelInfo
+			}
+			s.logger.Log(ctx, level, "Search answered", "provider", named.Name, "query", query, "results", len(results), "fallback", i > 0)
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if report, ok := ctx.Value(searchReportKey{}).(*SearchReport); ok && errors.Is(err, ErrSearchTimeout) {
+			report.TimedOut = append(report.TimedOut, named.Name)
+		}
+		if last {
+			break
+		}
+
+		next := s.stores[i+1].Name
+		if err != nil {
+			s.logger.Warn("Search failed, trying next provider", "provider", named.Name, "next", next, "query", query, "error", err)
+		} else {
+			s.logger.Warn("Search returned no results, trying next provider", "provider", named.Name, "next", next, "query", query)
+		}
+	}
+
+	return results, err
+}
+
+// GetToolCount returns the number of tools indexed
+func (s *FallbackSearchStore) GetToolCount() int {
+	if len(s.stores) == 0 {
+		return 0
+	}
+	return s.stores[0].Store.GetToolCount()
+}
@@ -0,0 +1,345 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// DefaultSchemaBudget is the largest tool schema payload sent in one LLM prompt
+const DefaultSchemaBudget = 200 * 1024
+
+// LLMSearchStore ranks tools by sending the cached tool schemas to a Searcher.
+// Catalogs larger than the schema budget are split into chunks that are
+// ranked separately; the per-chunk finalists are then ranked together.
+type LLMSearchStore struct {
+	name         string
+	searcher     Searcher
+	tools        []*tools.Tool
+	byName       map[string]*tools.Tool
+	toolSchemas  map[string]json.RawMessage // Per-tool JSON schema, for ranking finalists
+	chunks       [][]byte                   // Cached JSON schemas, split to fit schemaBudget
+	schemaBudget int                        // Max schema bytes per prompt
+	prompt       *PromptTemplate            // Custom ranking prompt (nil uses the searcher's own)
+	logger       *slog.Logger
+}
+
+// NewLLMSearchStore creates a search store backed by the given searcher.
+// name identifies the provider in logs and errors.
+func NewLLMSearchStore(name string, searcher Searcher, logger *slog.Logger) *LLMSearchStore {
+	return &LLMSearchStore{
+		name:         name,
+		searcher:     searcher,
+		tools:        make([]*tools.Tool, 0),
+		schemaBudget: DefaultSchemaBudget,
+		logger:       logger,
+	}
+}
+
+// SetSchemaBudget sets the max schema bytes per prompt. It applies from the
+// next BuildFromTools; zero or negative restores DefaultSchemaBudget.
+func (s *LLMSearchStore) SetSchemaBudget(maxBytes int) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSchemaBudget
+	}
+	s.schemaBudget = maxBytes
+}
+
+// SetPromptTemplate replaces the built-in ranking prompt. Searches with a
+// custom prompt go through the searcher's Complete; nil restores the default.
+func (s *LLMSearchStore) SetPromptTemplate(prompt *PromptTemplate) {
+	s.prompt = prompt
+}
+
+// BuildFromTools caches tool schemas for LLM queries
+func (s *LLMSearchStore) BuildFromTools(allTools []*tools.Tool) error {
+	s.logger.Info("Building LLM search store", "provider", s.name, "tool_count", len(allTools))
+
+	s.tools = allTools
+	s.byName = make(map[string]*tools.Tool, len(allTools))
+	s.toolSchemas = make(map[string]json.RawMessage, len(allTools))
+
+	// Marshal each tool's metadata with its full schema, then pack them into chunks
+	var chunks [][]byte
+	var current []json.RawMessage
+	currentSize, totalSize := 0, 0
+	for _, tool := range allTools {
+		s.byName[tool.Name] = tool
+
+		schema, err := json.Marshal(toolSchemaMetadata(tool))
+		if err != nil {
+			return fmt.Errorf("failed to marshal tool schemas: %w", err)
+		}
+		s.toolSchemas[tool.Name] = schema
+		totalSize += len(schema)
+
+		if len(current) > 0 && currentSize+len(schema) > s.schemaBudget {
+			chunks = append(chunks, joinSchemas(current))
+			current, currentSize = nil, 0
+		}
+		current = append(current, schema)
+		currentSize += len(schema) + 1
+	}
+	chunks = append(chunks, joinSchemas(current))
+
+	s.chunks = chunks
+
+	if len(chunks) > 1 {
+		s.logger.Info("Tool schemas exceed prompt budget, splitting into chunks", "provider", s.name, "schema_size_kb", totalSize/1024, "budget_kb", s.schemaBudget/1024, "chunks", len(chunks))
+	}
+	s.logger.Info("LLM search store built", "provider", s.name, "tool_count", len(s.tools), "schema_size_kb", totalSize/1024)
+
+	return nil
+}
+
+// toolSchemaMetadata describes a tool for the ranking prompt, including its full schema
+func toolSchemaMetadata(tool *tools.Tool) tools.ToolMetadata {
+	metadata := tools.ToolMetadata{
+		Name:        tool.Name,
+		Type:        tool.Type,
+		Category:    tool.Category,
+		Description: tool.Description,
+		Examples:    tool.Examples,
+	}
+
+	// Include full schema
+	if tool.InputSchema != nil {
+		if schemaMap, ok := tool.InputSchema.(map[string]any); ok {
+			metadata.Parameters = schemaMap
+		}
+	}
+
+	return metadata
+}
+
+// joinSchemas builds a JSON array from marshaled tool schemas
+func joinSchemas(schemas []json.RawMessage) []byte {
+	joined := []byte{'['}
+	for i, schema := range schemas {
+		if i > 0 {
+			joined = append(joined, ',')
+		}
+		joined = append(joined, schema...)
+	}
+	return append(joined, ']')
+}
+
+// Search ranks the indexed tools for the query, one LLM call per schema chunk
+// plus a final call to rank the chunks' finalists against each other
+func (s *LLMSearchStore) Search(ctx context.Context, query string, topK int) (results []ScoredTool, err error) {
+	ctx, span := startSearchSpan(ctx, s.name, query, topK)
+	defer func() { endSearchSpan(span, results, err) }()
+
+	if len(s.tools) == 0 {
+		return []ScoredTool{}, nil
+	}
+	if len(s.chunks) == 1 {
+		return s.rank(ctx, query, s.chunks[0], topK)
+	}
+
+	type chunkResult struct {
+		tools []ScoredTool
+		err   error
+	}
+	chunkResults := make([]chunkResult, len(s.chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range s.chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ranked, err := s.rank(ctx, query, chunk, topK)
+			chunkResults[i] = chunkResult{tools: ranked, err: err}
+		}()
+	}
+	wg.Wait()
+
+	// Merge per-chunk rankings by score; equal scores interleave the chunks
+	var finalists []ScoredTool
+	var firstErr error
+	for _, result := range chunkResults {
+		if result.err != nil {
+			s.logger.Warn("LLM search failed for schema chunk", "provider", s.name, "query", query, "error", result.err)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+		}
+	}
+	for pos := 0; ; pos++ {
+		added := false
+		for _, result := range chunkResults {
+			if pos < len(result.tools) {
+				finalists = append(finalists, result.tools[pos])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	sortByScore(finalists)
+
+	if len(finalists) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	if len(finalists) <= topK {
+		return finalists, nil
+	}
+
+	// Rank the finalists against each other using their full schemas
+	schemas := make([]json.RawMessage, len(finalists))
+	for i, tool := range finalists {
+		schemas[i] = s.toolSchemas[tool.Name]
+	}
+	ranked, err := s.rank(ctx, query, joinSchemas(schemas), topK)
+	if err != nil {
+		s.logger.Warn("Ranking chunk finalists failed, using merged chunk order", "provider", s.name, "query", query, "error", err)
+		return finalists[:topK], nil
+	}
+
+	return ranked, nil
+}
+
+// SearchBatch ranks the indexed tools for several queries. When the catalog
+// fits in one prompt, all queries share a single LLM call; otherwise (or with
+// a custom prompt, or if the batch response is unusable) each query is
+// searched on its own. Result i holds the tools for queries[i].
+func (s *LLMSearchStore) SearchBatch(ctx context.Context, queries []string, topK int) ([][]ScoredTool, error) {
+	results := make([][]ScoredTool, len(queries))
+	if len(s.tools) == 0 {
+		for i := range results {
+			results[i] = []ScoredTool{}
+		}
+		return results, nil
+	}
+
+	if len(queries) > 1 && len(s.chunks) == 1 && s.prompt == nil {
+		batch, err := s.searcher.SearchToolsBatch(ctx, queries, s.chunks[0], topK)
+		if err == nil {
+			for i, rankings := range batch {
+				resolved, unknown := s.resolve(rankings)
+				if len(unknown) > 0 {
+					s.logger.Warn("Dropped unknown tool names from LLM response", "provider", s.name, "query", queries[i], "unknown", unknown)
+				}
+				results[i] = resolved
+			}
+			return results, nil
+		}
+		s.logger.Warn("Batch LLM search failed, searching queries one by one", "provider", s.name, "queries", len(queries), "error", err)
+	}
+
+	for i, query := range queries {
+		found, err := s.Search(ctx, query, topK)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = found
+	}
+	return results, nil
+}
+
+// rank asks the LLM to rank the tools in schemas for the query. Names that
+// aren't in the catalog are dropped; if the response is malformed or names
+// no known tool at all, the LLM is asked once more with a corrective prompt.
+func (s *LLMSearchStore) rank(ctx context.Context, query string, schemas []byte, topK int) ([]ScoredTool, error) {
+	rankings, err := s.search(ctx, query, schemas, topK)
+	if err != nil && !errors.Is(err, ErrMalformedResponse) {
+		return nil, fmt.Errorf("%s search failed: %w", s.name, err)
+	}
+
+	results, unknown := s.resolve(rankings)
+	if err != nil || (len(results) == 0 && len(unknown) > 0) {
+		problem := "it was not a JSON array of tool names"
+		if err == nil {
+			problem = "none of these tools exist: " + strings.Join(unknown, ", ")
+		}
+		s.logger.Warn("Invalid LLM search response, retrying", "provider", s.name, "query", query, "problem", problem)
+
+		prompt, err := s.buildPrompt(query, schemas, topK)
+		if err != nil {
+			return nil, err
+		}
+		response, err := s.searcher.Complete(ctx, buildCorrectivePrompt(prompt, problem))
+		if err != nil {
+			return nil, fmt.Errorf("%s search failed on retry: %w", s.name, err)
+		}
+		rankings, err = parseRankings(response)
+		if err != nil {
+			return nil, fmt.Errorf("%s search failed on retry: %w", s.name, err)
+		}
+		results, unknown = s.resolve(rankings)
+	}
+
+	if len(unknown) > 0 {
+		s.logger.Warn("Dropped unknown tool names from LLM response", "provider", s.name, "query", query, "unknown", unknown)
+	}
+
+	s.logger.Debug("LLM search results", "provider", s.name, "query", query, "requested", topK, "returned", len(results))
+
+	return results, nil
+}
+
+// search runs one ranking call, through the custom prompt if one is set
+func (s *LLMSearchStore) search(ctx context.Context, query string, schemas []byte, topK int) ([]Ranking, error) {
+	if s.prompt == nil {
+		return s.searcher.SearchTools(ctx, query, schemas, topK)
+	}
+
+	prompt, err := s.prompt.Render(query, schemas, topK)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.searcher.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseRankings(response)
+}
+
+// buildPrompt returns the ranking prompt used for a query
+func (s *LLMSearchStore) buildPrompt(query string, schemas []byte, topK int) (string, error) {
+	if s.prompt == nil {
+		return buildSearchPrompt(query, schemas, topK), nil
+	}
+	return s.prompt.Render(query, schemas, topK)
+}
+
+// resolve maps rankings back to indexed tools ordered by score, skipping
+// duplicates and returning the names that aren't in the catalog
+func (s *LLMSearchStore) resolve(rankings []Ranking) ([]ScoredTool, []string) {
+	results := make([]ScoredTool, 0, len(rankings))
+	var unknown []string
+	seen := make(map[string]bool, len(rankings))
+	for _, ranking := range rankings {
+		if seen[ranking.Name] {
+			continue
+		}
+		seen[ranking.Name] = true
+
+		if tool, ok := s.byName[ranking.Name]; ok {
+			results = append(results, ScoredTool{Tool: tool, Score: ranking.Score})
+		} else {
+			unknown = append(unknown, ranking.Name)
+		}
+	}
+	sortByScore(results)
+	return results, unknown
+}
+
+// sortByScore orders results by descending score, keeping the given order for ties
+func sortByScore(results []ScoredTool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// GetToolCount returns the number of tools indexed
+func (s *LLMSearchStore) GetToolCount() int {
+	return len(s.tools)
+}
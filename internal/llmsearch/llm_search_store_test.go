@@ -0,0 +1,234 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedSearcher returns canned rankings and completions and records retry prompts
+type scriptedSearcher struct {
+	rankings   []Ranking
+	batch      [][]Ranking
+	err        error
+	completion string
+	prompts    []string
+}
+
+func (s *scriptedSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	return s.rankings, s.err
+}
+
+func (s *scriptedSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	if s.batch == nil {
+		return nil, ErrMalformedResponse
+	}
+	return s.batch, nil
+}
+
+func (s *scriptedSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	return s.completion, nil
+}
+
+// TestLLMSearchStore_DropsUnknownNames tests that unknown and duplicate names are dropped without a retry
+func TestLLMSearchStore_DropsUnknownNames(t *testing.T) {
+	searcher := &scriptedSearcher{rankings: []Ranking{{Name: "made_up_tool", Score: 0.9}, {Name: "browser_navigate", Score: 0.8}, {Name: "browser_navigate", Score: 0.8}}}
+	store := indexTestTools(t, NewLLMSearchStore("test", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "open a page", 3)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "browser_navigate", results[0].Name)
+	require.Empty(t, searcher.prompts, "Partially valid responses should not be retried")
+}
+
+// TestLLMSearchStore_RetriesMalformedResponse tests that a malformed response is retried with a corrective prompt
+func TestLLMSearchStore_RetriesMalformedResponse(t *testing.T) {
+	_, parseErr := parseRankings("Sure! The best tool is the browser.")
+	searcher := &scriptedSearcher{err: parseErr, completion: `["browser_screenshot"]`}
+	store := indexTestTools(t, NewLLMSearchStore("test", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "capture the page", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+	require.Len(t, searcher.prompts, 1)
+	require.Contains(t, searcher.prompts[0], "previous answer was rejected")
+}
+
+// TestLLMSearchStore_RetriesHallucinatedNames tests that a response naming only unknown tools is retried once
+func TestLLMSearchStore_RetriesHallucinatedNames(t *testing.T) {
+	searcher := &scriptedSearcher{rankings: []Ranking{{Name: "take_picture", Score: 1}}, completion: "still not sure"}
+	store := indexTestTools(t, NewLLMSearchStore("test", searcher, testLogger()))
+
+	_, err := store.Search(context.Background(), "capture the page", 1)
+	require.ErrorIs(t, err, ErrMalformedResponse)
+	require.Len(t, searcher.prompts, 1)
+	require.Contains(t, searcher.prompts[0], "take_picture")
+}
+
+// TestLLMSearchStore_OrdersByScore tests that results are ordered by the LLM's scores
+func TestLLMSearchStore_OrdersByScore(t *testing.T) {
+	searcher := &scriptedSearcher{rankings: []Ranking{{Name: "browser_navigate", Score: 0.3}, {Name: "browser_screenshot", Score: 0.9}}}
+	store := indexTestTools(t, NewLLMSearchStore("test", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "capture the page", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+	require.Equal(t, 0.9, results[0].Score)
+}
+
+// TestLLMSearchStore_PromptTemplate tests that a custom prompt template is filled in and used
+func TestLLMSearchStore_PromptTemplate(t *testing.T) {
+	prompt, err := ParsePromptTemplate(`Prefer read-only tools. Query: {{.Query}}. Pick {{.TopK}} of {{.Schemas}}`)
+	require.NoError(t, err)
+
+	searcher := &scriptedSearcher{completion: `[{"name": "browser_screenshot", "score": 0.8}]`}
+	store := NewLLMSearchStore("test", searcher, testLogger())
+	store.SetPromptTemplate(prompt)
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	results, err := store.Search(context.Background(), "capture the page", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+	require.Len(t, searcher.prompts, 1)
+	require.True(t, strings.HasPrefix(searcher.prompts[0], "Prefer read-only tools. Query: capture the page. Pick 1 of ["))
+	require.Contains(t, searcher.prompts[0], "browser_navigate")
+}
+
+// TestParsePromptTemplate_Invalid tests that broken templates and unknown fields are rejected
+func TestParsePromptTemplate_Invalid(t *testing.T) {
+	_, err := ParsePromptTemplate("{{.Query")
+	require.Error(t, err)
+
+	_, err = ParsePromptTemplate("{{.Tools}}")
+	require.Error(t, err, "Unknown fields should be rejected up front")
+}
+
+// TestLLMSearchStore_SearchBatch tests that a batch is ranked in one call and
+// each query's rankings are checked against the catalog
+func TestLLMSearchStore_SearchBatch(t *testing.T) {
+	searcher := &scriptedSearcher{batch: [][]Ranking{
+		{{Name: "browser_navigate", Score: 0.9}},
+		{{Name: "made_up_tool", Score: 0.9}, {Name: "browser_screenshot", Score: 0.7}},
+	}}
+	store := NewLLMSearchStore("test", searcher, testLogger())
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	results, err := store.SearchBatch(context.Background(), []string{"open a page", "capture the page"}, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "browser_navigate", results[0][0].Name)
+	require.Len(t, results[1], 1, "Unknown tool names should be dropped")
+	require.Equal(t, "browser_screenshot", results[1][0].Name)
+}
+
+// TestLLMSearchStore_SearchBatchFallsBackPerQuery tests that a failed batch is
+// retried one query at a time
+func TestLLMSearchStore_SearchBatchFallsBackPerQuery(t *testing.T) {
+	searcher := &echoSearcher{}
+	store := NewLLMSearchStore("test", searcher, testLogger())
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	results, err := store.SearchBatch(context.Background(), []string{"one", "two", "three"}, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, 3, searcher.calls, "A failed batch should be retried one query at a time")
+}
+
+// TestParseBatchRankings tests that batch responses are parsed into one ranking
+// per query and incomplete ones rejected
+func TestParseBatchRankings(t *testing.T) {
+	rankings, err := parseBatchRankings("```json\n"+`[[{"name": "a", "score": 0.9}], ["b", "c"]]`+"\n```", 2)
+	require.NoError(t, err)
+	require.Equal(t, [][]Ranking{{{Name: "a", Score: 0.9}}, {{Name: "b", Score: 1}, {Name: "c", Score: 0.5}}}, rankings)
+
+	_, err = parseBatchRankings(`[["a"]]`, 2)
+	require.ErrorIs(t, err, ErrMalformedResponse, "A missing result list should be rejected")
+
+	_, err = parseBatchRankings("no lists here", 1)
+	require.ErrorIs(t, err, ErrMalformedResponse)
+}
+
+// TestParseRankings tests that the response formats LLMs produce are all parsed
+func TestParseRankings(t *testing.T) {
+	expected := []Ranking{{Name: "a", Score: 1}, {Name: "b", Score: 0.5}}
+	for _, text := range []string{
+		`[{"name": "a", "score": 1}, {"name": "b", "score": 0.5}]`,
+		`["a", "b"]`,
+		"```json\n[\"a\", \"b\"]\n```",
+		`{"tools": [{"name": "a", "score": 1.7}, {"name": "b", "score": 0.5}]}`,
+		`Here are the tools: ["a", "b"] — hope that helps.`,
+	} {
+		rankings, err := parseRankings(text)
+		require.NoError(t, err, text)
+		require.Equal(t, expected, rankings, text)
+	}
+
+	_, err := parseRankings("no list here")
+	require.ErrorIs(t, err, ErrMalformedResponse)
+}
+
+// echoSearcher ranks the tools of each prompt in the order given and counts calls
+type echoSearcher struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *echoSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	var metadata []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(toolSchemas, &metadata); err != nil {
+		return nil, err
+	}
+	rankings := make([]Ranking, 0, topK)
+	for i := len(metadata) - 1; i >= 0 && len(rankings) < topK; i-- {
+		rankings = append(rankings, Ranking{Name: metadata[i].Name, Score: 0.5})
+	}
+	return rankings, nil
+}
+
+func (s *echoSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	return nil, ErrMalformedResponse
+}
+
+func (s *echoSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	return "", nil
+}
+
+// TestLLMSearchStore_ChunksLargeCatalogs tests that a catalog over the budget is searched chunk by chunk
+func TestLLMSearchStore_ChunksLargeCatalogs(t *testing.T) {
+	searcher := &echoSearcher{}
+	store := NewLLMSearchStore("test", searcher, testLogger())
+	store.SetSchemaBudget(1) // One tool per chunk
+	require.NoError(t, store.BuildFromTools(testTools()))
+	require.Len(t, store.chunks, len(testTools()))
+
+	results, err := store.Search(context.Background(), "anything", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, len(testTools())+1, searcher.calls, "One call per chunk plus one to rank the finalists")
+}
+
+// TestLLMSearchStore_SingleChunkWithinBudget tests that a catalog within the budget takes one call
+func TestLLMSearchStore_SingleChunkWithinBudget(t *testing.T) {
+	searcher := &echoSearcher{}
+	store := indexTestTools(t, NewLLMSearchStore("test", searcher, testLogger()))
+	require.Len(t, store.chunks, 1)
+
+	_, err := store.Search(context.Background(), "anything", 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, searcher.calls)
+}
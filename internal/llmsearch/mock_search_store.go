@@ -1,6 +1,7 @@
 package llmsearch
 
 import (
+	"context"
 	"log/slog"
 	"strings"
 
@@ -30,9 +31,9 @@ func (s *MockSearchStore) BuildFromTools(allTools []*tools.Tool) error {
 }
 
 // Search performs simple keyword matching for testing
-func (s *MockSearchStore) Search(query string, topK int) ([]*tools.Tool, error) {
+func (s *MockSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
 	if len(s.tools) == 0 {
-		return []*tools.Tool{}, nil
+		return []ScoredTool{}, nil
 	}
 
 	// Simple keyword matching - check if query words appear in tool name or description
@@ -79,10 +80,14 @@ func (s *MockSearchStore) Search(query string, topK int) ([]*tools.Tool, error)
 		}
 	}
 
-	// Return top K results
-	results := make([]*tools.Tool, 0, topK)
+	// Return top K results, scaling scores by the best possible match (name+description+category per word)
+	results := make([]ScoredTool, 0, topK)
 	for i := 0; i < len(scored) && i < topK; i++ {
-		results = append(results, scored[i].tool)
+		score := 0.0
+		if len(queryWords) > 0 {
+			score = float64(scored[i].score) / float64(6*len(queryWords))
+		}
+		results = append(results, ScoredTool{Tool: scored[i].tool, Score: score})
 	}
 
 	s.logger.Debug("Mock search completed", "query", query, "found", len(results))
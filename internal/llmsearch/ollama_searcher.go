@@ -0,0 +1,177 @@
+package llmsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ollamaDefaultModel is the model used when none is configured
+const ollamaDefaultModel = "llama3.2"
+
+func init() {
+	RegisterProvider(Provider{
+		Name:         "ollama",
+		DefaultModel: ollamaDefaultModel,
+		New: func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+			return NewOllamaSearcher(cfg.BaseURL, cfg.Model, cfg.Timeout, logger)
+		},
+	})
+}
+
+// OllamaSearcher uses a local Ollama model over HTTP to semantically match queries against tools
+type OllamaSearcher struct {
+	model   string
+	baseURL string
+	client  *http.Client
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewOllamaSearcher creates a new Ollama-based searcher.
+// It checks that the Ollama server is reachable before returning. Each chat
+// call is abandoned after timeout (DefaultSearchTimeout if zero).
+func NewOllamaSearcher(baseURL, model string, timeout time.Duration, logger *slog.Logger) (*OllamaSearcher, error) {
+	// Default to llama3.2 on the standard local endpoint if not specified
+	if model == "" {
+		model = ollamaDefaultModel
+	}
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	searcher := &OllamaSearcher{
+		model:   model,
+		baseURL: baseURL,
+		client:  &http.Client{},
+		timeout: timeout,
+		logger:  logger,
+	}
+
+	// Check the server is up (lists local models)
+	probe := &http.Client{Timeout: 3 * time.Second}
+	resp, err := probe.Get(baseURL + "/api/tags")
+	if err != nil {
+		return nil, fmt.Errorf("ollama not reachable at %s: %w", baseURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama not reachable at %s: status %d", baseURL, resp.StatusCode)
+	}
+
+	logger.Info("Created Ollama searcher", "model", model, "url", baseURL)
+
+	return searcher, nil
+}
+
+// SearchTools uses Ollama to find relevant tools for a query
+// Returns tool names ranked by relevance
+func (s *OllamaSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	prompt := buildSearchPrompt(query, toolSchemas, topK)
+
+	s.logger.Debug("Calling Ollama", "query", query, "topK", topK)
+
+	// Constrain the output to a JSON array of ranked tools
+	format := map[string]any{
+		"type":  "array",
+		"items": rankingSchema,
+	}
+
+	responseText, err := s.chat(ctx, prompt, format)
+	if err != nil {
+		return nil, err
+	}
+
+	rankings, err := parseRankings(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+
+	s.logger.Info("Ollama search completed", "query", query, "found", len(rankings))
+
+	return rankings, nil
+}
+
+// SearchToolsBatch uses Ollama to rank tools for several queries in one call
+func (s *OllamaSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	s.logger.Debug("Calling Ollama for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, s, "ollama", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to Ollama and returns the response text
+func (s *OllamaSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	return s.chat(ctx, prompt, nil)
+}
+
+// chat calls the Ollama /api/chat endpoint without streaming.
+// format optionally constrains the output with a JSON schema.
+func (s *OllamaSearcher) chat(ctx context.Context, prompt string, format any) (string, error) {
+	request := map[string]any{
+		"model": s.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream": false,
+	}
+	if format != nil {
+		request["format"] = format
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ollama request: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", callError(ctx, "ollama", fmt.Errorf("ollama request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ollama response: %w", err)
+	}
+
+	// Log raw response for debugging
+	s.logger.Debug("Ollama raw response", "status", resp.StatusCode, "body", string(data))
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	// The API returns: {"message":{"role":"assistant","content":"..."},"prompt_eval_count":N,"eval_count":N, ...}
+	var response struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int64 `json:"prompt_eval_count"`
+		EvalCount       int64 `json:"eval_count"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("failed to parse ollama response: %w, output: %s", err, string(data))
+	}
+	reportUsage(ctx, Usage{InputTokens: response.PromptEvalCount, OutputTokens: response.EvalCount})
+
+	if response.Message.Content == "" {
+		return "", fmt.Errorf("no content in ollama response")
+	}
+
+	return response.Message.Content, nil
+}
@@ -0,0 +1,66 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newMockOllama starts a fake Ollama server answering /api/chat with the given content
+func newMockOllama(t *testing.T, content string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[]}`))
+		case "/api/chat":
+			var request map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			require.Equal(t, "test-model", request["model"])
+			require.Equal(t, false, request["stream"])
+
+			json.NewEncoder(w).Encode(map[string]any{
+				"message": map[string]any{"role": "assistant", "content": content},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestOllamaSearcher_Search tests searching through an Ollama server
+func TestOllamaSearcher_Search(t *testing.T) {
+	server := newMockOllama(t, `["browser_navigate", "unknown_tool"]`)
+
+	searcher, err := NewOllamaSearcher(server.URL, "test-model", 0, testLogger())
+	require.NoError(t, err)
+
+	store := indexTestTools(t, NewLLMSearchStore("ollama", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "open a page", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "Unknown tool names should be dropped")
+	require.Equal(t, "browser_navigate", results[0].Name)
+}
+
+// TestOllamaSearcher_MalformedResponse tests that a free-text answer is an error
+func TestOllamaSearcher_MalformedResponse(t *testing.T) {
+	server := newMockOllama(t, "I think you want the browser tool")
+
+	searcher, err := NewOllamaSearcher(server.URL, "test-model", 0, testLogger())
+	require.NoError(t, err)
+
+	_, err = searcher.SearchTools(context.Background(), "open a page", []byte("[]"), 2)
+	require.Error(t, err)
+}
+
+// TestNewOllamaSearcher_Unreachable tests that an unreachable server is reported up front
+func TestNewOllamaSearcher_Unreachable(t *testing.T) {
+	_, err := NewOllamaSearcher("http://127.0.0.1:1", "test-model", 0, testLogger())
+	require.Error(t, err)
+}
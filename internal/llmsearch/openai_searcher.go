@@ -0,0 +1,205 @@
+package llmsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// openaiDefaultModel is the model used when none is configured
+const openaiDefaultModel = "gpt-4o-mini"
+
+func init() {
+	RegisterProvider(Provider{
+		Name:         "openai",
+		DefaultModel: openaiDefaultModel,
+		New: func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+			return NewOpenAISearcher(cfg.APIKey, cfg.BaseURL, cfg.Model, cfg.Timeout, logger)
+		},
+	})
+}
+
+// OpenAISearcher calls the OpenAI chat completions API directly to semantically match queries against tools
+type OpenAISearcher struct {
+	model   string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewOpenAISearcher creates a new OpenAI API-based searcher.
+// The API key falls back to the OPENAI_API_KEY environment variable.
+// baseURL may point to any OpenAI-compatible endpoint. Each call is abandoned
+// after timeout (DefaultSearchTimeout if zero).
+func NewOpenAISearcher(apiKey, baseURL, model string, timeout time.Duration, logger *slog.Logger) (*OpenAISearcher, error) {
+	// Default to gpt-4o-mini on the public API if not specified
+	if model == "" {
+		model = openaiDefaultModel
+	}
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("openai API key not configured: set openaiAPIKey or OPENAI_API_KEY")
+	}
+
+	logger.Info("Created OpenAI searcher", "model", model, "url", baseURL)
+
+	return &OpenAISearcher{
+		model:   model,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{},
+		timeout: timeout,
+		logger:  logger,
+	}, nil
+}
+
+// SearchTools uses the OpenAI API to find relevant tools for a query
+// Returns tool names ranked by relevance
+func (s *OpenAISearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	prompt := buildSearchPrompt(query, toolSchemas, topK)
+
+	s.logger.Debug("Calling OpenAI API", "query", query, "topK", topK)
+
+	// Structured output: the model must return {"tools": [{"name": ..., "score": ...}, ...]}
+	responseFormat := map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   "ranked_tools",
+			"strict": true,
+			"schema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"tools": map[string]any{
+						"type":  "array",
+						"items": rankingSchema,
+					},
+				},
+				"required":             []string{"tools"},
+				"additionalProperties": false,
+			},
+		},
+	}
+
+	responseText, err := s.chat(ctx, prompt, responseFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	rankings, err := parseRankings(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+
+	s.logger.Info("OpenAI search completed", "query", query, "found", len(rankings))
+
+	return rankings, nil
+}
+
+// SearchToolsBatch uses the OpenAI API to rank tools for several queries in one call
+func (s *OpenAISearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	s.logger.Debug("Calling OpenAI API for a batch", "queries", len(queries), "topK", topK)
+	return searchBatch(ctx, s, "openai", queries, toolSchemas, topK)
+}
+
+// Complete sends a prompt to the OpenAI API and returns the response text
+func (s *OpenAISearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	return s.chat(ctx, prompt, nil)
+}
+
+// chat calls the chat completions endpoint. responseFormat optionally
+// requests structured output.
+func (s *OpenAISearcher) chat(ctx context.Context, prompt string, responseFormat any) (string, error) {
+	messages := []map[string]string{
+		{"role": "user", "content": prompt},
+	}
+	if responseFormat != nil {
+		messages = append([]map[string]string{
+			{"role": "system", "content": "Respond with a JSON object whose \"tools\" field holds the ranked tools."},
+		}, messages...)
+	}
+
+	request := map[string]any{
+		"model":    s.model,
+		"messages": messages,
+	}
+	if responseFormat != nil {
+		request["response_format"] = responseFormat
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal openai request: %w", err)
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create openai request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", callError(ctx, "openai", fmt.Errorf("openai request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read openai response: %w", err)
+	}
+
+	// Log raw response for debugging
+	s.logger.Debug("OpenAI raw response", "status", resp.StatusCode, "body", string(data))
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("openai returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	// The API returns: {"choices":[{"message":{"content":"..."}}],"usage":{...}, ...}
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+				Refusal string `json:"refusal"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("failed to parse openai response: %w, output: %s", err, string(data))
+	}
+	reportUsage(ctx, Usage{InputTokens: response.Usage.PromptTokens, OutputTokens: response.Usage.CompletionTokens})
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no choices in openai response")
+	}
+	if refusal := response.Choices[0].Message.Refusal; refusal != "" {
+		return "", fmt.Errorf("openai refused the request: %s", refusal)
+	}
+	if response.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no content in openai response")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,112 @@
+package llmsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newMockOpenAI starts a fake OpenAI API answering /chat/completions with the given content
+func newMockOpenAI(t *testing.T, content string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+			return
+		}
+
+		var request map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "test-model", request["model"])
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"role": "assistant", "content": content}},
+			},
+			"usage": map[string]any{"prompt_tokens": 120, "completion_tokens": 15},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestOpenAISearcher_Search tests searching through the OpenAI API
+func TestOpenAISearcher_Search(t *testing.T) {
+	server := newMockOpenAI(t, `{"tools": ["browser_navigate", "unknown_tool"]}`)
+
+	searcher, err := NewOpenAISearcher("test-key", server.URL, "test-model", 0, testLogger())
+	require.NoError(t, err)
+
+	store := indexTestTools(t, NewLLMSearchStore("openai", searcher, testLogger()))
+
+	results, err := store.Search(context.Background(), "open a page", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "Unknown tool names should be dropped")
+	require.Equal(t, "browser_navigate", results[0].Name)
+}
+
+// TestOpenAISearcher_ReportsUsage tests that token usage of searches and completions is metered
+func TestOpenAISearcher_ReportsUsage(t *testing.T) {
+	server := newMockOpenAI(t, `{"tools": ["browser_navigate"]}`)
+
+	searcher, err := NewOpenAISearcher("test-key", server.URL, "test-model", 0, testLogger())
+	require.NoError(t, err)
+	stats := NewUsageStats()
+	metered := NewMeteredSearcher("openai", searcher, stats, testLogger())
+
+	_, err = metered.SearchTools(context.Background(), "open a page", []byte(`[]`), 1)
+	require.NoError(t, err)
+	_, err = metered.Complete(context.Background(), "translate this")
+	require.NoError(t, err)
+
+	usage := stats.Snapshot()
+	require.Len(t, usage, 1)
+	require.Equal(t, "openai", usage[0].Provider)
+	require.Equal(t, int64(2), usage[0].Calls)
+	require.Zero(t, usage[0].Errors)
+	require.Equal(t, int64(240), usage[0].InputTokens)
+	require.Equal(t, int64(30), usage[0].OutputTokens)
+}
+
+// TestMeteredSearcher_SlowCalls tests that calls reaching the slow threshold are counted
+func TestMeteredSearcher_SlowCalls(t *testing.T) {
+	stats := NewUsageStats()
+	metered := NewMeteredSearcher("test", &scriptedSearcher{}, stats, testLogger())
+
+	_, err := metered.SearchTools(context.Background(), "open a page", []byte(`[]`), 1)
+	require.NoError(t, err)
+	require.Zero(t, stats.Snapshot()[0].SlowCalls, "No threshold counts nothing as slow")
+
+	stats.SetSlowThreshold(time.Nanosecond)
+	_, err = metered.SearchTools(context.Background(), "open a page", []byte(`[]`), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Snapshot()[0].SlowCalls)
+}
+
+// TestOpenAISearcher_BadKey tests that a rejected API key is reported
+func TestOpenAISearcher_BadKey(t *testing.T) {
+	server := newMockOpenAI(t, `{"tools": []}`)
+
+	searcher, err := NewOpenAISearcher("wrong-key", server.URL, "test-model", 0, testLogger())
+	require.NoError(t, err)
+
+	_, err = searcher.SearchTools(context.Background(), "open a page", []byte("[]"), 2)
+	require.ErrorContains(t, err, "status 401")
+}
+
+// TestNewOpenAISearcher_MissingKey tests that an API key is required
+func TestNewOpenAISearcher_MissingKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+
+	_, err := NewOpenAISearcher("", "", "", 0, testLogger())
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package llmsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedResponse is returned when an LLM response contains no usable list of tool names
+var ErrMalformedResponse = errors.New("malformed LLM response")
+
+// parseRankings extracts a ranked list of tools from an LLM response.
+// Besides a bare JSON array it accepts code fences, a {"tools": [...]} object,
+// and an array surrounded by stray prose. Entries may be {"name", "score"}
+// objects or plain names; plain names get scores estimated from their rank.
+func parseRankings(text string) ([]Ranking, error) {
+	cleaned := stripCodeFence(text)
+
+	if rankings, ok := decodeRankings(cleaned); ok {
+		return rankings, nil
+	}
+
+	var wrapped struct {
+		Tools json.RawMessage `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(cleaned), &wrapped); err == nil && wrapped.Tools != nil {
+		if rankings, ok := decodeRankings(string(wrapped.Tools)); ok {
+			return rankings, nil
+		}
+	}
+
+	// Models sometimes add a sentence before or after the array
+	if start := strings.Index(cleaned, "["); start >= 0 {
+		if end := strings.LastIndex(cleaned, "]"); end > start {
+			if rankings, ok := decodeRankings(cleaned[start : end+1]); ok {
+				return rankings, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrMalformedResponse, text)
+}
+
+// parseBatchRankings extracts one ranked list per query from a batch
+// response, which must be a JSON array of exactly n rankings arrays
+func parseBatchRankings(text string, n int) ([][]Ranking, error) {
+	cleaned := stripCodeFence(text)
+
+	var lists []json.RawMessage
+	if err := json.Unmarshal([]byte(cleaned), &lists); err != nil {
+		// Models sometimes add a sentence before or after the array
+		start, end := strings.Index(cleaned, "["), strings.LastIndex(cleaned, "]")
+		if start < 0 || end <= start || json.Unmarshal([]byte(cleaned[start:end+1]), &lists) != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformedResponse, text)
+		}
+	}
+	if len(lists) != n {
+		return nil, fmt.Errorf("%w: expected %d result lists, got %d", ErrMalformedResponse, n, len(lists))
+	}
+
+	results := make([][]Ranking, n)
+	for i, list := range lists {
+		rankings, ok := decodeRankings(string(list))
+		if !ok {
+			return nil, fmt.Errorf("%w: result list %d: %s", ErrMalformedResponse, i+1, string(list))
+		}
+		results[i] = rankings
+	}
+	return results, nil
+}
+
+// decodeRankings decodes a JSON array of rankings or of plain tool names
+func decodeRankings(text string) ([]Ranking, bool) {
+	var rankings []Ranking
+	if err := json.Unmarshal([]byte(text), &rankings); err == nil {
+		for i := range rankings {
+			if rankings[i].Name == "" {
+				return nil, false
+			}
+			rankings[i].Score = clampScore(rankings[i].Score)
+		}
+		return rankings, true
+	}
+
+	var names []string
+	if err := json.Unmarshal([]byte(text), &names); err != nil {
+		return nil, false
+	}
+	rankings = make([]Ranking, len(names))
+	for i, name := range names {
+		rankings[i] = Ranking{Name: name, Score: positionalScore(i, len(names))}
+	}
+	return rankings, true
+}
+
+// positionalScore estimates a score from a rank when the LLM gave none:
+// 1 for the first of n results, decreasing linearly to 1/n for the last
+func positionalScore(rank, n int) float64 {
+	return float64(n-rank) / float64(n)
+}
+
+// clampScore keeps a score within [0, 1]
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
@@ -0,0 +1,138 @@
+package llmsearch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// buildSearchPrompt builds the tool ranking prompt sent to LLM searchers
+func buildSearchPrompt(query string, toolSchemas []byte, topK int) string {
+	return fmt.Sprintf(`You are helping match a user query to the most relevant tools.
+
+Given this query: "%s"
+
+And these available tools (JSON array with name, description, category, parameters):
+%s
+
+Return ONLY a JSON array of EXACTLY %d tools, ranked by relevance, each with a
+confidence score from 0 (unrelated) to 1 (exactly what the query asks for).
+Format: [{"name": "tool_name_1", "score": 0.95}, {"name": "tool_name_2", "score": 0.4}, ...]
+IMPORTANT: Return no more and no less than %d tools.
+
+Consider:
+- Semantic similarity between query and tool description
+- Tool category and parameters
+- Likely user intent
+
+Score honestly: a tool that is merely the least bad match should get a low score.
+
+Return ONLY the JSON array, no explanation.`, query, string(toolSchemas), topK, topK)
+}
+
+// buildSessionSearchPrompt ranks tools for a query in a conversation that
+// already received the tool list through buildSearchPrompt
+func buildSessionSearchPrompt(query string, topK int) string {
+	return fmt.Sprintf(`Using the same list of available tools as before, rank the tools for a new query.
+
+Given this query: "%s"
+
+Return ONLY a JSON array of EXACTLY %d tools, ranked by relevance, each with a
+confidence score from 0 (unrelated) to 1 (exactly what the query asks for).
+Format: [{"name": "tool_name_1", "score": 0.95}, {"name": "tool_name_2", "score": 0.4}, ...]
+IMPORTANT: Return no more and no less than %d tools, and only tools from that list.
+
+Return ONLY the JSON array, no explanation.`, query, topK, topK)
+}
+
+// buildBatchSearchPrompt builds a prompt ranking tools for several queries at once
+func buildBatchSearchPrompt(queries []string, toolSchemas []byte, topK int) string {
+	var numbered strings.Builder
+	for i, query := range queries {
+		fmt.Fprintf(&numbered, "%d. %q\n", i+1, query)
+	}
+
+	return fmt.Sprintf(`You are helping match user queries to the most relevant tools.
+
+Given these %d queries:
+%s
+And these available tools (JSON array with name, description, category, parameters):
+%s
+
+For EACH query, rank the %d most relevant tools, each with a confidence score
+from 0 (unrelated) to 1 (exactly what the query asks for).
+Return ONLY a JSON array with one inner array per query, in the same order as the queries.
+Format: [[{"name": "tool_name_1", "score": 0.95}, ...], [{"name": "tool_name_2", "score": 0.8}, ...]]
+IMPORTANT: Return exactly %d inner arrays.
+
+Score honestly: a tool that is merely the least bad match should get a low score.
+
+Return ONLY the JSON array, no explanation.`, len(queries), numbered.String(), string(toolSchemas), topK, len(queries))
+}
+
+// buildCorrectivePrompt asks again for a ranking after the previous response was rejected
+func buildCorrectivePrompt(prompt, problem string) string {
+	return prompt + fmt.Sprintf(`
+
+Your previous answer was rejected: %s.
+Use only tool names that appear in the list above, spelled exactly as given.`, problem)
+}
+
+// PromptTemplate is a user-supplied ranking prompt in text/template syntax.
+// Templates can use {{.Query}}, {{.Schemas}} (the tools as a JSON array) and
+// {{.TopK}}, and should ask for the same JSON array format as the default.
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// promptData is the data a PromptTemplate is executed with
+type promptData struct {
+	Query   string
+	Schemas string
+	TopK    int
+}
+
+// ParsePromptTemplate parses a ranking prompt template
+func ParsePromptTemplate(text string) (*PromptTemplate, error) {
+	tmpl, err := template.New("search-prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search prompt template: %w", err)
+	}
+
+	// Catch references to unknown fields now rather than on the first query
+	if err := tmpl.Execute(&bytes.Buffer{}, promptData{}); err != nil {
+		return nil, fmt.Errorf("invalid search prompt template: %w", err)
+	}
+	return &PromptTemplate{tmpl: tmpl}, nil
+}
+
+// LoadPromptTemplate reads and parses a ranking prompt template file
+func LoadPromptTemplate(path string) (*PromptTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search prompt template: %w", err)
+	}
+	return ParsePromptTemplate(string(data))
+}
+
+// Render executes the template for a query
+func (p *PromptTemplate) Render(query string, toolSchemas []byte, topK int) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, promptData{Query: query, Schemas: string(toolSchemas), TopK: topK}); err != nil {
+		return "", fmt.Errorf("failed to render search prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// rankingSchema is the JSON schema of one ranked tool, for providers with structured output
+var rankingSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name":  map[string]any{"type": "string"},
+		"score": map[string]any{"type": "number"},
+	},
+	"required":             []string{"name", "score"},
+	"additionalProperties": false,
+}
@@ -0,0 +1,88 @@
+package llmsearch
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderConfig holds the settings a provider needs to create its searcher.
+// Providers ignore fields they don't use.
+type ProviderConfig struct {
+	Model   string        // Model name (empty uses the provider default)
+	BaseURL string        // API endpoint for HTTP providers
+	APIKey  string        // API key for HTTP providers
+	Timeout time.Duration // Deadline for a single LLM call
+}
+
+// Provider describes a search provider selectable via settings.searchProvider
+type Provider struct {
+	Name         string
+	DefaultModel string
+	New          func(cfg ProviderConfig, logger *slog.Logger) (Searcher, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// RegisterProvider makes a provider available by name. Providers register
+// themselves from init, so adding one only takes a new searcher file.
+func RegisterProvider(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if _, exists := providers[p.Name]; exists {
+		panic("llmsearch: provider registered twice: " + p.Name)
+	}
+	providers[p.Name] = p
+}
+
+// IsProvider reports whether a provider with the given name is registered
+func IsProvider(name string) bool {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	_, ok := providers[name]
+	return ok
+}
+
+// ProviderNames returns the registered provider names in sorted order
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultModel returns the model a provider uses when none is configured
+func DefaultModel(name string) string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	return providers[name].DefaultModel
+}
+
+// NewSearcher creates the searcher for the named provider
+func NewSearcher(name string, cfg ProviderConfig, logger *slog.Logger) (Searcher, error) {
+	providersMu.RLock()
+	p, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown search provider: %s (supported: %s)", name, strings.Join(ProviderNames(), ", "))
+	}
+
+	if cfg.Model == "" {
+		cfg.Model = p.DefaultModel
+	}
+	return p.New(cfg, logger)
+}
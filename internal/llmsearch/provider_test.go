@@ -0,0 +1,32 @@
+package llmsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewSearcher_UnknownProvider tests that unknown providers list the supported ones
+func TestNewSearcher_UnknownProvider(t *testing.T) {
+	_, err := NewSearcher("nope", ProviderConfig{}, testLogger())
+	require.ErrorContains(t, err, "supported: claude, codex, copilot, ollama, openai")
+}
+
+// TestNewSearcher_ClaudeFallsBackToAPI tests that claude uses the API when the CLI is missing
+func TestNewSearcher_ClaudeFallsBackToAPI(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // No claude CLI
+
+	searcher, err := NewSearcher("claude", ProviderConfig{APIKey: "test-key"}, testLogger())
+	require.NoError(t, err)
+	require.IsType(t, &AnthropicSearcher{}, searcher)
+	require.Equal(t, "claude-haiku-4-5", searcher.(*AnthropicSearcher).model)
+}
+
+// TestNewSearcher_ClaudeWithoutCLIOrKey tests that claude needs either the CLI or an API key
+func TestNewSearcher_ClaudeWithoutCLIOrKey(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	_, err := NewSearcher("claude", ProviderConfig{}, testLogger())
+	require.Error(t, err)
+}
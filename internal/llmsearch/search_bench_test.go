@@ -0,0 +1,124 @@
+package llmsearch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// benchmarkSizes are the catalog sizes searches are benchmarked at
+var benchmarkSizes = []int{100, 1000, 10000}
+
+// benchmarkQueries are searched in turn, so no single query's terms dominate
+var benchmarkQueries = []string{
+	"take a screenshot of the page",
+	"read the contents of a file",
+	"create a pull request",
+	"query the database for recent orders",
+	"send a message to the team channel",
+}
+
+// benchmarkCatalog returns n tools spread over a few servers, with
+// descriptions varied enough to give the index a realistic vocabulary
+func benchmarkCatalog(n int) []*tools.Tool {
+	servers := []string{"browser", "filesystem", "github", "postgres", "slack", "jira", "docker", "kubernetes"}
+	verbs := []string{"read", "write", "list", "create", "delete", "update", "search", "query", "send", "take"}
+	objects := []string{"file", "page", "screenshot", "issue", "pull request", "table", "message", "channel", "container", "pod", "branch", "order"}
+
+	catalog := make([]*tools.Tool, n)
+	for i := range catalog {
+		server := servers[i%len(servers)]
+		verb := verbs[(i/len(servers))%len(verbs)]
+		object := objects[(i/(len(servers)*len(verbs)))%len(objects)]
+		catalog[i] = &tools.Tool{
+			Name:        fmt.Sprintf("%s_%s_%s_%d", server, verb, object, i),
+			Category:    server,
+			Description: fmt.Sprintf("%s a %s on %s, variant %d", verb, object, server, i),
+		}
+	}
+	return catalog
+}
+
+// benchmarkSearch runs b.N searches on a store built from each catalog size
+func benchmarkSearch(b *testing.B, newStore func() SearchStore) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("tools=%d", size), func(b *testing.B) {
+			store := newStore()
+			if err := store.BuildFromTools(benchmarkCatalog(size)); err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Search(ctx, benchmarkQueries[i%len(benchmarkQueries)], 5); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTFIDFSearchStore_Build measures indexing catalogs of each size
+func BenchmarkTFIDFSearchStore_Build(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("tools=%d", size), func(b *testing.B) {
+			catalog := benchmarkCatalog(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := NewTFIDFSearchStore(testLogger()).BuildFromTools(catalog); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTFIDFSearchStore_Search measures local TF-IDF searches
+func BenchmarkTFIDFSearchStore_Search(b *testing.B) {
+	benchmarkSearch(b, func() SearchStore { return NewTFIDFSearchStore(testLogger()) })
+}
+
+// BenchmarkTFIDFSearchStore_SearchParallel measures local TF-IDF searches
+// running concurrently on one store, as when several agents search at once
+func BenchmarkTFIDFSearchStore_SearchParallel(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("tools=%d", size), func(b *testing.B) {
+			store := NewTFIDFSearchStore(testLogger())
+			if err := store.BuildFromTools(benchmarkCatalog(size)); err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, err := store.Search(ctx, benchmarkQueries[i%len(benchmarkQueries)], 5); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkLLMSearchStore_Search measures the local work of an LLM search
+// (chunking, prompt building and resolving rankings) with an instant searcher
+func BenchmarkLLMSearchStore_Search(b *testing.B) {
+	benchmarkSearch(b, func() SearchStore { return NewLLMSearchStore("bench", &echoSearcher{}, testLogger()) })
+}
+
+// BenchmarkFallbackSearchStore_Search measures a failing provider falling
+// back to the TF-IDF index, as when the LLM is unavailable
+func BenchmarkFallbackSearchStore_Search(b *testing.B) {
+	benchmarkSearch(b, func() SearchStore {
+		return NewFallbackSearchStore([]NamedSearchStore{
+			{Name: "failing", Store: newFailingSearchStore(testLogger())},
+			{Name: "tfidf", Store: NewTFIDFSearchStore(testLogger())},
+		}, testLogger())
+	})
+}
@@ -0,0 +1,396 @@
+package llmsearch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSearchStore is a SearchStore whose Search always fails
+type failingSearchStore struct {
+	MockSearchStore
+}
+
+// newFailingSearchStore creates a failing store over an empty mock store
+func newFailingSearchStore(logger *slog.Logger) *failingSearchStore {
+	return &failingSearchStore{MockSearchStore: *NewMockSearchStore(logger)}
+}
+
+func (s *failingSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	return nil, fmt.Errorf("cli not available")
+}
+
+// timeoutSearchStore misses its deadline on every search
+type timeoutSearchStore struct {
+	MockSearchStore
+}
+
+func (s *timeoutSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	return nil, fmt.Errorf("%w: claude did not respond in time", ErrSearchTimeout)
+}
+
+// countingSearchStore counts Search calls on the wrapped mock store
+type countingSearchStore struct {
+	MockSearchStore
+	calls int
+}
+
+// newCountingSearchStore creates a counting store over an empty mock store
+func newCountingSearchStore(logger *slog.Logger) *countingSearchStore {
+	return &countingSearchStore{MockSearchStore: *NewMockSearchStore(logger)}
+}
+
+func (s *countingSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	s.calls++
+	return s.MockSearchStore.Search(ctx, query, topK)
+}
+
+// testLogger returns a logger that only reports errors, to keep test output quiet
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// testTools returns a small browser and filesystem catalog
+func testTools() []*tools.Tool {
+	return []*tools.Tool{
+		{Name: "browser_screenshot", Category: "browser", Description: "Take a screenshot of the current page"},
+		{Name: "browser_navigate", Category: "browser", Description: "Navigate the browser to a URL"},
+		{Name: "filesystem_read_file", Category: "filesystem", Description: "Read the contents of a file"},
+		{Name: "filesystem_write_file", Category: "filesystem", Description: "Write data to a file"},
+	}
+}
+
+// indexTestTools builds a search store from testTools and returns it
+func indexTestTools[S SearchStore](t *testing.T, store S) S {
+	t.Helper()
+	require.NoError(t, store.BuildFromTools(testTools()))
+	return store
+}
+
+// TestTFIDFSearchStore_RanksByRelevance tests that the best-matching tool ranks first
+func TestTFIDFSearchStore_RanksByRelevance(t *testing.T) {
+	store := indexTestTools(t, NewTFIDFSearchStore(testLogger()))
+	require.Equal(t, 4, store.GetToolCount())
+
+	results, err := store.Search(context.Background(), "take a screenshot", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+
+	results, err = store.Search(context.Background(), "read file", 5)
+	require.NoError(t, err)
+	require.Equal(t, "filesystem_read_file", results[0].Name)
+}
+
+// TestTFIDFSearchStore_EmptyQueryAndLimit tests that an empty query lists tools up to the limit and an unrelated one finds nothing
+func TestTFIDFSearchStore_EmptyQueryAndLimit(t *testing.T) {
+	store := indexTestTools(t, NewTFIDFSearchStore(testLogger()))
+
+	results, err := store.Search(context.Background(), "", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	results, err = store.Search(context.Background(), "unrelated gibberish", 5)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+// TestTFIDFSearchStore_IndexesExamples tests that usage examples are searchable
+func TestTFIDFSearchStore_IndexesExamples(t *testing.T) {
+	allTools := testTools()
+	allTools[1].Examples = []string{"open example.com in the browser"}
+
+	store := NewTFIDFSearchStore(testLogger())
+	require.NoError(t, store.BuildFromTools(allTools))
+
+	results, err := store.Search(context.Background(), "open example.com", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, "browser_navigate", results[0].Name)
+}
+
+// TestTFIDFSearchStore_TopKMatchesFullRanking tests that the best k results
+// are the first k of the full ranking, ties broken by name
+func TestTFIDFSearchStore_TopKMatchesFullRanking(t *testing.T) {
+	catalog := benchmarkCatalog(500)
+	store := NewTFIDFSearchStore(testLogger())
+	require.NoError(t, store.BuildFromTools(catalog))
+
+	for _, query := range append(benchmarkQueries, "") {
+		all, err := store.Search(context.Background(), query, len(catalog))
+		require.NoError(t, err)
+		require.True(t, sort.SliceIsSorted(all, func(i, j int) bool {
+			if all[i].Score != all[j].Score {
+				return all[i].Score > all[j].Score
+			}
+			return all[i].Name < all[j].Name
+		}), "Results should be ranked by score, then name")
+
+		for _, k := range []int{1, 5, 37} {
+			top, err := store.Search(context.Background(), query, k)
+			require.NoError(t, err)
+			require.Equal(t, all[:min(k, len(all))], top, "query %q, k %d", query, k)
+		}
+	}
+}
+
+// TestTFIDFSearchStore_ConcurrentSearch tests that searches running while the
+// store is re-indexed each see a complete index
+func TestTFIDFSearchStore_ConcurrentSearch(t *testing.T) {
+	store := NewTFIDFSearchStore(testLogger())
+	require.NoError(t, store.BuildFromTools(benchmarkCatalog(100)))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := range 200 {
+				results, err := store.Search(context.Background(), benchmarkQueries[i%len(benchmarkQueries)], 5)
+				require.NoError(t, err)
+				require.Len(t, results, 5)
+			}
+		})
+	}
+	for i := range 20 {
+		require.NoError(t, store.BuildFromTools(benchmarkCatalog(100+i*10)))
+	}
+	wg.Wait()
+}
+
+// TestFallbackSearchStore_UsesFallbackOnError tests that a failing store falls through to the next
+func TestFallbackSearchStore_UsesFallbackOnError(t *testing.T) {
+	logger := testLogger()
+	primary := newFailingSearchStore(logger)
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{{"primary", primary}, {"tfidf", NewTFIDFSearchStore(logger)}}, logger))
+
+	results, err := store.Search(context.Background(), "navigate url", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, "browser_navigate", results[0].Name)
+}
+
+// TestFallbackSearchStore_LogsFallbackAnswers tests that an answer from a
+// fallback is logged at info level, and one from the first store is not
+func TestFallbackSearchStore_LogsFallbackAnswers(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{{"mock", NewMockSearchStore(logger)}, {"tfidf", NewTFIDFSearchStore(logger)}}, logger))
+	_, err := store.Search(context.Background(), "write", 5)
+	require.NoError(t, err)
+	require.NotContains(t, logs.String(), "Search answered")
+
+	store = indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{{"primary", newFailingSearchStore(logger)}, {"tfidf", NewTFIDFSearchStore(logger)}}, logger))
+	_, err = store.Search(context.Background(), "navigate url", 5)
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), `level=INFO msg="Search answered" provider=tfidf`)
+}
+
+// TestFallbackSearchStore_ReportsTimeouts tests that timed-out stores are
+// noted in the search report while the chain moves on
+func TestFallbackSearchStore_ReportsTimeouts(t *testing.T) {
+	logger := testLogger()
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{
+		{"claude", &timeoutSearchStore{MockSearchStore: *NewMockSearchStore(logger)}},
+		{"codex", newFailingSearchStore(logger)},
+		{"tfidf", NewTFIDFSearchStore(logger)},
+	}, logger))
+
+	ctx, report := WithSearchReport(context.Background())
+	results, err := store.Search(ctx, "navigate url", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, []string{"claude"}, report.TimedOut, "Only timeouts should be reported")
+}
+
+// TestFallbackSearchStore_StopsWhenCancelled tests that a cancelled caller ends the chain
+func TestFallbackSearchStore_StopsWhenCancelled(t *testing.T) {
+	logger := testLogger()
+	primary := newFailingSearchStore(logger)
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{{"primary", primary}, {"tfidf", NewTFIDFSearchStore(logger)}}, logger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.Search(ctx, "navigate url", 5)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// TestFallbackSearchStore_PrefersPrimary tests that the first store answers when it can
+func TestFallbackSearchStore_PrefersPrimary(t *testing.T) {
+	logger := testLogger()
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{{"mock", NewMockSearchStore(logger)}, {"tfidf", NewTFIDFSearchStore(logger)}}, logger))
+
+	results, err := store.Search(context.Background(), "write", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "filesystem_write_file", results[0].Name)
+}
+
+// TestFallbackSearchStore_TriesChainInOrder tests that stores are tried in order until one finds results
+func TestFallbackSearchStore_TriesChainInOrder(t *testing.T) {
+	logger := testLogger()
+	failing := newFailingSearchStore(logger)
+	counting := newCountingSearchStore(logger)
+	store := NewFallbackSearchStore([]NamedSearchStore{
+		{"failing", failing},
+		{"mock", counting},
+		{"tfidf", NewTFIDFSearchStore(logger)},
+	}, logger)
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	// The mock store answers keyword matches itself
+	results, err := store.Search(context.Background(), "navigate", 5)
+	require.NoError(t, err)
+	require.Equal(t, "browser_navigate", results[0].Name)
+	require.Equal(t, 1, counting.calls)
+
+	// The mock store only splits on whitespace and finds nothing, so TF-IDF answers
+	results, err = store.Search(context.Background(), "navigate,page", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, 2, counting.calls)
+}
+
+// TestCachedSearchStore_CachesByQueryAndTopK tests that results are cached per normalized query and topK
+func TestCachedSearchStore_CachesByQueryAndTopK(t *testing.T) {
+	logger := testLogger()
+	inner := newCountingSearchStore(logger)
+	store := NewCachedSearchStore(inner, time.Minute, logger)
+	stats := NewCacheStats()
+	store.SetStats(stats)
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	first, err := store.Search(context.Background(), "read file", 5)
+	require.NoError(t, err)
+	second, err := store.Search(context.Background(), "  Read   FILE ", 5)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, 1, inner.calls, "Normalized query should hit the cache")
+
+	_, err = store.Search(context.Background(), "read file", 3)
+	require.NoError(t, err)
+	require.Equal(t, 2, inner.calls, "Different topK should miss the cache")
+	require.Equal(t, CacheUsage{Hits: 1, Misses: 2, HitRate: 1.0 / 3}, stats.Snapshot())
+}
+
+// TestCachedSearchStore_ExpiresAndTracksCatalog tests that entries expire and don't outlive a catalog change
+func TestCachedSearchStore_ExpiresAndTracksCatalog(t *testing.T) {
+	logger := testLogger()
+	inner := newCountingSearchStore(logger)
+	store := NewCachedSearchStore(inner, time.Minute, logger)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	require.NoError(t, store.BuildFromTools(testTools()))
+
+	store.Search(context.Background(), "navigate", 5)
+	now = now.Add(2 * time.Minute)
+	store.Search(context.Background(), "navigate", 5)
+	require.Equal(t, 2, inner.calls, "Expired entry should miss the cache")
+
+	// Changing the catalog changes the key
+	require.NoError(t, store.BuildFromTools(testTools()[:2]))
+	store.Search(context.Background(), "navigate", 5)
+	require.Equal(t, 3, inner.calls, "New catalog should miss the cache")
+}
+
+// TestCachedSearchStore_DoesNotCacheErrors tests that failed searches are retried rather than cached
+func TestCachedSearchStore_DoesNotCacheErrors(t *testing.T) {
+	logger := testLogger()
+	store := indexTestTools(t, NewCachedSearchStore(newFailingSearchStore(logger), time.Minute, logger))
+
+	_, err := store.Search(context.Background(), "anything", 5)
+	require.Error(t, err)
+	require.Empty(t, store.entries)
+}
+
+// fakeCompleter returns a canned completion and counts calls
+type fakeCompleter struct {
+	response string
+	calls    int
+	deadline time.Time // Deadline of the last call's context
+}
+
+func (c *fakeCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	c.calls++
+	c.deadline, _ = ctx.Deadline()
+	return c.response, nil
+}
+
+// TestGenerateExamples tests that generated examples are parsed and malformed ones rejected
+func TestGenerateExamples(t *testing.T) {
+	tool := &tools.Tool{
+		Name:        "fs_read_file",
+		Description: "Read a file",
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+	}
+
+	completer := &fakeCompleter{response: "Here you go:\n```json\n[{\"path\": \"README.md\"}, {\"path\": \"go.mod\"}, {\"path\": \"main.go\"}]\n```"}
+	examples, err := GenerateExamples(context.Background(), completer, tool, 2)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{"path": "README.md"}, {"path": "go.mod"}}, examples)
+
+	_, err = GenerateExamples(context.Background(), &fakeCompleter{response: "no examples"}, tool, 2)
+	require.ErrorIs(t, err, ErrMalformedResponse)
+}
+
+// TestLooksNonEnglish tests that non-English queries are detected without flagging English ones
+func TestLooksNonEnglish(t *testing.T) {
+	require.True(t, LooksNonEnglish("captura de pantalla"))
+	require.True(t, LooksNonEnglish("écrire un fichier"))
+	require.True(t, LooksNonEnglish("スクリーンショット"))
+	require.False(t, LooksNonEnglish("take a screenshot"))
+	require.False(t, LooksNonEnglish("read file from example.com"))
+	require.True(t, LooksNonEnglish("crear una captura de pantalla"))
+	require.True(t, LooksNonEnglish("datei mit text"))
+
+	// A single loanword or abbreviation is not enough
+	require.False(t, LooksNonEnglish("read config en masse"))
+	require.False(t, LooksNonEnglish("con file"))
+	require.False(t, LooksNonEnglish("del old branches"))
+	require.False(t, LooksNonEnglish("list das volumes on host"))
+	require.False(t, LooksNonEnglish("de facto"))
+}
+
+// TestTranslatingSearchStore_TranslatesNonEnglishQueries tests that non-English queries are translated once and English ones not at all
+func TestTranslatingSearchStore_TranslatesNonEnglishQueries(t *testing.T) {
+	logger := testLogger()
+	translator := &fakeCompleter{response: "```\n\"take a screenshot\"\n```"}
+	store := indexTestTools(t, NewTranslatingSearchStore(NewTFIDFSearchStore(logger), "claude", translator, logger))
+
+	results, err := store.Search(context.Background(), "captura de pantalla", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, "browser_screenshot", results[0].Name)
+	require.WithinDuration(t, time.Now().Add(translateTimeout), translator.deadline, time.Second, "Translation should have a short deadline of its own")
+
+	// Translations are cached and English queries are not translated
+	store.Search(context.Background(), "captura de pantalla", 5)
+	store.Search(context.Background(), "read file", 5)
+	require.Equal(t, 1, translator.calls)
+}
+
+// TestTranslatingSearchStore_SkipsTimedOutTranslator tests that a query isn't
+// translated by a provider that already timed out on the same search
+func TestTranslatingSearchStore_SkipsTimedOutTranslator(t *testing.T) {
+	logger := testLogger()
+	translator := &fakeCompleter{response: "take a screenshot"}
+	store := indexTestTools(t, NewFallbackSearchStore([]NamedSearchStore{
+		{"claude", &timeoutSearchStore{MockSearchStore: *NewMockSearchStore(logger)}},
+		{"tfidf", NewTranslatingSearchStore(NewTFIDFSearchStore(logger), "claude", translator, logger)},
+	}, logger))
+
+	ctx, report := WithSearchReport(context.Background())
+	_, err := store.Search(ctx, "captura de pantalla", 5)
+	require.NoError(t, err)
+	require.Equal(t, []string{"claude"}, report.TimedOut)
+	require.Zero(t, translator.calls)
+}
@@ -0,0 +1,225 @@
+package llmsearch
+
+import (
+	"container/heap"
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// TFIDFSearchStore is a local lexical search store using TF-IDF weighted cosine similarity.
+// It needs no external CLI and is used as a fallback when LLM search fails.
+// Searches may run concurrently with each other and with re-indexing.
+type TFIDFSearchStore struct {
+	index  atomic.Pointer[tfidfIndex]
+	logger *slog.Logger
+}
+
+// tfidfIndex is a built TF-IDF index. It is never changed once built:
+// re-indexing swaps in a new one, so searches never see a half-built index.
+type tfidfIndex struct {
+	tools    []*tools.Tool
+	postings map[string][]posting // Tools containing each term
+	idf      map[string]float64   // Inverse document frequency per term
+}
+
+// posting is a term's weight in the normalized TF-IDF vector of a tool
+type posting struct {
+	tool   int // Index into tfidfIndex.tools
+	weight float64
+}
+
+// NewTFIDFSearchStore creates a local TF-IDF search store
+func NewTFIDFSearchStore(logger *slog.Logger) *TFIDFSearchStore {
+	store := &TFIDFSearchStore{logger: logger}
+	store.index.Store(&tfidfIndex{})
+	return store
+}
+
+// BuildFromTools computes TF-IDF vectors for all tools
+func (s *TFIDFSearchStore) BuildFromTools(allTools []*tools.Tool) error {
+	docs := make([][]string, len(allTools))
+	df := make(map[string]int)
+	for i, tool := range allTools {
+		docs[i] = tokenize(createSearchableText(tool))
+
+		seen := make(map[string]bool)
+		for _, term := range docs[i] {
+			if !seen[term] {
+				seen[term] = true
+				df[term]++
+			}
+		}
+	}
+
+	idf := make(map[string]float64, len(df))
+	for term, count := range df {
+		idf[term] = math.Log(float64(len(allTools)+1)/float64(count+1)) + 1
+	}
+
+	// Searches only visit the tools sharing a term with the query
+	postings := make(map[string][]posting, len(df))
+	for i, doc := range docs {
+		for term, weight := range weigh(doc, idf) {
+			postings[term] = append(postings[term], posting{tool: i, weight: weight})
+		}
+	}
+
+	s.index.Store(&tfidfIndex{tools: allTools, postings: postings, idf: idf})
+
+	s.logger.Info("Built TF-IDF search store", "tool_count", len(allTools), "terms", len(idf))
+	return nil
+}
+
+// Search ranks tools by cosine similarity between the query and tool TF-IDF vectors
+func (s *TFIDFSearchStore) Search(ctx context.Context, query string, topK int) (results []ScoredTool, err error) {
+	_, span := startSearchSpan(ctx, "tfidf", query, topK)
+	defer func() { endSearchSpan(span, results, err) }()
+
+	index := s.index.Load()
+	if len(index.tools) == 0 || topK <= 0 {
+		return []ScoredTool{}, nil
+	}
+
+	queryTerms := tokenize(query)
+
+	var scores []float64 // Nil for an empty query, which lists tools in a stable order
+	if len(queryTerms) > 0 {
+		scores = make([]float64, len(index.tools))
+		queryVector := weigh(queryTerms, index.idf)
+		// Terms are added in a fixed order, so equal scores come out equal
+		// and ties rank the same on every search
+		for _, term := range slices.Sorted(maps.Keys(queryVector)) {
+			weight := queryVector[term]
+			for _, p := range index.postings[term] {
+				scores[p.tool] += weight * p.weight
+			}
+		}
+	}
+
+	// Both vectors have unit length, so scores are cosine similarities in [0, 1]
+	results = topTools(index.tools, scores, topK)
+
+	s.logger.Debug("TF-IDF search completed", "query", query, "found", len(results))
+
+	return results, nil
+}
+
+// GetToolCount returns the number of tools indexed
+func (s *TFIDFSearchStore) GetToolCount() int {
+	return len(s.index.Load().tools)
+}
+
+// topTools returns the k best-scored tools, by score and then name. Tools
+// scoring 0 are left out unless scores is nil, which ranks all tools by name.
+// A heap of the best k so far avoids sorting every match.
+func topTools(allTools []*tools.Tool, scores []float64, k int) []ScoredTool {
+	ranking := &toolRanking{tools: allTools, scores: scores, top: make([]int, 0, min(k, len(allTools)))}
+	for i := range allTools {
+		if scores != nil && scores[i] <= 0 {
+			continue
+		}
+		switch {
+		case len(ranking.top) < k:
+			ranking.top = append(ranking.top, i)
+			if len(ranking.top) == k {
+				heap.Init(ranking)
+			}
+		case ranking.above(i, ranking.top[0]):
+			ranking.top[0] = i
+			heap.Fix(ranking, 0)
+		}
+	}
+
+	sort.Slice(ranking.top, func(i, j int) bool { return ranking.above(ranking.top[i], ranking.top[j]) })
+	results := make([]ScoredTool, len(ranking.top))
+	for i, tool := range ranking.top {
+		results[i] = ScoredTool{Tool: allTools[tool], Score: ranking.score(tool)}
+	}
+	return results
+}
+
+// toolRanking is a min-heap of tool indexes, the worst-ranked on top
+type toolRanking struct {
+	tools  []*tools.Tool
+	scores []float64
+	top    []int
+}
+
+// score returns the score of tool i (0 without scores)
+func (r *toolRanking) score(i int) float64 {
+	if r.scores == nil {
+		return 0
+	}
+	return r.scores[i]
+}
+
+// above reports whether tool i ranks above tool j
+func (r *toolRanking) above(i, j int) bool {
+	if si, sj := r.score(i), r.score(j); si != sj {
+		return si > sj
+	}
+	if r.tools[i].Name != r.tools[j].Name {
+		return r.tools[i].Name < r.tools[j].Name
+	}
+	return i < j // Keep catalog order, as a stable sort would
+}
+
+func (r *toolRanking) Len() int           { return len(r.top) }
+func (r *toolRanking) Less(i, j int) bool { return r.above(r.top[j], r.top[i]) }
+func (r *toolRanking) Swap(i, j int)      { r.top[i], r.top[j] = r.top[j], r.top[i] }
+func (r *toolRanking) Push(x any)         { r.top = append(r.top, x.(int)) }
+func (r *toolRanking) Pop() any {
+	last := r.top[len(r.top)-1]
+	r.top = r.top[:len(r.top)-1]
+	return last
+}
+
+// createSearchableText builds the text indexed for a tool.
+// The name is repeated so name matches weigh more than description matches.
+// Usage examples from config are appended to improve recall.
+func createSearchableText(tool *tools.Tool) string {
+	parts := []string{tool.Name, tool.Name, tool.Category, tool.Description}
+	parts = append(parts, tool.Examples...)
+	return strings.Join(parts, " ")
+}
+
+// tokenize lowercases text and splits it on anything that isn't a letter or digit
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// weigh builds a unit-length TF-IDF vector for the given terms.
+// Terms unknown to the index are ignored.
+func weigh(terms []string, idf map[string]float64) map[string]float64 {
+	vector := make(map[string]float64)
+	for _, term := range terms {
+		if weight, ok := idf[term]; ok {
+			vector[term] += weight
+		}
+	}
+
+	norm := 0.0
+	for _, weight := range vector {
+		norm += weight * weight
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return vector
+	}
+
+	for term := range vector {
+		vector[term] /= norm
+	}
+	return vector
+}
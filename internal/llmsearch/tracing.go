@@ -0,0 +1,28 @@
+package llmsearch
+
+import (
+	"context"
+
+	"github.com/radutopala/onemcp/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces searches
+var tracer = otel.Tracer("github.com/radutopala/onemcp/internal/llmsearch")
+
+// startSearchSpan starts the span of one provider's search
+func startSearchSpan(ctx context.Context, provider, query string, topK int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "search "+provider, trace.WithAttributes(
+		attribute.String("onemcp.search.provider", provider),
+		attribute.String("onemcp.search.query", query),
+		attribute.Int("onemcp.search.top_k", topK),
+	))
+}
+
+// endSearchSpan records the outcome of a search and ends its span
+func endSearchSpan(span trace.Span, results []ScoredTool, err error) {
+	span.SetAttributes(attribute.Int("onemcp.search.results", len(results)))
+	telemetry.EndSpan(span, err)
+}
@@ -0,0 +1,142 @@
+package llmsearch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// foreignStopwords are common function words of frequent non-English query languages
+// (Spanish, French, German, Portuguese, Italian)
+var foreignStopwords = map[string]bool{
+	"de": true, "del": true, "la": true, "el": true, "los": true, "las": true, "una": true, "para": true, "con": true, "por": true, "que": true, "en": true,
+	"le": true, "les": true, "des": true, "du": true, "une": true, "pour": true, "avec": true, "dans": true, "sur": true,
+	"der": true, "das": true, "und": true, "mit": true, "ein": true, "eine": true, "für": true, "von": true, "auf": true,
+	"uma": true, "com": true, "dos": true, "il": true, "della": true, "di": true,
+}
+
+// LooksNonEnglish reports whether a query is likely not written in English:
+// it contains non-ASCII letters or non-English function words. One stray
+// function word, as in "con file" or "read config en masse", isn't enough: it
+// takes two, or one joining the words of a short query ("captura de pantalla").
+func LooksNonEnglish(query string) bool {
+	for _, r := range query {
+		if unicode.IsLetter(r) && r > unicode.MaxASCII {
+			return true
+		}
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	hits, joining := 0, false
+	for i, word := range words {
+		if foreignStopwords[word] {
+			hits++
+			joining = joining || (i > 0 && i < len(words)-1)
+		}
+	}
+	return hits >= 2 || (joining && 3*hits >= len(words))
+}
+
+// translateTimeout bounds a query translation, which is a short prompt; the
+// translator's own search timeout applies if it is shorter
+const translateTimeout = 10 * time.Second
+
+// TranslatingSearchStore translates non-English queries to English using an LLM
+// before searching the wrapped store, so lexical indexes still match them.
+type TranslatingSearchStore struct {
+	store      SearchStore
+	provider   string // Name of the translator's provider
+	translator Completer
+	mu         sync.Mutex
+	cache      map[string]string // Original query -> English translation
+	logger     *slog.Logger
+}
+
+// NewTranslatingSearchStore wraps a search store with query translation by
+// the given provider's completer
+func NewTranslatingSearchStore(store SearchStore, provider string, translator Completer, logger *slog.Logger) *TranslatingSearchStore {
+	return &TranslatingSearchStore{
+		store:      store,
+		provider:   provider,
+		translator: translator,
+		cache:      make(map[string]string),
+		logger:     logger,
+	}
+}
+
+// BuildFromTools builds the wrapped store
+func (s *TranslatingSearchStore) BuildFromTools(allTools []*tools.Tool) error {
+	return s.store.BuildFromTools(allTools)
+}
+
+// Search translates non-English queries before searching. If translation fails
+// the original query is searched, as it is without translating when the
+// translator's provider already timed out on this search.
+func (s *TranslatingSearchStore) Search(ctx context.Context, query string, topK int) ([]ScoredTool, error) {
+	if LooksNonEnglish(query) {
+		if report, ok := ctx.Value(searchReportKey{}).(*SearchReport); ok && slices.Contains(report.TimedOut, s.provider) {
+			s.logger.Info("Translator timed out on this search, searching original query", "query", query, "provider", s.provider)
+			return s.store.Search(ctx, query, topK)
+		}
+
+		translated, err := s.translate(ctx, query)
+		if err != nil {
+			s.logger.Warn("Query translation failed, searching original query", "query", query, "error", err)
+		} else {
+			s.logger.Info("Translated non-English query", "query", query, "translated", translated)
+			query = translated
+		}
+	}
+
+	return s.store.Search(ctx, query, topK)
+}
+
+// GetToolCount returns the number of tools indexed
+func (s *TranslatingSearchStore) GetToolCount() int {
+	return s.store.GetToolCount()
+}
+
+// translate returns the English translation of a query, using cached translations when possible
+func (s *TranslatingSearchStore) translate(ctx context.Context, query string) (string, error) {
+	s.mu.Lock()
+	translated, ok := s.cache[query]
+	s.mu.Unlock()
+	if ok {
+		return translated, nil
+	}
+
+	prompt := fmt.Sprintf(`Translate this search query for software tools into concise English.
+If it is already English, return it unchanged.
+
+Query: "%s"
+
+Return ONLY the translated query, no quotes and no explanation.`, query)
+
+	ctx, cancel := context.WithTimeout(ctx, translateTimeout)
+	defer cancel()
+	response, err := s.translator.Complete(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	translated = strings.Trim(stripCodeFence(response), "\"' \n")
+	if translated == "" {
+		return "", fmt.Errorf("empty translation")
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCacheEntries {
+		s.cache = make(map[string]string) // Start over rather than grow without bound
+	}
+	s.cache[query] = translated
+	s.mu.Unlock()
+
+	return translated, nil
+}
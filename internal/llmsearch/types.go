@@ -1,15 +1,102 @@
 package llmsearch
 
-import "github.com/radutopala/onemcp/internal/tools"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
 
 // SearchStore defines the interface for LLM-based semantic search
 type SearchStore interface {
 	// BuildFromTools prepares the search store with all available tools
 	BuildFromTools(allTools []*tools.Tool) error
 
-	// Search finds tools semantically similar to the query using LLM
-	Search(query string, topK int) ([]*tools.Tool, error)
+	// Search finds tools semantically similar to the query using LLM, best first
+	Search(ctx context.Context, query string, topK int) ([]ScoredTool, error)
 
 	// GetToolCount returns the number of tools indexed
 	GetToolCount() int
 }
+
+// ScoredTool is a search hit with its relevance score in [0, 1]
+type ScoredTool struct {
+	*tools.Tool
+	Score float64
+}
+
+// Ranking is a tool name ranked by an LLM, with its relevance score in [0, 1]
+type Ranking struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// Completer sends a free-form prompt to an LLM and returns its text response
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// Searcher ranks tools for a query using an LLM. Implementations also
+// answer free-form prompts, e.g. for query translation.
+type Searcher interface {
+	Completer
+
+	// SearchTools ranks the tools in toolSchemas most relevant to query, best first
+	SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error)
+
+	// SearchToolsBatch ranks tools for several queries in a single LLM call.
+	// Result i holds the rankings for queries[i].
+	SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error)
+}
+
+// searchBatch implements SearchToolsBatch for searchers on top of Complete
+func searchBatch(ctx context.Context, c Completer, provider string, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	responseText, err := c.Complete(ctx, buildBatchSearchPrompt(queries, toolSchemas, topK))
+	if err != nil {
+		return nil, err
+	}
+
+	rankings, err := parseBatchRankings(responseText, len(queries))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	return rankings, nil
+}
+
+// DefaultSearchTimeout bounds a single LLM call when no timeout is configured
+const DefaultSearchTimeout = 60 * time.Second
+
+// ErrSearchTimeout is returned when an LLM call does not finish within its timeout
+var ErrSearchTimeout = errors.New("search_timeout")
+
+// withTimeout bounds ctx by timeout, using DefaultSearchTimeout when timeout is unset
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultSearchTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// callError reports a failed LLM call, wrapping ErrSearchTimeout when ctx's deadline passed
+func callError(ctx context.Context, provider string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s did not respond in time", ErrSearchTimeout, provider)
+	}
+	return err
+}
+
+// stripCodeFence removes surrounding whitespace and markdown code fences from an LLM response
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,177 @@
+package llmsearch
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Usage is the token usage and cost reported for one LLM call. Providers
+// that don't report usage leave it zero.
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+}
+
+// usageKey is the context key of the *Usage a metered call collects into
+type usageKey struct{}
+
+// reportUsage adds usage reported by a provider to the metered call in ctx
+func reportUsage(ctx context.Context, usage Usage) {
+	if acc, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		acc.InputTokens += usage.InputTokens
+		acc.OutputTokens += usage.OutputTokens
+		acc.CostUSD += usage.CostUSD
+	}
+}
+
+// ProviderUsage summarizes the LLM calls made through one provider
+type ProviderUsage struct {
+	Provider       string  `json:"provider"`
+	Calls          int64   `json:"calls"`
+	Errors         int64   `json:"errors"`
+	TotalLatencyMs int64   `json:"total_latency_ms"`
+	AvgLatencyMs   int64   `json:"avg_latency_ms"`
+	MaxLatencyMs   int64   `json:"max_latency_ms"`
+	SlowCalls      int64   `json:"slow_calls"` // Calls that took at least the slow threshold
+	InputTokens    int64   `json:"input_tokens"`
+	OutputTokens   int64   `json:"output_tokens"`
+	CostUSD        float64 `json:"cost_usd"`
+}
+
+// UsageStats accumulates call counts, latency and token usage per provider
+type UsageStats struct {
+	mu            sync.Mutex
+	providers     map[string]*ProviderUsage
+	slowThreshold time.Duration // Latency above which a call is slow, 0 for never
+}
+
+// NewUsageStats creates an empty usage accumulator
+func NewUsageStats() *UsageStats {
+	return &UsageStats{providers: make(map[string]*ProviderUsage)}
+}
+
+// SetSlowThreshold sets the latency at which calls are counted and logged as
+// slow; zero or negative disables it
+func (u *UsageStats) SetSlowThreshold(threshold time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.slowThreshold = max(threshold, 0)
+}
+
+// record adds one call to a provider's totals. It returns the slow threshold
+// if the call exceeded it, or zero.
+func (u *UsageStats) record(provider string, latency time.Duration, usage Usage, err error) (slow time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	p, ok := u.providers[provider]
+	if !ok {
+		p = &ProviderUsage{Provider: provider}
+		u.providers[provider] = p
+	}
+
+	p.Calls++
+	if err != nil {
+		p.Errors++
+	}
+	ms := latency.Milliseconds()
+	p.TotalLatencyMs += ms
+	p.MaxLatencyMs = max(p.MaxLatencyMs, ms)
+	p.AvgLatencyMs = p.TotalLatencyMs / p.Calls
+	p.InputTokens += usage.InputTokens
+	p.OutputTokens += usage.OutputTokens
+	p.CostUSD += usage.CostUSD
+	if u.slowThreshold > 0 && latency >= u.slowThreshold {
+		p.SlowCalls++
+		return u.slowThreshold
+	}
+	return 0
+}
+
+// Snapshot returns the totals of every provider used so far, sorted by name
+func (u *UsageStats) Snapshot() []ProviderUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snapshot := make([]ProviderUsage, 0, len(u.providers))
+	for _, p := range u.providers {
+		snapshot = append(snapshot, *p)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Provider < snapshot[j].Provider
+	})
+	return snapshot
+}
+
+// MeteredSearcher wraps a Searcher, recording every call in a UsageStats
+type MeteredSearcher struct {
+	provider string
+	searcher Searcher
+	stats    *UsageStats
+	logger   *slog.Logger
+}
+
+// NewMeteredSearcher wraps searcher so its calls are counted under provider
+func NewMeteredSearcher(provider string, searcher Searcher, stats *UsageStats, logger *slog.Logger) *MeteredSearcher {
+	return &MeteredSearcher{
+		provider: provider,
+		searcher: searcher,
+		stats:    stats,
+		logger:   logger,
+	}
+}
+
+// SearchTools ranks tools through the wrapped searcher
+func (m *MeteredSearcher) SearchTools(ctx context.Context, query string, toolSchemas []byte, topK int) ([]Ranking, error) {
+	var rankings []Ranking
+	err := m.meter(ctx, func(ctx context.Context) error {
+		var err error
+		rankings, err = m.searcher.SearchTools(ctx, query, toolSchemas, topK)
+		return err
+	})
+	return rankings, err
+}
+
+// SearchToolsBatch ranks tools for several queries through the wrapped searcher
+func (m *MeteredSearcher) SearchToolsBatch(ctx context.Context, queries []string, toolSchemas []byte, topK int) ([][]Ranking, error) {
+	var rankings [][]Ranking
+	err := m.meter(ctx, func(ctx context.Context) error {
+		var err error
+		rankings, err = m.searcher.SearchToolsBatch(ctx, queries, toolSchemas, topK)
+		return err
+	})
+	return rankings, err
+}
+
+// Complete sends a prompt through the wrapped searcher
+func (m *MeteredSearcher) Complete(ctx context.Context, prompt string) (string, error) {
+	var response string
+	err := m.meter(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = m.searcher.Complete(ctx, prompt)
+		return err
+	})
+	return response, err
+}
+
+// meter times call and collects the usage the provider reports during it
+func (m *MeteredSearcher) meter(ctx context.Context, call func(ctx context.Context) error) error {
+	var usage Usage
+	start := time.Now()
+	err := call(context.WithValue(ctx, usageKey{}, &usage))
+	latency := time.Since(start)
+
+	slow := m.stats.record(m.provider, latency, usage, err)
+	m.logger.InfoContext(ctx, "LLM call finished", "provider", m.provider, "latency_ms", latency.Milliseconds(),
+		"input_tokens", usage.InputTokens, "output_tokens", usage.OutputTokens, "cost_usd", usage.CostUSD, "error", err != nil)
+	if slow > 0 {
+		m.logger.WarnContext(ctx, "Slow LLM search call", "provider", m.provider,
+			"latency_ms", latency.Milliseconds(), "threshold_ms", slow.Milliseconds())
+	}
+
+	return err
+}
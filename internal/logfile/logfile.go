@@ -0,0 +1,100 @@
+// Package logfile writes the aggregator's log to a file that is rotated once
+// it reaches a size limit, keeping a few of the previous files.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// File is a log file rotated by size. Rotated files are named after it with
+// a number: path.1 is the newest, path.N the oldest kept.
+type File struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	maxSize  int64 // Bytes before rotating, 0 for no limit
+	maxFiles int   // Rotated files kept
+}
+
+// Open opens path for appending, creating it if needed. It isn't rotated
+// until SetLimits sets a size.
+func Open(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &File{path: path, file: file, size: info.Size()}, nil
+}
+
+// SetLimits sets the size the file may reach before it is rotated (0 for no
+// limit) and how many rotated files are kept. A file already over the limit
+// is rotated right away.
+func (f *File) SetLimits(maxSize int64, maxFiles int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxSize = max(maxSize, 0)
+	f.maxFiles = max(maxFiles, 0)
+	if f.maxSize > 0 && f.size >= f.maxSize {
+		return f.rotate()
+	}
+	return nil
+}
+
+// Write appends p, rotating the file first if p would take it past its limit
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			// Keep logging to the full file rather than losing lines
+			fmt.Fprintf(os.Stderr, "logfile: failed to rotate %s: %v\n", f.path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// rotate shifts the rotated files up by one, dropping the oldest, moves the
+// file to path.1 and starts a new one. With no rotated files kept, the file
+// is truncated instead.
+func (f *File) rotate() error {
+	if f.maxFiles > 0 {
+		os.Remove(f.rotated(f.maxFiles))
+		for i := f.maxFiles - 1; i >= 1; i-- {
+			os.Rename(f.rotated(i), f.rotated(i+1)) // Missing files are fine
+		}
+		if err := os.Rename(f.path, f.rotated(1)); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file = file
+	f.size = 0
+	return nil
+}
+
+// rotated returns the name of the i-th rotated file
+func (f *File) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", f.path, i)
+}
@@ -0,0 +1,75 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readFile returns the contents of path, or "" if it doesn't exist
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestFile_Rotates tests that the file is rotated at its size limit and
+// only the newest rotated files are kept
+func TestFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onemcp.log")
+	file, err := Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, file.SetLimits(10, 2))
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, "fourth\n", readFile(t, path))
+	require.Equal(t, "third\n", readFile(t, path+".1"))
+	require.Equal(t, "second\n", readFile(t, path+".2"))
+	require.Empty(t, readFile(t, path+".3"), "Only two rotated files should be kept")
+}
+
+// TestFile_NoLimit tests that a file without a size limit is appended to forever
+func TestFile_NoLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onemcp.log")
+	require.NoError(t, os.WriteFile(path, []byte("earlier\n"), 0644))
+
+	file, err := Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	for range 100 {
+		_, err := file.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, "earlier\n"+strings.Repeat("line\n", 100), readFile(t, path))
+	require.Empty(t, readFile(t, path+".1"))
+}
+
+// TestFile_SetLimitsRotatesOversizedFile tests that a file already over the
+// limit is rotated as soon as the limit is set, and truncated when no
+// rotated files are kept
+func TestFile_SetLimitsRotatesOversizedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onemcp.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0644))
+
+	file, err := Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, file.SetLimits(50, 0))
+	_, err = file.Write([]byte("fresh\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, "fresh\n", readFile(t, path))
+	require.Empty(t, readFile(t, path+".1"))
+}
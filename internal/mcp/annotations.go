@@ -0,0 +1,34 @@
+package mcp
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// toolAnnotations converts the annotations an external server declared for a
+// tool, or returns nil if it declared none
+func toolAnnotations(annotations *mcp.ToolAnnotations) *tools.ToolAnnotations {
+	if annotations == nil {
+		return nil
+	}
+	return &tools.ToolAnnotations{
+		Title:           annotations.Title,
+		ReadOnlyHint:    annotations.ReadOnlyHint,
+		DestructiveHint: annotations.DestructiveHint,
+		IdempotentHint:  annotations.IdempotentHint,
+	}
+}
+
+// mcpAnnotations converts a registered tool's annotations back for listing it
+// directly, or returns nil if it has none
+func mcpAnnotations(annotations *tools.ToolAnnotations) *mcp.ToolAnnotations {
+	if annotations == nil {
+		return nil
+	}
+	return &mcp.ToolAnnotations{
+		Title:           annotations.Title,
+		ReadOnlyHint:    annotations.ReadOnlyHint,
+		DestructiveHint: annotations.DestructiveHint,
+		IdempotentHint:  annotations.IdempotentHint,
+	}
+}
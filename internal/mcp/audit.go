@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// Audit sink types
+const (
+	auditSinkFile    = "file"
+	auditSinkSyslog  = "syslog"
+	auditSinkWebhook = "webhook"
+	auditSinkStdout  = "stdout"
+)
+
+// Webhook delivery: events waiting to be posted, and how long a post may take
+const (
+	auditWebhookQueue   = 1000
+	auditWebhookTimeout = 10 * time.Second
+)
+
+// AuditSinkConfig is one destination of the audit log
+type AuditSinkConfig struct {
+	Type    string            `json:"type"`              // "file", "syslog", "webhook" or "stdout"
+	Path    string            `json:"path,omitempty"`    // file: JSON lines file, relative to the config file (default: cacheDir + "/audit.jsonl")
+	Network string            `json:"network,omitempty"` // syslog: "udp", "tcp" or "unix" (default: the local syslog daemon)
+	Address string            `json:"address,omitempty"` // syslog: server address, e.g. "logs.internal:514"
+	Tag     string            `json:"tag,omitempty"`     // syslog: message tag (default: "onemcp")
+	URL     string            `json:"url,omitempty"`     // webhook: endpoint every event is POSTed to as JSON
+	Headers map[string]string `json:"headers,omitempty"` // webhook: extra request headers; values may reference ${ENV_VAR}
+}
+
+// auditEvent is one tool call in the audit log. Arguments are only
+// fingerprinted, so the log never holds their contents.
+type auditEvent struct {
+	Timestamp       time.Time `json:"timestamp"`
+	Session         string    `json:"session,omitempty"` // Client session; empty over stdio
+	Client          string    `json:"client,omitempty"`  // Client name and version, as sent in initialize
+	RequestID       string    `json:"request_id,omitempty"`
+	Tool            string    `json:"tool"`
+	ArgumentsDigest string    `json:"arguments_digest"`
+	Outcome         string    `json:"outcome"` // "success" or "error"
+	ErrorType       string    `json:"error_type,omitempty"`
+	DurationMs      int64     `json:"duration_ms"`
+}
+
+// auditSink writes audit events, one JSON document each
+type auditSink interface {
+	write(event []byte) error
+	close() error
+}
+
+// auditLog sends every tool call to the configured sinks
+type auditLog struct {
+	mu     sync.Mutex
+	sinks  []auditSink
+	types  []string // Type of each sink, for log messages
+	logger *slog.Logger
+}
+
+// newAuditLog opens the configured sinks. Sinks that can't be opened are
+// skipped with a warning.
+func newAuditLog(configs []AuditSinkConfig, configPath, cacheDir string, logger *slog.Logger) *auditLog {
+	audit := &auditLog{logger: logger}
+	for _, config := range configs {
+		sink, err := openAuditSink(config, configPath, cacheDir, logger)
+		if err != nil {
+			logger.Warn("Failed to open audit sink, skipping it", "type", config.Type, "error", err)
+			continue
+		}
+		audit.sinks = append(audit.sinks, sink)
+		audit.types = append(audit.types, config.Type)
+		logger.Info("Writing audit log", "type", config.Type)
+	}
+	return audit
+}
+
+// openAuditSink opens one sink
+func openAuditSink(config AuditSinkConfig, configPath, cacheDir string, logger *slog.Logger) (auditSink, error) {
+	switch config.Type {
+	case auditSinkFile:
+		path := config.Path
+		if path == "" {
+			path = filepath.Join(cacheDir, "audit.jsonl")
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return &writerSink{writer: file, closer: file}, nil
+	case auditSinkSyslog:
+		tag := config.Tag
+		if tag == "" {
+			tag = "onemcp"
+		}
+		writer, err := dialSyslog(config.Network, config.Address, tag)
+		if err != nil {
+			return nil, err
+		}
+		return &writerSink{writer: writer, closer: writer}, nil
+	case auditSinkWebhook:
+		if config.URL == "" {
+			return nil, errors.New("webhook audit sink requires a url")
+		}
+		return newWebhookSink(config.URL, config.Headers, logger), nil
+	case auditSinkStdout:
+		return &writerSink{writer: os.Stdout, stdout: true}, nil
+	}
+	return nil, fmt.Errorf("unknown audit sink type %q (use file, syslog, webhook or stdout)", config.Type)
+}
+
+// record writes an execution to every sink
+func (a *auditLog) record(ctx context.Context, record tools.ExecutionRecord) {
+	event := auditEvent{
+		Timestamp:       record.Timestamp,
+		Session:         sessionID(ctx),
+		Client:          sessionClient(ctx),
+		RequestID:       requestID(ctx),
+		Tool:            record.ToolName,
+		ArgumentsDigest: record.ArgumentsDigest,
+		Outcome:         "success",
+		ErrorType:       record.ErrorType,
+		DurationMs:      record.ExecutionTimeMs,
+	}
+	if !record.Success {
+		event.Outcome = "error"
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, sink := range a.sinks {
+		if err := sink.write(data); err != nil {
+			a.logger.WarnContext(ctx, "Failed to write audit event", "type", a.types[i], "error", err)
+		}
+	}
+}
+
+// dropStdout closes the stdout sink, which would corrupt the stdio transport
+func (a *auditLog) dropStdout() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := len(a.sinks) - 1; i >= 0; i-- {
+		if writer, ok := a.sinks[i].(*writerSink); ok && writer.stdout {
+			a.logger.Warn("The stdout audit sink can't be used over stdio, where stdout carries the protocol; skipping it")
+			a.sinks = slices.Delete(a.sinks, i, i+1)
+			a.types = slices.Delete(a.types, i, i+1)
+		}
+	}
+}
+
+// close flushes and closes every sink
+func (a *auditLog) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, sink := range a.sinks {
+		if err := sink.close(); err != nil {
+			a.logger.Warn("Failed to close audit sink", "type", a.types[i], "error", err)
+		}
+	}
+	a.sinks, a.types = nil, nil
+}
+
+// writerSink writes events as lines to a file, syslog or stdout
+type writerSink struct {
+	writer io.Writer
+	closer io.Closer // nil for stdout
+	stdout bool
+}
+
+// write appends an event as one line
+func (w *writerSink) write(event []byte) error {
+	_, err := w.writer.Write(append(event, '\n'))
+	return err
+}
+
+// close closes the file or syslog connection
+func (w *writerSink) close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// webhookSink posts events to an HTTP endpoint from a queue, so a slow
+// endpoint never delays tool calls. Events are dropped while the queue is full.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	queue   chan []byte
+	done    chan struct{}
+	logger  *slog.Logger
+}
+
+// newWebhookSink starts posting to url
+func newWebhookSink(url string, headers map[string]string, logger *slog.Logger) *webhookSink {
+	sink := &webhookSink{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: auditWebhookTimeout},
+		queue:   make(chan []byte, auditWebhookQueue),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+	go sink.run()
+	return sink
+}
+
+// write queues an event for posting
+func (w *webhookSink) write(event []byte) error {
+	select {
+	case w.queue <- event:
+		return nil
+	default:
+		return errors.New("webhook queue full, event dropped")
+	}
+}
+
+// run posts queued events until the queue is closed
+func (w *webhookSink) run() {
+	defer close(w.done)
+	for event := range w.queue {
+		if err := w.post(event); err != nil {
+			w.logger.Warn("Failed to post audit event", "url", w.url, "error", err)
+		}
+	}
+}
+
+// post sends one event
+func (w *webhookSink) post(event []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// close posts the events still queued, giving up after the post timeout
+func (w *webhookSink) close() error {
+	close(w.queue)
+	select {
+	case <-w.done:
+		return nil
+	case <-time.After(auditWebhookTimeout):
+		return errors.New("timed out posting queued events")
+	}
+}
+
+// configureAudit opens the audit sinks and records every tool call to them
+func (s *AggregatorServer) configureAudit(settings Settings, configPath string) {
+	if len(settings.AuditSinks) == 0 {
+		return
+	}
+	s.audit = newAuditLog(settings.AuditSinks, configPath, s.cacheDir, s.logger)
+	s.registry.SetExecutionObserver(s.audit.record)
+}
+
+// sessionClientKey is the context key of the name and version of the client
+// a request comes from
+type sessionClientKey struct{}
+
+// sessionClient returns the client of the request in ctx, if known
+func sessionClient(ctx context.Context) string {
+	client, _ := ctx.Value(sessionClientKey{}).(string)
+	return client
+}
+
+// clientName describes the client of a session by the name and version it
+// sent in initialize
+func clientName(session *mcp.ServerSession) string {
+	params := session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+	if params.ClientInfo.Version == "" {
+		return params.ClientInfo.Name
+	}
+	return params.ClientInfo.Name + " " + params.ClientInfo.Version
+}
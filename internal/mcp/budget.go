@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+const (
+	bytesPerToken         = 4   // Rough token estimate used for budgeting
+	responseEnvelopeBytes = 200 // Room for the non-tool fields of a search response
+	trimmedDescriptionLen = 120 // Description length kept when trimming
+)
+
+// truncationReport lists what was trimmed to fit a search response into a token budget
+type truncationReport struct {
+	Examples     []string `json:"examples,omitempty"`     // Tools whose examples were dropped
+	Schemas      []string `json:"schemas,omitempty"`      // Tools whose schema was reduced to required parameters
+	Descriptions []string `json:"descriptions,omitempty"` // Tools whose description was shortened
+	Dropped      []string `json:"dropped,omitempty"`      // Tools removed from the response
+}
+
+// empty reports whether nothing was truncated
+func (r truncationReport) empty() bool {
+	return len(r.Examples) == 0 && len(r.Schemas) == 0 && len(r.Descriptions) == 0 && len(r.Dropped) == 0
+}
+
+// fitToTokenBudget trims search results until their estimated size fits maxTokens.
+// Trimming is progressive: examples are dropped, schemas are reduced to their
+// required parameters (output schemas are dropped), descriptions are shortened, and finally trailing tools are
+// removed. At least one tool is always kept.
+func fitToTokenBudget(metadata []tools.ToolMetadata, maxTokens int) ([]tools.ToolMetadata, truncationReport) {
+	var report truncationReport
+	budget := maxTokens*bytesPerToken - responseEnvelopeBytes
+
+	fits := func() bool {
+		data, _ := json.Marshal(metadata)
+		return len(data) <= budget
+	}
+
+	if fits() {
+		return metadata, report
+	}
+
+	// Work on a copy so the caller's slice isn't modified
+	metadata = append([]tools.ToolMetadata(nil), metadata...)
+
+	for i := range metadata {
+		if fits() {
+			return metadata, report
+		}
+		if len(metadata[i].Examples) > 0 {
+			metadata[i].Examples = nil
+			report.Examples = append(report.Examples, metadata[i].Name)
+		}
+	}
+
+	for i := len(metadata) - 1; i >= 0; i-- {
+		if fits() {
+			return metadata, report
+		}
+		if metadata[i].Parameters != nil || metadata[i].OutputSchema != nil {
+			if metadata[i].Parameters != nil {
+				metadata[i].Parameters = requiredOnlySchema(metadata[i].Parameters)
+			}
+			metadata[i].OutputSchema = nil
+			report.Schemas = append(report.Schemas, metadata[i].Name)
+		}
+	}
+
+	for i := len(metadata) - 1; i >= 0; i-- {
+		if fits() {
+			return metadata, report
+		}
+		if description := []rune(metadata[i].Description); len(description) > trimmedDescriptionLen {
+			metadata[i].Description = string(description[:trimmedDescriptionLen]) + "…"
+			report.Descriptions = append(report.Descriptions, metadata[i].Name)
+		}
+	}
+
+	for len(metadata) > 1 && !fits() {
+		report.Dropped = append(report.Dropped, metadata[len(metadata)-1].Name)
+		metadata = metadata[:len(metadata)-1]
+	}
+
+	return metadata, report
+}
+
+// requiredOnlySchema reduces an object schema to its required properties
+func requiredOnlySchema(schema map[string]any) map[string]any {
+	trimmed := make(map[string]any)
+	if schemaType, ok := schema["type"]; ok {
+		trimmed["type"] = schemaType
+	}
+
+	var required []string
+	switch req := schema["required"].(type) {
+	case []string:
+		required = req
+	case []any:
+		for _, name := range req {
+			if s, ok := name.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	kept := make(map[string]any)
+	for _, name := range required {
+		if property, ok := properties[name]; ok {
+			kept[name] = property
+		}
+	}
+
+	trimmed["properties"] = kept
+	if len(required) > 0 {
+		trimmed["required"] = required
+	}
+	return trimmed
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/radutopala/onemcp/internal/tools"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetTestMetadata returns two tools with long descriptions, schemas and examples
+func budgetTestMetadata() []tools.ToolMetadata {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":     map[string]any{"type": "string", "description": strings.Repeat("p", 400)},
+			"encoding": map[string]any{"type": "string", "description": strings.Repeat("e", 400)},
+		},
+		"required": []any{"path"},
+	}
+
+	return []tools.ToolMetadata{
+		{Name: "fs_read_file", Description: strings.Repeat("r", 500), Parameters: schema, Examples: []string{strings.Repeat("x", 200)}},
+		{Name: "fs_write_file", Description: strings.Repeat("w", 500), Parameters: schema},
+	}
+}
+
+// TestFitToTokenBudget_FitsUntouched tests that results within the budget are left alone
+func TestFitToTokenBudget_FitsUntouched(t *testing.T) {
+	metadata := budgetTestMetadata()
+
+	trimmed, report := fitToTokenBudget(metadata, 10000)
+	require.True(t, report.empty())
+	require.Equal(t, metadata, trimmed)
+}
+
+// TestFitToTokenBudget_TrimsProgressively tests that examples go before schemas and descriptions
+func TestFitToTokenBudget_TrimsProgressively(t *testing.T) {
+	metadata := budgetTestMetadata()
+
+	trimmed, report := fitToTokenBudget(metadata, 500)
+	require.Len(t, trimmed, 2)
+	require.Equal(t, []string{"fs_read_file"}, report.Examples)
+	require.NotEmpty(t, report.Schemas)
+	require.Empty(t, report.Dropped)
+
+	// Required parameters are kept
+	properties := trimmed[1].Parameters["properties"].(map[string]any)
+	require.Contains(t, properties, "path")
+	require.NotContains(t, properties, "encoding")
+
+	// Original metadata is left untouched
+	require.NotNil(t, metadata[0].Examples)
+}
+
+// TestFitToTokenBudget_DropsTools tests that trailing tools are dropped when trimming isn't enough
+func TestFitToTokenBudget_DropsTools(t *testing.T) {
+	trimmed, report := fitToTokenBudget(budgetTestMetadata(), 50)
+	require.Len(t, trimmed, 1)
+	require.Equal(t, []string{"fs_write_file"}, report.Dropped)
+	require.NotEmpty(t, report.Descriptions)
+}
@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcpclient"
+)
+
+// ServerCapabilitiesInput defines the input for server_capabilities
+type ServerCapabilitiesInput struct{}
+
+// serverCapabilities is one external server in the server_capabilities response
+type serverCapabilities struct {
+	Name string `json:"name"`
+	mcpclient.ServerInfo
+	Unproxied []string `json:"unproxied"`
+}
+
+// unproxiedFeatures lists what a server declared that the aggregator doesn't
+// pass on to clients. Tools, prompts, resources, logging and completions are
+// proxied; subscriptions and experimental capabilities are not, and
+// instructions only when forwardInstructions is on.
+func (s *AggregatorServer) unproxiedFeatures(info mcpclient.ServerInfo) []string {
+	unproxied := []string{}
+	if caps := info.Capabilities; caps != nil {
+		if caps.Resources != nil && caps.Resources.Subscribe {
+			unproxied = append(unproxied, "resources.subscribe")
+		}
+		experimental := make([]string, 0, len(caps.Experimental))
+		for key := range caps.Experimental {
+			experimental = append(experimental, "experimental."+key)
+		}
+		sort.Strings(experimental)
+		unproxied = append(unproxied, experimental...)
+	}
+	if info.Instructions != "" && !s.mergeInstructions {
+		unproxied = append(unproxied, "instructions")
+	}
+	return unproxied
+}
+
+func (s *AggregatorServer) handleServerCapabilities(ctx context.Context, req *mcp.CallToolRequest, input ServerCapabilitiesInput) (*mcp.CallToolResult, any, error) {
+	clients := s.connectedClients()
+	servers := make([]serverCapabilities, 0, len(clients))
+	for name, client := range clients {
+		info := client.ServerInfo()
+		servers = append(servers, serverCapabilities{Name: name, ServerInfo: info, Unproxied: s.unproxiedFeatures(info)})
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	resultJSON, _ := json.Marshal(map[string]any{"servers": servers})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/llmsearch"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// maxCompletionValues is the most values a completion result may carry
+const maxCompletionValues = 100
+
+// handleComplete answers completion/complete. MCP only defines prompt and
+// resource references, so meta-tool arguments are completed for prompt
+// references naming the meta-tool (e.g. {"type": "ref/prompt", "name":
+// "tool_execute"} with argument "tool_name"), by the name clients see.
+// Everything else is proxied to the server owning the prompt or resource.
+func (s *AggregatorServer) handleComplete(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	ref := req.Params.Ref
+	if ref == nil {
+		return completionResult(nil), nil
+	}
+	argument := req.Params.Argument
+
+	switch ref.Type {
+	case "ref/prompt":
+		if candidates, ok := s.metaToolCompletions(s.builtinMetaToolName(ref.Name), argument.Name); ok {
+			return completionResult(matchCompletions(candidates, argument.Value)), nil
+		}
+		server, prompt, ok := s.lookupExternalPrompt(ref.Name)
+		if !ok {
+			return completionResult(nil), nil
+		}
+		return s.completeExternal(ctx, server, &mcp.CompleteParams{
+			Argument: argument,
+			Context:  req.Params.Context,
+			Ref:      &mcp.CompleteReference{Type: ref.Type, Name: prompt},
+		})
+
+	case "ref/resource":
+		server, uri, ok := splitResourceURI(ref.URI)
+		if !ok {
+			return completionResult(nil), nil
+		}
+		return s.completeExternal(ctx, server, &mcp.CompleteParams{
+			Argument: argument,
+			Context:  req.Params.Context,
+			Ref:      &mcp.CompleteReference{Type: ref.Type, URI: uri},
+		})
+	}
+
+	return completionResult(nil), nil
+}
+
+// completeExternal proxies a completion request to an external server
+func (s *AggregatorServer) completeExternal(ctx context.Context, server string, params *mcp.CompleteParams) (*mcp.CompleteResult, error) {
+	client, ok := s.externalClient(server)
+	if !ok {
+		return completionResult(nil), nil
+	}
+
+	result, err := client.Complete(ctx, params)
+	if err != nil {
+		s.logger.Warn("Failed to complete argument on external server", "server", server, "argument", params.Argument.Name, "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// lookupExternalPrompt returns the server and original name of an advertised prompt
+func (s *AggregatorServer) lookupExternalPrompt(name string) (server, prompt string, ok bool) {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+
+	for server, entries := range s.prompts {
+		for _, entry := range entries {
+			if entry.name == name {
+				return server, entry.prompt.Name, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// metaToolCompletions returns the known values of a meta-tool argument
+func (s *AggregatorServer) metaToolCompletions(tool, argument string) ([]string, bool) {
+	switch tool + "." + argument {
+	case "tool_execute.tool_name", "tool_schema.tool_name", "tool_history.tool_name", "search_feedback.tool_name", "usage_examples.tool_name", "tool_validate.tool_name", "tool_disable.tool_name":
+		var names []string
+		for _, t := range s.registry.ListAll() {
+			if t.Type == tools.TypeTool {
+				names = append(names, t.Name)
+			}
+		}
+		return names, true
+	case "tool_search.match_mode":
+		return matchModes, true
+	case "tool_search.sort":
+		return sortOrders, true
+	case "tool_enable.tool_name":
+		return s.registry.Disabled(), true
+	case "tool_search.category":
+		seen := make(map[string]bool)
+		var categories []string
+		for _, item := range s.searchableItems() {
+			if item.Category != "" && !seen[item.Category] {
+				seen[item.Category] = true
+				categories = append(categories, item.Category)
+			}
+		}
+		return categories, true
+	case "tool_search.type":
+		return []string{string(tools.TypeTool), string(tools.TypePrompt), string(tools.TypeResource)}, true
+	case "tool_search.detail_level":
+		return detailLevels, true
+	case "prompt_search.detail_level":
+		return []string{"names_only", "summary", "detailed"}, true
+	case "resource_read.uri":
+		var uris []string
+		for _, item := range s.resourceItems() {
+			uris = append(uris, item.Name)
+		}
+		return uris, true
+	case "prompt_get.name":
+		var names []string
+		for _, item := range s.promptItems() {
+			names = append(names, item.Name)
+		}
+		return names, true
+	case "config_set.key":
+		return runtimeSettings, true
+	case "search_provider_set.provider", "vector_reindex.provider":
+		return append(llmsearch.ProviderNames(), tfidfProvider), true
+	case "server_restart.server":
+		s.clientsMu.RLock()
+		defer s.clientsMu.RUnlock()
+		return slices.Collect(maps.Keys(s.serverConfigs)), true
+	}
+	return nil, false
+}
+
+// matchCompletions returns the candidates starting with value (case-insensitive), sorted
+func matchCompletions(candidates []string, value string) []string {
+	prefix := strings.ToLower(value)
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// completionResult builds a completion result, truncated to maxCompletionValues
+func completionResult(values []string) *mcp.CompleteResult {
+	total := len(values)
+	if values == nil {
+		values = []string{} // avoid JSON null
+	}
+	if len(values) > maxCompletionValues {
+		values = values[:maxCompletionValues]
+	}
+	return &mcp.CompleteResult{
+		Completion: mcp.CompletionResultDetails{
+			Values:  values,
+			Total:   total,
+			HasMore: total > len(values),
+		},
+	}
+}
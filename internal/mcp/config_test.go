@@ -115,3 +115,140 @@ func TestLoadConfigMissingFile(t *testing.T) {
 	require.NotNil(t, config)
 	require.Equal(t, 0, config.Settings.SearchResultLimit)
 }
+
+// TestLoadConfigWithExamples tests loading per-tool usage examples from the config
+func TestLoadConfigWithExamples(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".onemcp.json")
+
+	configContent := `{
+  "mcpServers": {
+    "filesystem": {
+      "command": "echo",
+      "enabled": true,
+      "examples": ["work with files under /tmp"],
+      "toolExamples": {
+        "read_file": ["{\"path\": \"/tmp/notes.txt\"}"]
+      }
+    }
+  }
+}`
+
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	server := &AggregatorServer{
+		logger: logger,
+	}
+
+	config, err := server.loadConfig(configPath)
+	require.NoError(t, err)
+
+	fs := config.ExternalServers["filesystem"]
+	require.Equal(t, []string{"work with files under /tmp"}, fs.Examples)
+	require.Equal(t, []string{`{"path": "/tmp/notes.txt"}`}, fs.ToolExamples["read_file"])
+}
+
+// TestSetConfigSetting tests that persisting a setting edits only its value,
+// keeping comments, key order and formatting
+func TestSetConfigSetting(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, want string
+	}{
+		{
+			name: "replaces an existing value",
+			config: `{
+  // Local servers
+  "mcpServers": {"echo": {"command": "echo"}},
+  "settings": {
+    "minSearchScore": 0.1, // Raised later
+    "searchProvider": "tfidf"
+  }
+}`,
+			want: `{
+  // Local servers
+  "mcpServers": {"echo": {"command": "echo"}},
+  "settings": {
+    "minSearchScore": 0.4, // Raised later
+    "searchProvider": "tfidf"
+  }
+}`,
+		},
+		{
+			name: "adds a missing key",
+			config: `{
+  "settings": {
+    "searchProvider": "tfidf" /* local only */
+  }
+}`,
+			want: `{
+  "settings": {
+    "minSearchScore": 0.4,
+    "searchProvider": "tfidf" /* local only */
+  }
+}`,
+		},
+		{
+			name:   "adds to a one-line object",
+			config: `{"settings": {"searchProvider": "tfidf"}}`,
+			want:   `{"settings": {"minSearchScore": 0.4, "searchProvider": "tfidf"}}`,
+		},
+		{
+			name: "adds the settings object",
+			config: `{
+	"mcpServers": {} // none yet
+}`,
+			want: `{
+	"settings": {"minSearchScore": 0.4},
+	"mcpServers": {} // none yet
+}`,
+		},
+		{
+			name:   "ignores lookalikes in strings and comments",
+			config: `{"mcpServers": {"x": {"args": ["\"settings\": {}"]}}, /* "settings": {} */ "settings": {}}`,
+			want:   `{"mcpServers": {"x": {"args": ["\"settings\": {}"]}}, /* "settings": {} */ "settings": {"minSearchScore": 0.4}}`,
+		},
+		{
+			name:   "creates a config",
+			config: "",
+			want:   "{\n  \"settings\": {\n    \"minSearchScore\": 0.4\n  }\n}\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			edited, err := setConfigSetting([]byte(tc.config), "minSearchScore", 0.4)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(edited))
+		})
+	}
+
+	_, err := setConfigSetting([]byte(`["not", "an", "object"]`), "minSearchScore", 0.4)
+	require.ErrorIs(t, err, errConfigSyntax)
+	_, err = setConfigSetting([]byte(`{"settings": [1]}`), "minSearchScore", 0.4)
+	require.ErrorIs(t, err, errConfigSyntax)
+}
+
+// TestWriteConfigSetting tests that the config file keeps its permissions and
+// stays a symlink when a setting is persisted
+func TestWriteConfigSetting(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real.json")
+	require.NoError(t, os.WriteFile(target, []byte(`{"settings": {}}`), 0600))
+	link := filepath.Join(dir, ".onemcp.json")
+	require.NoError(t, os.Symlink(target, link))
+
+	require.NoError(t, writeConfigSetting(link, "logLevel", "debug"))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSymlink, info.Mode().Type(), "The symlink should be kept")
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm(), "The file's permissions should be kept")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, `{"settings": {"logLevel": "debug"}}`, string(data))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "No temporary file should be left behind")
+}
@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// errConfigSyntax reports a config file config_set can't find its way through
+var errConfigSyntax = errors.New("config file is not a JSON object")
+
+// writeConfigSetting sets settings.<key> in the config file at path, editing
+// only that value so comments, key order and formatting are kept. Symlinks
+// are followed, and the file keeps its permissions.
+func writeConfigSetting(path, key string, value any) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	edited, err := setConfigSetting(data, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to edit config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Written aside and renamed so a crash never leaves half a config
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	defer tmp.Close()
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if _, err := tmp.Write(edited); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// setConfigSetting returns the JSONC config data with settings.<key> set to
+// value. An existing value is replaced in place; otherwise the key is added
+// at the top of the settings object, which is created if missing.
+func setConfigSetting(data []byte, key string, value any) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	member, _ := json.Marshal(key)
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return fmt.Appendf(nil, "{\n  \"settings\": {\n    %s: %s\n  }\n}\n", member, encoded), nil
+	}
+
+	root := skipSpace(data, 0)
+	if root >= len(data) || data[root] != '{' {
+		return nil, errConfigSyntax
+	}
+	settings, _, ok, err := findMember(data, root, "settings")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return insertMember(data, root, fmt.Sprintf("\"settings\": {%s: %s}", member, encoded)), nil
+	}
+	if data[settings] != '{' {
+		return nil, fmt.Errorf("%w: settings is not an object", errConfigSyntax)
+	}
+
+	start, end, ok, err := findMember(data, settings, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return slices.Concat(data[:start], encoded, data[end:]), nil
+	}
+	return insertMember(data, settings, fmt.Sprintf("%s: %s", member, encoded)), nil
+}
+
+// insertMember adds entry as the first member of the object opening at
+// data[open], on its own line when the object's members are on theirs
+func insertMember(data []byte, open int, entry string) []byte {
+	next := skipSpace(data, open+1)
+	empty := next < len(data) && data[next] == '}'
+	switch indent, ok := memberIndent(data, open); {
+	case ok:
+		entry = "\n" + indent + entry
+		if !empty {
+			entry += ","
+		}
+	case !empty:
+		entry += ", "
+	}
+	return slices.Concat(data[:open+1], []byte(entry), data[open+1:])
+}
+
+// memberIndent returns the indentation of the first member of the object
+// opening at data[open], if it starts on a line of its own
+func memberIndent(data []byte, open int) (string, bool) {
+	line := open + 1
+	for line < len(data) && (data[line] == ' ' || data[line] == '\t' || data[line] == '\r') {
+		line++
+	}
+	if line >= len(data) || data[line] != '\n' {
+		return "", false
+	}
+	start := line + 1
+	end := start
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	if end >= len(data) || data[end] == '}' || data[end] == '\n' {
+		return "", false
+	}
+	return string(data[start:end]), true
+}
+
+// findMember looks up key in the object opening at data[open], returning
+// where its value starts and ends
+func findMember(data []byte, open int, key string) (start, end int, ok bool, err error) {
+	i := open + 1
+	for {
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return 0, 0, false, errConfigSyntax
+		}
+		if data[i] == '}' {
+			return 0, 0, false, nil
+		}
+		if data[i] != '"' {
+			return 0, 0, false, errConfigSyntax
+		}
+		nameEnd := skipString(data, i)
+		var name string
+		if err := json.Unmarshal(data[i:nameEnd], &name); err != nil {
+			return 0, 0, false, errConfigSyntax
+		}
+		i = skipSpace(data, nameEnd)
+		if i >= len(data) || data[i] != ':' {
+			return 0, 0, false, errConfigSyntax
+		}
+		start = skipSpace(data, i+1)
+		end = skipValue(data, start)
+		if end <= start {
+			return 0, 0, false, errConfigSyntax
+		}
+		if name == key {
+			return start, end, true, nil
+		}
+		i = skipSpace(data, end)
+		if i < len(data) && data[i] == ',' {
+			i++
+		}
+	}
+}
+
+// skipSpace skips whitespace and // and /* */ comments from data[i]
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r':
+			i++
+		case bytes.HasPrefix(data[i:], []byte("//")):
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				return len(data)
+			}
+			i += end + 1
+		case bytes.HasPrefix(data[i:], []byte("/*")):
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return len(data)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the index just past the string opening at data[i]
+func skipString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// skipValue returns the index just past the JSON value starting at data[i]
+func skipValue(data []byte, i int) int {
+	if i >= len(data) {
+		return i
+	}
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch {
+			case data[i] == '"':
+				i = skipString(data, i)
+				continue
+			case data[i] == '/' && i+1 < len(data) && (data[i+1] == '/' || data[i+1] == '*'):
+				i = skipSpace(data, i)
+				continue
+			case data[i] == '{' || data[i] == '[':
+				depth++
+			case data[i] == '}' || data[i] == ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return len(data)
+	}
+	// A number, true, false or null
+	for i < len(data) && !bytes.ContainsAny(data[i:i+1], " \t\r\n,}]/") {
+		i++
+	}
+	return i
+}
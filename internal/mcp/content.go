@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// externalOutput describes an external tool's result for a JSON response:
+// text blocks in full, other blocks by type (see contentSummaries), plus its
+// structured content and _meta. The non-text blocks are returned separately
+// so they can follow the JSON as their own content.
+func externalOutput(result *mcp.CallToolResult) (map[string]any, []mcp.Content) {
+	var blocks []mcp.Content
+	for _, content := range result.Content {
+		if _, ok := content.(*mcp.TextContent); !ok {
+			blocks = append(blocks, content)
+		}
+	}
+
+	output := map[string]any{"content": contentSummaries(result.Content)}
+	if result.StructuredContent != nil {
+		output["structured_content"] = result.StructuredContent
+	}
+	if len(result.Meta) > 0 {
+		output["meta"] = result.Meta
+	}
+	return output, blocks
+}
+
+// contentSummaries describes content blocks for a JSON result: text as is,
+// other blocks without their data, as those are returned separately
+func contentSummaries(blocks []mcp.Content) []map[string]any {
+	summaries := make([]map[string]any, 0, len(blocks))
+	for _, block := range blocks {
+		switch c := block.(type) {
+		case *mcp.TextContent:
+			summaries = append(summaries, map[string]any{"type": "text", "text": c.Text})
+		case *mcp.ImageContent:
+			summaries = append(summaries, map[string]any{"type": "image", "mime_type": c.MIMEType})
+		case *mcp.AudioContent:
+			summaries = append(summaries, map[string]any{"type": "audio", "mime_type": c.MIMEType})
+		case *mcp.EmbeddedResource:
+			summary := map[string]any{"type": "resource"}
+			if c.Resource != nil {
+				summary["uri"] = c.Resource.URI
+				summary["mime_type"] = c.Resource.MIMEType
+			}
+			summaries = append(summaries, summary)
+		case *mcp.ResourceLink:
+			summaries = append(summaries, map[string]any{"type": "resource_link", "uri": c.URI, "mime_type": c.MIMEType})
+		default:
+			summaries = append(summaries, map[string]any{"type": "unknown"})
+		}
+	}
+	return summaries
+}
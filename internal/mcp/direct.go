@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// Tool exposure modes (settings.mode)
+const (
+	modeSearch      = "search"      // Only meta-tools; tools are found with tool_search
+	modePassthrough = "passthrough" // Every registered tool is also listed directly
+	modeHybrid      = "hybrid"      // The most used tools are also listed directly
+)
+
+// defaultHybridToolCount is how many tools hybrid mode lists directly
+const defaultHybridToolCount = 10
+
+// directToolSet returns the registered tools to list directly in the current mode
+func (s *AggregatorServer) directToolSet() []*tools.Tool {
+	var candidates []*tools.Tool
+	switch s.mode {
+	case modePassthrough:
+		candidates = s.registry.ListAll()
+	case modeHybrid:
+		candidates = s.registry.MostUsed(s.hybridToolCount)
+	}
+
+	direct := make([]*tools.Tool, 0, len(candidates))
+	for _, tool := range candidates {
+		if tool.Type == tools.TypeTool && !s.registry.IsDisabled(tool.Name) {
+			direct = append(direct, tool)
+		}
+	}
+	return direct
+}
+
+// syncDirectTools updates the tools listed directly next to the meta-tools.
+// With refresh set, tools that stay listed are re-added so schema changes
+// reach clients; otherwise only additions and removals are applied. Every
+// change makes the SDK send tools/list_changed.
+func (s *AggregatorServer) syncDirectTools(refresh bool) {
+	if s.server == nil || s.mode == modeSearch {
+		return
+	}
+
+	s.directMu.Lock()
+	defer s.directMu.Unlock()
+
+	wanted := make(map[string]bool)
+	for _, tool := range s.directToolSet() {
+		wanted[tool.Name] = true
+		if refresh || !s.directTools[tool.Name] {
+			s.server.AddTool(&mcp.Tool{
+				Name:         tool.Name,
+				Description:  tool.Description,
+				InputSchema:  directInputSchema(tool.InputSchema),
+				OutputSchema: directOutputSchema(tool.OutputSchema),
+				Annotations:  mcpAnnotations(tool.Annotations),
+			}, s.directToolHandler(tool.Name))
+		}
+	}
+
+	var stale []string
+	for name := range s.directTools {
+		if !wanted[name] {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		s.server.RemoveTools(stale...)
+	}
+
+	s.directTools = wanted
+	s.logger.Debug("Synced directly listed tools", "mode", s.mode, "tools", len(wanted), "removed", len(stale))
+}
+
+// directInputSchema returns a tool's schema if the SDK accepts it as a tool
+// input schema, or an empty object schema otherwise
+func directInputSchema(schema any) any {
+	if m, ok := schema.(map[string]any); ok && m["type"] == "object" {
+		return m
+	}
+	return map[string]any{"type": "object"}
+}
+
+// directOutputSchema returns a tool's output schema if the SDK accepts it, or nil
+func directOutputSchema(schema any) any {
+	if m, ok := schema.(map[string]any); ok && m["type"] == "object" {
+		return m
+	}
+	return nil
+}
+
+// directToolHandler executes a directly listed tool through the registry,
+// returning its result as the call's content
+func (s *AggregatorServer) directToolHandler(name string) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var arguments map[string]any
+		if len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &arguments); err != nil {
+				return nil, fmt.Errorf("invalid arguments for %s: %w", name, err)
+			}
+		}
+
+		result, err := s.registry.Execute(ctx, name, arguments)
+		if s.mode == modeHybrid {
+			s.syncDirectTools(false)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// External results, errors reported by the tool included, are passed
+		// through as the server returned them
+		if result.Output != nil {
+			return result.Output, nil
+		}
+
+		if !result.Success {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: result.Error},
+				},
+			}, nil
+		}
+
+		resultJSON, _ := json.Marshal(result.Result)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// disabledToolsPath returns the file the disabled tools are kept in, or ""
+// without a cache directory
+func (s *AggregatorServer) disabledToolsPath() string {
+	if s.cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(s.cacheDir, "disabled-tools.json")
+}
+
+// loadDisabledTools disables the tools disabled in earlier runs. A missing
+// or unreadable file leaves every tool enabled.
+func (s *AggregatorServer) loadDisabledTools() {
+	path := s.disabledToolsPath()
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		s.logger.Warn("Failed to read disabled tools", "path", path, "error", err)
+		return
+	}
+	for _, name := range names {
+		s.registry.SetDisabled(name, true)
+	}
+}
+
+// saveDisabledTools writes the disabled tools to the cache directory
+func (s *AggregatorServer) saveDisabledTools() error {
+	path := s.disabledToolsPath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.registry.Disabled(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// enabledItems drops disabled tools from catalog items
+func (s *AggregatorServer) enabledItems(items []*tools.Tool) []*tools.Tool {
+	enabled := items[:0]
+	for _, item := range items {
+		if item.Type != tools.TypeTool || !s.registry.IsDisabled(item.Name) {
+			enabled = append(enabled, item)
+		}
+	}
+	return enabled
+}
+
+// ToolDisableInput defines the input for tool_disable
+type ToolDisableInput struct {
+	ToolName string `json:"tool_name" jsonschema:"Name of the tool to disable"`
+}
+
+// ToolEnableInput defines the input for tool_enable
+type ToolEnableInput struct {
+	ToolName string `json:"tool_name" jsonschema:"Name of the disabled tool to enable again"`
+}
+
+// handleToolDisable removes a tool from search and blocks its execution
+// until tool_enable, across restarts
+func (s *AggregatorServer) handleToolDisable(ctx context.Context, req *mcp.CallToolRequest, input ToolDisableInput) (*mcp.CallToolResult, any, error) {
+	s.disabledMu.Lock()
+	defer s.disabledMu.Unlock()
+
+	tool, err := s.registry.Get(input.ToolName)
+	if err != nil {
+		return metaToolError(err, "tool_not_found"), nil, nil
+	}
+	if tool.Type != tools.TypeTool {
+		return metaToolError(fmt.Errorf("%s is a %s, not a tool", input.ToolName, tool.Type), "invalid_input"), nil, nil
+	}
+	if s.registry.IsDisabled(input.ToolName) {
+		return metaToolError(fmt.Errorf("tool %s is already disabled", input.ToolName), "already_disabled"), nil, nil
+	}
+
+	s.registry.SetDisabled(input.ToolName, true)
+	return s.disabledChanged(ctx, input.ToolName, true), nil, nil
+}
+
+// handleToolEnable restores a tool disabled with tool_disable
+func (s *AggregatorServer) handleToolEnable(ctx context.Context, req *mcp.CallToolRequest, input ToolEnableInput) (*mcp.CallToolResult, any, error) {
+	s.disabledMu.Lock()
+	defer s.disabledMu.Unlock()
+
+	// Disabled tools of servers that aren't connected can be enabled too
+	if !s.registry.IsDisabled(input.ToolName) {
+		return metaToolError(fmt.Errorf("tool %s is not disabled", input.ToolName), "not_disabled"), nil, nil
+	}
+
+	s.registry.SetDisabled(input.ToolName, false)
+	return s.disabledChanged(ctx, input.ToolName, false), nil, nil
+}
+
+// disabledChanged saves the disabled tools and re-indexes the catalog after
+// a tool was disabled or enabled; the caller holds disabledMu
+func (s *AggregatorServer) disabledChanged(ctx context.Context, name string, disabled bool) *mcp.CallToolResult {
+	result := map[string]any{
+		"tool_name": name,
+		"disabled":  disabled,
+	}
+
+	if err := s.saveDisabledTools(); err != nil {
+		// The change still applies to this run
+		s.logger.WarnContext(ctx, "Failed to save disabled tools", "error", err)
+		result["persisted"] = false
+		result["persist_error"] = err.Error()
+	} else {
+		result["persisted"] = s.disabledToolsPath() != ""
+	}
+
+	if err := s.rebuildSearchStore(); err != nil {
+		s.logger.WarnContext(ctx, "Failed to re-index after disabling a tool", "tool", name, "error", err)
+	}
+	s.syncDirectTools(false)
+	result["disabled_tools"] = s.registry.Disabled()
+
+	resultJSON, _ := json.Marshal(result)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/llmsearch"
+)
+
+// generatedExampleCount is how many examples usage_examples asks the LLM for
+const generatedExampleCount = 3
+
+// Sources of usage examples
+const (
+	exampleSourceConfig    = "config"    // Written in the server config (examples, toolExamples)
+	exampleSourceRecorded  = "recorded"  // Arguments of a recent successful call
+	exampleSourceGenerated = "generated" // Generated by the search LLM from the input schema
+)
+
+// usageExample is one example invocation of a tool. Config examples are free
+// text; recorded and generated ones are argument objects.
+type usageExample struct {
+	Source    string         `json:"source"`
+	Example   string         `json:"example,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// UsageExamplesInput defines the input for usage_examples
+type UsageExamplesInput struct {
+	ToolName string `json:"tool_name" jsonschema:"Name of the tool, as returned by tool_search (e.g. 'playwright_browser_navigate')"`
+	Generate bool   `json:"generate,omitempty" jsonschema:"Also generate examples with the search LLM when config or recorded examples exist. Default: false (generate only when there are none)"`
+}
+
+// handleUsageExamples collects example invocations of a tool from the config
+// and recent successful calls, and generates some with the search LLM when
+// there are none or they are asked for
+func (s *AggregatorServer) handleUsageExamples(ctx context.Context, req *mcp.CallToolRequest, input UsageExamplesInput) (*mcp.CallToolResult, any, error) {
+	tool, err := s.registry.Get(input.ToolName)
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      err.Error(),
+			"error_type": "tool_not_found",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+
+	examples := []usageExample{}
+	for _, example := range tool.Examples {
+		examples = append(examples, usageExample{Source: exampleSourceConfig, Example: example})
+	}
+	for _, arguments := range s.registry.RecordedArguments(tool.Name) {
+		examples = append(examples, usageExample{Source: exampleSourceRecorded, Arguments: arguments})
+	}
+
+	result := map[string]any{
+		"tool_name": tool.Name,
+	}
+	if len(examples) == 0 || input.Generate {
+		s.searchMu.RLock()
+		completer := s.completer
+		s.searchMu.RUnlock()
+
+		if completer == nil {
+			result["generation_error"] = "no search LLM available to generate examples"
+		} else {
+			genCtx, cancel := context.WithTimeout(ctx, s.searchTimeout)
+			generated, err := llmsearch.GenerateExamples(genCtx, completer, tool, generatedExampleCount)
+			cancel()
+			if err != nil {
+				s.logger.WarnContext(ctx, "Failed to generate usage examples", "tool", tool.Name, "error", err)
+				result["generation_error"] = err.Error()
+			}
+			for _, arguments := range generated {
+				examples = append(examples, usageExample{Source: exampleSourceGenerated, Arguments: arguments})
+			}
+		}
+	}
+	result["examples"] = examples
+
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
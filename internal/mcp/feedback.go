@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Ranking boost from search feedback: each report that a tool was used for a
+// query adds feedbackBoost to its score for that query, up to maxFeedbackBoost
+const (
+	feedbackBoost    = 0.1
+	maxFeedbackBoost = 0.3
+)
+
+// searchFeedback is one report of which search result an agent used for a
+// query. Reports are appended to a JSON lines file, so they double as
+// labelled query/tool pairs for evaluating search quality.
+type searchFeedback struct {
+	Query     string    `json:"query"`
+	ToolName  string    `json:"tool_name,omitempty"` // Empty when none of the results matched
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// feedbackStore counts the tools used per query, loaded from and appended to
+// a file in the cache directory
+type feedbackStore struct {
+	path   string
+	once   sync.Once
+	mu     sync.Mutex
+	counts map[string]map[string]int // Reports by normalized query, then tool name
+}
+
+// newFeedbackStore creates a store backed by path; the file is read on first use
+func newFeedbackStore(path string) *feedbackStore {
+	return &feedbackStore{path: path, counts: make(map[string]map[string]int)}
+}
+
+// normalizeFeedbackQuery makes queries differing only in case and spacing
+// share their feedback
+func normalizeFeedbackQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// load reads the reports recorded so far. Unreadable lines are skipped.
+func (f *feedbackStore) load() {
+	f.once.Do(func() {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var report searchFeedback
+			if json.Unmarshal(scanner.Bytes(), &report) == nil {
+				f.add(report)
+			}
+		}
+	})
+}
+
+// add counts a report; the caller holds mu or is loading
+func (f *feedbackStore) add(report searchFeedback) {
+	if report.ToolName == "" {
+		return // Kept on disk for evaluation, but nothing to boost
+	}
+	query := normalizeFeedbackQuery(report.Query)
+	if f.counts[query] == nil {
+		f.counts[query] = make(map[string]int)
+	}
+	f.counts[query][report.ToolName]++
+}
+
+// record counts a report and appends it to the file, returning how many
+// times the tool has now been reported for the query
+func (f *feedbackStore) record(report searchFeedback) (int, error) {
+	f.load()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.add(report)
+	count := f.counts[normalizeFeedbackQuery(report.Query)][report.ToolName]
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return count, err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return count, err
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return count, err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return count, err
+}
+
+// boosts returns the score boost of every tool reported for a query
+func (f *feedbackStore) boosts(query string) map[string]float64 {
+	f.load()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := f.counts[normalizeFeedbackQuery(query)]
+	if len(counts) == 0 {
+		return nil
+	}
+	boosts := make(map[string]float64, len(counts))
+	for name, count := range counts {
+		boosts[name] = min(float64(count)*feedbackBoost, maxFeedbackBoost)
+	}
+	return boosts
+}
+
+// searchFeedbackStore returns the feedback store, created in the cache
+// directory on first use
+func (s *AggregatorServer) searchFeedbackStore() *feedbackStore {
+	s.feedbackOnce.Do(func() {
+		s.feedback = newFeedbackStore(filepath.Join(s.cacheDir, "search-feedback.jsonl"))
+	})
+	return s.feedback
+}
+
+// SearchFeedbackInput defines the input for search_feedback
+type SearchFeedbackInput struct {
+	Query    string `json:"query" jsonschema:"The tool_search query the results came from, exactly as sent"`
+	ToolName string `json:"tool_name,omitempty" jsonschema:"Name of the result that was actually used. Omit when none of the results matched."`
+}
+
+// handleSearchFeedback records which search result was used for a query, so
+// later searches for it rank that tool higher
+func (s *AggregatorServer) handleSearchFeedback(ctx context.Context, req *mcp.CallToolRequest, input SearchFeedbackInput) (*mcp.CallToolResult, any, error) {
+	errorResult := func(message, errorType string) *mcp.CallToolResult {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      message,
+			"error_type": errorType,
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}
+	}
+
+	if strings.TrimSpace(input.Query) == "" {
+		return errorResult("query is required", "invalid_input"), nil, nil
+	}
+	if input.ToolName != "" {
+		if _, err := s.registry.Get(input.ToolName); err != nil {
+			return errorResult(err.Error(), "tool_not_found"), nil, nil
+		}
+	}
+
+	count, err := s.searchFeedbackStore().record(searchFeedback{
+		Query:     input.Query,
+		ToolName:  input.ToolName,
+		Timestamp: time.Now().UTC(),
+	})
+	// The boosts changed the ranking of cached responses. Bumping the cache
+	// version also keeps searches already reading the old boosts from
+	// caching their response.
+	s.searchResponses.invalidate()
+	if err != nil {
+		// The report still counts for this run
+		s.logger.WarnContext(ctx, "Failed to save search feedback", "error", err)
+	}
+	s.logger.InfoContext(ctx, "Search feedback recorded", "query", input.Query, "tool", input.ToolName)
+
+	result := map[string]any{
+		"query":    input.Query,
+		"recorded": true,
+	}
+	if input.ToolName != "" {
+		result["tool_name"] = input.ToolName
+		result["reports"] = count
+		result["boost"] = min(float64(count)*feedbackBoost, maxFeedbackBoost)
+	} else {
+		result["none_matched"] = true
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultPingInterval is how often external servers are pinged when their
+// config doesn't set pingInterval
+const defaultPingInterval = 30 * time.Second
+
+// serverHealth is the keepalive state of an external server
+type serverHealth struct {
+	Healthy             bool      `json:"healthy"`
+	LastPing            time.Time `json:"last_ping,omitzero"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Reconnecting        bool      `json:"reconnecting,omitempty"` // Connection dropped; being re-established
+	Idle                bool      `json:"idle,omitempty"`         // Lazy server not connected yet
+	Cached              bool      `json:"cached,omitempty"`       // Not connected; tools listed from the snapshot
+	Suspect             bool      `json:"suspect,omitempty"`      // A tool call hung past callCeiling; cleared when the server reconnects
+}
+
+// pingInterval returns a server's keepalive interval, or 0 if pings are disabled
+func pingInterval(seconds int) time.Duration {
+	switch {
+	case seconds < 0:
+		return 0
+	case seconds == 0:
+		return defaultPingInterval
+	default:
+		return time.Duration(seconds) * time.Second
+	}
+}
+
+// startKeepalive marks a newly connected server healthy and pings it every
+// interval until the aggregator is closed or the server's client is replaced
+// (e.g. by server_restart)
+func (s *AggregatorServer) startKeepalive(name string, interval time.Duration) {
+	s.healthMu.Lock()
+	s.health[name] = &serverHealth{Healthy: true}
+	s.healthMu.Unlock()
+
+	if interval == 0 {
+		return
+	}
+
+	client, _ := s.externalClient(name)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.keepaliveCtx.Done():
+				return
+			case <-ticker.C:
+				if current, ok := s.externalClient(name); ok && current != client {
+					return
+				}
+				s.pingServer(s.keepaliveCtx, name, interval/2)
+			}
+		}
+	}()
+}
+
+// pingServer pings an external server once and records the outcome. Servers
+// are marked unhealthy on the first failed ping and healthy again on the next
+// successful one.
+func (s *AggregatorServer) pingServer(ctx context.Context, name string, timeout time.Duration) {
+	client, ok := s.externalClient(name)
+	if !ok {
+		return
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := client.Ping(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return // Shutting down
+	}
+
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	health := s.health[name]
+	health.LastPing = time.Now()
+	if err != nil {
+		health.ConsecutiveFailures++
+		health.LastError = err.Error()
+		if health.Healthy {
+			s.logger.Warn("External server stopped answering pings, marking unhealthy", "name", name, "error", err)
+		}
+		health.Healthy = false
+		return
+	}
+
+	if !health.Healthy {
+		s.logger.Info("External server answers pings again, marking healthy", "name", name, "failed_pings", health.ConsecutiveFailures)
+	}
+	health.Healthy = true
+	health.ConsecutiveFailures = 0
+	health.LastError = ""
+}
+
+// degradedServer is a server whose tools may be missing from or fail in
+// search results for now
+type degradedServer struct {
+	Server string `json:"server"`
+	Reason string `json:"reason"`
+}
+
+// degradedServers lists the servers that are down, reconnecting, served from
+// their snapshot, or that failed to connect at startup, sorted by name
+func (s *AggregatorServer) degradedServers() []degradedServer {
+	var degraded []degradedServer
+	s.healthMu.RLock()
+	for name, health := range s.health {
+		var reason string
+		switch {
+		case health.Idle:
+			continue // Lazy and not needed yet
+		case health.Cached:
+			reason = "not connected, tools listed from the last snapshot"
+		case health.Reconnecting:
+			reason = "connection lost, reconnecting"
+		case !health.Healthy:
+			reason = "not responding"
+		default:
+			continue
+		}
+		if health.LastError != "" {
+			reason += ": " + health.LastError
+		}
+		degraded = append(degraded, degradedServer{Server: name, Reason: reason})
+	}
+
+	// Servers that failed at startup without a snapshot have no health, nor tools
+	for _, timing := range s.startup.snapshot().Servers {
+		if _, ok := s.health[timing.Name]; !ok && timing.Error != "" {
+			degraded = append(degraded, degradedServer{Server: timing.Name, Reason: "failed to connect at startup: " + timing.Error})
+		}
+	}
+	s.healthMu.RUnlock()
+
+	sort.Slice(degraded, func(i, j int) bool {
+		return degraded[i].Server < degraded[j].Server
+	})
+	return degraded
+}
+
+// ServerStatusInput defines the input for server_status
+type ServerStatusInput struct{}
+
+// serverStatus is one external server in the server_status response
+type serverStatus struct {
+	Name  string `json:"name"`
+	Tools int    `json:"tools"`
+	serverHealth
+}
+
+func (s *AggregatorServer) handleServerStatus(ctx context.Context, req *mcp.CallToolRequest, input ServerStatusInput) (*mcp.CallToolResult, any, error) {
+	toolCounts := make(map[string]int)
+	for _, tool := range s.registry.ListAll() {
+		toolCounts[tool.SourceName]++
+	}
+
+	s.healthMu.RLock()
+	servers := make([]serverStatus, 0, len(s.health))
+	for name, health := range s.health {
+		servers = append(servers, serverStatus{Name: name, Tools: toolCounts[name], serverHealth: *health})
+	}
+	s.healthMu.RUnlock()
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	resultJSON, _ := json.Marshal(map[string]any{"servers": servers})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/llmsearch"
+)
+
+// healthPingTimeout is how long the health meta-tool waits for a server's ping
+const healthPingTimeout = 5 * time.Second
+
+// minCacheDirFreeBytes is the free space under which the cache directory check warns
+const minCacheDirFreeBytes = 100 << 20
+
+// Outcomes of a health check, best first
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// healthCheck is the outcome of one check run by the health meta-tool
+type healthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // pass, warn or fail
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // How to fix a warning or failure
+}
+
+// HealthInput defines the input for health
+type HealthInput struct{}
+
+// handleHealth runs active checks of the aggregator and the servers behind it
+// and reports each with a hint on how to fix it
+func (s *AggregatorServer) handleHealth(ctx context.Context, req *mcp.CallToolRequest, input HealthInput) (*mcp.CallToolResult, any, error) {
+	var checks []healthCheck
+	checks = append(checks, s.checkServers(ctx)...)
+	checks = append(checks, s.checkSearchIndex())
+	checks = append(checks, s.checkSearchProviders()...)
+	checks = append(checks, s.checkCacheDir())
+
+	status := checkPass
+	for _, check := range checks {
+		if check.Status == checkFail || (check.Status == checkWarn && status == checkPass) {
+			status = check.Status
+		}
+	}
+
+	resultJSON, _ := json.Marshal(map[string]any{
+		"status": status,
+		"checks": checks,
+	})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// checkServers pings every connected external server at once; idle lazy
+// servers are not started for it
+func (s *AggregatorServer) checkServers(ctx context.Context) []healthCheck {
+	s.healthMu.RLock()
+	names := make([]string, 0, len(s.health))
+	for name, health := range s.health {
+		if !health.Idle && !health.Cached && !health.Reconnecting {
+			names = append(names, name)
+		}
+	}
+	s.healthMu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Go(func() {
+			s.pingServer(ctx, name, healthPingTimeout)
+		})
+	}
+	wg.Wait()
+
+	s.healthMu.RLock()
+	checks := make([]healthCheck, 0, len(s.health))
+	for name, health := range s.health {
+		check := healthCheck{Name: "server:" + name}
+		switch {
+		case health.Idle:
+			check.Status = checkPass
+			check.Message = "lazy server, not started yet"
+		case health.Cached:
+			check.Status = checkFail
+			check.Message = "not connected; its tools are listed from the snapshot and calls will fail"
+			check.Hint = fmt.Sprintf("Check the server's command and the OneMCP log, then run server_restart with server %q", name)
+		case health.Reconnecting:
+			check.Status = checkWarn
+			check.Message = "connection dropped, reconnecting"
+			check.Hint = fmt.Sprintf("Wait for the reconnect or run server_restart with server %q", name)
+		case !health.Healthy:
+			check.Status = checkFail
+			check.Message = "ping failed: " + health.LastError
+			check.Hint = fmt.Sprintf("Run server_restart with server %q; if it keeps failing, check the server's logs", name)
+		default:
+			check.Status = checkPass
+			check.Message = "answered ping"
+		}
+		checks = append(checks, check)
+	}
+	s.healthMu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+	return checks
+}
+
+// checkSearchIndex verifies the search index holds every tool, prompt and
+// resource in the catalog
+func (s *AggregatorServer) checkSearchIndex() healthCheck {
+	check := healthCheck{Name: "search_index"}
+	catalog := len(s.searchableItems())
+
+	s.searchMu.RLock()
+	store := s.searchStore
+	s.searchMu.RUnlock()
+
+	switch {
+	case store == nil && catalog == 0:
+		check.Status = checkPass
+		check.Message = "nothing to index"
+	case store == nil:
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("no search index for %d catalog items; tool_search finds nothing", catalog)
+		check.Hint = "Run server_refresh to build the index"
+	case store.GetToolCount() != catalog:
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("%d items indexed, catalog has %d", store.GetToolCount(), catalog)
+		check.Hint = "Run vector_reindex to rebuild the index, or server_refresh to also reload the servers"
+	default:
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("%d items indexed", catalog)
+	}
+	return check
+}
+
+// checkSearchProviders creates a searcher for every LLM provider in the
+// search chain, which finds missing CLIs, API keys and unreachable servers
+func (s *AggregatorServer) checkSearchProviders() []healthCheck {
+	s.searchMu.RLock()
+	active := s.searchProvider
+	chain := s.providerChainLocked()
+	configs := make(map[string]llmsearch.ProviderConfig, len(chain))
+	for _, provider := range chain {
+		cfg := s.providerConfigs[provider]
+		cfg.Timeout = s.searchTimeout
+		configs[provider] = cfg
+	}
+	s.searchMu.RUnlock()
+
+	var checks []healthCheck
+	for _, provider := range chain {
+		if provider == tfidfProvider {
+			break
+		}
+		check := healthCheck{Name: "search_provider:" + provider}
+		if _, err := llmsearch.NewSearcher(provider, configs[provider], s.logger); err != nil {
+			// Search still answers from the next provider or the local index
+			check.Status = checkWarn
+			check.Message = err.Error()
+			check.Hint = "Install or configure the provider (CLI in PATH, API key or URL in settings), or switch with search_provider_set"
+			if provider == active {
+				check.Message += "; searches fall back to the next provider"
+			}
+		} else {
+			check.Status = checkPass
+			check.Message = "available"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkCacheDir verifies the cache directory is writable and has free space
+// for snapshots, tokens and runner caches
+func (s *AggregatorServer) checkCacheDir() healthCheck {
+	check := healthCheck{Name: "cache_dir"}
+	if s.cacheDir == "" {
+		check.Status = checkWarn
+		check.Message = "no cache directory; snapshots, OAuth tokens and search feedback are not kept"
+		check.Hint = "Set cacheDir in settings"
+		return check
+	}
+
+	if err := os.MkdirAll(s.cacheDir, 0700); err != nil {
+		check.Status = checkFail
+		check.Message = err.Error()
+		check.Hint = "Fix the permissions of the cache directory or set cacheDir in settings"
+		return check
+	}
+	probe, err := os.CreateTemp(s.cacheDir, ".health-*")
+	if err != nil {
+		check.Status = checkFail
+		check.Message = "not writable: " + err.Error()
+		check.Hint = "Fix the permissions of the cache directory or set cacheDir in settings"
+		return check
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	free, err := freeDiskSpace(s.cacheDir)
+	switch {
+	case err != nil:
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("%s is writable (free space unknown: %v)", s.cacheDir, err)
+	case free < minCacheDirFreeBytes:
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("%s has only %d MB free", s.cacheDir, free>>20)
+		check.Hint = "Free disk space or point cacheDir at another disk"
+	default:
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("%s is writable, %d MB free", s.cacheDir, free>>20)
+	}
+	return check
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultHistoryLimit is how many executions tool_history returns per page
+const defaultHistoryLimit = 20
+
+// ToolHistoryInput defines the input for tool_history
+type ToolHistoryInput struct {
+	ToolName string `json:"tool_name,omitempty" jsonschema:"Optional tool name to list only that tool's executions"`
+	Offset   int    `json:"offset,omitempty" jsonschema:"Number of executions to skip for pagination. Default: 0"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"Maximum executions to return. Default: 20"`
+}
+
+// handleToolHistory lists recent tool executions, newest first. Arguments
+// are reported as a digest so repeated calls can be spotted cheaply.
+func (s *AggregatorServer) handleToolHistory(ctx context.Context, req *mcp.CallToolRequest, input ToolHistoryInput) (*mcp.CallToolResult, any, error) {
+	history := s.registry.History()
+	if input.ToolName != "" {
+		filtered := history[:0]
+		for _, record := range history {
+			if record.ToolName == input.ToolName {
+				filtered = append(filtered, record)
+			}
+		}
+		history = filtered
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	offset := max(input.Offset, 0)
+	start := min(offset, len(history))
+	end := min(start+limit, len(history))
+
+	resultJSON, _ := json.Marshal(map[string]any{
+		"total_count":    len(history),
+		"returned_count": end - start,
+		"offset":         offset,
+		"limit":          limit,
+		"has_more":       end < len(history),
+		"executions":     history[start:end],
+	})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultInstructionsMaxChars is how much of each external server's
+// instructions is kept when instructionsMaxChars isn't set
+const defaultInstructionsMaxChars = 500
+
+// aggregateInstructions merges the instructions of the connected external
+// servers into the aggregator's own, one condensed section per server sorted
+// by name. It returns "" if no server declared instructions.
+func (s *AggregatorServer) aggregateInstructions() string {
+	if !s.mergeInstructions {
+		return ""
+	}
+
+	clients := s.connectedClients()
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sections []string
+	for _, name := range names {
+		instructions := condenseInstructions(clients[name].ServerInfo().Instructions, s.instructionsLimit)
+		if instructions != "" {
+			sections = append(sections, "## "+name+"\n"+instructions)
+		}
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+
+	s.logger.Info("Forwarding external server instructions", "servers", len(sections))
+	return s.expandMetaToolNames("Tools of these servers are found with {{tool_search}} and run with {{tool_execute}}, prefixed with the server name. Their usage guidance:\n\n") +
+		strings.Join(sections, "\n\n")
+}
+
+// condenseInstructions collapses whitespace and cuts instructions to at most
+// maxChars runes at a word boundary, marking the cut with an ellipsis
+func condenseInstructions(instructions string, maxChars int) string {
+	condensed := strings.Join(strings.Fields(instructions), " ")
+	runes := []rune(condensed)
+	if len(runes) <= maxChars {
+		return condensed
+	}
+
+	cut := string(runes[:maxChars])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcpclient"
+)
+
+// registerLazyServer registers a lazy server's tools from its snapshot without
+// connecting. It reports false if there is no usable snapshot, in which case
+// the server has to be connected to learn its tools.
+func (s *AggregatorServer) registerLazyServer(name string, config mcpclient.MCPServerConfig) bool {
+	if !s.registerFromSnapshot(name, config, &serverHealth{Healthy: true, Idle: true}) {
+		s.logger.Info("No tool snapshot for lazy server, connecting now", "name", name)
+		return false
+	}
+	s.logger.Info("Registered lazy external server from snapshot", "name", name)
+	return true
+}
+
+// registerFromSnapshot registers a server's tools from its snapshot, with an
+// executor that connects the server on the first call. It reports false if
+// there is no usable snapshot.
+func (s *AggregatorServer) registerFromSnapshot(name string, config mcpclient.MCPServerConfig, health *serverHealth) bool {
+	externalTools, err := s.loadToolSnapshot(name)
+	if err != nil {
+		s.logger.Debug("No usable tool snapshot", "name", name, "error", err)
+		return false
+	}
+
+	s.registry.RegisterExternalExecutor(name, &lazyExecutor{server: s, name: name, config: config})
+	s.registerExternalTools(name, config, externalTools)
+
+	s.clientsMu.Lock()
+	s.serverConfigs[name] = config
+	s.clientsMu.Unlock()
+
+	s.healthMu.Lock()
+	s.health[name] = health
+	s.healthMu.Unlock()
+
+	s.logger.Info("Registered external tools from snapshot", "name", name, "tools", len(externalTools))
+	return true
+}
+
+// serverLocks serializes connecting each external server, so a slow or hung
+// server only holds up calls to itself
+type serverLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // Lock of each server by name
+}
+
+// lock locks the named server and returns the function unlocking it
+func (l *serverLocks) lock(name string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := l.locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[name] = lock
+	}
+	l.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// lazyExecutor connects a server registered from its snapshot (a lazy server,
+// or one that couldn't be connected at startup) on its first tool call and
+// hands the call to the connected client. Connecting replaces it as the
+// server's executor.
+type lazyExecutor struct {
+	server *AggregatorServer
+	name   string
+	config mcpclient.MCPServerConfig
+}
+
+// CallTool connects the server if needed and calls the tool on it
+func (e *lazyExecutor) CallTool(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	client, err := e.server.connectLazyServer(ctx, e.name, e.config)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTool(ctx, toolName, arguments)
+}
+
+// connectLazyServer connects a server registered from its snapshot, once. Its
+// snapshot tools are replaced by the ones it lists now and the search index is
+// rebuilt in the background.
+func (s *AggregatorServer) connectLazyServer(ctx context.Context, name string, config mcpclient.MCPServerConfig) (*mcpclient.MCPClient, error) {
+	defer s.connectLocks.lock(name)()
+
+	if client, ok := s.externalClient(name); ok {
+		return client, nil // Connected by an earlier call
+	}
+
+	s.logger.Info("Connecting external server on first use", "name", name)
+	snapshot := s.registry.UnregisterSource(name)
+	if err := s.connectExternalServer(ctx, name, config, nil); err != nil {
+		// Keep serving the snapshot so the next call can retry
+		if externalTools, loadErr := s.loadToolSnapshot(name); loadErr == nil {
+			s.registerExternalTools(name, config, externalTools)
+		}
+		s.setSnapshotHealth(name, err)
+		return nil, fmt.Errorf("failed to connect server %s: %w", name, err)
+	}
+
+	go func() {
+		if err := s.rebuildSearchStore(); err != nil {
+			s.logger.Error("Failed to rebuild search store after connecting server", "name", name, "error", err)
+		}
+	}()
+
+	client, _ := s.externalClient(name)
+	s.logger.Info("Connected external server registered from snapshot", "name", name, "snapshot_tools", snapshot)
+	return client, nil
+}
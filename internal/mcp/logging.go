@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultServerLogLevel is the minimum level forwarded from external servers
+// that don't configure logLevel
+const defaultServerLogLevel mcp.LoggingLevel = "warning"
+
+// serverLogLevels maps MCP logging levels to the aggregator log levels
+var serverLogLevels = map[mcp.LoggingLevel]slog.Level{
+	"debug":     mcp.LevelDebug,
+	"info":      mcp.LevelInfo,
+	"notice":    mcp.LevelNotice,
+	"warning":   mcp.LevelWarning,
+	"error":     mcp.LevelError,
+	"critical":  mcp.LevelCritical,
+	"alert":     mcp.LevelAlert,
+	"emergency": mcp.LevelEmergency,
+}
+
+// subscribeServerLogs asks an external server for log messages at its
+// configured minimum level. "off" leaves the server's logging untouched.
+func (s *AggregatorServer) subscribeServerLogs(ctx context.Context, name string, configured string) {
+	if configured == "off" {
+		return
+	}
+
+	level := mcp.LoggingLevel(configured)
+	if level == "" {
+		level = defaultServerLogLevel
+	} else if _, ok := serverLogLevels[level]; !ok {
+		s.logger.Warn("Unknown logLevel, using default", "name", name, "logLevel", configured, "default", defaultServerLogLevel)
+		level = defaultServerLogLevel
+	}
+
+	client, ok := s.externalClient(name)
+	if !ok {
+		return
+	}
+	if err := client.SetLogLevel(ctx, level); err != nil {
+		s.logger.Warn("Failed to subscribe to external server logs", "name", name, "error", err)
+	}
+}
+
+// handleLoggingMessage writes a log message from an external server to the
+// aggregator log and re-emits it to connected clients, tagged with the server
+// name. Clients only receive messages at or above the level they set.
+func (s *AggregatorServer) handleLoggingMessage(ctx context.Context, name string, params *mcp.LoggingMessageParams) {
+	level, ok := serverLogLevels[params.Level]
+	if !ok {
+		level = slog.LevelInfo
+	}
+
+	loggerName := name
+	if params.Logger != "" {
+		loggerName = name + "/" + params.Logger
+	}
+	s.logger.Log(ctx, level, "External server log", "server", name, "logger", params.Logger, "data", params.Data)
+
+	if s.server == nil {
+		return
+	}
+	for session := range s.server.Sessions() {
+		err := session.Log(ctx, &mcp.LoggingMessageParams{
+			Level:  params.Level,
+			Logger: loggerName,
+			Data:   params.Data,
+		})
+		if err != nil {
+			s.logger.Debug("Failed to forward external server log to client", "server", name, "error", err)
+		}
+	}
+}
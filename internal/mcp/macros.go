@@ -0,0 +1,580 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// Macros are registered as internal tools named macroPrefix + macro name, in
+// macroCategory
+const (
+	macroPrefix   = "macro_"
+	macroCategory = "macro"
+)
+
+// macroCallsKey is the context key of the macros a request is replaying,
+// outermost first
+type macroCallsKey struct{}
+
+// macroName is what a macro or slot may be called
+var macroName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// slotPlaceholder matches the {{slot}} placeholders in step arguments
+var slotPlaceholder = regexp.MustCompile(`\{\{([a-zA-Z0-9_-]+)\}\}`)
+
+// MacroStep is one tool call of a macro, recorded or written in the config.
+// Slots appear in string arguments as {{slot}}.
+type MacroStep struct {
+	ToolName  string         `json:"tool_name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// MacroConfig defines a macro in the config, without recording it. Its slots
+// are the {{slot}} placeholders in the step arguments.
+type MacroConfig struct {
+	Description string      `json:"description"`
+	Steps       []MacroStep `json:"steps"`
+}
+
+// macro is a saved sequence of tool calls, replayed as one tool
+type macro struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Slots       []string    `json:"slots,omitempty"`
+	Steps       []MacroStep `json:"steps"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// macroRecording is a macro being recorded by a client session
+type macroRecording struct {
+	name        string
+	description string
+	steps       []MacroStep
+}
+
+// macroState holds the saved macros and the recordings in progress
+type macroState struct {
+	mu         sync.Mutex
+	macros     map[string]*macro          // Recorded macros by name
+	configured map[string]*macro          // Macros defined in the config, which recordings can't replace
+	recordings map[string]*macroRecording // Recordings by client session ID
+}
+
+// macrosPath returns the file saved macros persist to, or "" without a cache directory
+func (s *AggregatorServer) macrosPath() string {
+	if s.cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(s.cacheDir, "macros.json")
+}
+
+// loadMacros registers the macros defined in the config and those recorded
+// by earlier runs. Invalid macros, and macros that lead back to themselves,
+// are skipped with a warning; a missing or unreadable macros file leaves no
+// recorded macros.
+func (s *AggregatorServer) loadMacros(configured map[string]MacroConfig) {
+	s.macros.mu.Lock()
+	defer s.macros.mu.Unlock()
+	s.macros.macros = make(map[string]*macro)
+	s.macros.configured = make(map[string]*macro)
+	s.macros.recordings = make(map[string]*macroRecording)
+
+	valid := make(map[string]*macro, len(configured))
+	for name, config := range configured {
+		m := &macro{Name: name, Description: config.Description, Steps: config.Steps, Slots: macroSlots(config.Steps)}
+		if err := validateMacro(m); err != nil {
+			s.logger.Warn("Skipping invalid macro in config", "name", name, "error", err)
+			continue
+		}
+		valid[name] = m
+	}
+	for name, m := range valid {
+		// Replaying a macro that leads back to itself would never end
+		if cycle := macroCycle(valid, name); cycle != nil {
+			s.logger.Warn("Skipping macro in config that calls itself", "name", name, "cycle", strings.Join(cycle, " -> "))
+			continue
+		}
+		if err := s.registry.Register(s.macroTool(m)); err != nil {
+			s.logger.Warn("Failed to register macro", "name", name, "error", err)
+			continue
+		}
+		s.macros.configured[name] = m
+	}
+
+	path := s.macrosPath()
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Warn("Failed to read macros", "path", path, "error", err)
+		}
+		return
+	}
+	var saved []*macro
+	if err := json.Unmarshal(data, &saved); err != nil {
+		s.logger.Warn("Failed to parse macros", "path", path, "error", err)
+		return
+	}
+	graph := maps.Clone(s.macros.configured)
+	var recorded []*macro
+	for _, m := range saved {
+		if _, ok := s.macros.configured[m.Name]; ok {
+			s.logger.Warn("Recorded macro shadowed by the config, ignoring it", "name", m.Name)
+			continue
+		}
+		if err := validateMacro(m); err != nil {
+			s.logger.Warn("Skipping invalid recorded macro", "name", m.Name, "error", err)
+			continue
+		}
+		graph[m.Name] = m
+		recorded = append(recorded, m)
+	}
+	for _, m := range recorded {
+		if cycle := macroCycle(graph, m.Name); cycle != nil {
+			s.logger.Warn("Skipping recorded macro that calls itself", "name", m.Name, "cycle", strings.Join(cycle, " -> "))
+			continue
+		}
+		if err := s.registry.Register(s.macroTool(m)); err != nil {
+			s.logger.Warn("Failed to register macro", "name", m.Name, "error", err)
+			continue
+		}
+		s.macros.macros[m.Name] = m
+	}
+	s.logger.Info("Loaded macros", "path", path, "count", len(s.macros.macros))
+}
+
+// validateMacro checks a configured or recorded macro can be registered and
+// replayed on its own. Calls into other macros are checked by macroCycle.
+func validateMacro(m *macro) error {
+	if !macroName.MatchString(m.Name) {
+		return fmt.Errorf("invalid macro name %q: use letters, digits, '_' and '-'", m.Name)
+	}
+	if len(m.Steps) == 0 {
+		return errors.New("macro has no steps")
+	}
+	for i, step := range m.Steps {
+		if step.ToolName == "" {
+			return fmt.Errorf("step %d has no tool_name", i+1)
+		}
+		if step.ToolName == macroPrefix+m.Name {
+			return fmt.Errorf("macro %s can't call itself", m.Name)
+		}
+	}
+	return nil
+}
+
+// macroCycle returns the macros through which the named macro ends up
+// calling itself, e.g. [a b a], or nil if it never does
+func macroCycle(macros map[string]*macro, name string) []string {
+	visited := make(map[string]bool)
+	var walk func(path []string) []string
+	walk = func(path []string) []string {
+		m := macros[path[len(path)-1]]
+		if m == nil {
+			return nil
+		}
+		for _, step := range m.Steps {
+			callee, ok := strings.CutPrefix(step.ToolName, macroPrefix)
+			if !ok {
+				continue
+			}
+			if callee == name {
+				return append(slices.Clip(path), callee)
+			}
+			if visited[callee] {
+				continue
+			}
+			visited[callee] = true
+			if cycle := walk(append(slices.Clip(path), callee)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk([]string{name})
+}
+
+// macroGraphLocked returns every registered macro, configured or recorded, by
+// name. Callers must hold macros.mu.
+func (s *AggregatorServer) macroGraphLocked() map[string]*macro {
+	graph := maps.Clone(s.macros.configured)
+	maps.Copy(graph, s.macros.macros)
+	return graph
+}
+
+// macroSlots returns the slots used in the step arguments, sorted
+func macroSlots(steps []MacroStep) []string {
+	seen := make(map[string]bool)
+	var find func(value any)
+	find = func(value any) {
+		switch v := value.(type) {
+		case string:
+			for _, match := range slotPlaceholder.FindAllStringSubmatch(v, -1) {
+				seen[match[1]] = true
+			}
+		case map[string]any:
+			for _, item := range v {
+				find(item)
+			}
+		case []any:
+			for _, item := range v {
+				find(item)
+			}
+		}
+	}
+	for _, step := range steps {
+		find(step.Arguments)
+	}
+
+	slots := make([]string, 0, len(seen))
+	for slot := range seen {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	return slots
+}
+
+// saveMacrosLocked writes every recorded macro to the state file. Callers must
+// hold macros.mu.
+func (s *AggregatorServer) saveMacrosLocked() error {
+	path := s.macrosPath()
+	if path == "" {
+		return nil
+	}
+
+	saved := make([]*macro, 0, len(s.macros.macros))
+	for _, m := range s.macros.macros {
+		saved = append(saved, m)
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].Name < saved[j].Name
+	})
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// macroTool builds the internal tool that replays a macro
+func (s *AggregatorServer) macroTool(m *macro) *tools.Tool {
+	properties := make(map[string]any, len(m.Slots))
+	required := make([]any, len(m.Slots))
+	for i, slot := range m.Slots {
+		properties[slot] = map[string]any{"description": fmt.Sprintf("Value of {{%s}} in the macro's steps", slot)}
+		required[i] = slot
+	}
+
+	steps := make([]string, len(m.Steps))
+	for i, step := range m.Steps {
+		steps[i] = step.ToolName
+	}
+	description := m.Description
+	if description != "" {
+		description += " "
+	}
+	description += fmt.Sprintf("(Macro running %s)", strings.Join(steps, ", "))
+
+	return &tools.Tool{
+		Name:        macroPrefix + m.Name,
+		Category:    macroCategory,
+		Description: description,
+		Source:      tools.SourceInternal,
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		},
+		Handler: func(ctx context.Context, params map[string]any) (map[string]any, error) {
+			return s.replayMacro(ctx, m, params)
+		},
+	}
+}
+
+// replayMacro runs a macro's steps in order with its slots filled in,
+// stopping at the first step that fails
+func (s *AggregatorServer) replayMacro(ctx context.Context, m *macro, params map[string]any) (map[string]any, error) {
+	// Macros may call other macros, but never one that is still running:
+	// steps run synchronously, so a cycle would recurse until the stack overflows
+	running, _ := ctx.Value(macroCallsKey{}).([]string)
+	if slices.Contains(running, m.Name) {
+		return nil, fmt.Errorf("macro %s calls itself: %s -> %s", m.Name, strings.Join(running, " -> "), m.Name)
+	}
+	ctx = context.WithValue(ctx, macroCallsKey{}, append(slices.Clip(running), m.Name))
+
+	values := make(map[string]any, len(m.Slots))
+	for _, slot := range m.Slots {
+		value, ok := params[slot]
+		if !ok {
+			return nil, fmt.Errorf("missing slot: %s", slot)
+		}
+		values[slot] = value
+	}
+
+	var steps []map[string]any
+	for i, step := range m.Steps {
+		arguments, _ := fillSlots(step.Arguments, values).(map[string]any)
+		result, err := s.registry.Execute(ctx, step.ToolName, arguments)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, step.ToolName, err)
+		}
+		if !result.Success {
+			return nil, fmt.Errorf("step %d (%s) failed after %d completed steps: %s", i+1, step.ToolName, i, result.Error)
+		}
+
+		var output any = result.Result
+		if result.Output != nil {
+			output, _ = externalOutput(result.Output)
+		}
+		steps = append(steps, map[string]any{
+			"tool_name":         step.ToolName,
+			"result":            output,
+			"execution_time_ms": result.ExecutionTimeMs,
+		})
+	}
+	return map[string]any{"steps": steps}, nil
+}
+
+// fillSlots replaces {{slot}} in string values with the slot's value. A
+// string that is only a placeholder takes the value as is, so slots can fill
+// numbers and objects too.
+func fillSlots(value any, values map[string]any) any {
+	switch v := value.(type) {
+	case string:
+		for slot, filler := range values {
+			placeholder := "{{" + slot + "}}"
+			if v == placeholder {
+				return filler
+			}
+			v = strings.ReplaceAll(v, placeholder, fmt.Sprint(filler))
+		}
+		return v
+	case map[string]any:
+		filled := make(map[string]any, len(v))
+		for key, item := range v {
+			filled[key] = fillSlots(item, values)
+		}
+		return filled
+	case []any:
+		filled := make([]any, len(v))
+		for i, item := range v {
+			filled[i] = fillSlots(item, values)
+		}
+		return filled
+	}
+	return value
+}
+
+// makeSlots replaces a recorded value with {{slot}} in string values
+func makeSlots(value any, slot, recorded string) any {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, recorded, "{{"+slot+"}}")
+	case map[string]any:
+		slotted := make(map[string]any, len(v))
+		for key, item := range v {
+			slotted[key] = makeSlots(item, slot, recorded)
+		}
+		return slotted
+	case []any:
+		slotted := make([]any, len(v))
+		for i, item := range v {
+			slotted[i] = makeSlots(item, slot, recorded)
+		}
+		return slotted
+	}
+	return value
+}
+
+// recordMacroStep adds a successful tool_execute call to the recording of the
+// session in ctx, if it is recording
+func (s *AggregatorServer) recordMacroStep(ctx context.Context, toolName string, arguments map[string]any) {
+	s.macros.mu.Lock()
+	defer s.macros.mu.Unlock()
+
+	recording, ok := s.macros.recordings[sessionID(ctx)]
+	if !ok {
+		return
+	}
+	// Round-trip through JSON so steps don't share maps with the caller
+	var copied map[string]any
+	if data, err := json.Marshal(arguments); err == nil {
+		json.Unmarshal(data, &copied)
+	}
+	recording.steps = append(recording.steps, MacroStep{ToolName: toolName, Arguments: copied})
+}
+
+// MacroRecordStartInput defines the input for macro_record_start
+type MacroRecordStartInput struct {
+	Name        string `json:"name" jsonschema:"Name of the macro (letters, digits, '_' and '-'); it is saved as the tool macro_<name>"`
+	Description string `json:"description,omitempty" jsonschema:"What the macro does, shown in tool_search results"`
+}
+
+// handleMacroRecordStart starts recording the session's successful
+// tool_execute calls
+func (s *AggregatorServer) handleMacroRecordStart(ctx context.Context, req *mcp.CallToolRequest, input MacroRecordStartInput) (*mcp.CallToolResult, any, error) {
+	if !macroName.MatchString(input.Name) {
+		return metaToolError(fmt.Errorf("invalid macro name %q: use letters, digits, '_' and '-'", input.Name), "invalid_input"), nil, nil
+	}
+
+	s.macros.mu.Lock()
+	if _, ok := s.macros.configured[input.Name]; ok {
+		s.macros.mu.Unlock()
+		return metaToolError(fmt.Errorf("macro %s is defined in the config; record under another name", input.Name), "invalid_input"), nil, nil
+	}
+	session := sessionID(ctx)
+	if recording, ok := s.macros.recordings[session]; ok {
+		s.macros.mu.Unlock()
+		return metaToolError(fmt.Errorf("already recording macro %s; stop it with macro_record_stop first", recording.name), "already_recording"), nil, nil
+	}
+	s.macros.recordings[session] = &macroRecording{name: input.Name, description: input.Description}
+	s.macros.mu.Unlock()
+
+	s.logger.InfoContext(ctx, "Started recording macro", "name", input.Name)
+	resultJSON, _ := json.Marshal(map[string]any{
+		"recording": input.Name,
+		"message":   "Successful tool_execute calls are now recorded. Call macro_record_stop to save them.",
+	})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// MacroRecordStopInput defines the input for macro_record_stop
+type MacroRecordStopInput struct {
+	Slots   map[string]string `json:"slots,omitempty" jsonschema:"Parameters of the macro: slot name to a value used in the recorded arguments. Every occurrence of the value becomes a parameter named after the slot, e.g. {\"url\": \"https://example.com\"}."`
+	Discard bool              `json:"discard,omitempty" jsonschema:"Stop recording without saving. Default: false"`
+}
+
+// handleMacroRecordStop ends the session's recording and saves it as a macro
+// tool, replacing any macro of the same name
+func (s *AggregatorServer) handleMacroRecordStop(ctx context.Context, req *mcp.CallToolRequest, input MacroRecordStopInput) (*mcp.CallToolResult, any, error) {
+	s.macros.mu.Lock()
+	session := sessionID(ctx)
+	recording, ok := s.macros.recordings[session]
+	if !ok {
+		s.macros.mu.Unlock()
+		return metaToolError(errors.New("not recording; start with macro_record_start"), "not_recording"), nil, nil
+	}
+	if input.Discard {
+		delete(s.macros.recordings, session)
+		s.macros.mu.Unlock()
+		s.logger.InfoContext(ctx, "Discarded macro recording", "name", recording.name)
+		resultJSON, _ := json.Marshal(map[string]any{"discarded": recording.name})
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+	if len(recording.steps) == 0 {
+		s.macros.mu.Unlock()
+		return metaToolError(errors.New("no tool_execute calls recorded yet; make some or stop with discard"), "empty_macro"), nil, nil
+	}
+
+	m := &macro{
+		Name:        recording.name,
+		Description: recording.description,
+		Steps:       recording.steps,
+		CreatedAt:   time.Now(),
+	}
+	if err := validateMacro(m); err != nil {
+		s.macros.mu.Unlock()
+		return metaToolError(err, "invalid_input"), nil, nil
+	}
+	// The recording may call a macro that calls back into the one it replaces
+	graph := s.macroGraphLocked()
+	graph[m.Name] = m
+	if cycle := macroCycle(graph, m.Name); cycle != nil {
+		s.macros.mu.Unlock()
+		return metaToolError(fmt.Errorf("macro %s would call itself: %s", m.Name, strings.Join(cycle, " -> ")), "invalid_input"), nil, nil
+	}
+	for slot, recorded := range input.Slots {
+		if !macroName.MatchString(slot) || recorded == "" {
+			s.macros.mu.Unlock()
+			return metaToolError(fmt.Errorf("invalid slot %q: names use letters, digits, '_' and '-', values can't be empty", slot), "invalid_input"), nil, nil
+		}
+		m.Slots = append(m.Slots, slot)
+	}
+	sort.Strings(m.Slots)
+	for i, step := range m.Steps {
+		var arguments any = step.Arguments
+		for _, slot := range m.Slots {
+			arguments = makeSlots(arguments, slot, input.Slots[slot])
+		}
+		m.Steps[i].Arguments, _ = arguments.(map[string]any)
+	}
+
+	s.registry.Unregister(macroPrefix + m.Name)
+	if err := s.registry.Register(s.macroTool(m)); err != nil {
+		s.macros.mu.Unlock()
+		return metaToolError(err, "invalid_input"), nil, nil
+	}
+	delete(s.macros.recordings, session)
+	s.macros.macros[m.Name] = m
+	saveErr := s.saveMacrosLocked()
+	s.macros.mu.Unlock()
+
+	if err := s.rebuildSearchStore(); err != nil {
+		s.logger.WarnContext(ctx, "Failed to index macro", "name", m.Name, "error", err)
+	}
+	s.logger.InfoContext(ctx, "Saved macro", "name", m.Name, "steps", len(m.Steps), "slots", m.Slots)
+
+	result := map[string]any{
+		"tool_name": macroPrefix + m.Name,
+		"steps":     m.Steps,
+		"slots":     m.Slots,
+		"persisted": saveErr == nil && s.macrosPath() != "",
+	}
+	if saveErr != nil {
+		s.logger.ErrorContext(ctx, "Failed to persist macros", "path", s.macrosPath(), "error", saveErr)
+		result["persist_error"] = saveErr.Error()
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// metaToolError reports a failed meta-tool call with its error type
+func metaToolError(err error, errorType string) *mcp.CallToolResult {
+	resultJSON, _ := json.Marshal(map[string]any{
+		"error":      err.Error(),
+		"error_type": errorType,
+	})
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}
+}
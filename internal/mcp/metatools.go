@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// metaTools are the built-in names of the meta-tools. The metaToolPrefix and
+// metaToolNames settings change the names clients see.
+var metaTools = []string{
+	"tool_search", "search_feedback", "tool_execute", "macro_record_start", "macro_record_stop",
+	"tool_schema", "tool_validate", "prompt_search", "prompt_get", "resource_read", "usage_examples",
+	"tool_execute_parallel", "tool_history", "search_provider_set", "vector_reindex", "stats",
+	"config_get", "config_set", "health", "session_info", "server_status", "server_capabilities",
+	"server_refresh", "server_restart", "tool_disable", "tool_enable",
+}
+
+// validToolName matches the names MCP allows for tools
+var validToolName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// metaToolNameCandidates returns the names a meta-tool may be exposed under,
+// most wanted first: its name from metaToolNames, its prefixed name and its
+// built-in name. Invalid names are left out.
+func metaToolNameCandidates(name string, settings Settings) []string {
+	var candidates []string
+	if renamed, ok := settings.MetaToolNames[name]; ok {
+		candidates = append(candidates, renamed)
+	}
+	if settings.MetaToolPrefix != "" {
+		candidates = append(candidates, settings.MetaToolPrefix+name)
+	}
+	candidates = slices.DeleteFunc(candidates, func(candidate string) bool {
+		return !validToolName.MatchString(candidate)
+	})
+	return append(candidates, name)
+}
+
+// configureMetaToolNames applies the metaToolPrefix and metaToolNames
+// settings. Names in metaToolNames are used as given, without the prefix. A
+// name that is invalid or clashes with another meta-tool's name is rejected:
+// a clashing rename falls back to the prefixed name, and a clashing prefixed
+// name to the built-in one, so the tool keeping its name is never replaced.
+func (s *AggregatorServer) configureMetaToolNames(settings Settings) {
+	for name := range settings.MetaToolNames {
+		if !slices.Contains(metaTools, name) {
+			s.logger.Warn("Unknown meta-tool in metaToolNames, ignoring", "name", name)
+		}
+	}
+
+	candidates := make(map[string][]string)
+	for _, name := range metaTools {
+		candidates[name] = metaToolNameCandidates(name, settings)
+		if renamed, ok := settings.MetaToolNames[name]; ok && !validToolName.MatchString(renamed) {
+			s.logger.Warn("Invalid meta-tool name, ignoring", "meta_tool", name, "name", renamed)
+		}
+	}
+
+	// Until no two meta-tools share a name, the claimant with the fewest
+	// candidates left (built-in before prefixed before renamed) keeps a name
+	// and the others move on to their next candidate. Built-in names are
+	// unique, so this ends.
+	for {
+		claims := make(map[string][]string) // Exposed name -> meta-tools
+		for _, name := range metaTools {
+			claims[candidates[name][0]] = append(claims[candidates[name][0]], name)
+		}
+
+		clashed := false
+		for exposed, claimants := range claims {
+			if len(claimants) < 2 {
+				continue
+			}
+			clashed = true
+			fewest := len(candidates[slices.MinFunc(claimants, func(a, b string) int {
+				return cmp.Compare(len(candidates[a]), len(candidates[b]))
+			})])
+			ties := 0
+			for _, name := range claimants {
+				if len(candidates[name]) == fewest {
+					ties++
+				}
+			}
+			for _, name := range claimants {
+				if len(candidates[name]) == fewest && ties == 1 {
+					continue
+				}
+				s.logger.Warn("Meta-tool name already taken, ignoring", "meta_tool", name, "name", exposed)
+				candidates[name] = candidates[name][1:]
+			}
+		}
+		if !clashed {
+			break
+		}
+	}
+
+	renames := make(map[string]string)
+	for _, name := range metaTools {
+		if exposed := candidates[name][0]; exposed != name {
+			renames[name] = exposed
+		}
+	}
+	if len(renames) == 0 {
+		return
+	}
+
+	s.metaToolRenames = renames
+	s.logger.Info("Renamed meta-tools", "count", len(renames), "prefix", settings.MetaToolPrefix)
+}
+
+// metaToolName returns the name clients see for a meta-tool
+func (s *AggregatorServer) metaToolName(name string) string {
+	if exposed, ok := s.metaToolRenames[name]; ok {
+		return exposed
+	}
+	return name
+}
+
+// builtinMetaToolName returns the built-in name of a meta-tool from the name
+// clients see, or the name itself if it isn't a renamed meta-tool
+func (s *AggregatorServer) builtinMetaToolName(exposed string) string {
+	for name, renamed := range s.metaToolRenames {
+		if renamed == exposed {
+			return name
+		}
+	}
+	return exposed
+}
+
+// metaToolReference matches the meta-tools descriptions and instructions
+// refer to, written {{name}} so prose such as "health" is left alone
+var metaToolReference = regexp.MustCompile(`\{\{([a-z_]+)\}\}`)
+
+// expandMetaToolNames replaces the {{name}} references in text with the
+// names clients see
+func (s *AggregatorServer) expandMetaToolNames(text string) string {
+	return metaToolReference.ReplaceAllStringFunc(text, func(reference string) string {
+		return s.metaToolName(reference[2 : len(reference)-2])
+	})
+}
+
+// addMetaTool registers a meta-tool under the name clients see, with the
+// {{name}} references in its description expanded to match
+func addMetaTool[In, Out any](s *AggregatorServer, server *mcp.Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, Out]) {
+	tool.Name = s.metaToolName(tool.Name)
+	tool.Description = s.expandMetaToolNames(tool.Description)
+	mcp.AddTool(server, tool, handler)
+}
@@ -0,0 +1,197 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/mcpclient"
+)
+
+// latencyWindowSize is how many recent tool_search latencies the
+// percentiles are computed from
+const latencyWindowSize = 1000
+
+// latencyPercentiles summarizes the recent latencies of an operation
+type latencyPercentiles struct {
+	Count int64 `json:"count"` // All operations, including those no longer in the window
+	P50Ms int64 `json:"p50_ms"`
+	P90Ms int64 `json:"p90_ms"`
+	P99Ms int64 `json:"p99_ms"`
+	MaxMs int64 `json:"max_ms"` // Of the window
+}
+
+// latencyWindow keeps the latencies of the last latencyWindowSize operations
+type latencyWindow struct {
+	mu      sync.Mutex
+	count   int64
+	samples []int64 // Milliseconds, a ring once full
+}
+
+// record adds one operation
+func (w *latencyWindow) record(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency.Milliseconds())
+	} else {
+		w.samples[w.count%latencyWindowSize] = latency.Milliseconds()
+	}
+	w.count++
+}
+
+// percentiles returns the nearest-rank percentiles of the window
+func (w *latencyWindow) percentiles() latencyPercentiles {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples)
+	count := w.count
+	w.mu.Unlock()
+
+	result := latencyPercentiles{Count: count}
+	if len(sorted) == 0 {
+		return result
+	}
+	slices.Sort(sorted)
+	rank := func(p int) int64 {
+		return sorted[(p*len(sorted)+99)/100-1]
+	}
+	result.P50Ms, result.P90Ms, result.P99Ms = rank(50), rank(90), rank(99)
+	result.MaxMs = sorted[len(sorted)-1]
+	return result
+}
+
+// serverMetrics is one external server in the stats response
+type serverMetrics struct {
+	Name string `json:"name"`
+	mcpclient.CallMetrics
+}
+
+// serverCallMetrics returns the tool call metrics of every connected server,
+// sorted by name
+func (s *AggregatorServer) serverCallMetrics() []serverMetrics {
+	clients := s.connectedClients()
+	servers := make([]serverMetrics, 0, len(clients))
+	for name, client := range clients {
+		servers = append(servers, serverMetrics{Name: name, CallMetrics: client.Metrics()})
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+	return servers
+}
+
+// labelEscaper escapes Prometheus label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricsHandler serves the aggregator's uptime, tool call and search cache
+// counters, and the external servers' tool call metrics and health, in the
+// Prometheus text exposition format
+func (s *AggregatorServer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.writeMetrics(w)
+	})
+}
+
+// writeMetrics writes the metrics served by MetricsHandler
+func (s *AggregatorServer) writeMetrics(w io.Writer) {
+	servers := s.serverCallMetrics()
+
+	s.healthMu.RLock()
+	healthy := make(map[string]bool, len(s.health))
+	for name, health := range s.health {
+		healthy[name] = health.Healthy
+	}
+	s.healthMu.RUnlock()
+
+	family := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	label := func(server string) string {
+		return `server="` + labelEscaper.Replace(server) + `"`
+	}
+
+	family("onemcp_uptime_seconds", "gauge", "Seconds since the aggregator started.")
+	fmt.Fprintf(w, "onemcp_uptime_seconds %d\n", int64(time.Since(s.startedAt).Seconds()))
+
+	var toolCalls, toolErrors int64
+	for _, tool := range s.registry.ToolStats() {
+		toolCalls += tool.Calls
+		toolErrors += tool.Errors
+	}
+	family("onemcp_tool_calls_total", "counter", "Tool calls executed through the aggregator.")
+	fmt.Fprintf(w, "onemcp_tool_calls_total %d\n", toolCalls)
+	family("onemcp_tool_call_errors_total", "counter", "Tool calls executed through the aggregator that failed.")
+	fmt.Fprintf(w, "onemcp_tool_call_errors_total %d\n", toolErrors)
+
+	cache := s.searchCache.Snapshot()
+	family("onemcp_search_cache_hits_total", "counter", "LLM search queries answered from the result cache.")
+	fmt.Fprintf(w, "onemcp_search_cache_hits_total %d\n", cache.Hits)
+	family("onemcp_search_cache_misses_total", "counter", "LLM search queries the result cache could not answer.")
+	fmt.Fprintf(w, "onemcp_search_cache_misses_total %d\n", cache.Misses)
+
+	family("onemcp_server_healthy", "gauge", "Whether the external MCP server answers pings (1) or not (0).")
+	names := make([]string, 0, len(healthy))
+	for name := range healthy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := 0
+		if healthy[name] {
+			value = 1
+		}
+		fmt.Fprintf(w, "onemcp_server_healthy{%s} %d\n", label(name), value)
+	}
+
+	family("onemcp_server_tool_calls_total", "counter", "Tool calls made to the external MCP server.")
+	for _, server := range servers {
+		fmt.Fprintf(w, "onemcp_server_tool_calls_total{%s} %d\n", label(server.Name), server.Calls)
+	}
+
+	family("onemcp_server_tool_call_errors_total", "counter", "Tool calls to the external MCP server that failed, timed out or returned an error.")
+	for _, server := range servers {
+		fmt.Fprintf(w, "onemcp_server_tool_call_errors_total{%s} %d\n", label(server.Name), server.Errors)
+	}
+
+	family("onemcp_server_tool_call_timeouts_total", "counter", "Tool calls to the external MCP server that ran past its callTimeout.")
+	for _, server := range servers {
+		fmt.Fprintf(w, "onemcp_server_tool_call_timeouts_total{%s} %d\n", label(server.Name), server.Timeouts)
+	}
+
+	family("onemcp_server_slow_tool_calls_total", "counter", "Tool calls to the external MCP server that took at least its slowCallThreshold.")
+	for _, server := range servers {
+		fmt.Fprintf(w, "onemcp_server_slow_tool_calls_total{%s} %d\n", label(server.Name), server.SlowCalls)
+	}
+
+	family("onemcp_llm_slow_calls_total", "counter", "LLM search calls that took at least slowSearchThreshold.")
+	for _, provider := range s.searchUsage.Snapshot() {
+		fmt.Fprintf(w, "onemcp_llm_slow_calls_total{provider=\"%s\"} %d\n", labelEscaper.Replace(provider.Provider), provider.SlowCalls)
+	}
+
+	family("onemcp_server_last_error_timestamp_seconds", "gauge", "Unix time of the external MCP server's last failed tool call.")
+	for _, server := range servers {
+		if !server.LastErrorAt.IsZero() {
+			fmt.Fprintf(w, "onemcp_server_last_error_timestamp_seconds{%s} %d\n", label(server.Name), server.LastErrorAt.Unix())
+		}
+	}
+
+	family("onemcp_server_tool_call_duration_seconds", "histogram", "Latency of tool calls to the external MCP server.")
+	for _, server := range servers {
+		for _, bucket := range server.LatencyBuckets {
+			le := "+Inf"
+			if bucket.LeMs > 0 {
+				le = strconv.FormatFloat(float64(bucket.LeMs)/1000, 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "onemcp_server_tool_call_duration_seconds_bucket{%s,le=%q} %d\n", label(server.Name), le, bucket.Count)
+		}
+		fmt.Fprintf(w, "onemcp_server_tool_call_duration_seconds_sum{%s} %s\n", label(server.Name), strconv.FormatFloat(float64(server.TotalLatencyMs)/1000, 'g', -1, 64))
+		fmt.Fprintf(w, "onemcp_server_tool_call_duration_seconds_count{%s} %d\n", label(server.Name), server.Calls)
+	}
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// defaultMaxParallel is how many calls tool_execute_parallel runs at once
+// when maxParallel isn't set
+const defaultMaxParallel = 4
+
+// ToolExecuteParallelInput defines the input for tool_execute_parallel
+type ToolExecuteParallelInput struct {
+	Tools []ToolExecuteInput `json:"tools" jsonschema:"Independent tool calls to run concurrently, each with tool_name and arguments"`
+}
+
+// handleToolExecuteParallel runs independent tool calls concurrently, bounded
+// by maxParallel. Each result is reported like a tool_execute result; their
+// images, audio and resources follow the JSON as content blocks, in order.
+func (s *AggregatorServer) handleToolExecuteParallel(ctx context.Context, req *mcp.CallToolRequest, input ToolExecuteParallelInput) (*mcp.CallToolResult, any, error) {
+	calls := make([]tools.ToolExecution, len(input.Tools))
+	for i, call := range input.Tools {
+		calls[i] = tools.ToolExecution{ToolName: call.ToolName, Arguments: call.Arguments}
+	}
+
+	batch := s.registry.ExecuteParallel(ctx, calls, s.maxParallel)
+	if s.mode == modeHybrid {
+		s.syncDirectTools(false)
+	}
+
+	results := make([]map[string]any, len(batch.Results))
+	var blocks []mcp.Content
+	for i, result := range batch.Results {
+		var output any = result.Result
+		if result.Output != nil {
+			var resultBlocks []mcp.Content
+			output, resultBlocks = externalOutput(result.Output)
+			blocks = append(blocks, resultBlocks...)
+		}
+		results[i] = map[string]any{
+			"success":           result.Success,
+			"tool_name":         result.ToolName,
+			"result":            output,
+			"error":             result.Error,
+			"error_type":        result.ErrorType,
+			"execution_time_ms": result.ExecutionTimeMs,
+		}
+	}
+
+	result := map[string]any{
+		"results":                 results,
+		"successful_count":        batch.SuccessfulCount,
+		"failed_count":            batch.FailedCount,
+		"total_execution_time_ms": batch.TotalExecutionTimeMs,
+		"max_parallel":            s.maxParallel,
+	}
+	if id := requestID(ctx); id != "" {
+		result["request_id"] = id
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: append([]mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		}, blocks...),
+	}, nil, nil
+}
@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcpclient"
+)
+
+// defaultProgressInterval is how often a running tool_execute call reports
+// that it is still alive
+const defaultProgressInterval = 10 * time.Second
+
+// progressReporter sends the progress notifications of one tool_execute call
+// to the client that asked for them with a progress token
+type progressReporter struct {
+	mu        sync.Mutex // Serializes notifications so they arrive in order
+	session   *mcp.ServerSession
+	token     any
+	toolName  string
+	start     time.Time
+	forwarded bool // The downstream server reports progress itself
+	logger    *slog.Logger
+}
+
+// heartbeat reports the time elapsed so far, unless the downstream server
+// reports its own progress
+func (p *progressReporter) heartbeat(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.forwarded {
+		return
+	}
+	elapsed := time.Since(p.start).Round(time.Second)
+	p.notify(ctx, &mcp.ProgressNotificationParams{
+		Progress: elapsed.Seconds(),
+		Message:  fmt.Sprintf("%s running for %s", p.toolName, elapsed),
+	})
+}
+
+// forward passes on a progress notification from the downstream server
+func (p *progressReporter) forward(ctx context.Context, params *mcp.ProgressNotificationParams) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forwarded = true
+	p.notify(ctx, &mcp.ProgressNotificationParams{
+		Progress: params.Progress,
+		Total:    params.Total,
+		Message:  params.Message,
+	})
+}
+
+// notify sends a notification under the client's token; the caller holds mu
+func (p *progressReporter) notify(ctx context.Context, params *mcp.ProgressNotificationParams) {
+	params.ProgressToken = p.token
+	if err := p.session.NotifyProgress(ctx, params); err != nil {
+		p.logger.DebugContext(ctx, "Failed to send progress notification", "tool", p.toolName, "error", err)
+	}
+}
+
+// startProgress reports the progress of a tool call if the client asked for
+// it: elapsed time every progressInterval and, once the downstream server
+// reports its own, that progress instead. The returned context carries the
+// downstream forwarding; stop ends the reports.
+func (s *AggregatorServer) startProgress(ctx context.Context, req *mcp.CallToolRequest, toolName string) (context.Context, func()) {
+	if req == nil || req.Session == nil || req.Params == nil || req.Params.GetProgressToken() == nil {
+		return ctx, func() {}
+	}
+
+	reporter := &progressReporter{
+		session:  req.Session,
+		token:    req.Params.GetProgressToken(),
+		toolName: toolName,
+		start:    time.Now(),
+		logger:   s.logger,
+	}
+	progressCtx := mcpclient.WithProgress(ctx, func(params *mcp.ProgressNotificationParams) {
+		reporter.forward(ctx, params)
+	})
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reporter.heartbeat(ctx)
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return progressCtx, func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcpclient"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// externalPrompt is a downstream prompt and the name it is advertised under
+type externalPrompt struct {
+	name   string // <server>_<prompt>, suffixed with _2, _3... on conflicts
+	prompt mcpclient.Prompt
+}
+
+// uniquePromptName returns base, or base with the first free numeric suffix
+func uniquePromptName(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", base, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// loadExternalPrompts lists a server's prompts and publishes them upstream.
+// Failures are logged; a server without prompts still serves its tools.
+func (s *AggregatorServer) loadExternalPrompts(ctx context.Context, name string, client *mcpclient.MCPClient) {
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list prompts from external server", "name", name, "error", err)
+		return
+	}
+	s.setExternalPrompts(name, prompts)
+}
+
+// setExternalPrompts replaces the prompts recorded for a server and, once the
+// upstream server exists, the prompts it advertises for that server. Prompt
+// names are prefixed with the server name; a name another server already
+// claimed gets a numeric suffix.
+func (s *AggregatorServer) setExternalPrompts(name string, prompts []mcpclient.Prompt) {
+	s.promptsMu.Lock()
+	previous := s.prompts[name]
+	delete(s.prompts, name)
+
+	taken := make(map[string]bool)
+	for _, entries := range s.prompts {
+		for _, entry := range entries {
+			taken[entry.name] = true
+		}
+	}
+
+	entries := make([]externalPrompt, 0, len(prompts))
+	for _, p := range prompts {
+		base := name + "_" + p.Name
+		upstream := uniquePromptName(base, taken)
+		if upstream != base {
+			s.logger.Warn("Prompt name conflict, renaming", "server", name, "prompt", p.Name, "name", upstream)
+		}
+		taken[upstream] = true
+		entries = append(entries, externalPrompt{name: upstream, prompt: p})
+	}
+	if len(entries) > 0 {
+		s.prompts[name] = entries
+	}
+	s.promptsMu.Unlock()
+
+	if s.server == nil {
+		return // Published by publishExternalPrompts once the server is created
+	}
+
+	if len(previous) > 0 {
+		names := make([]string, len(previous))
+		for i, entry := range previous {
+			names[i] = entry.name
+		}
+		s.server.RemovePrompts(names...)
+	}
+	s.publishPrompts(name, entries)
+}
+
+// publishExternalPrompts advertises every recorded downstream prompt upstream
+func (s *AggregatorServer) publishExternalPrompts() {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+
+	for name, entries := range s.prompts {
+		s.publishPrompts(name, entries)
+	}
+}
+
+// publishPrompts adds a server's prompts to the upstream server, proxying prompts/get
+func (s *AggregatorServer) publishPrompts(name string, entries []externalPrompt) {
+	for _, entry := range entries {
+		arguments := make([]*mcp.PromptArgument, len(entry.prompt.Arguments))
+		for i, arg := range entry.prompt.Arguments {
+			arguments[i] = &mcp.PromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			}
+		}
+
+		s.server.AddPrompt(&mcp.Prompt{
+			Name:        entry.name,
+			Title:       entry.prompt.Title,
+			Description: entry.prompt.Description,
+			Arguments:   arguments,
+		}, s.externalPromptHandler(name, entry.prompt.Name))
+	}
+}
+
+// externalPromptHandler proxies prompts/get to the server owning the prompt
+func (s *AggregatorServer) externalPromptHandler(server, prompt string) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		client, ok := s.externalClient(server)
+		if !ok {
+			return nil, fmt.Errorf("external server not connected: %s", server)
+		}
+
+		result, err := client.GetPrompt(ctx, prompt, req.Params.Arguments)
+		if err != nil {
+			s.logger.Error("Failed to get external prompt", "server", server, "prompt", prompt, "error", err)
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// PromptSearchInput defines the input for prompt_search
+type PromptSearchInput struct {
+	Query       string `json:"query,omitempty" jsonschema:"Search term to find prompts by name or description. Supports natural language queries (e.g., 'review code', 'summarize document')."`
+	DetailLevel string `json:"detail_level,omitempty" jsonschema:"Detail level: 'names_only', 'summary' (name + description) or 'detailed' (includes the prompt's arguments). Default: 'summary'"`
+	Offset      int    `json:"offset,omitempty" jsonschema:"Number of results to skip for pagination. Default: 0"`
+}
+
+// handlePromptSearch is tool_search restricted to prompts
+func (s *AggregatorServer) handlePromptSearch(ctx context.Context, req *mcp.CallToolRequest, input PromptSearchInput) (*mcp.CallToolResult, any, error) {
+	return s.handleToolSearch(ctx, req, ToolSearchInput{
+		Query:       input.Query,
+		Type:        string(tools.TypePrompt),
+		DetailLevel: input.DetailLevel,
+		Offset:      input.Offset,
+	})
+}
+
+// PromptGetInput defines the input for prompt_get
+type PromptGetInput struct {
+	Name      string            `json:"name" jsonschema:"Name of the prompt, as returned by prompt_search (e.g. 'github_review_pr')"`
+	Arguments map[string]string `json:"arguments,omitempty" jsonschema:"Prompt arguments as string values"`
+}
+
+// handlePromptGet fetches a prompt from the server owning it, like prompts/get,
+// for clients that only use tools
+func (s *AggregatorServer) handlePromptGet(ctx context.Context, req *mcp.CallToolRequest, input PromptGetInput) (*mcp.CallToolResult, any, error) {
+	server, prompt, ok := s.lookupExternalPrompt(input.Name)
+	if !ok {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      fmt.Sprintf("prompt not found: %s", input.Name),
+			"error_type": "prompt_not_found",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+
+	result, err := s.externalPromptHandler(server, prompt)(ctx, &mcp.GetPromptRequest{
+		Params: &mcp.GetPromptParams{Name: prompt, Arguments: input.Arguments},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get prompt %s: %w", input.Name, err)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// handlePromptListChanged re-lists a server's prompts after it emits
+// prompts/list_changed, in the background like tool refreshes
+func (s *AggregatorServer) handlePromptListChanged(ctx context.Context, name string) {
+	go func() {
+		client, ok := s.externalClient(name)
+		if !ok {
+			return
+		}
+		s.loadExternalPrompts(context.Background(), name, client)
+		if err := s.rebuildSearchStore(); err != nil {
+			s.logger.Error("Failed to rebuild search store after prompt change", "name", name, "error", err)
+		}
+	}()
+}
+
+// promptItems returns the downstream prompts as searchable items, with their
+// arguments as a parameter schema
+func (s *AggregatorServer) promptItems() []*tools.Tool {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+
+	var items []*tools.Tool
+	for name, entries := range s.prompts {
+		category := s.serverConfig(name).Category
+		if category == "" {
+			category = name
+		}
+		for _, entry := range entries {
+			description := entry.prompt.Description
+			if description == "" {
+				description = entry.prompt.Title
+			}
+			if description == "" {
+				description = entry.prompt.Name
+			}
+			items = append(items, &tools.Tool{
+				Name:        entry.name,
+				Category:    category,
+				Description: description,
+				InputSchema: promptArgumentsSchema(entry.prompt.Arguments),
+				Source:      tools.SourceExternal,
+				SourceName:  name,
+				Alias:       entry.prompt.Name,
+				Type:        tools.TypePrompt,
+			})
+		}
+	}
+	return items
+}
+
+// promptArgumentsSchema describes prompt arguments as a JSON schema of string properties
+func promptArgumentsSchema(arguments []mcpclient.PromptArgument) map[string]any {
+	properties := make(map[string]any, len(arguments))
+	required := []string{}
+	for _, arg := range arguments {
+		property := map[string]any{"type": "string"}
+		if arg.Description != "" {
+			property["description"] = arg.Description
+		}
+		properties[arg.Name] = property
+		if arg.Required {
+			required = append(required, arg.Name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// tool_search match modes
+const (
+	matchSemantic = "semantic" // Ranked by the search store
+	matchExact    = "exact"    // Names equal to the query, ignoring case
+	matchPrefix   = "prefix"   // Names starting with the query, ignoring case
+	matchRegex    = "regex"    // Names matching the query as a regular expression
+)
+
+// matchModes lists the tool_search match modes
+var matchModes = []string{matchSemantic, matchExact, matchPrefix, matchRegex}
+
+// tool_search result orders
+const (
+	sortRelevance    = "relevance"     // Best match first, as ranked (the default)
+	sortName         = "name"          // By name
+	sortCategory     = "category"      // By category, then name
+	sortRecentlyUsed = "recently_used" // Last executed first, then by name
+	sortMostUsed     = "most_used"     // Most executed first, then by name
+)
+
+// sortOrders lists the tool_search result orders
+var sortOrders = []string{sortRelevance, sortName, sortCategory, sortRecentlyUsed, sortMostUsed}
+
+// toolUsage is what the usage-based orders sort by
+type toolUsage struct {
+	lastUsed map[string]time.Time // Last execution per tool name
+	calls    map[string]int64     // Executions per tool name
+}
+
+// sortResults orders search results in place; relevance and unknown orders
+// keep them as ranked. The other orders break ties by name, so the order is
+// the same from one search to the next.
+func sortResults(items []tools.CollapsedTool, order string, usage toolUsage) {
+	var compare func(a, b tools.CollapsedTool) int
+	switch order {
+	case sortName:
+		compare = func(a, b tools.CollapsedTool) int { return 0 }
+	case sortCategory:
+		compare = func(a, b tools.CollapsedTool) int {
+			return cmp.Compare(a.Category, b.Category)
+		}
+	case sortRecentlyUsed:
+		compare = func(a, b tools.CollapsedTool) int {
+			return usage.lastUsed[b.Name].Compare(usage.lastUsed[a.Name])
+		}
+	case sortMostUsed:
+		compare = func(a, b tools.CollapsedTool) int {
+			return cmp.Compare(usage.calls[b.Name], usage.calls[a.Name])
+		}
+	default:
+		return
+	}
+
+	slices.SortStableFunc(items, func(a, b tools.CollapsedTool) int {
+		return cmp.Or(compare(a, b), strings.Compare(a.Name, b.Name))
+	})
+}
+
+// nameMatcher returns what a name must satisfy in a non-semantic match mode.
+// The query is used as is: the filter syntax only applies to semantic search.
+func nameMatcher(mode, query string) (func(name string) bool, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required with match_mode %q", mode)
+	}
+
+	switch mode {
+	case matchExact:
+		return func(name string) bool {
+			return strings.EqualFold(name, query)
+		}, nil
+	case matchPrefix:
+		prefix := strings.ToLower(query)
+		return func(name string) bool {
+			return strings.HasPrefix(strings.ToLower(name), prefix)
+		}, nil
+	case matchRegex:
+		pattern, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return pattern.MatchString, nil
+	}
+	return nil, fmt.Errorf("unknown match_mode %q: use one of %s", mode, strings.Join(matchModes, ", "))
+}
+
+// matchNames returns the catalog items whose names match, sorted by name
+func matchNames(items []*tools.Tool, matches func(name string) bool) []*tools.Tool {
+	var matched []*tools.Tool
+	for _, item := range items {
+		if matches(item.Name) {
+			matched = append(matched, item)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *tools.Tool) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return matched
+}
+
+// searchQuery is a tool_search query with its filter syntax parsed out.
+// Supported syntax:
+//   - "-term" excludes results whose name, category or description contain term
+//   - "name:term" keeps results whose name contains term
+//   - "category:value" keeps results in that category
+//   - "type:value" keeps results of that capability type
+//
+// Everything else is free text passed to the search store.
+type searchQuery struct {
+	text       string
+	excluded   []string
+	names      []string
+	categories []string
+	types      []string
+}
+
+// parseSearchQuery splits a raw query into free text and post-filters
+func parseSearchQuery(raw string) searchQuery {
+	var query searchQuery
+	var text []string
+
+	for _, field := range strings.Fields(raw) {
+		lower := strings.ToLower(field)
+
+		if strings.HasPrefix(lower, "-") && len(lower) > 1 {
+			query.excluded = append(query.excluded, lower[1:])
+			continue
+		}
+
+		key, value, found := strings.Cut(lower, ":")
+		if !found || value == "" {
+			text = append(text, field)
+			continue
+		}
+
+		switch key {
+		case "name":
+			query.names = append(query.names, value)
+			text = append(text, value) // Name terms also steer the semantic search
+		case "category":
+			query.categories = append(query.categories, value)
+		case "type":
+			query.types = append(query.types, value)
+		default:
+			text = append(text, field)
+		}
+	}
+
+	query.text = strings.Join(text, " ")
+	return query
+}
+
+// hasFilters reports whether the query contains any post-filter
+func (q searchQuery) hasFilters() bool {
+	return len(q.excluded) > 0 || len(q.names) > 0 || len(q.categories) > 0 || len(q.types) > 0
+}
+
+// matches reports whether a tool passes all post-filters
+func (q searchQuery) matches(tool *tools.Tool) bool {
+	name := strings.ToLower(tool.Name)
+	category := strings.ToLower(tool.Category)
+	description := strings.ToLower(tool.Description)
+
+	for _, term := range q.excluded {
+		if strings.Contains(name, term) || strings.Contains(category, term) || strings.Contains(description, term) {
+			return false
+		}
+	}
+	for _, term := range q.names {
+		if !strings.Contains(name, term) {
+			return false
+		}
+	}
+	if len(q.categories) > 0 && !slices.Contains(q.categories, category) {
+		return false
+	}
+	if len(q.types) > 0 && !slices.Contains(q.types, string(tool.Type)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseSearchQuery tests that field filters and exclusions are split from the query text
+func TestParseSearchQuery(t *testing.T) {
+	query := parseSearchQuery("category:FileSystem read -Directory name:file type:tool foo:bar")
+
+	require.Equal(t, "read file foo:bar", query.text)
+	require.Equal(t, []string{"directory"}, query.excluded)
+	require.Equal(t, []string{"file"}, query.names)
+	require.Equal(t, []string{"filesystem"}, query.categories)
+	require.Equal(t, []string{"tool"}, query.types)
+	require.True(t, query.hasFilters())
+}
+
+// TestParseSearchQuery_PlainText tests that a query without filters is kept as is
+func TestParseSearchQuery_PlainText(t *testing.T) {
+	query := parseSearchQuery("take a screenshot - now")
+
+	require.Equal(t, "take a screenshot - now", query.text)
+	require.False(t, query.hasFilters())
+}
+
+// TestSearchQueryMatches tests matching tools against field filters and exclusions
+func TestSearchQueryMatches(t *testing.T) {
+	readFile := &tools.Tool{Name: "fs_read_file", Category: "filesystem", Description: "Read a file", Type: tools.TypeTool}
+	listDir := &tools.Tool{Name: "fs_list_directory", Category: "filesystem", Description: "List a directory", Type: tools.TypeTool}
+	navigate := &tools.Tool{Name: "browser_navigate", Category: "browser", Description: "Open a URL", Type: tools.TypeTool}
+
+	query := parseSearchQuery("category:filesystem -directory")
+	require.True(t, query.matches(readFile))
+	require.False(t, query.matches(listDir))
+	require.False(t, query.matches(navigate))
+
+	query = parseSearchQuery("name:navigate")
+	require.True(t, query.matches(navigate))
+	require.False(t, query.matches(readFile))
+
+	query = parseSearchQuery("type:prompt")
+	require.False(t, query.matches(readFile))
+}
+
+// TestSortResults tests each result order
+func TestSortResults(t *testing.T) {
+	items := func() []tools.CollapsedTool {
+		return []tools.CollapsedTool{
+			{Tool: &tools.Tool{Name: "fs_write", Category: "filesystem"}},
+			{Tool: &tools.Tool{Name: "browser_click", Category: "browser"}},
+			{Tool: &tools.Tool{Name: "fs_read", Category: "filesystem"}},
+			{Tool: &tools.Tool{Name: "browser_open", Category: "browser"}},
+		}
+	}
+	names := func(items []tools.CollapsedTool) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	now := time.Now()
+	usage := toolUsage{
+		lastUsed: map[string]time.Time{"fs_read": now.Add(-time.Hour), "browser_open": now},
+		calls:    map[string]int64{"fs_read": 3, "fs_write": 3, "browser_open": 1},
+	}
+
+	for order, want := range map[string][]string{
+		sortRelevance:    {"fs_write", "browser_click", "fs_read", "browser_open"},
+		sortName:         {"browser_click", "browser_open", "fs_read", "fs_write"},
+		sortCategory:     {"browser_click", "browser_open", "fs_read", "fs_write"},
+		sortRecentlyUsed: {"browser_open", "fs_read", "browser_click", "fs_write"},
+		sortMostUsed:     {"fs_read", "fs_write", "browser_open", "browser_click"},
+	} {
+		sorted := items()
+		sortResults(sorted, order, usage)
+		require.Equal(t, want, names(sorted), order)
+	}
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+)
+
+// handleServerDisconnected marks a server whose connection dropped as
+// degraded until its client reconnects, or as failed if it doesn't reconnect.
+// Its tools stay registered; calls to them fail in the meantime.
+func (s *AggregatorServer) handleServerDisconnected(name string, err error) {
+	reconnect := s.serverConfig(name).Reconnect
+	reconnecting := reconnect == nil || *reconnect
+
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	health, ok := s.health[name]
+	if !ok {
+		return
+	}
+	health.Healthy = false
+	health.Reconnecting = reconnecting
+	if err != nil {
+		health.LastError = err.Error()
+	} else {
+		health.LastError = "connection closed"
+	}
+}
+
+// handleServerGaveUp marks a server whose process kept crashing as no longer
+// being restarted
+func (s *AggregatorServer) handleServerGaveUp(name string, err error) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if health, ok := s.health[name]; ok {
+		health.Reconnecting = false
+		health.LastError = "crashed too often, no longer restarted"
+		if err != nil {
+			health.LastError += ": " + err.Error()
+		}
+	}
+}
+
+// handleServerHung marks a server suspect after the watchdog cancelled one of
+// its tool calls
+func (s *AggregatorServer) handleServerHung(name, toolName string) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if health, ok := s.health[name]; ok {
+		health.Suspect = true
+		health.LastError = "tool call hung: " + toolName
+	}
+}
+
+// handleServerReconnected restores a server after its client reconnected:
+// tools are re-listed and re-indexed, resources, prompts and log forwarding
+// are set up again, and the server is marked healthy
+func (s *AggregatorServer) handleServerReconnected(ctx context.Context, name string) {
+	client, ok := s.externalClient(name)
+	if !ok {
+		return
+	}
+
+	if err := s.refreshExternalTools(ctx, name); err != nil {
+		s.logger.Error("Failed to refresh external tools after reconnecting", "name", name, "error", err)
+	}
+	s.loadExternalResources(ctx, name, client)
+	s.loadExternalPrompts(ctx, name, client)
+	s.subscribeServerLogs(ctx, name, s.serverConfig(name).LogLevel)
+
+	s.healthMu.Lock()
+	if health, ok := s.health[name]; ok {
+		health.Healthy = true
+		health.Reconnecting = false
+		health.Suspect = false
+		health.ConsecutiveFailures = 0
+		health.LastError = ""
+	}
+	s.healthMu.Unlock()
+
+	s.logger.Info("External server restored after reconnecting", "name", name)
+}
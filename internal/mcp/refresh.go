@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// ServerRefreshInput defines the input for server_refresh
+type ServerRefreshInput struct {
+	Server string `json:"server,omitempty" jsonschema:"Name of the external server to refresh (default: all connected servers)"`
+}
+
+// toolsDiff is how a server's registered tools changed on refresh. Tool names
+// are unprefixed, as the server lists them.
+type toolsDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// empty reports whether a refresh left the server's tools as they were
+func (d toolsDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// serverRefresh is one external server in the server_refresh response
+type serverRefresh struct {
+	Name string `json:"name"`
+	toolsDiff
+	Error string `json:"error,omitempty"`
+}
+
+// sourceTools fingerprints the registered tools of an external server by
+// unprefixed name. The fingerprint covers everything the server declares for
+// a tool, so a changed description or schema shows up as a different value.
+func (s *AggregatorServer) sourceTools(name string) map[string]string {
+	fingerprints := make(map[string]string)
+	for _, tool := range s.registry.ListAll() {
+		if tool.Source != tools.SourceExternal || tool.SourceName != name {
+			continue
+		}
+		data, _ := json.Marshal(struct {
+			Description  string
+			InputSchema  any
+			OutputSchema any
+			Annotations  *tools.ToolAnnotations
+		}{tool.Description, tool.InputSchema, tool.OutputSchema, tool.Annotations})
+		fingerprints[strings.TrimPrefix(tool.Name, name+"_")] = string(data)
+	}
+	return fingerprints
+}
+
+// diffTools compares two fingerprint maps returned by sourceTools
+func diffTools(before, after map[string]string) toolsDiff {
+	diff := toolsDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for name, fingerprint := range after {
+		previous, existed := before[name]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, name)
+		case previous != fingerprint:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// refreshServerTools re-lists tools from an external server and applies the
+// difference to the registry in one update, so tools that didn't change stay
+// callable throughout. The search store is left to the caller, so several
+// servers can be refreshed with a single rebuild, and skipped if nothing changed.
+func (s *AggregatorServer) refreshServerTools(ctx context.Context, name string) (toolsDiff, error) {
+	client, ok := s.externalClient(name)
+	if !ok {
+		return toolsDiff{}, fmt.Errorf("external server not connected: %s", name)
+	}
+
+	externalTools, err := client.RefreshTools(ctx)
+	if err != nil {
+		return toolsDiff{}, err
+	}
+
+	config := s.serverConfig(name)
+	s.saveToolSnapshot(name, externalTools)
+
+	before := s.sourceTools(name)
+	s.registry.ReplaceSource(name, s.externalToolEntries(name, config, externalTools))
+	diff := diffTools(before, s.sourceTools(name))
+
+	s.logger.Info("Refreshed external MCP server tools", "name", name, "tools", len(externalTools),
+		"added", len(diff.Added), "removed", len(diff.Removed), "changed", len(diff.Changed))
+	return diff, nil
+}
+
+func (s *AggregatorServer) handleServerRefresh(ctx context.Context, req *mcp.CallToolRequest, input ServerRefreshInput) (*mcp.CallToolResult, any, error) {
+	var names []string
+	if input.Server != "" {
+		if _, ok := s.externalClient(input.Server); !ok {
+			return nil, nil, fmt.Errorf("external server not connected: %s", input.Server)
+		}
+		names = []string{input.Server}
+	} else {
+		for name := range s.connectedClients() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	servers := make([]serverRefresh, 0, len(names))
+	changed := false
+	for _, name := range names {
+		refresh := serverRefresh{Name: name}
+		diff, err := s.refreshServerTools(ctx, name)
+		if err != nil {
+			refresh.Error = err.Error()
+		} else {
+			refresh.toolsDiff = diff
+			changed = changed || !diff.empty()
+		}
+		servers = append(servers, refresh)
+	}
+
+	// Re-index once, and only if a catalog actually changed
+	if changed {
+		if err := s.rebuildSearchStore(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resultJSON, _ := json.Marshal(map[string]any{"servers": servers})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
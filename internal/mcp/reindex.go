@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// VectorReindexInput defines the input for vector_reindex
+type VectorReindexInput struct {
+	Provider string `json:"provider,omitempty" jsonschema:"Search provider to index with: 'claude', 'codex', 'copilot', 'ollama', 'openai' or 'tfidf'. Keeps the active provider if empty."`
+	Model    string `json:"model,omitempty" jsonschema:"Optional model for the provider. Keeps the current model if empty."`
+}
+
+// handleVectorReindex rebuilds the search index from scratch, dropping cached
+// results, and reports what was indexed. A failed rebuild keeps the old index.
+func (s *AggregatorServer) handleVectorReindex(ctx context.Context, req *mcp.CallToolRequest, input VectorReindexInput) (*mcp.CallToolResult, any, error) {
+	s.searchMu.RLock()
+	previousProvider := s.searchProvider
+	previousModel := s.currentModelLocked()
+	previousCount := 0
+	if s.searchStore != nil {
+		previousCount = s.searchStore.GetToolCount()
+	}
+	s.searchMu.RUnlock()
+
+	provider := input.Provider
+	if provider == "" {
+		provider = previousProvider
+	}
+
+	start := time.Now()
+	if err := s.RebuildWithProvider(provider, input.Model); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to rebuild search index", "provider", provider, "error", err)
+		return metaToolError(err, "reindex_failed"), nil, nil
+	}
+	duration := time.Since(start)
+	s.notifyCatalogChanged()
+
+	// Count what the index was built from
+	types := make(map[tools.ItemType]int)
+	for _, item := range s.searchableItems() {
+		types[item.Type]++
+	}
+
+	s.searchMu.RLock()
+	result := map[string]any{
+		"provider":               s.searchProvider,
+		"model":                  s.currentModelLocked(),
+		"previous_provider":      previousProvider,
+		"previous_model":         previousModel,
+		"chain":                  s.providerChainLocked(),
+		"indexed_items":          s.searchStore.GetToolCount(),
+		"previous_indexed_items": previousCount,
+		"tools":                  types[tools.TypeTool],
+		"prompts":                types[tools.TypePrompt],
+		"resources":              types[tools.TypeResource],
+		"duration_ms":            duration.Milliseconds(),
+	}
+	s.searchMu.RUnlock()
+	s.logger.InfoContext(ctx, "Search index rebuilt", "provider", result["provider"], "indexed_items", result["indexed_items"], "duration", duration)
+
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
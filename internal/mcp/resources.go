@@ -0,0 +1,283 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/mcpclient"
+	"github.com/radutopala/onemcp/internal/tools"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// resourceURIPrefix namespaces the URIs of downstream resources so that
+// servers exposing the same URI don't collide:
+// onemcp://<server>/<original URI>
+const resourceURIPrefix = "onemcp://"
+
+// namespaceResourceURI returns the aggregator URI of a server's resource
+func namespaceResourceURI(server, uri string) string {
+	return resourceURIPrefix + server + "/" + uri
+}
+
+// splitResourceURI returns the server and original URI of an aggregator resource URI
+func splitResourceURI(uri string) (server, original string, ok bool) {
+	rest, found := strings.CutPrefix(uri, resourceURIPrefix)
+	if !found {
+		return "", "", false
+	}
+	server, original, found = strings.Cut(rest, "/")
+	if !found || server == "" || original == "" {
+		return "", "", false
+	}
+	return server, original, true
+}
+
+// loadExternalResources lists a server's resources and resource templates and
+// publishes them upstream. Failures are logged; a server without resources
+// still serves its tools.
+func (s *AggregatorServer) loadExternalResources(ctx context.Context, name string, client *mcpclient.MCPClient) {
+	resources, err := client.ListResources(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list resources from external server", "name", name, "error", err)
+	} else {
+		s.setExternalResources(name, resources)
+	}
+
+	templates, err := client.ListResourceTemplates(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list resource templates from external server", "name", name, "error", err)
+		return
+	}
+	s.setExternalResourceTemplates(name, templates)
+}
+
+// setExternalResources replaces the resources recorded for a server and, once
+// the upstream server exists, the resources it advertises for that server
+func (s *AggregatorServer) setExternalResources(name string, resources []mcpclient.Resource) {
+	s.resourcesMu.Lock()
+	previous := s.resources[name]
+	if len(resources) == 0 {
+		delete(s.resources, name)
+	} else {
+		s.resources[name] = resources
+	}
+	s.resourcesMu.Unlock()
+
+	if s.server == nil {
+		return // Published by publishExternalResources once the server is created
+	}
+
+	if len(previous) > 0 {
+		uris := make([]string, len(previous))
+		for i, r := range previous {
+			uris[i] = namespaceResourceURI(name, r.URI)
+		}
+		s.server.RemoveResources(uris...)
+	}
+	s.publishResources(name, resources)
+}
+
+// publishExternalResources advertises every recorded downstream resource and
+// resource template upstream
+func (s *AggregatorServer) publishExternalResources() {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+
+	for name, resources := range s.resources {
+		s.publishResources(name, resources)
+	}
+	for name, templates := range s.resourceTemplates {
+		s.publishResourceTemplates(name, templates)
+	}
+}
+
+// publishResources adds a server's resources to the upstream server, proxying reads
+func (s *AggregatorServer) publishResources(name string, resources []mcpclient.Resource) {
+	for _, r := range resources {
+		uri := namespaceResourceURI(name, r.URI)
+		if _, err := url.Parse(uri); err != nil {
+			s.logger.Warn("Skipping resource with invalid URI", "server", name, "uri", r.URI, "error", err)
+			continue
+		}
+
+		s.server.AddResource(&mcp.Resource{
+			URI:         uri,
+			Name:        name + "_" + r.Name,
+			Title:       r.Title,
+			Description: r.Description,
+			MIMEType:    r.MIMEType,
+			Size:        r.Size,
+		}, s.readExternalResource)
+	}
+}
+
+// setExternalResourceTemplates replaces the resource templates recorded for a
+// server and, once the upstream server exists, the templates it advertises
+func (s *AggregatorServer) setExternalResourceTemplates(name string, templates []mcpclient.ResourceTemplate) {
+	s.resourcesMu.Lock()
+	previous := s.resourceTemplates[name]
+	if len(templates) == 0 {
+		delete(s.resourceTemplates, name)
+	} else {
+		s.resourceTemplates[name] = templates
+	}
+	s.resourcesMu.Unlock()
+
+	if s.server == nil {
+		return // Published by publishExternalResources once the server is created
+	}
+
+	if len(previous) > 0 {
+		uriTemplates := make([]string, len(previous))
+		for i, t := range previous {
+			uriTemplates[i] = namespaceResourceURI(name, t.URITemplate)
+		}
+		s.server.RemoveResourceTemplates(uriTemplates...)
+	}
+	s.publishResourceTemplates(name, templates)
+}
+
+// publishResourceTemplates adds a server's resource templates to the upstream
+// server. Reads of URIs matching a template are proxied like resource reads.
+func (s *AggregatorServer) publishResourceTemplates(name string, templates []mcpclient.ResourceTemplate) {
+	for _, t := range templates {
+		uriTemplate := namespaceResourceURI(name, t.URITemplate)
+		if _, err := uritemplate.New(uriTemplate); err != nil {
+			s.logger.Warn("Skipping resource template with invalid URI template", "server", name, "uri_template", t.URITemplate, "error", err)
+			continue
+		}
+
+		s.server.AddResourceTemplate(&mcp.ResourceTemplate{
+			URITemplate: uriTemplate,
+			Name:        name + "_" + t.Name,
+			Title:       t.Title,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+		}, s.readExternalResource)
+	}
+}
+
+// readExternalResource proxies resources/read to the server owning the URI
+func (s *AggregatorServer) readExternalResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	server, original, ok := splitResourceURI(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	client, ok := s.externalClient(server)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	result, err := client.ReadResource(ctx, original)
+	if err != nil {
+		s.logger.Error("Failed to read external resource", "server", server, "uri", original, "error", err)
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+
+	// Contents carry downstream URIs; hand back the namespaced ones
+	for _, content := range result.Contents {
+		if content != nil && content.URI != "" {
+			content.URI = namespaceResourceURI(server, content.URI)
+		}
+	}
+	return result, nil
+}
+
+// ResourceReadInput defines the input for resource_read
+type ResourceReadInput struct {
+	URI string `json:"uri" jsonschema:"Namespaced resource URI, as returned by tool_search (e.g. 'onemcp://docs/file:///notes.txt')"`
+}
+
+// handleResourceRead reads a resource from the server owning its URI, like
+// resources/read, for clients that only use tools
+func (s *AggregatorServer) handleResourceRead(ctx context.Context, req *mcp.CallToolRequest, input ResourceReadInput) (*mcp.CallToolResult, any, error) {
+	server, _, ok := splitResourceURI(input.URI)
+	if ok {
+		_, ok = s.externalClient(server)
+	}
+	if !ok {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      fmt.Sprintf("resource not found: %s", input.URI),
+			"error_type": "resource_not_found",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+
+	result, err := s.readExternalResource(ctx, &mcp.ReadResourceRequest{
+		Params: &mcp.ReadResourceParams{URI: input.URI},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// handleResourceListChanged re-lists a server's resources after it emits
+// resources/list_changed, in the background like tool refreshes
+func (s *AggregatorServer) handleResourceListChanged(ctx context.Context, name string) {
+	go func() {
+		client, ok := s.externalClient(name)
+		if !ok {
+			return
+		}
+		s.loadExternalResources(context.Background(), name, client)
+		if err := s.rebuildSearchStore(); err != nil {
+			s.logger.Error("Failed to rebuild search store after resource change", "name", name, "error", err)
+		}
+	}()
+}
+
+// resourceItems returns the downstream resources as searchable items, named
+// by their aggregator URI so search results can be passed to resources/read
+func (s *AggregatorServer) resourceItems() []*tools.Tool {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+
+	var items []*tools.Tool
+	for name, resources := range s.resources {
+		category := s.serverConfig(name).Category
+		if category == "" {
+			category = name
+		}
+		for _, r := range resources {
+			description := r.Description
+			if description == "" {
+				description = r.Title
+			}
+			if description == "" {
+				description = r.Name
+			}
+			items = append(items, &tools.Tool{
+				Name:        namespaceResourceURI(name, r.URI),
+				Category:    category,
+				Description: description,
+				Source:      tools.SourceExternal,
+				SourceName:  name,
+				Alias:       r.URI,
+				Type:        tools.TypeResource,
+			})
+		}
+	}
+	return items
+}
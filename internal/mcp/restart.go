@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ServerRestartInput defines the input for server_restart
+type ServerRestartInput struct {
+	Server string `json:"server" jsonschema:"Name of the external server to restart"`
+}
+
+// restartServer closes a server's connection, which stops its process or
+// container, and connects it again from its config. Its tools, resources and
+// prompts are registered anew. If it can't be connected, its tools are served
+// from the snapshot and the next call retries, as after a failed startup.
+func (s *AggregatorServer) restartServer(ctx context.Context, name string) error {
+	// Don't race a lazy connect of the same server
+	defer s.connectLocks.lock(name)()
+
+	s.clientsMu.Lock()
+	config, known := s.serverConfigs[name]
+	client, connected := s.externalClients[name]
+	delete(s.externalClients, name)
+	s.clientsMu.Unlock()
+	if !known {
+		return fmt.Errorf("unknown external server: %s", name)
+	}
+
+	s.logger.Info("Restarting external MCP server", "name", name, "connected", connected)
+	if connected {
+		client.Close()
+	}
+	s.registry.UnregisterSource(name)
+
+	err := s.connectExternalServer(ctx, name, config, nil)
+	if err != nil {
+		s.logger.Error("Failed to reconnect restarted external server", "name", name, "error", err)
+		s.registerSnapshotFallback(name, config, err)
+	}
+
+	if rebuildErr := s.rebuildSearchStore(); rebuildErr != nil {
+		s.logger.Error("Failed to rebuild search store after restarting server", "name", name, "error", rebuildErr)
+	}
+	return err
+}
+
+func (s *AggregatorServer) handleServerRestart(ctx context.Context, req *mcp.CallToolRequest, input ServerRestartInput) (*mcp.CallToolResult, any, error) {
+	if err := s.restartServer(ctx, input.Server); err != nil {
+		return nil, nil, fmt.Errorf("failed to restart %s: %w", input.Server, err)
+	}
+
+	toolCount := 0
+	for _, tool := range s.registry.ListAll() {
+		if tool.SourceName == input.Server {
+			toolCount++
+		}
+	}
+
+	resultJSON, _ := json.Marshal(map[string]any{
+		"server":    input.Server,
+		"restarted": true,
+		"tools":     toolCount,
+	})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// handleClientInitialized starts tracking a client session and fetches its
+// roots once the session is initialized
+func (s *AggregatorServer) handleClientInitialized(ctx context.Context, req *mcp.InitializedRequest) {
+	s.trackSession(req.Session)
+	go s.syncRoots(req.Session)
+}
+
+// handleRootsListChanged re-fetches the client's roots after it emits
+// roots/list_changed, in the background like tool refreshes
+func (s *AggregatorServer) handleRootsListChanged(ctx context.Context, req *mcp.RootsListChangedRequest) {
+	s.logger.InfoContext(ctx, "Received roots/list_changed from client")
+	go s.syncRoots(req.Session)
+}
+
+// syncRoots lists the roots of an upstream client and forwards the roots of
+// all sessions to every external server. The roots capability has no presence
+// marker, so a client that doesn't support roots just fails the request.
+func (s *AggregatorServer) syncRoots(session *mcp.ServerSession) {
+	if params := session.InitializeParams(); params == nil || params.Capabilities == nil {
+		return
+	}
+
+	result, err := session.ListRoots(context.Background(), nil)
+	if err != nil {
+		s.logger.Debug("Client did not list roots", "session", session.ID(), "error", err)
+		return
+	}
+
+	s.rootsMu.Lock()
+	s.roots[session.ID()] = result.Roots
+	s.rootsMu.Unlock()
+
+	s.forwardRoots()
+}
+
+// forgetSessionRoots stops forwarding the roots of a closed session
+func (s *AggregatorServer) forgetSessionRoots(session string) {
+	s.rootsMu.Lock()
+	_, had := s.roots[session]
+	delete(s.roots, session)
+	s.rootsMu.Unlock()
+
+	if had {
+		s.forwardRoots()
+	}
+}
+
+// forwardRoots hands the roots of all client sessions to every external server
+func (s *AggregatorServer) forwardRoots() {
+	roots := s.clientRoots()
+	s.logger.Info("Forwarding client roots to external servers", "count", len(roots))
+	for _, client := range s.connectedClients() {
+		client.SetRoots(roots)
+	}
+}
+
+// clientRoots returns the roots listed by all client sessions, de-duplicated
+// by URI and sorted, or nil if no client listed any
+func (s *AggregatorServer) clientRoots() []*mcp.Root {
+	s.rootsMu.RLock()
+	defer s.rootsMu.RUnlock()
+
+	if len(s.roots) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	roots := []*mcp.Root{}
+	for _, sessionRoots := range s.roots {
+		for _, root := range sessionRoots {
+			if !seen[root.URI] {
+				seen[root.URI] = true
+				roots = append(roots, root)
+			}
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].URI < roots[j].URI
+	})
+	return roots
+}
@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// errSamplingUnsupported is returned to downstream servers when no connected client can sample
+var errSamplingUnsupported = errors.New("sampling not supported: no connected client accepts sampling/createMessage")
+
+// handleCreateMessage forwards a downstream server's sampling/createMessage
+// request to the upstream client and relays the client's response back
+func (s *AggregatorServer) handleCreateMessage(ctx context.Context, name string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	session := s.samplingSession()
+	if session == nil {
+		s.logger.Warn("Rejecting sampling request, no client supports sampling", "server", name)
+		return nil, errSamplingUnsupported
+	}
+
+	s.logger.Info("Forwarding sampling request to client", "server", name, "max_tokens", params.MaxTokens)
+	result, err := session.CreateMessage(ctx, params)
+	if err != nil {
+		s.logger.Warn("Client sampling request failed", "server", name, "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// samplingSession returns an upstream session whose client supports sampling
+func (s *AggregatorServer) samplingSession() *mcp.ServerSession {
+	if s.server == nil {
+		return nil
+	}
+	for session := range s.server.Sessions() {
+		if params := session.InitializeParams(); params != nil && params.Capabilities != nil && params.Capabilities.Sampling != nil {
+			return session
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// ToolSchemaInput defines the input for tool_schema
+type ToolSchemaInput struct {
+	ToolName string `json:"tool_name" jsonschema:"Name of the tool, as returned by tool_search (e.g. 'playwright_browser_navigate')"`
+}
+
+// handleToolSchema returns everything known about one tool: the same fields
+// as a full_schema search result, without depending on the search ranking it
+func (s *AggregatorServer) handleToolSchema(ctx context.Context, req *mcp.CallToolRequest, input ToolSchemaInput) (*mcp.CallToolResult, any, error) {
+	tool, err := s.registry.Get(input.ToolName)
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      err.Error(),
+			"error_type": "tool_not_found",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+
+	metadata := tools.ToolMetadata{
+		Name:        tool.Name,
+		Type:        tool.Type,
+		Category:    tool.Category,
+		Server:      tool.SourceName,
+		Alias:       tool.Alias,
+		Description: tool.Description,
+		Examples:    tool.Examples,
+		Annotations: tool.Annotations,
+	}
+	metadata.Parameters, _ = tool.InputSchema.(map[string]any)
+	metadata.OutputSchema, _ = tool.OutputSchema.(map[string]any)
+
+	resultJSON, _ := json.Marshal(metadata)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/radutopala/onemcp/internal/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the schema file, as named by the schemaFileFormat setting
+const (
+	schemaFormatJSON     = "json"
+	schemaFormatYAML     = "yaml"
+	schemaFormatMarkdown = "markdown"
+)
+
+// schemaFormatExtensions maps each schema file format to the extension of its default file
+var schemaFormatExtensions = map[string]string{
+	schemaFormatJSON:     ".json",
+	schemaFormatYAML:     ".yaml",
+	schemaFormatMarkdown: ".md",
+}
+
+// schemaFormatFromPath infers the schema file format from a file extension,
+// falling back to JSON
+func schemaFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return schemaFormatYAML
+	case ".md", ".markdown":
+		return schemaFormatMarkdown
+	}
+	return schemaFormatJSON
+}
+
+// configureSchemaFile applies the schemaFile and schemaFileFormat settings.
+// Without a path, the catalog is written to the cache directory.
+func (s *AggregatorServer) configureSchemaFile(settings Settings, configPath string) {
+	format := settings.SchemaFileFormat
+	if format != "" && schemaFormatExtensions[format] == "" {
+		s.logger.Warn("Unknown schema file format, using the file extension", "format", format)
+		format = ""
+	}
+
+	path := settings.SchemaFile
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	if format == "" {
+		format = schemaFormatFromPath(path)
+	}
+	if path == "" {
+		path = filepath.Join(s.cacheDir, "tools-schema"+schemaFormatExtensions[format])
+	}
+
+	s.schemaFilePath = path
+	s.schemaFileFormat = format
+}
+
+// catalogTool is one executable tool as listed in the schema file
+type catalogTool struct {
+	Name         string                 `json:"name"`
+	Category     string                 `json:"category"`
+	Server       string                 `json:"server,omitempty"`
+	Alias        string                 `json:"alias,omitempty"`
+	Description  string                 `json:"description"`
+	Parameters   any                    `json:"parameters,omitempty"`
+	OutputSchema any                    `json:"output_schema,omitempty"`
+	Annotations  *tools.ToolAnnotations `json:"annotations,omitempty"`
+	Examples     []string               `json:"examples,omitempty"`
+}
+
+// schemaCatalog is the content of the schema file
+type schemaCatalog struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	ToolCount   int           `json:"tool_count"`
+	Tools       []catalogTool `json:"tools"`
+}
+
+// schemaCatalogTools lists every enabled executable tool with its full
+// schemas, sorted by name. Meta-tools are listed by tools/list already.
+func (s *AggregatorServer) schemaCatalogTools() []catalogTool {
+	items := s.enabledItems(s.registry.ListAll())
+	catalog := make([]catalogTool, 0, len(items))
+	for _, tool := range items {
+		if tool.Type != "" && tool.Type != tools.TypeTool {
+			continue
+		}
+		catalog = append(catalog, catalogTool{
+			Name:         tool.Name,
+			Category:     tool.Category,
+			Server:       tool.SourceName,
+			Alias:        tool.Alias,
+			Description:  tool.Description,
+			Parameters:   tool.InputSchema,
+			OutputSchema: tool.OutputSchema,
+			Annotations:  tool.Annotations,
+			Examples:     tool.Examples,
+		})
+	}
+	slices.SortFunc(catalog, func(a, b catalogTool) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return catalog
+}
+
+// encodeSchemaCatalog renders the catalog in a schema file format
+func encodeSchemaCatalog(catalog schemaCatalog, format string) ([]byte, error) {
+	switch format {
+	case schemaFormatYAML:
+		// Round-trip through JSON so YAML keys match the JSON field names
+		data, err := json.Marshal(catalog)
+		if err != nil {
+			return nil, err
+		}
+		var generic any
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(generic)
+	case schemaFormatMarkdown:
+		return markdownSchemaCatalog(catalog)
+	}
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// markdownSchemaCatalog renders the catalog as a document with one section per tool
+func markdownSchemaCatalog(catalog schemaCatalog) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Tool catalog\n\n%d tools, generated %s.\n", catalog.ToolCount, catalog.GeneratedAt.Format(time.RFC3339))
+	for _, tool := range catalog.Tools {
+		fmt.Fprintf(&buf, "\n## %s\n\n", tool.Name)
+		if tool.Description != "" {
+			fmt.Fprintf(&buf, "%s\n\n", tool.Description)
+		}
+		fmt.Fprintf(&buf, "- Category: %s\n", tool.Category)
+		if tool.Server != "" {
+			fmt.Fprintf(&buf, "- Server: %s (as `%s`)\n", tool.Server, tool.Alias)
+		}
+		if tool.Annotations.Destructive() {
+			buf.WriteString("- Destructive: yes\n")
+		}
+		for _, example := range tool.Examples {
+			fmt.Fprintf(&buf, "- Example: `%s`\n", example)
+		}
+
+		for _, schema := range []struct {
+			title string
+			value any
+		}{{"Parameters", tool.Parameters}, {"Output schema", tool.OutputSchema}} {
+			if schema.value == nil {
+				continue
+			}
+			data, err := json.MarshalIndent(schema.value, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode schema of %s: %w", tool.Name, err)
+			}
+			fmt.Fprintf(&buf, "\n%s:\n\n```json\n%s\n```\n", schema.title, data)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// writeSchemaFile writes every executable tool with its full schemas to the
+// schema file, so agents and external tooling can explore the whole catalog
+// without paginating tool_search. The file is replaced atomically, so readers
+// never see half a catalog.
+func (s *AggregatorServer) writeSchemaFile() {
+	if s.schemaFilePath == "" {
+		return
+	}
+
+	s.schemaFileMu.Lock()
+	defer s.schemaFileMu.Unlock()
+
+	catalogTools := s.schemaCatalogTools()
+	data, err := encodeSchemaCatalog(schemaCatalog{
+		GeneratedAt: time.Now().UTC(),
+		ToolCount:   len(catalogTools),
+		Tools:       catalogTools,
+	}, s.schemaFileFormat)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.schemaFilePath), 0755)
+	}
+	if err == nil {
+		tmp := s.schemaFilePath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, s.schemaFilePath)
+		}
+	}
+	if err != nil {
+		s.schemaFileWritten.Store(false)
+		s.logger.Warn("Failed to write schema file", "path", s.schemaFilePath, "error", err)
+		return
+	}
+
+	s.schemaFileWritten.Store(true)
+	s.logger.Debug("Wrote schema file", "path", s.schemaFilePath, "format", s.schemaFileFormat, "tools", len(catalogTools))
+}
+
+// schemaFile returns the path of the schema file, or "" if it couldn't be written
+func (s *AggregatorServer) schemaFile() string {
+	if !s.schemaFileWritten.Load() {
+		return ""
+	}
+	return s.schemaFilePath
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// defaultSearchResponseTTL is how long complete tool_search responses are cached
+const defaultSearchResponseTTL = 30 * time.Second
+
+// maxCachedSearchResponses bounds the number of cached tool_search responses
+const maxCachedSearchResponses = 500
+
+// searchResponseKey identifies a tool_search response: the input, the
+// settings it was answered with and the catalog version it was computed on
+type searchResponseKey struct {
+	input       ToolSearchInput
+	detailLevel string
+	limit       int
+	minScore    float64
+	version     uint64
+}
+
+// cachedSearchResponse is a cached tool_search response
+type cachedSearchResponse struct {
+	result   map[string]any
+	storedAt time.Time
+}
+
+// searchResponseCache caches complete tool_search responses for a short
+// time, since agents often repeat the same discovery query within a session.
+// Re-indexing bumps the catalog version, which drops every response.
+type searchResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration // 0 disables caching
+	version uint64
+	entries map[searchResponseKey]cachedSearchResponse
+	now     func() time.Time
+}
+
+// newSearchResponseCache creates an empty cache keeping responses for ttl
+func newSearchResponseCache(ttl time.Duration) *searchResponseCache {
+	return &searchResponseCache{
+		ttl:     ttl,
+		entries: make(map[searchResponseKey]cachedSearchResponse),
+		now:     time.Now,
+	}
+}
+
+// key returns the key of a search on the current catalog
+func (c *searchResponseCache) key(input ToolSearchInput, detailLevel string, limit int, minScore float64) searchResponseKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return searchResponseKey{input: input, detailLevel: detailLevel, limit: limit, minScore: minScore, version: c.version}
+}
+
+// get returns a copy of the response cached under key, if still fresh
+func (c *searchResponseCache) get(key searchResponseKey) (map[string]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.storedAt) >= c.ttl {
+		return nil, false
+	}
+	return maps.Clone(entry.result), true
+}
+
+// put caches a copy of result under key, unless the catalog was re-indexed
+// while it was being computed
+func (c *searchResponseCache) put(key searchResponseKey, result map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 || key.version != c.version {
+		return
+	}
+	c.evictLocked()
+	c.entries[key] = cachedSearchResponse{result: maps.Clone(result), storedAt: c.now()}
+}
+
+// invalidate drops every response, for a catalog that was re-indexed
+func (c *searchResponseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	clear(c.entries)
+}
+
+// evictLocked drops expired responses and, if still full, the oldest one.
+// Callers must hold mu.
+func (c *searchResponseCache) evictLocked() {
+	if len(c.entries) < maxCachedSearchResponses {
+		return
+	}
+
+	now := c.now()
+	var oldestKey searchResponseKey
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if now.Sub(entry.storedAt) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest.IsZero() || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+
+	if len(c.entries) >= maxCachedSearchResponses && !oldest.IsZero() {
+		delete(c.entries, oldestKey)
+	}
+}
@@ -1,16 +1,26 @@
 package mcp
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/radutopala/onemcp/internal/llmsearch"
 	"github.com/radutopala/onemcp/internal/mcpclient"
 	"github.com/radutopala/onemcp/internal/tools"
 	"github.com/tidwall/jsonc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -19,44 +29,247 @@ import (
 type Config struct {
 	Settings        Settings                             `json:"settings"`
 	ExternalServers map[string]mcpclient.MCPServerConfig `json:"mcpServers"`
+	Macros          map[string]MacroConfig               `json:"macros"` // Composite tools registered as macro_<name>
 }
 
 // Settings represents OneMCP settings
 type Settings struct {
-	SearchResultLimit int    `json:"searchResultLimit"` // Number of tools to return per search (default: 5)
-	SearchProvider    string `json:"searchProvider"`    // LLM search provider: "claude", "codex", or "copilot" (default: "claude")
-	ClaudeModel       string `json:"claudeModel"`       // Claude model: "haiku", "sonnet", "opus" (default: "haiku")
-	CodexModel        string `json:"codexModel"`        // Codex model: "gpt-5-codex-mini", "gpt-5-codex", etc. (default: "gpt-5-codex-mini")
-	CopilotModel      string `json:"copilotModel"`      // Copilot model (default: "claude-haiku-4.5")
+	SearchResultLimit int      `json:"searchResultLimit"` // Number of tools to return per search (default: 5)
+	SearchProvider    string   `json:"searchProvider"`    // LLM search provider: "claude", "codex", "copilot", "ollama", or "openai" (default: "claude")
+	SearchProviders   []string `json:"searchProviders"`   // Providers tried in order per query, e.g. ["claude", "codex", "tfidf"] (default: [searchProvider, "tfidf"])
+	ClaudeModel       string   `json:"claudeModel"`       // Claude model: "haiku", "sonnet", "opus" (default: "haiku")
+	AnthropicAPIKey   string   `json:"anthropicAPIKey"`   // Anthropic API key used when the claude CLI is unavailable (default: $ANTHROPIC_API_KEY)
+	AnthropicBaseURL  string   `json:"anthropicBaseURL"`  // Anthropic API base URL (default: "https://api.anthropic.com")
+	CodexModel        string   `json:"codexModel"`        // Codex model: "gpt-5-codex-mini", "gpt-5-codex", etc. (default: "gpt-5-codex-mini")
+	CopilotModel      string   `json:"copilotModel"`      // Copilot model (default: "claude-haiku-4.5")
+	OllamaModel       string   `json:"ollamaModel"`       // Ollama model (default: "llama3.2")
+	OllamaURL         string   `json:"ollamaURL"`         // Ollama server URL (default: "http://localhost:11434")
+	OpenAIModel       string   `json:"openaiModel"`       // OpenAI model (default: "gpt-4o-mini")
+	OpenAIBaseURL     string   `json:"openaiBaseURL"`     // OpenAI-compatible API base URL (default: "https://api.openai.com/v1")
+	OpenAIAPIKey      string   `json:"openaiAPIKey"`      // OpenAI API key (default: $OPENAI_API_KEY)
+	SearchCacheTTL    int      `json:"searchCacheTTL"`    // Seconds to cache LLM search results (default: 300, negative disables)
+	SearchResponseTTL int      `json:"searchResponseTTL"` // Seconds to cache complete tool_search responses (default: 30, negative disables)
+	SearchTimeout     int      `json:"searchTimeout"`     // Seconds before an LLM search call is abandoned (default: 60)
+	MinSearchScore    float64  `json:"minSearchScore"`    // Drop search results scored below this relevance, 0-1 (default: 0)
+	SchemaBudgetKB    int      `json:"schemaBudgetKB"`    // KB of tool schemas per LLM prompt before the catalog is chunked (default: 200)
+	SearchPromptFile  string   `json:"searchPromptFile"`  // Template file replacing the LLM ranking prompt, relative to the config file (default: built-in prompt)
+	TranslateQueries  *bool    `json:"translateQueries"`  // Translate non-English queries via the LLM before searching (default: true)
+	Mode              string   `json:"mode"`              // Tool exposure: "search" (meta-tools only), "passthrough" (all tools listed directly) or "hybrid" (most used listed directly) (default: "search")
+	HybridToolCount   int      `json:"hybridToolCount"`   // Most used tools listed directly in hybrid mode (default: 10)
+	PageSize          int      `json:"pageSize"`          // Items per page of tools/list, resources/list and prompts/list (default: 100)
+	SessionRateLimit  int      `json:"sessionRateLimit"`  // Tool calls allowed per client session per minute (default: 0, unlimited)
+	MaxParallel       int      `json:"maxParallel"`       // Tool calls run at once by tool_execute_parallel (default: 4)
+
+	DefaultDetailLevel string `json:"defaultDetailLevel"` // tool_search detail level when a call sets none: "names_only", "summary", "detailed" or "full_schema" (default: "summary")
+	LogLevel           string `json:"logLevel"`           // OneMCP's own log level: "debug", "info", "warn" or "error" (default: $MCP_LOG_LEVEL, else "info")
+
+	LogMaxSizeMB int `json:"logMaxSizeMB"` // MB the log file may reach before it is rotated (default: 10, negative disables rotation)
+	LogMaxFiles  int `json:"logMaxFiles"`  // Rotated log files kept next to it (default: 3, negative keeps none)
+
+	ForwardInstructions  *bool `json:"forwardInstructions"`  // Merge external servers' instructions into OneMCP's own (default: true)
+	InstructionsMaxChars int   `json:"instructionsMaxChars"` // Characters of instructions kept per external server (default: 500)
+
+	CacheDir string `json:"cacheDir"` // Directory for tool snapshots, OAuth tokens and runner caches (default: the user cache dir + "/onemcp")
+
+	SchemaFile       string `json:"schemaFile"`       // File listing every executable tool with full schemas, relative to the config file (default: cacheDir + "/tools-schema.json")
+	SchemaFileFormat string `json:"schemaFileFormat"` // Schema file format: "json", "yaml" or "markdown" (default: from the schemaFile extension, else "json")
+
+	MetaToolPrefix string            `json:"metaToolPrefix"` // Prefix added to every meta-tool name, e.g. "onemcp_" (default: none)
+	MetaToolNames  map[string]string `json:"metaToolNames"`  // Names replacing individual meta-tool names, e.g. {"tool_search": "find_tools"}
+
+	StartupConcurrency int `json:"startupConcurrency"` // External servers connected at once during startup (default: 8)
+	StartupTimeout     int `json:"startupTimeout"`     // Seconds to wait for external servers at startup (default: 120)
+
+	SlowCallThreshold   int `json:"slowCallThreshold"`   // Milliseconds before a tool call on an external server is logged and counted as slow (default: 10000, negative disables)
+	SlowSearchThreshold int `json:"slowSearchThreshold"` // Milliseconds before an LLM search call is logged and counted as slow (default: 5000, negative disables)
+	CallCeiling         int `json:"callCeiling"`         // Seconds before the watchdog cancels any external tool call as hung and marks its server suspect (default: 600, negative disables)
+
+	DuplicateCollapse DuplicateCollapseSettings `json:"duplicateCollapse"` // Near-duplicate collapsing in search results
+
+	AuditSinks []AuditSinkConfig `json:"auditSinks"` // Destinations of the audit log of tool calls: file, syslog, webhook or stdout (default: none)
+}
+
+// providerConfigs maps each search provider to its model and endpoint settings.
+// Empty models fall back to the provider's default.
+func (s Settings) providerConfigs() map[string]llmsearch.ProviderConfig {
+	return map[string]llmsearch.ProviderConfig{
+		"claude":  {Model: s.ClaudeModel, APIKey: s.AnthropicAPIKey, BaseURL: s.AnthropicBaseURL},
+		"codex":   {Model: s.CodexModel},
+		"copilot": {Model: s.CopilotModel},
+		"ollama":  {Model: s.OllamaModel, BaseURL: s.OllamaURL},
+		"openai":  {Model: s.OpenAIModel, APIKey: s.OpenAIAPIKey, BaseURL: s.OpenAIBaseURL},
+	}
+}
+
+// DuplicateCollapseSettings controls folding near-duplicate tools from different servers into one search result
+type DuplicateCollapseSettings struct {
+	Enabled    *bool           `json:"enabled,omitempty"`    // Collapse near-duplicates (default: true)
+	Threshold  float64         `json:"threshold,omitempty"`  // Name+description word similarity needed to collapse, 0-1 (default: 0.8)
+	Categories map[string]bool `json:"categories,omitempty"` // Per-category override of Enabled
+}
+
+// enabledFor reports whether collapsing applies to tools in the given category
+func (d DuplicateCollapseSettings) enabledFor(category string) bool {
+	if enabled, ok := d.Categories[category]; ok {
+		return enabled
+	}
+	return d.Enabled == nil || *d.Enabled
 }
 
 // AggregatorServer implements a generic MCP aggregator
 type AggregatorServer struct {
-	server            *mcp.Server
-	logger            *slog.Logger
-	registry          *tools.Registry
-	searchStore       llmsearch.SearchStore // LLM-powered semantic search
-	externalClients   map[string]*mcpclient.MCPClient
-	searchResultLimit int    // Number of tools to return per search
-	searchProvider    string // LLM search provider: claude, codex, or copilot
-	claudeModel       string // Claude model to use
-	codexModel        string // Codex model to use
-	copilotModel      string // Copilot model to use
+	server             *mcp.Server
+	logger             *slog.Logger
+	registry           *tools.Registry
+	searchMu           sync.RWMutex          // Guards searchStore and completer
+	searchStore        llmsearch.SearchStore // LLM-powered semantic search
+	completer          llmsearch.Completer   // First available search LLM, for free-form prompts; nil with TF-IDF only
+	clientsMu          sync.RWMutex          // Guards externalClients and serverConfigs
+	externalClients    map[string]*mcpclient.MCPClient
+	serverConfigs      map[string]mcpclient.MCPServerConfig    // Configs of connected and lazy external servers
+	resourcesMu        sync.RWMutex                            // Guards resources and resourceTemplates
+	resources          map[string][]mcpclient.Resource         // Resources listed by each external server
+	resourceTemplates  map[string][]mcpclient.ResourceTemplate // Resource templates listed by each external server
+	promptsMu          sync.RWMutex                            // Guards prompts
+	prompts            map[string][]externalPrompt             // Prompts listed by each external server
+	rootsMu            sync.RWMutex                            // Guards roots
+	roots              map[string][]*mcp.Root                  // Roots last listed by each client session
+	limiter            *sessionLimiter                         // Per-session tool call rate limit (nil if unlimited)
+	healthMu           sync.RWMutex                            // Guards health
+	health             map[string]*serverHealth                // Keepalive state of each external server
+	keepaliveCtx       context.Context                         // Cancelled on Close to stop keepalive pings
+	stopKeepalive      context.CancelFunc                      // Cancels keepaliveCtx
+	settingsMu         sync.RWMutex                            // Guards searchResultLimit, detailLevel, minSearchScore and logLevel, which config_set changes at runtime
+	searchResultLimit  int                                     // Number of tools to return per search
+	detailLevel        string                                  // Default tool_search detail level
+	searchProvider     string                                  // LLM search provider: claude, codex, copilot, ollama, or openai
+	fallbackProviders  []string                                // Providers tried after searchProvider, in order
+	providerConfigs    map[string]llmsearch.ProviderConfig     // Per-provider model and endpoint settings
+	searchCacheTTL     time.Duration                           // How long LLM search results are cached (0 disables)
+	searchTimeout      time.Duration                           // Deadline for a single LLM search call
+	schemaBudget       int                                     // Max tool schema bytes per LLM prompt
+	searchPrompt       *llmsearch.PromptTemplate               // Custom LLM ranking prompt (nil uses the built-in one)
+	searchUsage        *llmsearch.UsageStats                   // LLM call counts, latency and token usage per provider
+	searchCache        *llmsearch.CacheStats                   // Hits and misses of the LLM search result caches
+	searchResponses    *searchResponseCache                    // Recent complete tool_search responses
+	searchLatency      latencyWindow                           // Recent tool_search latencies
+	startedAt          time.Time                               // When the aggregator was created
+	minSearchScore     float64                                 // Default relevance threshold for search results
+	duplicateCollapse  DuplicateCollapseSettings               // Near-duplicate collapsing settings
+	translateQueries   bool                                    // Translate non-English queries before searching
+	mode               string                                  // Tool exposure mode: search, passthrough or hybrid
+	hybridToolCount    int                                     // Most used tools listed directly in hybrid mode
+	directMu           sync.Mutex                              // Guards directTools
+	directTools        map[string]bool                         // Tools currently listed directly next to the meta-tools
+	mergeInstructions  bool                                    // Merge external servers' instructions into the aggregator's own
+	instructionsLimit  int                                     // Characters of instructions kept per external server
+	cacheDir           string                                  // Directory for tool snapshots, OAuth tokens and runner caches
+	connectLocks       serverLocks                             // Serializes connecting each lazy or restarted server
+	startupWorkers     int                                     // External servers connected at once during startup
+	startupTimeout     time.Duration                           // Deadline for connecting external servers at startup
+	slowCallThreshold  int                                     // Milliseconds before an external tool call is slow, negative for never
+	callCeiling        int                                     // Seconds before an external tool call is cancelled as hung, negative for never
+	maxParallel        int                                     // Tool calls run at once by tool_execute_parallel
+	progressInterval   time.Duration                           // How often a running tool_execute reports progress
+	configPath         string                                  // Config file config_set persists settings to
+	configuredLogLevel string                                  // logLevel setting, applied by UseLogLevel
+	logMaxSize         int64                                   // Bytes the log file may reach before it is rotated, 0 for no limit
+	logMaxFiles        int                                     // Rotated log files kept
+	logLevel           *slog.LevelVar                          // Level of the aggregator's log handler (nil if it can't change)
+	feedbackOnce       sync.Once                               // Creates feedback on first use
+	feedback           *feedbackStore                          // Tools agents reported using per search query
+	macros             macroState                              // Saved macros and recordings in progress
+	disabledMu         sync.Mutex                              // Serializes tool_disable and tool_enable
+	schemaFilePath     string                                  // File the catalog with full schemas is written to
+	schemaFileFormat   string                                  // Format of the schema file: json, yaml or markdown
+	schemaFileMu       sync.Mutex                              // Serializes schema file writes
+	schemaFileWritten  atomic.Bool                             // Whether the schema file holds the current catalog
+	metaToolRenames    map[string]string                       // Names clients see for renamed meta-tools, by built-in name
+	audit              *auditLog                               // Sinks every tool call is audited to (nil if none)
+	startup            startupReport                           // Time taken by each phase of startup
 }
 
+// defaultPageSize is the number of items per page of the list methods
+const defaultPageSize = 100
+
+// Startup defaults: external servers connected at once, and how long startup waits for them
+const (
+	defaultStartupConcurrency = 8
+	defaultStartupTimeout     = 2 * time.Minute
+)
+
+// Slow call defaults: latencies at which external tool calls and LLM search
+// calls are logged and counted as slow, in milliseconds
+const (
+	defaultSlowCallThreshold   = 10000
+	defaultSlowSearchThreshold = 5000
+)
+
+// Log rotation defaults: size of the log file and rotated files kept
+const (
+	defaultLogMaxSizeMB = 10
+	defaultLogMaxFiles  = 3
+)
+
+// defaultCallCeiling is the seconds after which the watchdog cancels an
+// external tool call as hung
+const defaultCallCeiling = 600
+
 // NewAggregatorServer creates a new generic aggregator server
 func NewAggregatorServer(name, version, configPath string, logger *slog.Logger) (*AggregatorServer, error) {
 	ctx := context.Background()
+	start := time.Now()
+
+	// Tag log records with the client session of the request being handled
+	logger = slog.New(sessionLogHandler{logger.Handler()})
+
+	keepaliveCtx, stopKeepalive := context.WithCancel(ctx)
 
 	aggregator := &AggregatorServer{
 		logger:            logger,
 		registry:          tools.NewRegistry(logger),
 		externalClients:   make(map[string]*mcpclient.MCPClient),
+		serverConfigs:     make(map[string]mcpclient.MCPServerConfig),
+		resources:         make(map[string][]mcpclient.Resource),
+		resourceTemplates: make(map[string][]mcpclient.ResourceTemplate),
+		prompts:           make(map[string][]externalPrompt),
+		roots:             make(map[string][]*mcp.Root),
+		health:            make(map[string]*serverHealth),
+		keepaliveCtx:      keepaliveCtx,
+		stopKeepalive:     stopKeepalive,
 		searchResultLimit: 5, // Default limit
+		detailLevel:       defaultDetailLevel,
+		searchCacheTTL:    5 * time.Minute,
+		searchTimeout:     llmsearch.DefaultSearchTimeout,
+		schemaBudget:      llmsearch.DefaultSchemaBudget,
+		duplicateCollapse: DuplicateCollapseSettings{Threshold: 0.8},
+		translateQueries:  true,
+		searchUsage:       llmsearch.NewUsageStats(),
+		searchCache:       llmsearch.NewCacheStats(),
+		searchResponses:   newSearchResponseCache(defaultSearchResponseTTL),
+		startedAt:         time.Now(),
+		mode:              modeSearch,
+		hybridToolCount:   defaultHybridToolCount,
+		directTools:       make(map[string]bool),
+		mergeInstructions: true,
+		instructionsLimit: defaultInstructionsMaxChars,
+		cacheDir:          defaultCacheDir(),
+		startupWorkers:    defaultStartupConcurrency,
+		startupTimeout:    defaultStartupTimeout,
+		slowCallThreshold: defaultSlowCallThreshold,
+		callCeiling:       defaultCallCeiling,
+		logMaxSize:        defaultLogMaxSizeMB << 20,
+		logMaxFiles:       defaultLogMaxFiles,
+		maxParallel:       defaultMaxParallel,
+		progressInterval:  defaultProgressInterval,
+		configPath:        configPath,
 	}
 
+	aggregator.searchUsage.SetSlowThreshold(defaultSlowSearchThreshold * time.Millisecond)
+
 	// Load configuration and initialize external MCP servers
 	config, err := aggregator.loadConfig(configPath)
+	aggregator.startup.configLoad = time.Since(start)
 	if err != nil {
 		logger.Warn("Failed to load config, using defaults", "error", err)
 		// Set default search provider
@@ -72,54 +285,220 @@ func NewAggregatorServer(name, version, configPath string, logger *slog.Logger)
 			logger.Info("Using custom search result limit", "limit", config.Settings.SearchResultLimit)
 		}
 
+		if config.Settings.SearchCacheTTL > 0 {
+			aggregator.searchCacheTTL = time.Duration(config.Settings.SearchCacheTTL) * time.Second
+		} else if config.Settings.SearchCacheTTL < 0 {
+			aggregator.searchCacheTTL = 0
+			logger.Info("LLM search result caching disabled")
+		}
+		if config.Settings.SearchResponseTTL > 0 {
+			aggregator.searchResponses.ttl = time.Duration(config.Settings.SearchResponseTTL) * time.Second
+		} else if config.Settings.SearchResponseTTL < 0 {
+			aggregator.searchResponses.ttl = 0
+		}
+
+		if config.Settings.SearchTimeout > 0 {
+			aggregator.searchTimeout = time.Duration(config.Settings.SearchTimeout) * time.Second
+		}
+
+		if config.Settings.SlowCallThreshold != 0 {
+			aggregator.slowCallThreshold = config.Settings.SlowCallThreshold
+		}
+		if config.Settings.CallCeiling != 0 {
+			aggregator.callCeiling = config.Settings.CallCeiling
+		}
+		if config.Settings.SlowSearchThreshold != 0 {
+			aggregator.searchUsage.SetSlowThreshold(time.Duration(config.Settings.SlowSearchThreshold) * time.Millisecond)
+		}
+
+		aggregator.minSearchScore = config.Settings.MinSearchScore
+
+		if level := config.Settings.DefaultDetailLevel; level != "" {
+			if slices.Contains(detailLevels, level) {
+				aggregator.detailLevel = level
+			} else {
+				logger.Warn("Unknown default detail level, using summary", "level", level)
+			}
+		}
+
+		if level := config.Settings.LogLevel; level != "" {
+			if _, ok := logLevels[level]; ok {
+				aggregator.configuredLogLevel = level
+			} else {
+				logger.Warn("Unknown log level, ignoring", "level", level)
+			}
+		}
+
+		if config.Settings.LogMaxSizeMB > 0 {
+			aggregator.logMaxSize = int64(config.Settings.LogMaxSizeMB) << 20
+		} else if config.Settings.LogMaxSizeMB < 0 {
+			aggregator.logMaxSize = 0
+		}
+		if config.Settings.LogMaxFiles != 0 {
+			aggregator.logMaxFiles = max(config.Settings.LogMaxFiles, 0)
+		}
+
+		if config.Settings.SchemaBudgetKB > 0 {
+			aggregator.schemaBudget = config.Settings.SchemaBudgetKB * 1024
+		}
+
+		if config.Settings.SearchPromptFile != "" {
+			promptPath := config.Settings.SearchPromptFile
+			if !filepath.IsAbs(promptPath) {
+				promptPath = filepath.Join(filepath.Dir(configPath), promptPath)
+			}
+			prompt, err := llmsearch.LoadPromptTemplate(promptPath)
+			if err != nil {
+				logger.Warn("Failed to load search prompt template, using the built-in prompt", "path", promptPath, "error", err)
+			} else {
+				aggregator.searchPrompt = prompt
+				logger.Info("Using custom search prompt template", "path", promptPath)
+			}
+		}
+
+		if config.Settings.TranslateQueries != nil {
+			aggregator.translateQueries = *config.Settings.TranslateQueries
+		}
+
+		switch config.Settings.Mode {
+		case "", modeSearch:
+		case modePassthrough, modeHybrid:
+			aggregator.mode = config.Settings.Mode
+			logger.Info("Listing tools directly", "mode", aggregator.mode)
+		default:
+			logger.Warn("Unknown mode, using search", "mode", config.Settings.Mode)
+		}
+		if config.Settings.HybridToolCount > 0 {
+			aggregator.hybridToolCount = config.Settings.HybridToolCount
+		}
+
+		if config.Settings.SessionRateLimit > 0 {
+			aggregator.limiter = newSessionLimiter(config.Settings.SessionRateLimit)
+			logger.Info("Limiting tool calls per session", "per_minute", config.Settings.SessionRateLimit)
+		}
+
+		if config.Settings.ForwardInstructions != nil {
+			aggregator.mergeInstructions = *config.Settings.ForwardInstructions
+		}
+		if config.Settings.InstructionsMaxChars > 0 {
+			aggregator.instructionsLimit = config.Settings.InstructionsMaxChars
+		}
+
+		if config.Settings.MaxParallel > 0 {
+			aggregator.maxParallel = config.Settings.MaxParallel
+		}
+
+		if config.Settings.StartupConcurrency > 0 {
+			aggregator.startupWorkers = config.Settings.StartupConcurrency
+		}
+		if config.Settings.StartupTimeout > 0 {
+			aggregator.startupTimeout = time.Duration(config.Settings.StartupTimeout) * time.Second
+		}
+
+		if config.Settings.CacheDir != "" {
+			aggregator.cacheDir = config.Settings.CacheDir
+			if !filepath.IsAbs(aggregator.cacheDir) {
+				aggregator.cacheDir = filepath.Join(filepath.Dir(configPath), aggregator.cacheDir)
+			}
+		}
+
+		aggregator.duplicateCollapse.Enabled = config.Settings.DuplicateCollapse.Enabled
+		aggregator.duplicateCollapse.Categories = config.Settings.DuplicateCollapse.Categories
+		if config.Settings.DuplicateCollapse.Threshold > 0 {
+			aggregator.duplicateCollapse.Threshold = config.Settings.DuplicateCollapse.Threshold
+		}
+
 		// Set default search provider if not specified
 		if config.Settings.SearchProvider == "" {
 			config.Settings.SearchProvider = "claude"
 		}
 
+		// Working directories of stdio servers are relative to the config file,
+		// which {{configDir}} in commands and arguments refers to
+		configDir, err := filepath.Abs(filepath.Dir(configPath))
+		if err != nil {
+			configDir = filepath.Dir(configPath)
+		}
+		for name, serverConfig := range config.ExternalServers {
+			if serverConfig.Cwd != "" && !filepath.IsAbs(serverConfig.Cwd) {
+				serverConfig.Cwd = filepath.Join(filepath.Dir(configPath), serverConfig.Cwd)
+			}
+			serverConfig.ConfigDir = configDir
+			config.ExternalServers[name] = serverConfig
+		}
+
 		// Initialize external servers from config
 		if err := aggregator.initializeExternalServersFromConfig(ctx, config.ExternalServers); err != nil {
 			logger.Warn("Failed to initialize external servers, continuing without them", "error", err)
 		}
 	}
 
+	aggregator.configureSchemaFile(config.Settings, configPath)
+	aggregator.configureAudit(config.Settings, configPath)
+	aggregator.configureMetaToolNames(config.Settings)
+
+	// Macros are tools like any other, so they're indexed with them
+	aggregator.loadMacros(config.Macros)
+	aggregator.loadDisabledTools()
+
 	// Store search provider configuration
 	aggregator.searchProvider = config.Settings.SearchProvider
-	aggregator.claudeModel = config.Settings.ClaudeModel
-	if aggregator.claudeModel == "" {
-		aggregator.claudeModel = "haiku" // default
-	}
-	aggregator.codexModel = config.Settings.CodexModel
-	if aggregator.codexModel == "" {
-		aggregator.codexModel = "gpt-5-codex-mini" // default
-	}
-	aggregator.copilotModel = config.Settings.CopilotModel
-	if aggregator.copilotModel == "" {
-		aggregator.copilotModel = "claude-haiku-4.5" // default
+	if len(config.Settings.SearchProviders) > 0 {
+		aggregator.searchProvider = config.Settings.SearchProviders[0]
+		aggregator.fallbackProviders = config.Settings.SearchProviders[1:]
 	}
+	aggregator.providerConfigs = config.Settings.providerConfigs()
 	logger.Info("Using search provider", "provider", aggregator.searchProvider)
 
+	// List results are paginated with nextCursor; passthrough catalogs can be large
+	pageSize := defaultPageSize
+	if config.Settings.PageSize > 0 {
+		pageSize = config.Settings.PageSize
+	}
+
 	// Create MCP server
 	server := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    name,
 			Version: version,
 		},
-		nil,
+		&mcp.ServerOptions{
+			// Usage guidance of the external servers reaches clients with ours
+			Instructions: aggregator.aggregateInstructions(),
+			HasResources: true, // Downstream resources and prompts are proxied as they appear
+			HasPrompts:   true,
+			PageSize:     pageSize,
+			// Client roots are forwarded to external servers
+			InitializedHandler:      aggregator.handleClientInitialized,
+			RootsListChangedHandler: aggregator.handleRootsListChanged,
+			// Meta-tool arguments are completed locally, prompt and resource arguments by their server
+			CompletionHandler: aggregator.handleComplete,
+		},
 	)
 
+	// Requests carry their client session for logging and rate limiting
+	server.AddReceivingMiddleware(aggregator.sessionMiddleware)
+
 	// Register meta-tools (both in MCP server and registry)
 	if err := aggregator.registerMetaTools(server); err != nil {
 		return nil, fmt.Errorf("failed to register meta-tools: %w", err)
 	}
 
 	aggregator.server = server
+	aggregator.publishExternalResources()
+	aggregator.publishExternalPrompts()
+	aggregator.syncDirectTools(true)
 
 	// Initialize search store for LLM-powered semantic search
+	indexStart := time.Now()
 	if err := aggregator.initializeSearchStore(); err != nil {
 		logger.Warn("Failed to initialize search store, semantic search disabled", "error", err)
 	}
+	aggregator.startup.searchIndex = time.Since(indexStart)
+	aggregator.writeSchemaFile()
 
+	aggregator.startup.total = time.Since(start)
+	aggregator.startup.log(logger)
 	return aggregator, nil
 }
 
@@ -149,13 +528,22 @@ func (s *AggregatorServer) loadConfig(configPath string) (*Config, error) {
 	return &config, nil
 }
 
-// initializeExternalServersFromConfig connects to external MCP servers from config
+// initializeExternalServersFromConfig connects to external MCP servers from config.
+// Servers are connected concurrently, at most startupConcurrency at a time;
+// servers still connecting when startupTimeout expires are given up on.
 func (s *AggregatorServer) initializeExternalServersFromConfig(ctx context.Context, servers map[string]mcpclient.MCPServerConfig) error {
 	if len(servers) == 0 {
 		s.logger.Info("No external servers configured")
 		return nil
 	}
 
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, s.startupTimeout)
+	defer cancel()
+
+	sem := make(chan struct{}, s.startupWorkers)
+	var wg sync.WaitGroup
+
 	// Initialize each external server
 	for name, serverConfig := range servers {
 		if !serverConfig.Enabled {
@@ -163,20 +551,82 @@ func (s *AggregatorServer) initializeExternalServersFromConfig(ctx context.Conte
 			continue
 		}
 
-		if err := s.connectExternalServer(ctx, name, serverConfig); err != nil {
-			s.logger.Error("Failed to connect external server", "name", name, "error", err)
+		// Lazy servers with a tool snapshot are connected on first use
+		if serverConfig.Lazy && s.registerLazyServer(name, serverConfig) {
 			continue
 		}
+
+		timing := s.startup.addServer(name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { timing.TotalMs = time.Since(start).Milliseconds() }()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				s.logger.Error("Startup timeout reached before connecting external server", "name", name, "timeout", s.startupTimeout)
+				timing.Error = ctx.Err().Error()
+				s.registerSnapshotFallback(name, serverConfig, ctx.Err())
+				return
+			}
+
+			if err := s.connectExternalServer(ctx, name, serverConfig, timing); err != nil {
+				if ctx.Err() != nil {
+					s.logger.Error("Startup timeout reached while connecting external server", "name", name, "timeout", s.startupTimeout, "error", err)
+				} else {
+					s.logger.Error("Failed to connect external server", "name", name, "error", err)
+				}
+				timing.Error = err.Error()
+				s.registerSnapshotFallback(name, serverConfig, err)
+				return
+			}
+			s.logger.Info("Connected external server during startup", "name", name,
+				"connect_ms", timing.ConnectMs, "list_tools_ms", timing.ListToolsMs, "total_ms", time.Since(start).Milliseconds())
+		}()
 	}
+	wg.Wait()
 
-	s.logger.Info("Initialized external servers", "count", len(s.externalClients))
+	s.startup.servers = time.Since(start)
+	s.logger.Info("Initialized external servers", "count", len(s.connectedClients()), "duration_ms", s.startup.servers.Milliseconds())
 	return nil
 }
 
 // connectExternalServer connects to a single external MCP server and registers its tools.
-func (s *AggregatorServer) connectExternalServer(ctx context.Context, name string, config mcpclient.MCPServerConfig) error {
+// During startup, timing receives how long connecting and listing tools took; it's nil otherwise.
+func (s *AggregatorServer) connectExternalServer(ctx context.Context, name string, config mcpclient.MCPServerConfig, timing *serverStartup) error {
+	handlers := mcpclient.Handlers{
+		ToolListChanged:     s.handleToolListChanged,
+		ResourceListChanged: s.handleResourceListChanged,
+		PromptListChanged:   s.handlePromptListChanged,
+		CreateMessage:       s.handleCreateMessage,
+		LoggingMessage:      s.handleLoggingMessage,
+		Disconnected:        s.handleServerDisconnected,
+		Reconnected:         s.handleServerReconnected,
+		GaveUp:              s.handleServerGaveUp,
+		Hung:                s.handleServerHung,
+	}
+
+	// OAuth tokens are cached next to the tool snapshots unless a file is configured
+	if config.OAuth != nil && config.OAuth.TokenFile == "" {
+		oauth := *config.OAuth
+		oauth.TokenFile = filepath.Join(s.cacheDir, "oauth", name+".json")
+		config.OAuth = &oauth
+	}
+	if config.Runner != "" && config.RunnerCache == "" {
+		config.RunnerCache = filepath.Join(s.cacheDir, "runners")
+	}
+	if config.SlowCallThreshold == 0 {
+		config.SlowCallThreshold = s.slowCallThreshold
+	}
+	if config.CallCeiling == 0 {
+		config.CallCeiling = s.callCeiling
+	}
+
 	// Create MCP client
-	client, err := mcpclient.NewMCPClient(ctx, name, config, s.logger)
+	phaseStart := time.Now()
+	client, err := mcpclient.NewMCPClientWithHandlers(ctx, name, config, handlers, s.logger)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
@@ -187,99 +637,367 @@ func (s *AggregatorServer) connectExternalServer(ctx context.Context, name strin
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 
+	if timing != nil {
+		timing.ConnectMs = time.Since(phaseStart).Milliseconds()
+	}
+
 	// List available tools
+	phaseStart = time.Now()
 	externalTools, err := client.ListTools(ctx)
 	if err != nil {
 		client.Close()
 		return fmt.Errorf("failed to list tools: %w", err)
 	}
+	if timing != nil {
+		timing.ListToolsMs = time.Since(phaseStart).Milliseconds()
+	}
+	s.saveToolSnapshot(name, externalTools)
+
+	// Hand over the client's roots, if they are already known
+	if roots := s.clientRoots(); roots != nil {
+		client.SetRoots(roots)
+	}
 
 	// Register the executor
 	s.registry.RegisterExternalExecutor(name, client)
 
 	// Register each tool
+	s.registerExternalTools(name, config, externalTools)
+
+	// Store the client
+	s.clientsMu.Lock()
+	s.externalClients[name] = client
+	s.serverConfigs[name] = config
+	s.clientsMu.Unlock()
+
+	// Receive the server's log messages at its configured level
+	s.subscribeServerLogs(ctx, name, config.LogLevel)
+
+	// Ping the server so a dead connection is noticed before the next tool call
+	s.startKeepalive(name, pingInterval(config.PingInterval))
+
+	// Proxy the server's resources and prompts, if it has any
+	s.loadExternalResources(ctx, name, client)
+	s.loadExternalPrompts(ctx, name, client)
+
+	s.logger.Info("Connected to external MCP server", "name", name, "tools", len(externalTools))
+	return nil
+}
+
+// registerExternalTools registers the tools listed by an external server in the registry.
+// With blockDestructive set, tools annotated as destructive are skipped.
+func (s *AggregatorServer) registerExternalTools(name string, config mcpclient.MCPServerConfig, externalTools []mcpclient.Tool) {
+	for _, tool := range s.externalToolEntries(name, config, externalTools) {
+		if err := s.registry.Register(tool); err != nil {
+			s.logger.Warn("Failed to register external tool", "server", name, "tool", tool.Alias, "error", err)
+		}
+	}
+}
+
+// externalToolEntries builds the registry entries for the tools listed by an
+// external server, prefixed with the server name and carrying their output
+// schema, annotations and configured usage examples.
+// With blockDestructive set, tools annotated as destructive are left out.
+func (s *AggregatorServer) externalToolEntries(name string, config mcpclient.MCPServerConfig, externalTools []mcpclient.Tool) []*tools.Tool {
 	category := config.Category
 	if category == "" {
 		category = name // Use server name as category if not specified
 	}
+	entries := make([]*tools.Tool, 0, len(externalTools))
 	for _, tool := range externalTools {
-		if err := s.registry.RegisterExternalTool(name, category, tool.Name, tool.Description, tool.InputSchema); err != nil {
-			s.logger.Warn("Failed to register external tool", "server", name, "tool", tool.Name, "error", err)
+		annotations := toolAnnotations(tool.Annotations)
+		if config.BlockDestructive && annotations.Destructive() {
+			s.logger.Info("Skipping destructive external tool", "server", name, "tool", tool.Name)
 			continue
 		}
+
+		entry := &tools.Tool{
+			Name:        name + "_" + tool.Name,
+			Category:    category,
+			Description: tool.Description,
+			Source:      tools.SourceExternal,
+			SourceName:  name,
+			Alias:       tool.Name,
+			Type:        tools.TypeTool,
+			InputSchema: tool.InputSchema,
+			Annotations: annotations,
+		}
+		if tool.OutputSchema != nil {
+			entry.OutputSchema = tool.OutputSchema
+		}
+
+		// Attach server-wide and per-tool usage examples from config
+		examples := append(append([]string{}, config.Examples...), config.ToolExamples[tool.Name]...)
+		if len(examples) > 0 {
+			entry.Examples = examples
+		}
+		entries = append(entries, entry)
 	}
+	return entries
+}
 
-	// Store the client
-	s.externalClients[name] = client
+// handleToolListChanged re-indexes a server's tools after it emits tools/list_changed.
+// The refresh runs in the background so the client's notification handler is not blocked.
+func (s *AggregatorServer) handleToolListChanged(ctx context.Context, name string) {
+	go func() {
+		if err := s.refreshExternalTools(context.Background(), name); err != nil {
+			s.logger.Error("Failed to refresh external tools", "name", name, "error", err)
+		}
+	}()
+}
 
-	s.logger.Info("Connected to external MCP server", "name", name, "tools", len(externalTools))
+// externalClient returns the client of a connected external server
+func (s *AggregatorServer) externalClient(name string) (*mcpclient.MCPClient, bool) {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	client, ok := s.externalClients[name]
+	return client, ok
+}
+
+// connectedClients returns a snapshot of the connected external servers' clients by name
+func (s *AggregatorServer) connectedClients() map[string]*mcpclient.MCPClient {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	return maps.Clone(s.externalClients)
+}
+
+// serverConfig returns the config of a connected external server
+func (s *AggregatorServer) serverConfig(name string) mcpclient.MCPServerConfig {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	return s.serverConfigs[name]
+}
+
+// refreshExternalTools re-lists tools from an external server, updates its entries
+// in the registry and rebuilds the search store if any of them changed.
+func (s *AggregatorServer) refreshExternalTools(ctx context.Context, name string) error {
+	diff, err := s.refreshServerTools(ctx, name)
+	if err != nil || diff.empty() {
+		return err
+	}
+	return s.rebuildSearchStore()
+}
+
+// searchableItems returns everything indexed for semantic search. Each item
+// carries a Type so prompts and resources can be indexed alongside tools.
+func (s *AggregatorServer) searchableItems() []*tools.Tool {
+	items := append(s.enabledItems(s.registry.ListAll()), s.resourceItems()...)
+	return append(items, s.promptItems()...)
+}
+
+// rebuildSearchStore re-indexes all registry tools into a new search store
+// for the current providers, creating the store if it was never initialized
+// (e.g. no tools at startup), and notifies connected clients of the catalog
+// change. Searches still running keep using the previous store.
+func (s *AggregatorServer) rebuildSearchStore() error {
+	// Clients see the new catalog size, and the schema file lists it, even if re-indexing fails
+	defer s.writeSchemaFile()
+	defer s.notifyCatalogChanged()
+	defer s.searchResponses.invalidate()
+
+	s.searchMu.Lock()
+	if s.searchStore == nil {
+		s.searchMu.Unlock()
+		return s.initializeSearchStore()
+	}
+	defer s.searchMu.Unlock()
+
+	if err := s.buildSearchStoreLocked(s.searchableItems(), true); err != nil {
+		return fmt.Errorf("failed to rebuild search store: %w", err)
+	}
 	return nil
 }
 
 // initializeSearchStore builds the LLM-powered search store
 func (s *AggregatorServer) initializeSearchStore() error {
-	// Get all tools from registry
-	allTools := s.registry.ListAll()
+	// Get all tools (and other searchable capabilities)
+	allTools := s.searchableItems()
 
 	if len(allTools) == 0 {
 		s.logger.Info("No tools to index in search store")
 		return nil
 	}
 
-	var store llmsearch.SearchStore
-	var err error
+	s.searchMu.Lock()
+	defer s.searchMu.Unlock()
 
-	// Create search store based on provider
-	switch s.searchProvider {
-	case "claude":
-		s.logger.Info("Creating Claude searcher", "model", s.claudeModel)
-		searcher, err := llmsearch.NewClaudeSearcher(s.claudeModel, s.logger)
-		if err != nil {
-			return fmt.Errorf("failed to create Claude searcher: %w", err)
+	return s.buildSearchStoreLocked(allTools, true)
+}
+
+// tfidfProvider names the local TF-IDF index in settings.searchProviders
+const tfidfProvider = "tfidf"
+
+// newSearchStoreLocked creates an empty search store for the given provider,
+// along with its searcher for free-form prompts. Callers must hold searchMu.
+func (s *AggregatorServer) newSearchStoreLocked(provider string) (llmsearch.SearchStore, llmsearch.Completer, error) {
+	if !llmsearch.IsProvider(provider) {
+		return nil, nil, fmt.Errorf("unknown search provider: %s (supported: %s)", provider, strings.Join(llmsearch.ProviderNames(), ", "))
+	}
+
+	cfg := s.providerConfigs[provider]
+	cfg.Timeout = s.searchTimeout
+
+	s.logger.Info("Creating searcher", "provider", provider, "model", s.modelLocked(provider))
+	created, err := llmsearch.NewSearcher(provider, cfg, s.logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s searcher: %w", provider, err)
+	}
+	searcher := llmsearch.NewMeteredSearcher(provider, created, s.searchUsage, s.logger)
+	store := llmsearch.NewLLMSearchStore(provider, searcher, s.logger)
+	store.SetSchemaBudget(s.schemaBudget)
+	store.SetPromptTemplate(s.searchPrompt)
+	return store, searcher, nil
+}
+
+// providerChainLocked returns the providers tried per query: the active
+// provider, then the configured fallbacks. Callers must hold searchMu.
+func (s *AggregatorServer) providerChainLocked() []string {
+	chain := []string{s.searchProvider}
+	for _, provider := range s.fallbackProviders {
+		if !slices.Contains(chain, provider) {
+			chain = append(chain, provider)
 		}
-		store = llmsearch.NewClaudeSearchStore(searcher, s.logger)
+	}
+	return chain
+}
 
-	case "codex":
-		s.logger.Info("Creating Codex searcher", "model", s.codexModel)
-		searcher, err := llmsearch.NewCodexSearcher(s.codexModel, s.logger)
-		if err != nil {
-			return fmt.Errorf("failed to create Codex searcher: %w", err)
+// buildSearchStoreLocked creates a store chaining the configured providers,
+// indexes the given tools and installs it. Each query goes to the providers in
+// order until one answers, ending with the local TF-IDF index (which is always
+// last, even if not listed). Non-English queries reaching the local index are
+// translated by the first available LLM, within a short deadline of its own
+// and only if that LLM didn't just time out on the same query. If
+// allowFallbackOnly is set, providers whose searcher can't be created (e.g.
+// CLI missing) are skipped; otherwise the active provider must work.
+// Callers must hold searchMu.
+func (s *AggregatorServer) buildSearchStoreLocked(allTools []*tools.Tool, allowFallbackOnly bool) error {
+	if s.searchProvider != tfidfProvider && !llmsearch.IsProvider(s.searchProvider) {
+		_, _, err := s.newSearchStoreLocked(s.searchProvider)
+		return err
+	}
+
+	var chain []llmsearch.NamedSearchStore
+	var completer llmsearch.Completer
+	var completerProvider string
+	for _, provider := range s.providerChainLocked() {
+		if provider == tfidfProvider {
+			break // Nothing after the local index is ever reached
 		}
-		store = llmsearch.NewCodexSearchStore(searcher, s.logger)
 
-	case "copilot":
-		s.logger.Info("Creating Copilot searcher", "model", s.copilotModel)
-		searcher, err := llmsearch.NewCopilotSearcher(s.copilotModel, s.logger)
+		llmStore, searcher, err := s.newSearchStoreLocked(provider)
 		if err != nil {
-			return fmt.Errorf("failed to create Copilot searcher: %w", err)
+			if provider == s.searchProvider && !allowFallbackOnly {
+				return err
+			}
+			s.logger.Warn("LLM search provider unavailable, skipping", "provider", provider, "error", err)
+			continue
 		}
-		store = llmsearch.NewCopilotSearchStore(searcher, s.logger)
 
-	default:
-		return fmt.Errorf("unknown search provider: %s (supported: claude, codex, copilot)", s.searchProvider)
+		if s.searchCacheTTL > 0 {
+			cached := llmsearch.NewCachedSearchStore(llmStore, s.searchCacheTTL, s.logger)
+			cached.SetStats(s.searchCache)
+			llmStore = cached
+		}
+		chain = append(chain, llmsearch.NamedSearchStore{Name: provider, Store: llmStore})
+		if completer == nil {
+			completer, completerProvider = searcher, provider
+		}
+	}
+
+	var store llmsearch.SearchStore
+	if len(chain) == 0 {
+		if s.searchProvider != tfidfProvider {
+			s.logger.Warn("LLM search unavailable, using local TF-IDF search only", "provider", s.searchProvider)
+		}
+		store = llmsearch.NewTFIDFSearchStore(s.logger)
+	} else {
+		// LLM searchers understand any language, so only the lexical
+		// fallback needs queries in English
+		var local llmsearch.SearchStore = llmsearch.NewTFIDFSearchStore(s.logger)
+		if s.translateQueries {
+			local = llmsearch.NewTranslatingSearchStore(local, completerProvider, completer, s.logger)
+		}
+		chain = append(chain, llmsearch.NamedSearchStore{Name: tfidfProvider, Store: local})
+		store = llmsearch.NewFallbackSearchStore(chain, s.logger)
 	}
 
 	// Build search index from all tools
-	if err = store.BuildFromTools(allTools); err != nil {
+	if err := store.BuildFromTools(allTools); err != nil {
 		return fmt.Errorf("failed to build search store: %w", err)
 	}
 
 	s.searchStore = store
-	s.logger.Info("Search store initialized successfully", "provider", s.searchProvider, "indexed_tools", store.GetToolCount())
+	s.completer = completer
+	s.searchResponses.invalidate()
+	s.logger.Info("Search store initialized successfully", "provider", s.searchProvider, "chain", s.providerChainLocked(), "indexed_tools", store.GetToolCount())
+
+	return nil
+}
+
+// RebuildWithProvider switches the search provider (and optionally its model) at
+// runtime and re-indexes all tools. The previous store and settings are kept if
+// the new provider cannot be created or built.
+func (s *AggregatorServer) RebuildWithProvider(provider, model string) error {
+	s.searchMu.Lock()
+	defer s.searchMu.Unlock()
+
+	prevProvider := s.searchProvider
+	prevConfig, hadConfig := s.providerConfigs[provider]
+
+	s.searchProvider = provider
+	if model != "" {
+		cfg := prevConfig
+		cfg.Model = model
+		s.providerConfigs[provider] = cfg
+	}
+
+	if err := s.buildSearchStoreLocked(s.searchableItems(), false); err != nil {
+		s.searchProvider = prevProvider
+		if hadConfig {
+			s.providerConfigs[provider] = prevConfig
+		} else {
+			delete(s.providerConfigs, provider)
+		}
+		return err
+	}
 
+	s.logger.Info("Switched search provider", "from", prevProvider, "to", provider, "model", s.currentModelLocked())
 	return nil
 }
+
+// currentModelLocked returns the model of the active search provider.
+// Callers must hold searchMu.
+func (s *AggregatorServer) currentModelLocked() string {
+	return s.modelLocked(s.searchProvider)
+}
+
+// modelLocked returns the configured (or default) model of a provider.
+// Callers must hold searchMu.
+func (s *AggregatorServer) modelLocked(provider string) string {
+	if model := s.providerConfigs[provider].Model; model != "" {
+		return model
+	}
+	return llmsearch.DefaultModel(provider)
+}
+
 func (s *AggregatorServer) Close() error {
-	for name, client := range s.externalClients {
+	s.stopKeepalive()
+	for name, client := range s.connectedClients() {
 		if err := client.Close(); err != nil {
 			s.logger.Warn("Error closing external client", "name", name, "error", err)
 		}
 	}
+	if s.audit != nil {
+		s.audit.close()
+	}
 	return nil
 }
 
 // Run starts the MCP server with the given transport
 func (s *AggregatorServer) Run(ctx context.Context, transport mcp.Transport) error {
+	if _, ok := transport.(*mcp.StdioTransport); ok && s.audit != nil {
+		s.audit.dropStdout()
+	}
 	return s.server.Run(ctx, transport)
 }
 
@@ -287,77 +1005,384 @@ func (s *AggregatorServer) Run(ctx context.Context, transport mcp.Transport) err
 
 func (s *AggregatorServer) registerMetaTools(server *mcp.Server) error {
 	// Register tool_search
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "tool_search",
-		Description: "Search and discover available tools using semantic search. Supports natural language queries (e.g., 'capture webpage screenshot', 'navigate browser', 'fetch data'). Returns up to 5 tools per query ranked by relevance. Use 'summary' or 'detailed' level to see descriptions and schemas.",
-	}, s.handleToolSearch)
+	s.registerToolSearch(server)
+
+	// Register search_feedback
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "search_feedback",
+		Description: "Report which {{tool_search}} result was actually used for a query, or omit tool_name when none matched. Reported tools rank higher in later searches for the same query.",
+	}, s.handleSearchFeedback)
 
 	// Register tool_execute
-	mcp.AddTool(server, &mcp.Tool{
+	addMetaTool(s, server, &mcp.Tool{
 		Name:        "tool_execute",
-		Description: "Execute a single tool by name with parameters. Use tool_search first to discover available tools.",
+		Description: "Execute a single tool by name with parameters. Use {{tool_search}} first to discover available tools.",
 	}, s.handleToolExecute)
 
+	// Register macro_record_start
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "macro_record_start",
+		Description: "Start recording a macro: every successful {{tool_execute}} call of this session is recorded until {{macro_record_stop}}, which saves the calls as one tool named macro_<name>.",
+	}, s.handleMacroRecordStart)
+
+	// Register macro_record_stop
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "macro_record_stop",
+		Description: "Stop recording and save the recorded {{tool_execute}} calls as the tool macro_<name>, replayed in order by one {{tool_execute}} call. Values listed in slots become the macro's parameters. Saved macros persist across restarts.",
+	}, s.handleMacroRecordStop)
+
+	// Register tool_schema
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_schema",
+		Description: "Return the complete input schema of one tool by name, plus its output schema, annotations and examples when available. Use it to get the exact parameters before calling {{tool_execute}}.",
+	}, s.handleToolSchema)
+
+	// Register tool_validate
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_validate",
+		Description: "Check arguments against a tool's input schema without executing it. Reports missing required properties, undeclared properties, wrong types and values outside an enum, so you can fix them before an expensive or destructive {{tool_execute}} call.",
+	}, s.handleToolValidate)
+
+	// Register prompt_search
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "prompt_search",
+		Description: "Search the prompts offered by the connected servers using semantic search, like {{tool_search}} restricted to prompts. Use {{prompt_get}} to fetch a prompt's messages.",
+	}, s.handlePromptSearch)
+
+	// Register prompt_get
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "prompt_get",
+		Description: "Get a prompt by name with its arguments and return its messages. Use {{prompt_search}} first to discover available prompts and their arguments.",
+	}, s.handlePromptGet)
+
+	// Register resource_read
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "resource_read",
+		Description: "Read a resource by its namespaced URI (onemcp://<server>/<uri>) and return its contents. Use {{tool_search}} with type 'resource' to discover resources.",
+	}, s.handleResourceRead)
+
+	// Register usage_examples
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "usage_examples",
+		Description: "Return example invocations of a tool: examples from the config, arguments of recent successful calls and, when none exist or generate is set, arguments generated by the search LLM. Use it to build valid arguments for {{tool_execute}} on the first try.",
+	}, s.handleUsageExamples)
+
+	// Register tool_execute_parallel
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_execute_parallel",
+		Description: "Execute several independent tools concurrently and return every result, in request order, with its own timing. Use it instead of repeated {{tool_execute}} calls when no call depends on another's output.",
+	}, s.handleToolExecuteParallel)
+
+	// Register tool_history
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_history",
+		Description: "List recent tool executions, newest first, with an arguments digest, success, error type, duration and timestamp. Use it in long sessions to recall which tools were already run instead of repeating calls.",
+	}, s.handleToolHistory)
+
+	// Register search_provider_set, listing every provider it accepts
+	providers := append(llmsearch.ProviderNames(), tfidfProvider)
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "search_provider_set",
+		Description: fmt.Sprintf("Admin tool: switch the semantic search provider (%s) and optionally its model at runtime, then rebuild the search index. %s is the local index, which needs no LLM.", strings.Join(providers, ", "), tfidfProvider),
+		InputSchema: searchProviderSetSchema(providers),
+	}, s.handleSearchProviderSet)
+
+	// Register vector_reindex
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "vector_reindex",
+		Description: "Admin tool: rebuild the search index from scratch, optionally with another search provider and model, and report indexing stats. Use it after bulk server changes or when search results look stale.",
+	}, s.handleVectorReindex)
+
+	// Register stats
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "stats",
+		Description: "Report aggregator statistics: uptime, tool calls and errors per tool, search index size, {{tool_search}} latency percentiles, search cache hit rate, LLM search calls, latency and token usage per provider, and tool call latency and errors per external MCP server.",
+	}, s.handleStats)
+
+	// Register config_get
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "config_get",
+		Description: "Report the runtime settings {{config_set}} can change (searchResultLimit, defaultDetailLevel, minSearchScore, logLevel) and the config file path.",
+	}, s.handleConfigGet)
+
+	// Register config_set
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "config_set",
+		Description: "Change a runtime setting (searchResultLimit, defaultDetailLevel, minSearchScore or logLevel) of the running aggregator without a restart. Set persist to also write it to the config file.",
+	}, s.handleConfigSet)
+
+	// Register health
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "health",
+		Description: "Run active health checks: ping every connected external server, compare the search index with the catalog, verify the LLM search providers are available and the cache directory is writable with free space. Reports pass, warn or fail per check with a hint on how to fix it.",
+	}, s.handleHealth)
+
+	// Register session_info
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "session_info",
+		Description: "Describe the calling client session: client name and version, negotiated protocol version, client capabilities and roots, the tool exposure mode, rate limit and disabled tools that apply to it, and the effective runtime settings.",
+	}, s.handleSessionInfo)
+
+	// Register server_status
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "server_status",
+		Description: "Report the health of each external MCP server: whether it answers keepalive pings, when it was last pinged, the last error and its tool count.",
+	}, s.handleServerStatus)
+
+	// Register server_capabilities
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "server_capabilities",
+		Description: "Report what each external MCP server declared when connecting: its protocol version, capabilities (tools, prompts, resources, logging) and instructions, plus the declared features the aggregator doesn't proxy.",
+	}, s.handleServerCapabilities)
+
+	// Register server_refresh
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "server_refresh",
+		Description: "Admin tool: re-list the tools of one external MCP server (or all of them), update the registry and search index, and report which tools were added, removed or changed.",
+	}, s.handleServerRefresh)
+
+	// Register server_restart
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "server_restart",
+		Description: "Admin tool: disconnect an external MCP server and connect it again, restarting its process or container, then re-register its tools. Use it when a server is wedged.",
+	}, s.handleServerRestart)
+
+	// Register tool_disable
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_disable",
+		Description: "Admin tool: disable a misbehaving tool. It's removed from search and its calls fail with error_type 'tool_disabled' until {{tool_enable}}, also after a restart.",
+	}, s.handleToolDisable)
+
+	// Register tool_enable
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_enable",
+		Description: "Admin tool: enable a tool disabled with {{tool_disable}}, restoring it to search and execution.",
+	}, s.handleToolEnable)
+
 	return nil
 }
 
+// registerToolSearch (re)registers tool_search, whose description summarizes
+// the current catalog. Re-registering it makes the SDK send tools/list_changed.
+func (s *AggregatorServer) registerToolSearch(server *mcp.Server) {
+	addMetaTool(s, server, &mcp.Tool{
+		Name:        "tool_search",
+		Description: "Search and discover available tools using semantic search. Supports natural language queries (e.g., 'capture webpage screenshot', 'navigate browser', 'fetch data'). Returns up to 5 tools per query ranked by relevance. Use 'summary' or 'detailed' level to see descriptions and schemas." + s.catalogSummary(),
+	}, s.handleToolSearch)
+}
+
+// catalogSummary describes the size of the searchable catalog
+func (s *AggregatorServer) catalogSummary() string {
+	items := s.searchableItems()
+	servers := make(map[string]bool)
+	for _, item := range items {
+		if item.Source == tools.SourceExternal {
+			servers[item.SourceName] = true
+		}
+	}
+	return fmt.Sprintf(" Catalog: %d searchable items from %d servers.", len(items), len(servers))
+}
+
+// notifyCatalogChanged tells connected clients to re-list tools after the
+// aggregator's catalog changed (servers refreshed, resources or prompts updated)
+func (s *AggregatorServer) notifyCatalogChanged() {
+	if s.server == nil {
+		return
+	}
+	s.registerToolSearch(s.server)
+	s.syncDirectTools(true)
+	s.logger.Info("Catalog changed, sent tools/list_changed to clients")
+}
+
 // === META-TOOL HANDLERS ===
 
 // ToolSearchInput defines the input for tool_search
 type ToolSearchInput struct {
-	Query       string `json:"query,omitempty" jsonschema:"Search term to filter tools by name or description. Supports natural language queries (e.g., 'capture screenshot', 'navigate browser', 'read file')."`
-	Category    string `json:"category,omitempty" jsonschema:"Optional category filter"`
-	DetailLevel string `json:"detail_level,omitempty" jsonschema:"Detail level: 'names_only' (just names, for broad exploration), 'summary' (name + description, recommended for targeted search), 'detailed' (includes parameter schema), 'full_schema' (complete schema). Default: 'summary'. Use 'summary' or 'detailed' when searching for specific functionality."`
-	Offset      int    `json:"offset,omitempty" jsonschema:"Number of results to skip for pagination. Default: 0"`
+	Query         string  `json:"query,omitempty" jsonschema:"Search term to filter tools by name or description. Supports natural language queries (e.g., 'capture screenshot', 'navigate browser', 'read file'). Also supports '-term' to exclude matches and 'name:', 'category:' and 'type:' field filters (e.g., 'category:filesystem read -directory')."`
+	Category      string  `json:"category,omitempty" jsonschema:"Optional category filter"`
+	Type          string  `json:"type,omitempty" jsonschema:"Optional capability type filter: 'tool', 'prompt' or 'resource'. Default: all types"`
+	DetailLevel   string  `json:"detail_level,omitempty" jsonschema:"Detail level: 'names_only' (just names, for broad exploration), 'summary' (name + description, recommended for targeted search), 'detailed' (includes parameter schema), 'full_schema' (complete schema). Default: the defaultDetailLevel setting ('summary'). Use 'summary' or 'detailed' when searching for specific functionality."`
+	Offset        int     `json:"offset,omitempty" jsonschema:"Number of results to skip for pagination. Default: 0"`
+	MinScore      float64 `json:"min_score,omitempty" jsonschema:"Optional minimum relevance score (0-1). Results scored lower are dropped. Defaults to the minSearchScore setting."`
+	MaxTokens     int     `json:"max_tokens,omitempty" jsonschema:"Optional token budget for the response. Examples, optional parameters and long descriptions are trimmed (and trailing tools dropped) to fit; a 'truncated' field reports what was removed."`
+	Sort          string  `json:"sort,omitempty" jsonschema:"Result order: 'relevance' (default), 'name', 'category' (then name), 'recently_used' or 'most_used' (executions so far, then name). Applied before pagination, so pages don't overlap."`
+	IncludeServer bool    `json:"include_server,omitempty" jsonschema:"Report the server providing each result and its name there at every detail level. By default the server is included from 'summary' and the name from 'detailed' up."`
+	MatchMode     string  `json:"match_mode,omitempty" jsonschema:"How the query is matched: 'semantic' (default) ranks by relevance; 'exact', 'prefix' and 'regex' match tool names directly, case-insensitively for exact and prefix, which is faster when you know part of the name. The query syntax only applies to semantic search."`
 }
 
 func (s *AggregatorServer) handleToolSearch(ctx context.Context, req *mcp.CallToolRequest, input ToolSearchInput) (*mcp.CallToolResult, any, error) {
+	defer func(start time.Time) {
+		s.searchLatency.record(time.Since(start))
+	}(time.Now())
+	ctx, span := startMetaToolSpan(ctx, req, "tool_search", attribute.String("onemcp.search.query", input.Query))
+	defer span.End()
+
+	s.settingsMu.RLock()
 	detailLevel := input.DetailLevel
 	if detailLevel == "" {
-		detailLevel = "summary"
+		detailLevel = s.detailLevel
 	}
 
 	// Use configured limit
 	limit := s.searchResultLimit
+	minScore := s.minSearchScore
+	s.settingsMu.RUnlock()
 
 	offset := input.Offset
 	if offset < 0 {
 		offset = 0
 	}
 
-	var foundTools []*tools.Tool
+	matchMode := input.MatchMode
+	if matchMode == "" {
+		matchMode = matchSemantic
+	}
+	var matchName func(name string) bool
+	if matchMode != matchSemantic {
+		var err error
+		if matchName, err = nameMatcher(matchMode, input.Query); err != nil {
+			return metaToolError(err, "invalid_input"), nil, nil
+		}
+	}
 
-	s.logger.Info("Tool search request", "query", input.Query, "category", input.Category, "detail_level", input.DetailLevel, "offset", offset, "limit", limit)
+	if input.Sort != "" && !slices.Contains(sortOrders, input.Sort) {
+		return metaToolError(fmt.Errorf("unknown sort %q: use one of %s", input.Sort, strings.Join(sortOrders, ", ")), "invalid_input"), nil, nil
+	}
 
-	// Use LLM-powered semantic search
-	if s.searchStore != nil {
-		var err error
-		foundTools, err = s.searchStore.Search(input.Query, limit*3) // Get more results for filtering
+	// Agents repeat discovery queries; usage-based orders change with every call, so aren't cached
+	cacheKey := s.searchResponses.key(input, detailLevel, limit, minScore)
+	cacheable := input.Sort != sortRecentlyUsed && input.Sort != sortMostUsed
+	if cacheable {
+		if result, ok := s.searchResponses.get(cacheKey); ok {
+			s.logger.InfoContext(ctx, "Tool search answered from the response cache", "query", input.Query)
+			return s.searchResult(result), nil, nil
+		}
+	}
+
+	var foundTools []*tools.Tool
+	var timedOut []string // LLM providers that missed their deadline for this query
+	scores := make(map[string]float64)
+
+	s.logger.InfoContext(ctx, "Tool search request", "query", input.Query, "match_mode", matchMode, "sort", input.Sort, "category", input.Category, "type", input.Type, "detail_level", input.DetailLevel, "offset", offset, "limit", limit)
+
+	// Search the current store without holding the lock: re-indexing swaps in
+	// a new store rather than changing this one, so a slow LLM call never
+	// holds up a re-index (or the searches queued behind it)
+	s.searchMu.RLock()
+	store := s.searchStore
+	s.searchMu.RUnlock()
+
+	// Parse exclusions and field filters out of the query before searching
+	query := parseSearchQuery(input.Query)
+
+	if matchName != nil {
+		// Name matches skip the search store, so they're found even when it misses them
+		foundTools = matchNames(s.searchableItems(), matchName)
+		for _, tool := range foundTools {
+			scores[tool.Name] = 1
+		}
+		s.logger.InfoContext(ctx, "Matched tool names", "query", input.Query, "match_mode", matchMode, "results_found", len(foundTools))
+	} else if store != nil {
+		// Use LLM-powered semantic search
+		searchCtx, report := llmsearch.WithSearchReport(ctx)
+		results, err := store.Search(searchCtx, query.text, limit*3) // Get more results for filtering
+		if len(report.TimedOut) > 0 {
+			// Answered further down the chain; tell the client, and don't keep
+			// the weaker answer around once the provider recovers
+			s.logger.WarnContext(ctx, "Semantic search timed out", "query", query.text, "timeout", s.searchTimeout, "providers", report.TimedOut)
+			timedOut = report.TimedOut
+			cacheable = false
+		}
 		if err != nil {
-			s.logger.Error("Semantic search failed", "error", err)
+			s.logger.ErrorContext(ctx, "Semantic search failed", "error", err)
 			foundTools = []*tools.Tool{} // Return empty results on error
+			cacheable = false
 		} else {
-			s.logger.Info("Semantic search completed", "query", input.Query, "results_found", len(foundTools))
+			s.logger.InfoContext(ctx, "Semantic search completed", "query", query.text, "results_found", len(results))
+		}
+
+		// Drop low-relevance results; an empty query only lists tools, so it has no scores to threshold
+		if input.MinScore > 0 {
+			minScore = input.MinScore
+		}
+		// Tools agents reported using for this query rank higher
+		var boosts map[string]float64
+		if query.text != "" {
+			boosts = s.searchFeedbackStore().boosts(input.Query)
+		}
+		for _, result := range results {
+			score := result.Score
+			if boost := boosts[result.Name]; boost > 0 {
+				score = min(score+boost, 1)
+			}
+			if query.text != "" && score < minScore {
+				continue
+			}
+			foundTools = append(foundTools, result.Tool)
+			scores[result.Name] = score
+		}
+		if len(foundTools) != len(results) {
+			s.logger.InfoContext(ctx, "Applied score threshold", "min_score", minScore, "before", len(results), "after", len(foundTools))
+		}
+		if len(boosts) > 0 {
+			slices.SortStableFunc(foundTools, func(a, b *tools.Tool) int {
+				return cmp.Compare(scores[b.Name], scores[a.Name])
+			})
+			s.logger.InfoContext(ctx, "Applied search feedback boosts", "query", input.Query, "boosted_tools", len(boosts))
 		}
 
-		// Apply category filter if specified
-		if input.Category != "" {
+		// Apply query syntax filters
+		if query.hasFilters() {
 			filtered := make([]*tools.Tool, 0, len(foundTools))
 			for _, tool := range foundTools {
-				if tool.Category == input.Category {
+				if query.matches(tool) {
 					filtered = append(filtered, tool)
 				}
 			}
-			s.logger.Info("Applied category filter", "category", input.Category, "before", len(foundTools), "after", len(filtered))
+			s.logger.InfoContext(ctx, "Applied query filters", "query", input.Query, "before", len(foundTools), "after", len(filtered))
 			foundTools = filtered
 		}
 	} else {
 		// No search store available
-		s.logger.Warn("Search store not initialized")
+		s.logger.WarnContext(ctx, "Search store not initialized")
 		foundTools = []*tools.Tool{}
 	}
 
-	totalCount := len(foundTools)
+	// Apply category filter if specified
+	if input.Category != "" {
+		filtered := make([]*tools.Tool, 0, len(foundTools))
+		for _, tool := range foundTools {
+			if tool.Category == input.Category {
+				filtered = append(filtered, tool)
+			}
+		}
+		s.logger.InfoContext(ctx, "Applied category filter", "category", input.Category, "before", len(foundTools), "after", len(filtered))
+		foundTools = filtered
+	}
+
+	// Apply type filter if specified
+	if input.Type != "" {
+		filtered := make([]*tools.Tool, 0, len(foundTools))
+		for _, tool := range foundTools {
+			if string(tool.Type) == input.Type {
+				filtered = append(filtered, tool)
+			}
+		}
+		s.logger.InfoContext(ctx, "Applied type filter", "type", input.Type, "before", len(foundTools), "after", len(filtered))
+		foundTools = filtered
+	}
+
+	// Fold near-duplicates from different servers so they don't crowd out other results
+	collapsedTools := tools.CollapseNearDuplicates(foundTools, s.duplicateCollapse.Threshold, s.duplicateCollapse.enabledFor)
+	if len(collapsedTools) != len(foundTools) {
+		s.logger.InfoContext(ctx, "Collapsed near-duplicate tools", "before", len(foundTools), "after", len(collapsedTools))
+	}
+
+	if input.Sort != "" && input.Sort != sortRelevance {
+		sortResults(collapsedTools, input.Sort, s.toolUsage())
+	}
+
+	totalCount := len(collapsedTools)
 
 	// Apply pagination
 	start := offset
@@ -368,34 +1393,55 @@ func (s *AggregatorServer) handleToolSearch(ctx context.Context, req *mcp.CallTo
 	if end > totalCount {
 		end = totalCount
 	}
-	paginatedTools := foundTools[start:end]
+	paginatedTools := collapsedTools[start:end]
 
-	s.logger.Info("Tool search response", "total_found", totalCount, "returned", len(paginatedTools), "offset", offset, "limit", limit)
+	s.logger.InfoContext(ctx, "Tool search response", "total_found", totalCount, "returned", len(paginatedTools), "offset", offset, "limit", limit)
 
 	toolMetadata := make([]tools.ToolMetadata, len(paginatedTools))
 	for i, tool := range paginatedTools {
 		metadata := tools.ToolMetadata{
-			Name:     tool.Name,
-			Category: tool.Category,
+			Name:         tool.Name,
+			Type:         tool.Type,
+			Category:     tool.Category,
+			Score:        scores[tool.Name],
+			Alternatives: tool.Alternatives,
 		}
 
 		// Include fields based on detail level
 		if detailLevel != "names_only" {
 			metadata.Description = tool.Description
+			metadata.Annotations = tool.Annotations
+		}
+		if input.IncludeServer || detailLevel != "names_only" {
+			metadata.Server = tool.SourceName
+		}
+		if input.IncludeServer || detailLevel == "detailed" || detailLevel == "full_schema" {
+			metadata.Alias = tool.Alias
 		}
 
-		// Include schema based on detail level
+		// Include schema and examples based on detail level
 		if detailLevel == "detailed" || detailLevel == "full_schema" {
+			metadata.Examples = tool.Examples
 			if tool.InputSchema != nil {
 				if schemaMap, ok := tool.InputSchema.(map[string]any); ok {
 					metadata.Parameters = schemaMap
 				}
 			}
 		}
+		if detailLevel == "full_schema" {
+			metadata.OutputSchema, _ = tool.OutputSchema.(map[string]any)
+		}
 
 		toolMetadata[i] = metadata
 	}
 
+	// Trim the response to fit the caller's token budget
+	var truncated truncationReport
+	if input.MaxTokens > 0 {
+		toolMetadata, truncated = fitToTokenBudget(toolMetadata, input.MaxTokens)
+		end = start + len(toolMetadata)
+	}
+
 	result := map[string]any{
 		"total_count":    totalCount,
 		"returned_count": len(toolMetadata),
@@ -404,6 +1450,34 @@ func (s *AggregatorServer) handleToolSearch(ctx context.Context, req *mcp.CallTo
 		"has_more":       end < totalCount,
 		"tools":          toolMetadata,
 	}
+	// The schema file lists every tool with full schemas, for exploring beyond this page
+	if schemaFile := s.schemaFile(); schemaFile != "" {
+		result["schema_file"] = schemaFile
+		if end < totalCount {
+			result["message"] = fmt.Sprintf("Showing %d of %d tools. For complete tool list with full schemas, search with filesystem tools in: %s", len(toolMetadata), totalCount, schemaFile)
+		}
+	}
+	if len(timedOut) > 0 {
+		result["timed_out_providers"] = timedOut
+	}
+	if !truncated.empty() {
+		result["truncated"] = truncated
+		s.logger.InfoContext(ctx, "Trimmed search response to token budget", "max_tokens", input.MaxTokens, "dropped", len(truncated.Dropped))
+	}
+	if cacheable {
+		s.searchResponses.put(cacheKey, result)
+	}
+
+	return s.searchResult(result), nil, nil
+}
+
+// searchResult builds the tool_search result for a response, noting the
+// servers currently degraded, which a cached response can't know about
+func (s *AggregatorServer) searchResult(result map[string]any) *mcp.CallToolResult {
+	// Missing tools may be an outage rather than a capability gap
+	if degraded := s.degradedServers(); len(degraded) > 0 {
+		result["degraded"] = degraded
+	}
 
 	// Convert result to JSON for the text content
 	resultJSON, _ := json.Marshal(result)
@@ -412,7 +1486,21 @@ func (s *AggregatorServer) handleToolSearch(ctx context.Context, req *mcp.CallTo
 		Content: []mcp.Content{
 			&mcp.TextContent{Text: string(resultJSON)},
 		},
-	}, nil, nil
+	}
+}
+
+// toolUsage collects when and how often each tool was executed
+func (s *AggregatorServer) toolUsage() toolUsage {
+	usage := toolUsage{lastUsed: make(map[string]time.Time), calls: make(map[string]int64)}
+	for _, record := range s.registry.History() {
+		if _, seen := usage.lastUsed[record.ToolName]; !seen {
+			usage.lastUsed[record.ToolName] = record.Timestamp // Newest first
+		}
+	}
+	for _, stats := range s.registry.ToolStats() {
+		usage.calls[stats.ToolName] = stats.Calls
+	}
+	return usage
 }
 
 // ToolExecuteInput defines the input for tool_execute
@@ -422,7 +1510,24 @@ type ToolExecuteInput struct {
 }
 
 func (s *AggregatorServer) handleToolExecute(ctx context.Context, req *mcp.CallToolRequest, input ToolExecuteInput) (*mcp.CallToolResult, any, error) {
-	result, err := s.registry.Execute(ctx, input.ToolName, input.Arguments)
+	ctx, span := startMetaToolSpan(ctx, req, "tool_execute", attribute.String("onemcp.tool", input.ToolName))
+	defer span.End()
+
+	// Long calls report progress so clients can tell them from a hang
+	progressCtx, stopProgress := s.startProgress(ctx, req, input.ToolName)
+	result, err := s.registry.Execute(progressCtx, input.ToolName, input.Arguments)
+	stopProgress()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	} else if !result.Success {
+		span.SetStatus(codes.Error, result.Error)
+	}
+	if err == nil && result.Success {
+		s.recordMacroStep(ctx, input.ToolName, input.Arguments)
+	}
+	if s.mode == modeHybrid {
+		s.syncDirectTools(false)
+	}
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -432,18 +1537,134 @@ func (s *AggregatorServer) handleToolExecute(ctx context.Context, req *mcp.CallT
 		}, nil, nil
 	}
 
+	// Images, audio and resources follow the JSON result as their own content
+	// blocks; the JSON only describes them
+	var output any = result.Result
+	var blocks []mcp.Content
+	var structured any
+	if result.Output != nil {
+		output, blocks = externalOutput(result.Output)
+		structured = result.Output.StructuredContent
+	}
+
 	// Convert ExecutionResult to map[string]any
 	resultMap := map[string]any{
 		"success":           result.Success,
 		"tool_name":         result.ToolName,
-		"result":            result.Result,
+		"result":            output,
 		"error":             result.Error,
 		"error_type":        result.ErrorType,
 		"execution_time_ms": result.ExecutionTimeMs,
 	}
+	// The request ID finds the call's lines in the log
+	if id := requestID(ctx); id != "" {
+		resultMap["request_id"] = id
+	}
 
 	resultJSON, _ := json.Marshal(resultMap)
 
+	return &mcp.CallToolResult{
+		Content: append([]mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		}, blocks...),
+		StructuredContent: structured,
+	}, nil, nil
+}
+
+// SearchProviderSetInput defines the input for search_provider_set. Its
+// schema is built by searchProviderSetSchema, from the registered providers.
+type SearchProviderSetInput struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
+// searchProviderSetSchema is the input schema of search_provider_set,
+// accepting the given providers
+func searchProviderSetSchema(providers []string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"provider": map[string]any{
+				"type":        "string",
+				"enum":        providers,
+				"description": "Search provider to switch to: " + strings.Join(providers, ", "),
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Optional model for the provider. Keeps the current model if empty.",
+			},
+		},
+		"required":             []string{"provider"},
+		"additionalProperties": false,
+	}
+}
+
+func (s *AggregatorServer) handleSearchProviderSet(ctx context.Context, req *mcp.CallToolRequest, input SearchProviderSetInput) (*mcp.CallToolResult, any, error) {
+	s.searchMu.RLock()
+	previous := s.searchProvider
+	s.searchMu.RUnlock()
+
+	if err := s.RebuildWithProvider(input.Provider, input.Model); err != nil {
+		s.logger.Error("Failed to switch search provider", "provider", input.Provider, "error", err)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	s.searchMu.RLock()
+	result := map[string]any{
+		"previous_provider": previous,
+		"provider":          s.searchProvider,
+		"model":             s.currentModelLocked(),
+		"indexed_tools":     s.searchStore.GetToolCount(),
+	}
+	s.searchMu.RUnlock()
+
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// StatsInput defines the input for stats
+type StatsInput struct{}
+
+func (s *AggregatorServer) handleStats(ctx context.Context, req *mcp.CallToolRequest, input StatsInput) (*mcp.CallToolResult, any, error) {
+	toolStats := s.registry.ToolStats()
+	var totalCalls, totalErrors int64
+	for _, tool := range toolStats {
+		totalCalls += tool.Calls
+		totalErrors += tool.Errors
+	}
+
+	s.searchMu.RLock()
+	indexed := 0
+	if s.searchStore != nil {
+		indexed = s.searchStore.GetToolCount()
+	}
+	result := map[string]any{
+		"search_provider": s.searchProvider,
+		"indexed_tools":   indexed,
+		"llm_usage":       s.searchUsage.Snapshot(),
+	}
+	s.searchMu.RUnlock()
+	result["uptime_seconds"] = int64(time.Since(s.startedAt).Seconds())
+	result["total_calls"] = totalCalls
+	result["total_errors"] = totalErrors
+	result["tools"] = toolStats
+	result["search_latency"] = s.searchLatency.percentiles()
+	result["search_cache"] = s.searchCache.Snapshot()
+	result["servers"] = s.serverCallMetrics()
+	result["startup"] = s.startup.snapshot()
+
+	resultJSON, _ := json.Marshal(result)
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			&mcp.TextContent{Text: string(resultJSON)},
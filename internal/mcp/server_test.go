@@ -1,19 +1,62 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/radutopala/onemcp/internal/llmsearch"
+	"github.com/radutopala/onemcp/internal/telemetry"
 	"github.com/radutopala/onemcp/internal/tools"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/yaml.v3"
 )
 
+// TestMain keeps the tool snapshots servers save out of the user's cache directory
+func TestMain(m *testing.M) {
+	if os.Getenv("ONEMCP_TEST_STDIO_SERVER") == "1" {
+		os.Exit(m.Run()) // A helper server process saves nothing
+	}
+
+	dir, err := os.MkdirTemp("", "onemcp-cache")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	userCacheDir = func() (string, error) { return dir, nil }
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // AggregatorServerTestSuite is the test suite for AggregatorServer
 type AggregatorServerTestSuite struct {
 	suite.Suite
@@ -231,6 +274,164 @@ func (s *AggregatorServerTestSuite) TestToolSearch_FixedLimit() {
 	require.LessOrEqual(s.T(), int(response["returned_count"].(float64)), 5, "Should return at most 5 tools")
 }
 
+// TestToolSearch_CollapsesNearDuplicates tests that near-duplicate tools from different servers are folded
+func (s *AggregatorServerTestSuite) TestToolSearch_CollapsesNearDuplicates() {
+	s.server.registry.RegisterExternalTool("fs1", "filesystem", "read_file", "Read the contents of a file", map[string]any{"type": "object"})
+	s.server.registry.RegisterExternalTool("fs2", "filesystem", "read_file", "Read the contents of a file", map[string]any{"type": "object"})
+	require.NoError(s.T(), s.server.searchStore.BuildFromTools(s.server.registry.ListAll()))
+
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "read_file", Category: "filesystem"})
+	require.NoError(s.T(), err)
+
+	response := s.parseToolSearchResponse(result)
+	require.Equal(s.T(), float64(1), response["total_count"])
+
+	tool := response["tools"].([]any)[0].(map[string]any)
+	require.Len(s.T(), tool["alternatives"], 1)
+
+	// Disabling collapsing for the category keeps both results
+	s.server.duplicateCollapse.Categories = map[string]bool{"filesystem": false}
+	s.server.searchResponses.invalidate() // Collapsing is only configured at startup otherwise
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "read_file", Category: "filesystem"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), float64(2), s.parseToolSearchResponse(result)["total_count"])
+}
+
+// TestToolSearch_ResponseCache tests that repeated searches are answered from
+// the response cache until it expires or the catalog is re-indexed
+func (s *AggregatorServerTestSuite) TestToolSearch_ResponseCache() {
+	search := func(input ToolSearchInput) float64 {
+		result, _, err := s.server.handleToolSearch(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		return s.parseToolSearchResponse(result)["total_count"].(float64)
+	}
+	input := ToolSearchInput{DetailLevel: "names_only"}
+	before := search(input)
+
+	// Indexing without going through a re-index leaves the cached response
+	s.server.registry.RegisterExternalTool("extra", "test", "extra_tool", "Another test tool", map[string]any{"type": "object"})
+	require.NoError(s.T(), s.server.searchStore.BuildFromTools(s.server.registry.ListAll()))
+	require.Equal(s.T(), before, search(input), "Repeated search should be cached")
+	require.Equal(s.T(), before+1, search(ToolSearchInput{DetailLevel: "names_only", Offset: 1}), "Another offset is another response")
+	require.Equal(s.T(), before+1, search(ToolSearchInput{DetailLevel: "names_only", Sort: sortMostUsed}), "Usage-based orders shouldn't be cached")
+
+	s.server.searchResponses.invalidate()
+	require.Equal(s.T(), before+1, search(input), "Re-indexing should drop cached responses")
+
+	s.server.registry.RegisterExternalTool("extra", "test", "another_tool", "Yet another test tool", map[string]any{"type": "object"})
+	require.NoError(s.T(), s.server.searchStore.BuildFromTools(s.server.registry.ListAll()))
+	s.server.searchResponses.now = func() time.Time { return time.Now().Add(defaultSearchResponseTTL) }
+	require.Equal(s.T(), before+2, search(input), "Expired responses should be recomputed")
+}
+
+// TestToolSearch_TypeFilter tests filtering search results by capability type
+func (s *AggregatorServerTestSuite) TestToolSearch_TypeFilter() {
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Type: "tool"})
+	require.NoError(s.T(), err)
+	response := s.parseToolSearchResponse(result)
+	require.Equal(s.T(), float64(3), response["total_count"])
+	require.Equal(s.T(), "tool", response["tools"].([]any)[0].(map[string]any)["type"])
+
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Type: "prompt"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), float64(0), s.parseToolSearchResponse(result)["total_count"])
+}
+
+// TestToolSearch_QuerySyntax tests negative terms and field filters in the query
+func (s *AggregatorServerTestSuite) TestToolSearch_QuerySyntax() {
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test -second"})
+	require.NoError(s.T(), err)
+
+	response := s.parseToolSearchResponse(result)
+	for _, tool := range response["tools"].([]any) {
+		require.NotEqual(s.T(), "test_tool_2", tool.(map[string]any)["name"])
+	}
+
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "category:other tool"})
+	require.NoError(s.T(), err)
+
+	response = s.parseToolSearchResponse(result)
+	require.Equal(s.T(), float64(1), response["total_count"])
+	require.Equal(s.T(), "another_category_tool", response["tools"].([]any)[0].(map[string]any)["name"])
+}
+
+// TestToolSearch_MinScore tests relevance scores and the min_score threshold
+func (s *AggregatorServerTestSuite) TestToolSearch_MinScore() {
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test first"})
+	require.NoError(s.T(), err)
+	response := s.parseToolSearchResponse(result)
+	toolsList := response["tools"].([]any)
+	require.Len(s.T(), toolsList, 2)
+	require.Greater(s.T(), toolsList[0].(map[string]any)["score"], toolsList[1].(map[string]any)["score"])
+
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test first", MinScore: 0.6})
+	require.NoError(s.T(), err)
+	response = s.parseToolSearchResponse(result)
+	toolsList = response["tools"].([]any)
+	require.Len(s.T(), toolsList, 1)
+	require.Equal(s.T(), "test_tool_1", toolsList[0].(map[string]any)["name"])
+}
+
+// timeoutSearchStore simulates an LLM search that misses its deadline
+type timeoutSearchStore struct {
+	llmsearch.MockSearchStore
+}
+
+func (t *timeoutSearchStore) Search(ctx context.Context, query string, topK int) ([]llmsearch.ScoredTool, error) {
+	return nil, fmt.Errorf("%w: claude CLI did not respond in time", llmsearch.ErrSearchTimeout)
+}
+
+// TestToolSearch_Timeout tests that a query answered after a provider timed
+// out names that provider and isn't cached
+func (s *AggregatorServerTestSuite) TestToolSearch_Timeout() {
+	s.server.searchStore = llmsearch.NewFallbackSearchStore([]llmsearch.NamedSearchStore{
+		{Name: "claude", Store: &timeoutSearchStore{}},
+		{Name: tfidfProvider, Store: s.server.searchStore},
+	}, s.server.logger)
+
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test"})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+
+	response := s.parseToolSearchResponse(result)
+	require.Equal(s.T(), []any{"claude"}, response["timed_out_providers"])
+	require.NotEmpty(s.T(), response["tools"])
+	require.Empty(s.T(), s.server.searchResponses.entries, "Fallback answers shouldn't be cached")
+}
+
+// blockingSearchStore simulates an LLM search that runs until released
+type blockingSearchStore struct {
+	llmsearch.MockSearchStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSearchStore) Search(ctx context.Context, query string, topK int) ([]llmsearch.ScoredTool, error) {
+	close(b.started)
+	<-b.release
+	return nil, nil
+}
+
+// TestToolSearch_DoesNotBlockReindex tests that re-indexing finishes while a slow search is running
+func (s *AggregatorServerTestSuite) TestToolSearch_DoesNotBlockReindex() {
+	store := &blockingSearchStore{started: make(chan struct{}), release: make(chan struct{})}
+	s.server.searchStore = store
+	s.server.searchProvider = tfidfProvider
+
+	searched := make(chan struct{})
+	go func() {
+		defer close(searched)
+		s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test"})
+	}()
+	<-store.started
+
+	require.NoError(s.T(), s.server.rebuildSearchStore())
+	require.NotSame(s.T(), store, s.server.searchStore)
+
+	close(store.release)
+	<-searched
+}
+
 // TestSearchStoreInitialization tests that search store is initialized with tools
 func (s *AggregatorServerTestSuite) TestSearchStoreInitialization() {
 	// Verify search store is initialized
@@ -257,6 +458,60 @@ func (s *AggregatorServerTestSuite) TestToolSearch_ReturnsResults() {
 	require.Contains(s.T(), response, "returned_count", "Response should contain returned_count")
 }
 
+// TestToolSearch_MatchMode tests matching tool names directly instead of searching
+func (s *AggregatorServerTestSuite) TestToolSearch_MatchMode() {
+	names := func(input ToolSearchInput) []string {
+		result, _, err := s.server.handleToolSearch(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		require.False(s.T(), result.IsError)
+		var names []string
+		for _, tool := range s.parseToolSearchResponse(result)["tools"].([]any) {
+			names = append(names, tool.(map[string]any)["name"].(string))
+		}
+		return names
+	}
+
+	require.Equal(s.T(), []string{"test_tool_2"}, names(ToolSearchInput{Query: "TEST_TOOL_2", MatchMode: "exact"}))
+	require.Equal(s.T(), []string{"test_tool_1", "test_tool_2"}, names(ToolSearchInput{Query: "test_", MatchMode: "prefix"}))
+	require.Equal(s.T(), []string{"another_category_tool", "test_tool_1"}, names(ToolSearchInput{Query: "category|_1$", MatchMode: "regex"}))
+	require.Empty(s.T(), names(ToolSearchInput{Query: "test_tool", MatchMode: "exact"}))
+
+	// The category filter still applies
+	require.Equal(s.T(), []string{"another_category_tool"}, names(ToolSearchInput{Query: "_tool", MatchMode: "regex", Category: "other"}))
+
+	for _, input := range []ToolSearchInput{
+		{Query: "test_(", MatchMode: "regex"},
+		{Query: "", MatchMode: "prefix"},
+		{Query: "test", MatchMode: "fuzzy"},
+	} {
+		result, _, err := s.server.handleToolSearch(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		require.True(s.T(), result.IsError, input.MatchMode)
+		require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"invalid_input"`)
+	}
+}
+
+// TestToolSearch_Sort tests ordering search results by usage and rejecting unknown orders
+func (s *AggregatorServerTestSuite) TestToolSearch_Sort() {
+	for _, name := range []string{"test_tool_2", "test_tool_2", "another_category_tool"} {
+		_, err := s.server.registry.Execute(s.ctx, name, nil)
+		require.NoError(s.T(), err)
+	}
+
+	result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Sort: "most_used", DetailLevel: "names_only"})
+	require.NoError(s.T(), err)
+	var names []string
+	for _, tool := range s.parseToolSearchResponse(result)["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	require.Equal(s.T(), []string{"test_tool_2", "another_category_tool", "test_tool_1"}, names)
+
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Sort: "alphabetical"})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"invalid_input"`)
+}
+
 // TestServerClose tests that server closes cleanly
 func (s *AggregatorServerTestSuite) TestServerClose() {
 	// Close the server
@@ -302,7 +557,3823 @@ func (s *AggregatorServerTestSuite) TestToolExecute_NotFound() {
 	require.Equal(s.T(), "tool_not_found", response["error_type"])
 }
 
+// TestToolSchema tests fetching the complete schema of one tool
+func (s *AggregatorServerTestSuite) TestToolSchema() {
+	readOnly := &tools.ToolAnnotations{ReadOnlyHint: true}
+	require.NoError(s.T(), s.server.registry.SetAnnotations("test_tool_1", readOnly))
+	require.NoError(s.T(), s.server.registry.SetOutputSchema("test_tool_1", map[string]any{"type": "object"}))
+
+	result, _, err := s.server.handleToolSchema(s.ctx, nil, ToolSchemaInput{ToolName: "test_tool_1"})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+
+	var metadata tools.ToolMetadata
+	require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &metadata))
+	require.Equal(s.T(), "test_tool_1", metadata.Name)
+	require.Equal(s.T(), "First test tool", metadata.Description)
+	require.Equal(s.T(), map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"param1": map[string]any{"type": "string"}},
+	}, metadata.Parameters)
+	require.Equal(s.T(), map[string]any{"type": "object"}, metadata.OutputSchema)
+	require.Equal(s.T(), readOnly, metadata.Annotations)
+}
+
+// TestToolSchema_NotFound tests error handling for missing tools
+func (s *AggregatorServerTestSuite) TestToolSchema_NotFound() {
+	result, _, err := s.server.handleToolSchema(s.ctx, nil, ToolSchemaInput{ToolName: "nonexistent_tool"})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"tool_not_found"`)
+}
+
+// TestToolValidate tests that arguments are checked against the schema without executing the tool
+func (s *AggregatorServerTestSuite) TestToolValidate() {
+	validate := func(arguments map[string]any) map[string]any {
+		result, _, err := s.server.handleToolValidate(s.ctx, nil, ToolValidateInput{ToolName: "test_tool_2", Arguments: arguments})
+		require.NoError(s.T(), err)
+		require.False(s.T(), result.IsError)
+		return s.parseToolSearchResponse(result)
+	}
+
+	response := validate(map[string]any{"param2": 4})
+	require.Equal(s.T(), true, response["valid"])
+	require.Empty(s.T(), response["issues"])
+
+	response = validate(map[string]any{"param2": "four"})
+	require.Equal(s.T(), false, response["valid"])
+	issue := response["issues"].([]any)[0].(map[string]any)
+	require.Equal(s.T(), "param2", issue["path"])
+	require.Equal(s.T(), tools.IssueWrongType, issue["kind"])
+
+	// An undeclared property is only a warning
+	response = validate(map[string]any{"parm2": 4})
+	require.Equal(s.T(), true, response["valid"])
+	require.Equal(s.T(), tools.IssueExtra, response["issues"].([]any)[0].(map[string]any)["kind"])
+
+	require.Empty(s.T(), s.server.registry.History(), "Validating must not execute the tool")
+
+	result, _, err := s.server.handleToolValidate(s.ctx, nil, ToolValidateInput{ToolName: "nonexistent_tool"})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"tool_not_found"`)
+}
+
+// TestToolExecuteParallel tests that parallel results come back in request order with per-call timing
+func (s *AggregatorServerTestSuite) TestToolExecuteParallel() {
+	result, _, err := s.server.handleToolExecuteParallel(s.ctx, nil, ToolExecuteParallelInput{
+		Tools: []ToolExecuteInput{
+			{ToolName: "test_tool_1", Arguments: map[string]any{"param1": "value1"}},
+			{ToolName: "nonexistent_tool", Arguments: map[string]any{}},
+			{ToolName: "test_tool_2", Arguments: map[string]any{}},
+		},
+	})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+
+	var response struct {
+		Results []struct {
+			Success         bool   `json:"success"`
+			ToolName        string `json:"tool_name"`
+			ErrorType       string `json:"error_type"`
+			ExecutionTimeMs *int64 `json:"execution_time_ms"`
+		} `json:"results"`
+		SuccessfulCount int `json:"successful_count"`
+		FailedCount     int `json:"failed_count"`
+		MaxParallel     int `json:"max_parallel"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Len(s.T(), response.Results, 3)
+	require.Equal(s.T(), "test_tool_1", response.Results[0].ToolName)
+	require.True(s.T(), response.Results[0].Success)
+	require.NotNil(s.T(), response.Results[0].ExecutionTimeMs)
+	require.Equal(s.T(), "tool_not_found", response.Results[1].ErrorType)
+	require.Equal(s.T(), "test_tool_2", response.Results[2].ToolName)
+	require.Equal(s.T(), 2, response.SuccessfulCount)
+	require.Equal(s.T(), 1, response.FailedCount)
+	require.Equal(s.T(), defaultMaxParallel, response.MaxParallel)
+}
+
+// TestToolHistory tests listing recent executions with filtering and pagination
+func (s *AggregatorServerTestSuite) TestToolHistory() {
+	for _, name := range []string{"test_tool_1", "test_tool_2", "test_tool_1"} {
+		_, _, err := s.server.handleToolExecute(s.ctx, nil, ToolExecuteInput{ToolName: name, Arguments: map[string]any{}})
+		require.NoError(s.T(), err)
+	}
+
+	history := func(input ToolHistoryInput) map[string]any {
+		result, _, err := s.server.handleToolHistory(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		var response map[string]any
+		require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response
+	}
+
+	all := history(ToolHistoryInput{})
+	require.Equal(s.T(), float64(3), all["total_count"])
+	executions := all["executions"].([]any)
+	require.Len(s.T(), executions, 3)
+	require.Equal(s.T(), "test_tool_1", executions[0].(map[string]any)["tool_name"])
+	require.Equal(s.T(), "test_tool_2", executions[1].(map[string]any)["tool_name"])
+	require.Equal(s.T(), true, executions[0].(map[string]any)["success"])
+	require.NotEmpty(s.T(), executions[0].(map[string]any)["arguments_digest"])
+
+	page := history(ToolHistoryInput{Offset: 1, Limit: 1})
+	require.Equal(s.T(), float64(1), page["returned_count"])
+	require.Equal(s.T(), true, page["has_more"])
+	require.Equal(s.T(), "test_tool_2", page["executions"].([]any)[0].(map[string]any)["tool_name"])
+
+	filtered := history(ToolHistoryInput{ToolName: "test_tool_1"})
+	require.Equal(s.T(), float64(2), filtered["total_count"])
+	require.Equal(s.T(), false, filtered["has_more"])
+}
+
+// TestSearchFeedback_DropsCachedResponses tests that recording feedback drops
+// cached tool_search responses, so the next search applies the new boost
+func (s *AggregatorServerTestSuite) TestSearchFeedback_DropsCachedResponses() {
+	s.server.cacheDir = s.T().TempDir()
+
+	first, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test tool"})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), s.server.searchResponses.entries, 1, "The response should be cached")
+
+	_, _, err = s.server.handleSearchFeedback(s.ctx, nil, SearchFeedbackInput{Query: "test tool", ToolName: "test_tool_2"})
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), s.server.searchResponses.entries)
+
+	second, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test tool"})
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), s.parseToolSearchResponse(first)["tools"], s.parseToolSearchResponse(second)["tools"])
+}
+
+// TestSearchFeedback tests that reported tools rank higher for the same query and that reports persist
+func (s *AggregatorServerTestSuite) TestSearchFeedback() {
+	s.server.cacheDir = s.T().TempDir()
+
+	search := func(query string) []tools.ToolMetadata {
+		result, _, err := s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: query})
+		require.NoError(s.T(), err)
+		var response struct {
+			Tools []tools.ToolMetadata `json:"tools"`
+		}
+		require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response.Tools
+	}
+	score := func(results []tools.ToolMetadata, name string) float64 {
+		for _, tool := range results {
+			if tool.Name == name {
+				return tool.Score
+			}
+		}
+		s.T().Fatalf("%s not in results", name)
+		return 0
+	}
+
+	before := search("test tool")
+	require.Equal(s.T(), score(before, "test_tool_1"), score(before, "test_tool_2"))
+
+	result, _, err := s.server.handleSearchFeedback(s.ctx, nil, SearchFeedbackInput{Query: "Test  Tool", ToolName: "test_tool_2"})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"reports":1`)
+
+	after := search("test tool")
+	require.Equal(s.T(), "test_tool_2", after[0].Name)
+	require.Greater(s.T(), score(after, "test_tool_2"), score(before, "test_tool_2"))
+	require.Equal(s.T(), score(before, "test_tool_1"), score(after, "test_tool_1"))
+
+	// None matched is recorded without boosting anything
+	result, _, err = s.server.handleSearchFeedback(s.ctx, nil, SearchFeedbackInput{Query: "send email"})
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"none_matched":true`)
+
+	// Unknown tools are rejected
+	result, _, err = s.server.handleSearchFeedback(s.ctx, nil, SearchFeedbackInput{Query: "test tool", ToolName: "nonexistent_tool"})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"tool_not_found"`)
+
+	// Reports survive a restart and are kept as query/tool pairs
+	reloaded := newFeedbackStore(filepath.Join(s.server.cacheDir, "search-feedback.jsonl"))
+	require.Equal(s.T(), map[string]float64{"test_tool_2": feedbackBoost}, reloaded.boosts("test tool"))
+	data, err := os.ReadFile(filepath.Join(s.server.cacheDir, "search-feedback.jsonl"))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), strings.Split(strings.TrimSpace(string(data)), "\n"), 2)
+}
+
+// TestConfigSettings tests that config_get reports settings and config_set applies and persists them
+func (s *AggregatorServerTestSuite) TestConfigSettings() {
+	s.server.configPath = filepath.Join(s.T().TempDir(), ".onemcp.json")
+	require.NoError(s.T(), os.WriteFile(s.server.configPath, []byte(`{
+  // Servers are kept when a setting is persisted
+  "mcpServers": {"echo": {"command": "echo"}},
+  "settings": {"searchProvider": "tfidf"}
+}`), 0644))
+	level := new(slog.LevelVar)
+	s.server.UseLogLevel(level)
+
+	call := func(input ConfigSetInput) map[string]any {
+		result, _, err := s.server.handleConfigSet(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		var response map[string]any
+		require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response
+	}
+
+	result, _, err := s.server.handleConfigGet(s.ctx, nil, ConfigGetInput{})
+	require.NoError(s.T(), err)
+	var current struct {
+		Settings   map[string]any `json:"settings"`
+		ConfigPath string         `json:"config_path"`
+	}
+	require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &current))
+	require.Equal(s.T(), float64(5), current.Settings["searchResultLimit"])
+	require.Equal(s.T(), "summary", current.Settings["defaultDetailLevel"])
+	require.Equal(s.T(), "info", current.Settings["logLevel"])
+	require.Equal(s.T(), s.server.configPath, current.ConfigPath)
+
+	// Applied to the live server without persisting
+	response := call(ConfigSetInput{Key: "defaultDetailLevel", Value: "names_only"})
+	require.Equal(s.T(), "summary", response["previous"])
+	require.Equal(s.T(), false, response["persisted"])
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test"})
+	require.NoError(s.T(), err)
+	require.NotContains(s.T(), result.Content[0].(*mcp.TextContent).Text, "First test tool")
+
+	response = call(ConfigSetInput{Key: "searchResultLimit", Value: float64(1)})
+	require.Equal(s.T(), float64(1), response["value"])
+	result, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test"})
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"has_more":true`)
+
+	response = call(ConfigSetInput{Key: "logLevel", Value: "debug"})
+	require.Equal(s.T(), "info", response["previous"])
+	require.Equal(s.T(), slog.LevelDebug, level.Level())
+
+	// Persisted into the config file, keeping the rest of it
+	response = call(ConfigSetInput{Key: "minSearchScore", Value: 0.4, Persist: true})
+	require.Equal(s.T(), true, response["persisted"])
+	require.Equal(s.T(), 0.4, s.server.minSearchScore)
+	config, err := s.server.loadConfig(s.server.configPath)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 0.4, config.Settings.MinSearchScore)
+	require.Equal(s.T(), "tfidf", config.Settings.SearchProvider)
+	require.Contains(s.T(), config.ExternalServers, "echo")
+	data, err := os.ReadFile(s.server.configPath)
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), string(data), "// Servers are kept when a setting is persisted")
+
+	// Invalid keys and values leave the settings alone
+	for _, input := range []ConfigSetInput{
+		{Key: "searchProvider", Value: "openai"},
+		{Key: "searchResultLimit", Value: float64(0)},
+		{Key: "searchResultLimit", Value: 2.5},
+		{Key: "minSearchScore", Value: float64(2)},
+		{Key: "defaultDetailLevel", Value: "everything"},
+		{Key: "logLevel", Value: "loud"},
+	} {
+		result, _, err := s.server.handleConfigSet(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		require.True(s.T(), result.IsError, input)
+		require.Contains(s.T(), result.Content[0].(*mcp.TextContent).Text, `"error_type":"invalid_setting"`)
+	}
+	require.Equal(s.T(), 1, s.server.searchResultLimit)
+	require.Equal(s.T(), "names_only", s.server.detailLevel)
+}
+
+// cannedCompleter answers every prompt with the same response
+type cannedCompleter struct {
+	response string
+}
+
+func (c cannedCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	return c.response, nil
+}
+
+// TestUsageExamples tests collecting config, recorded and generated examples
+func (s *AggregatorServerTestSuite) TestUsageExamples() {
+	usageExamples := func(input UsageExamplesInput) map[string]any {
+		result, _, err := s.server.handleUsageExamples(s.ctx, nil, input)
+		require.NoError(s.T(), err)
+		require.False(s.T(), result.IsError)
+		var response map[string]any
+		require.NoError(s.T(), json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response
+	}
+
+	// Nothing to show and no LLM to ask
+	s.server.completer = nil
+	response := usageExamples(UsageExamplesInput{ToolName: "test_tool_1"})
+	require.Empty(s.T(), response["examples"])
+	require.NotEmpty(s.T(), response["generation_error"])
+
+	// Generated when there is nothing else
+	s.server.completer = cannedCompleter{response: `[{"param1": "generated"}]`}
+	response = usageExamples(UsageExamplesInput{ToolName: "test_tool_1"})
+	require.Equal(s.T(), []any{
+		map[string]any{"source": "generated", "arguments": map[string]any{"param1": "generated"}},
+	}, response["examples"])
+
+	// Config and recorded examples make generation opt-in
+	require.NoError(s.T(), s.server.registry.SetExamples("test_tool_1", []string{"fetch the first record"}))
+	_, _, err := s.server.handleToolExecute(s.ctx, nil, ToolExecuteInput{ToolName: "test_tool_1", Arguments: map[string]any{"param1": "used"}})
+	require.NoError(s.T(), err)
+	response = usageExamples(UsageExamplesInput{ToolName: "test_tool_1"})
+	require.Equal(s.T(), []any{
+		map[string]any{"source": "config", "example": "fetch the first record"},
+		map[string]any{"source": "recorded", "arguments": map[string]any{"param1": "used"}},
+	}, response["examples"])
+
+	response = usageExamples(UsageExamplesInput{ToolName: "test_tool_1", Generate: true})
+	require.Len(s.T(), response["examples"], 3)
+
+	result, _, err := s.server.handleUsageExamples(s.ctx, nil, UsageExamplesInput{ToolName: "nonexistent_tool"})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+}
+
+// TestSearchProviderSet tests switching the search provider at runtime
+func (s *AggregatorServerTestSuite) TestSearchProviderSet() {
+	// Use mock CLIs so the provider can be created without real LLMs
+	mockBinariesDir, err := filepath.Abs(filepath.Join("..", "..", "test", "mock-binaries"))
+	require.NoError(s.T(), err)
+	s.T().Setenv("PATH", mockBinariesDir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	previousStore := s.server.searchStore
+
+	result, _, err := s.server.handleSearchProviderSet(s.ctx, nil, SearchProviderSetInput{
+		Provider: "codex",
+		Model:    "gpt-5-codex",
+	})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+
+	response := s.parseToolSearchResponse(result)
+	require.Equal(s.T(), "codex", response["provider"])
+	require.Equal(s.T(), "gpt-5-codex", response["model"])
+	require.Equal(s.T(), float64(3), response["indexed_tools"])
+	require.Equal(s.T(), "codex", s.server.searchProvider)
+	require.NotSame(s.T(), previousStore, s.server.searchStore)
+}
+
+// TestSearchProviderSet_Unknown tests that an unknown provider keeps the current store
+func (s *AggregatorServerTestSuite) TestSearchProviderSet_Unknown() {
+	previousStore := s.server.searchStore
+	previousProvider := s.server.searchProvider
+
+	result, _, err := s.server.handleSearchProviderSet(s.ctx, nil, SearchProviderSetInput{
+		Provider: "unknown",
+	})
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.IsError)
+	require.Same(s.T(), previousStore, s.server.searchStore)
+	require.Equal(s.T(), previousProvider, s.server.searchProvider)
+}
+
+// TestSearchProviderSet_ListsEveryProvider tests that search_provider_set
+// describes and accepts every registered provider, the local index included
+func (s *AggregatorServerTestSuite) TestSearchProviderSet_ListsEveryProvider() {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := s.server.server.Connect(s.ctx, serverTransport, nil)
+	require.NoError(s.T(), err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(s.ctx, clientTransport, nil)
+	require.NoError(s.T(), err)
+	defer session.Close()
+
+	listed, err := session.ListTools(s.ctx, nil)
+	require.NoError(s.T(), err)
+	index := slices.IndexFunc(listed.Tools, func(tool *mcp.Tool) bool { return tool.Name == "search_provider_set" })
+	require.GreaterOrEqual(s.T(), index, 0)
+	tool := listed.Tools[index]
+
+	schema, err := json.Marshal(tool.InputSchema)
+	require.NoError(s.T(), err)
+	var parsed struct {
+		Properties struct {
+			Provider struct {
+				Enum []string `json:"enum"`
+			} `json:"provider"`
+		} `json:"properties"`
+	}
+	require.NoError(s.T(), json.Unmarshal(schema, &parsed))
+	want := append(llmsearch.ProviderNames(), tfidfProvider)
+	require.Equal(s.T(), want, parsed.Properties.Provider.Enum)
+	for _, provider := range want {
+		require.Contains(s.T(), tool.Description, provider)
+	}
+
+	result, err := session.CallTool(s.ctx, &mcp.CallToolParams{Name: "search_provider_set", Arguments: map[string]any{"provider": tfidfProvider}})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+	require.Equal(s.T(), tfidfProvider, s.server.searchProvider)
+}
+
+// TestSearchProviderChain tests that settings.searchProviders builds a fallback chain
+func (s *AggregatorServerTestSuite) TestSearchProviderChain() {
+	mockBinariesDir, err := filepath.Abs(filepath.Join("..", "..", "test", "mock-binaries"))
+	require.NoError(s.T(), err)
+	s.T().Setenv("PATH", mockBinariesDir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+
+	s.server.searchMu.Lock()
+	defer s.server.searchMu.Unlock()
+	s.server.searchProvider = "codex"
+	s.server.fallbackProviders = []string{"codex", "tfidf", "copilot"}
+
+	require.Equal(s.T(), []string{"codex", "tfidf", "copilot"}, s.server.providerChainLocked())
+	require.NoError(s.T(), s.server.buildSearchStoreLocked(s.server.searchableItems(), false))
+	require.Equal(s.T(), 3, s.server.searchStore.GetToolCount())
+	// Queries reach the LLMs untranslated; only the local index translates them
+	require.IsType(s.T(), &llmsearch.FallbackSearchStore{}, s.server.searchStore)
+
+	// A chain of only the local index skips the LLMs entirely
+	s.server.searchProvider = "tfidf"
+	s.server.fallbackProviders = nil
+	require.NoError(s.T(), s.server.buildSearchStoreLocked(s.server.searchableItems(), false))
+	require.IsType(s.T(), &llmsearch.TFIDFSearchStore{}, s.server.searchStore)
+}
+
+// TestStats tests that stats reports the search index and LLM usage
+func (s *AggregatorServerTestSuite) TestStats() {
+	result, _, err := s.server.handleStats(s.ctx, nil, StatsInput{})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.IsError)
+
+	response := s.parseToolSearchResponse(result)
+	require.Equal(s.T(), float64(3), response["indexed_tools"])
+	require.Contains(s.T(), response, "llm_usage")
+	require.Contains(s.T(), response, "uptime_seconds")
+	require.Contains(s.T(), response, "search_cache")
+
+	_, _, err = s.server.handleToolSearch(s.ctx, nil, ToolSearchInput{Query: "test"})
+	require.NoError(s.T(), err)
+	_, err = s.server.registry.Execute(s.ctx, "test_tool_1", map[string]any{"input": "x"})
+	require.NoError(s.T(), err)
+
+	result, _, err = s.server.handleStats(s.ctx, nil, StatsInput{})
+	require.NoError(s.T(), err)
+	response = s.parseToolSearchResponse(result)
+	require.Equal(s.T(), float64(1), response["total_calls"])
+	require.Equal(s.T(), float64(1), response["search_latency"].(map[string]any)["count"])
+	require.Equal(s.T(), "test_tool_1", response["tools"].([]any)[0].(map[string]any)["tool_name"])
+}
+
 // TestAggregatorServerTestSuite runs the test suite
 func TestAggregatorServerTestSuite(t *testing.T) {
 	suite.Run(t, new(AggregatorServerTestSuite))
 }
+
+// TestRefreshOnToolListChanged tests that tools added by a downstream server after startup get registered
+func TestRefreshOnToolListChanged(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "first", Description: "First tool"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	defer httpServer.Close()
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + httpServer.URL + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	_, err = server.registry.Get("down_first")
+	require.NoError(t, err)
+
+	// Adding a tool makes the downstream server emit tools/list_changed
+	mcp.AddTool(downstream, &mcp.Tool{Name: "second", Description: "Second tool"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	require.Eventually(t, func() bool {
+		_, err := server.registry.Get("down_second")
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond, "Tool added after startup should be registered")
+}
+
+// TestExternalResources tests that downstream resources are listed, searchable and readable under namespaced URIs
+func TestExternalResources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	downstream.AddResource(&mcp.Resource{URI: "file:///notes.txt", Name: "notes", Description: "Meeting notes", MIMEType: "text/plain"},
+		func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+			return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+				{URI: req.Params.URI, MIMEType: "text/plain", Text: "hello"},
+			}}, nil
+		})
+
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	defer httpServer.Close()
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"docs": {"url": "` + httpServer.URL + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	const uri = "onemcp://docs/file:///notes.txt"
+	items := server.searchableItems()
+	require.True(t, slices.ContainsFunc(items, func(item *tools.Tool) bool {
+		return item.Name == uri && item.Type == tools.TypeResource
+	}), "Resources should be indexed for search")
+
+	// Connect an upstream client to the aggregator
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	require.NotNil(t, session.InitializeResult().Capabilities.Resources)
+
+	listed, err := session.ListResources(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, listed.Resources, 1)
+	require.Equal(t, uri, listed.Resources[0].URI)
+	require.Equal(t, "docs_notes", listed.Resources[0].Name)
+
+	read, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+	require.NoError(t, err)
+	require.Len(t, read.Contents, 1)
+	require.Equal(t, "hello", read.Contents[0].Text)
+	require.Equal(t, uri, read.Contents[0].URI)
+
+	_, err = session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "onemcp://missing/file:///x"})
+	require.Error(t, err)
+
+	// The same read through the resource_read meta-tool
+	result, _, err := server.handleResourceRead(context.Background(), nil, ResourceReadInput{URI: uri})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	var contents mcp.ReadResourceResult
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &contents))
+	require.Len(t, contents.Contents, 1)
+	require.Equal(t, "hello", contents.Contents[0].Text)
+	require.Equal(t, uri, contents.Contents[0].URI)
+
+	result, _, err = server.handleResourceRead(context.Background(), nil, ResourceReadInput{URI: "onemcp://missing/file:///x"})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"error_type":"resource_not_found"`)
+}
+
+// TestExternalResourceTemplates tests that downstream resource templates are
+// listed under namespaced URI templates and that matching URIs are readable
+func TestExternalResourceTemplates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	downstream.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: "file:///{name}", Name: "file", Description: "A file by name", MIMEType: "text/plain"},
+		func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+			return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+				{URI: req.Params.URI, MIMEType: "text/plain", Text: "contents of " + strings.TrimPrefix(req.Params.URI, "file:///")},
+			}}, nil
+		})
+	url := serveDownstream(t, downstream)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"docs": {"url": "` + url + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	listed, err := session.ListResourceTemplates(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, listed.ResourceTemplates, 1)
+	require.Equal(t, "onemcp://docs/file:///{name}", listed.ResourceTemplates[0].URITemplate)
+	require.Equal(t, "docs_file", listed.ResourceTemplates[0].Name)
+	require.Equal(t, "text/plain", listed.ResourceTemplates[0].MIMEType)
+
+	const uri = "onemcp://docs/file:///readme"
+	read, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+	require.NoError(t, err)
+	require.Len(t, read.Contents, 1)
+	require.Equal(t, "contents of readme", read.Contents[0].Text)
+	require.Equal(t, uri, read.Contents[0].URI)
+
+	// Templates are dropped when the server stops listing them
+	server.setExternalResourceTemplates("docs", nil)
+	listed, err = session.ListResourceTemplates(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, listed.ResourceTemplates)
+}
+
+// TestHealth tests that health reports failing servers, a stale search index and unavailable providers with hints
+func TestHealth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo the input"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "ok"}}}, nil, nil
+		})
+	// The "down" server stops answering once broken is set
+	var broken atomic.Bool
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil)
+	dying := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			http.Error(w, "gone", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(dying.Close)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProviders": ["ollama", "tfidf"], "ollamaURL": "http://127.0.0.1:1", "cacheDir": "cache"},
+		"mcpServers": {
+			"up": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1},
+			"down": {"url": "` + dying.URL + `", "enabled": true, "pingInterval": -1, "reconnect": false}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	broken.Store(true)
+
+	health := func() (string, map[string]healthCheck) {
+		result, _, err := server.handleHealth(context.Background(), nil, HealthInput{})
+		require.NoError(t, err)
+		var response struct {
+			Status string        `json:"status"`
+			Checks []healthCheck `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		checks := make(map[string]healthCheck)
+		for _, check := range response.Checks {
+			checks[check.Name] = check
+		}
+		return response.Status, checks
+	}
+
+	status, checks := health()
+	require.Equal(t, checkFail, status)
+	require.Equal(t, checkPass, checks["server:up"].Status)
+	require.Equal(t, checkFail, checks["server:down"].Status)
+	require.Contains(t, checks["server:down"].Hint, "server_restart")
+	require.Equal(t, checkPass, checks["search_index"].Status)
+	require.Equal(t, checkWarn, checks["search_provider:ollama"].Status)
+	require.NotEmpty(t, checks["search_provider:ollama"].Hint)
+	require.Equal(t, checkPass, checks["cache_dir"].Status)
+	require.Contains(t, checks["cache_dir"].Message, filepath.Join(dir, "cache"))
+
+	// A tool registered without re-indexing is missing from the index
+	require.NoError(t, server.registry.RegisterExternalTool("up", "", "unindexed", "Not indexed", map[string]any{"type": "object"}))
+	_, checks = health()
+	require.Equal(t, checkWarn, checks["search_index"].Status)
+	require.Contains(t, checks["search_index"].Hint, "server_refresh")
+}
+
+// TestMacros tests recording tool_execute calls as a macro with slots, replaying it and loading it after a restart
+func TestMacros(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "greet", Description: "Greet someone"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("hello %v", input["name"])}}}, nil, nil
+		})
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf", "cacheDir": "cache"},
+		"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	ctx := context.Background()
+
+	execute := func(server *AggregatorServer, tool string, arguments map[string]any) string {
+		result, _, err := server.handleToolExecute(ctx, nil, ToolExecuteInput{ToolName: tool, Arguments: arguments})
+		require.NoError(t, err)
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+
+	result, _, err := server.handleMacroRecordStop(ctx, nil, MacroRecordStopInput{})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"error_type":"not_recording"`)
+
+	result, _, err = server.handleMacroRecordStart(ctx, nil, MacroRecordStartInput{Name: "greet_twice", Description: "Greet someone twice"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	execute(server, "down_greet", map[string]any{"name": "Ada"})
+	execute(server, "down_missing", nil) // Failed calls aren't recorded
+	execute(server, "down_greet", map[string]any{"name": "Dr. Ada"})
+
+	result, _, err = server.handleMacroRecordStop(ctx, nil, MacroRecordStopInput{Slots: map[string]string{"who": "Ada"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	var saved struct {
+		ToolName  string      `json:"tool_name"`
+		Steps     []MacroStep `json:"steps"`
+		Persisted bool        `json:"persisted"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &saved))
+	require.Equal(t, "macro_greet_twice", saved.ToolName)
+	require.Equal(t, []MacroStep{
+		{ToolName: "down_greet", Arguments: map[string]any{"name": "{{who}}"}},
+		{ToolName: "down_greet", Arguments: map[string]any{"name": "Dr. {{who}}"}},
+	}, saved.Steps)
+	require.True(t, saved.Persisted)
+
+	output := execute(server, "macro_greet_twice", map[string]any{"who": "Bob"})
+	require.Contains(t, output, `"success":true`)
+	require.Contains(t, output, "hello Bob")
+	require.Contains(t, output, "hello Dr. Bob")
+	require.Contains(t, execute(server, "macro_greet_twice", nil), "missing slot: who")
+
+	// Saved macros are searchable
+	result, _, err = server.handleToolSearch(ctx, nil, ToolSearchInput{Query: "greet twice", Category: "macro"})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, "macro_greet_twice")
+
+	// and registered again after a restart
+	restarted, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer restarted.Close()
+	require.Contains(t, execute(restarted, "macro_greet_twice", map[string]any{"who": "Cy"}), "hello Dr. Cy")
+
+	// Re-recording greet_twice through a macro that calls it would make a cycle
+	record := func(name, tool string) *mcp.CallToolResult {
+		_, _, err := restarted.handleMacroRecordStart(ctx, nil, MacroRecordStartInput{Name: name})
+		require.NoError(t, err)
+		execute(restarted, tool, map[string]any{"who": "Di"})
+		result, _, err := restarted.handleMacroRecordStop(ctx, nil, MacroRecordStopInput{})
+		require.NoError(t, err)
+		return result
+	}
+	require.False(t, record("greet_again", "macro_greet_twice").IsError)
+	result = record("greet_twice", "macro_greet_again")
+	require.True(t, result.IsError)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, "would call itself")
+
+	// Invalid and cyclic macros in the macros file are skipped on load
+	cyclic := `[
+		{"name": "loop_a", "steps": [{"tool_name": "macro_loop_b"}]},
+		{"name": "loop_b", "steps": [{"tool_name": "macro_loop_a"}]},
+		{"name": "no_steps"},
+		{"name": "bad name", "steps": [{"tool_name": "down_greet"}]},
+		{"name": "fine", "steps": [{"tool_name": "down_greet"}]}
+	]`
+	require.NoError(t, os.WriteFile(restarted.macrosPath(), []byte(cyclic), 0644))
+	reloaded, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer reloaded.Close()
+	for _, name := range []string{"macro_loop_a", "macro_loop_b", "macro_no_steps", "macro_bad name"} {
+		_, err := reloaded.registry.Get(name)
+		require.Error(t, err, "%s should be skipped", name)
+	}
+	_, err = reloaded.registry.Get("macro_fine")
+	require.NoError(t, err)
+}
+
+// TestConfigMacros tests that macros defined in the config are registered, searchable and can't be recorded over
+func TestConfigMacros(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "greet", Description: "Greet someone"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("hello %v", input["name"])}}}, nil, nil
+		})
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf", "cacheDir": "cache"},
+		"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}},
+		"macros": {
+			"welcome": {
+				"description": "Welcome a guest and their host",
+				"steps": [
+					{"tool_name": "down_greet", "arguments": {"name": "{{guest}}"}},
+					{"tool_name": "down_greet", "arguments": {"name": "{{host}} of {{guest}}"}}
+				]
+			},
+			"empty": {"description": "No steps"},
+			"bad name": {"steps": [{"tool_name": "down_greet"}]},
+			"ping": {"steps": [{"tool_name": "macro_pong"}]},
+			"pong": {"steps": [{"tool_name": "down_greet"}, {"tool_name": "macro_ping"}]}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	ctx := context.Background()
+
+	tool, err := server.registry.Get("macro_welcome")
+	require.NoError(t, err)
+	require.Equal(t, []any{"guest", "host"}, tool.InputSchema.(map[string]any)["required"])
+	_, err = server.registry.Get("macro_empty")
+	require.Error(t, err, "Macros without steps should be skipped")
+	for _, name := range []string{"macro_ping", "macro_pong"} {
+		_, err = server.registry.Get(name)
+		require.Error(t, err, "Macros calling each other should be skipped")
+	}
+
+	result, _, err := server.handleToolExecute(ctx, nil, ToolExecuteInput{ToolName: "macro_welcome", Arguments: map[string]any{"guest": "Ada", "host": "Bob"}})
+	require.NoError(t, err)
+	output := result.Content[0].(*mcp.TextContent).Text
+	require.Contains(t, output, "hello Ada")
+	require.Contains(t, output, "hello Bob of Ada")
+
+	result, _, err = server.handleToolSearch(ctx, nil, ToolSearchInput{Query: "welcome guest", Category: "macro"})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, "macro_welcome")
+
+	result, _, err = server.handleMacroRecordStart(ctx, nil, MacroRecordStartInput{Name: "welcome"})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, "defined in the config")
+
+	// Config macros aren't written to the macros file
+	_, err = os.Stat(server.macrosPath())
+	require.True(t, os.IsNotExist(err))
+
+	// A cycle that slips past loading fails the step instead of recursing forever
+	for _, m := range []*macro{
+		{Name: "tick", Steps: []MacroStep{{ToolName: "macro_tock"}}},
+		{Name: "tock", Steps: []MacroStep{{ToolName: "macro_tick"}}},
+	} {
+		require.NoError(t, server.registry.Register(server.macroTool(m)))
+	}
+	result, _, err = server.handleToolExecute(ctx, nil, ToolExecuteInput{ToolName: "macro_tick"})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, "macro tick calls itself")
+}
+
+// TestToolDisable tests that disabled tools leave search, fail to execute and stay disabled after a restart
+func TestToolDisable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "greet", Description: "Greet someone"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("hello %v", input["name"])}}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf", "cacheDir": "cache"},
+		"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	ctx := context.Background()
+
+	text := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+	search := func(server *AggregatorServer) string {
+		result, _, err := server.handleToolSearch(ctx, nil, ToolSearchInput{Query: "greet someone"})
+		require.NoError(t, err)
+		return text(result)
+	}
+	execute := func(server *AggregatorServer) string {
+		result, _, err := server.handleToolExecute(ctx, nil, ToolExecuteInput{ToolName: "down_greet", Arguments: map[string]any{"name": "Ada"}})
+		require.NoError(t, err)
+		return text(result)
+	}
+	require.Contains(t, search(server), "down_greet")
+
+	result, _, err := server.handleToolDisable(ctx, nil, ToolDisableInput{ToolName: "down_missing"})
+	require.NoError(t, err)
+	require.Contains(t, text(result), `"error_type":"tool_not_found"`)
+	result, _, err = server.handleToolEnable(ctx, nil, ToolEnableInput{ToolName: "down_greet"})
+	require.NoError(t, err)
+	require.Contains(t, text(result), `"error_type":"not_disabled"`)
+
+	result, _, err = server.handleToolDisable(ctx, nil, ToolDisableInput{ToolName: "down_greet"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Contains(t, text(result), `"persisted":true`)
+	require.NotContains(t, search(server), "down_greet")
+	require.Contains(t, execute(server), `"error_type":"tool_disabled"`)
+
+	// Still disabled after a restart
+	restarted, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer restarted.Close()
+	require.Contains(t, execute(restarted), `"error_type":"tool_disabled"`)
+	completions, _ := restarted.metaToolCompletions("tool_enable", "tool_name")
+	require.Equal(t, []string{"down_greet"}, completions)
+
+	result, _, err = restarted.handleToolEnable(ctx, nil, ToolEnableInput{ToolName: "down_greet"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Contains(t, search(restarted), "down_greet")
+	require.Contains(t, execute(restarted), "hello Ada")
+}
+
+// TestVectorReindex tests that vector_reindex picks up tools missing from a stale index and keeps the index on failure
+func TestVectorReindex(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf"},
+		"mcpServers": {"docs": {"url": "` + serveDownstream(t, reviewPrompt("review")) + `", "enabled": true, "pingInterval": -1}}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	ctx := context.Background()
+
+	// A tool registered without re-indexing leaves the index stale
+	require.NoError(t, server.registry.RegisterExternalTool("docs", "", "lint", "Lint a file", map[string]any{"type": "object"}))
+	require.Equal(t, 1, server.searchStore.GetToolCount())
+
+	result, _, err := server.handleVectorReindex(ctx, nil, VectorReindexInput{})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	var stats struct {
+		Provider             string `json:"provider"`
+		IndexedItems         int    `json:"indexed_items"`
+		PreviousIndexedItems int    `json:"previous_indexed_items"`
+		Tools                int    `json:"tools"`
+		Prompts              int    `json:"prompts"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &stats))
+	require.Equal(t, "tfidf", stats.Provider)
+	require.Equal(t, 2, stats.IndexedItems)
+	require.Equal(t, 1, stats.PreviousIndexedItems)
+	require.Equal(t, 1, stats.Tools)
+	require.Equal(t, 1, stats.Prompts)
+
+	result, _, err = server.handleVectorReindex(ctx, nil, VectorReindexInput{Provider: "no-such-provider"})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"error_type":"reindex_failed"`)
+	require.Equal(t, "tfidf", server.searchProvider)
+	require.Equal(t, 2, server.searchStore.GetToolCount())
+}
+
+// TestToolSearch_Server tests that search results tell apart same-named tools of different servers
+func TestToolSearch_Server(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	fileServer := func(root string) *mcp.Server {
+		server := mcp.NewServer(&mcp.Implementation{Name: "files", Version: "1.0.0"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "read_file", Description: "Read a file under " + root},
+			func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+		return server
+	}
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf"},
+		"mcpServers": {
+			"home": {"url": "` + serveDownstream(t, fileServer("/home")) + `", "enabled": true, "pingInterval": -1},
+			"work": {"url": "` + serveDownstream(t, fileServer("/work")) + `", "enabled": true, "pingInterval": -1}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	search := func(input ToolSearchInput) map[string]tools.ToolMetadata {
+		result, _, err := server.handleToolSearch(context.Background(), nil, input)
+		require.NoError(t, err)
+		var response struct {
+			Tools []tools.ToolMetadata `json:"tools"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		byName := make(map[string]tools.ToolMetadata)
+		for _, tool := range response.Tools {
+			byName[tool.Name] = tool
+		}
+		return byName
+	}
+
+	results := search(ToolSearchInput{Query: "read file", DetailLevel: "summary"})
+	require.Equal(t, "home", results["home_read_file"].Server)
+	require.Equal(t, "work", results["work_read_file"].Server)
+	require.Empty(t, results["work_read_file"].Alias, "The alias needs the detailed level")
+
+	results = search(ToolSearchInput{Query: "read file", DetailLevel: "names_only"})
+	require.Empty(t, results["home_read_file"].Server)
+
+	results = search(ToolSearchInput{Query: "read file", DetailLevel: "names_only", IncludeServer: true})
+	require.Equal(t, "home", results["home_read_file"].Server)
+	require.Equal(t, "read_file", results["home_read_file"].Alias)
+}
+
+// TestSchemaFile tests that the catalog is written to the configured schema file in each format
+func TestSchemaFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{
+		"settings": {"searchProvider": "tfidf", "searchResultLimit": 1, "schemaFile": "catalog/tools.yml"},
+		"mcpServers": {"docs": {"url": "` + serveDownstream(t, reviewPrompt("review")) + `", "enabled": true, "pingInterval": -1}}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	schemaPath := filepath.Join(dir, "catalog", "tools.yml")
+	read := func() schemaCatalog {
+		data, err := os.ReadFile(schemaPath)
+		require.NoError(t, err)
+		var generic map[string]any
+		require.NoError(t, yaml.Unmarshal(data, &generic))
+		data, err = json.Marshal(generic)
+		require.NoError(t, err)
+		var catalog schemaCatalog
+		require.NoError(t, json.Unmarshal(data, &catalog))
+		return catalog
+	}
+	catalog := read()
+	require.Empty(t, catalog.Tools, "Prompts are not executable tools")
+
+	require.NoError(t, server.registry.RegisterExternalTool("docs", "docs", "lint", "Lint a file", map[string]any{"type": "object"}))
+	require.NoError(t, server.registry.RegisterExternalTool("docs", "docs", "format", "Format a file", map[string]any{"type": "object"}))
+	require.NoError(t, server.rebuildSearchStore())
+	catalog = read()
+	require.Equal(t, 2, catalog.ToolCount)
+	require.Equal(t, "docs_format", catalog.Tools[0].Name)
+	require.Equal(t, "lint", catalog.Tools[1].Alias)
+	require.Equal(t, map[string]any{"type": "object"}, catalog.Tools[1].Parameters)
+
+	result, _, err := server.handleToolSearch(context.Background(), nil, ToolSearchInput{Query: "file", Type: "tool"})
+	require.NoError(t, err)
+	var response map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Equal(t, schemaPath, response["schema_file"])
+	require.Contains(t, response["message"], "Showing 1 of 2 tools")
+
+	data, err := encodeSchemaCatalog(catalog, schemaFormatMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "## docs_lint\n\nLint a file\n\n- Category: docs\n- Server: docs (as `lint`)\n")
+	require.Contains(t, string(data), "```json\n{\n  \"type\": \"object\"\n}\n```")
+
+	require.Equal(t, schemaFormatMarkdown, schemaFormatFromPath("tools.md"))
+	require.Equal(t, schemaFormatJSON, schemaFormatFromPath(""))
+}
+
+// serveDownstream serves an MCP server over Streamable HTTP for the duration of the test
+func serveDownstream(t *testing.T, server *mcp.Server) string {
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return server
+	}, nil))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+// noopServer creates a downstream server with one tool, noop, that does nothing
+func noopServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	return server
+}
+
+// reviewPrompt creates a downstream server with one prompt that echoes its "file" argument
+func reviewPrompt(name string) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "prompts", Version: "1.0.0"}, nil)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        name,
+		Description: "Review a file",
+		Arguments:   []*mcp.PromptArgument{{Name: "file", Required: true}},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: "Review " + req.Params.Arguments["file"]}},
+		}}, nil
+	})
+	return server
+}
+
+// TestExternalPrompts tests that downstream prompts are prefixed, de-conflicted and proxied
+func TestExternalPrompts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// "a" + "b_review" and "a_b" + "review" both prefix to "a_b_review"
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {
+		"a": {"url": "` + serveDownstream(t, reviewPrompt("b_review")) + `", "enabled": true},
+		"a_b": {"url": "` + serveDownstream(t, reviewPrompt("review")) + `", "enabled": true}
+	}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	require.NotNil(t, session.InitializeResult().Capabilities.Prompts)
+
+	listed, err := session.ListPrompts(context.Background(), nil)
+	require.NoError(t, err)
+	names := make([]string, len(listed.Prompts))
+	for i, prompt := range listed.Prompts {
+		names[i] = prompt.Name
+	}
+	require.ElementsMatch(t, []string{"a_b_review", "a_b_review_2"}, names)
+
+	for _, name := range names {
+		result, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{Name: name, Arguments: map[string]string{"file": "main.go"}})
+		require.NoError(t, err)
+		require.Equal(t, "Review main.go", result.Messages[0].Content.(*mcp.TextContent).Text)
+	}
+
+	items := server.searchableItems()
+	require.True(t, slices.ContainsFunc(items, func(item *tools.Tool) bool {
+		return item.Name == "a_b_review" && item.Type == tools.TypePrompt
+	}), "Prompts should be indexed for search")
+}
+
+// TestPromptMetaTools tests finding prompts with prompt_search and fetching them with prompt_get
+func TestPromptMetaTools(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"docs": {"url": "` + serveDownstream(t, reviewPrompt("review")) + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	mockStore := llmsearch.NewMockSearchStore(logger)
+	require.NoError(t, mockStore.BuildFromTools(server.searchableItems()))
+	server.searchStore = mockStore
+
+	result, _, err := server.handlePromptSearch(context.Background(), nil, PromptSearchInput{Query: "review", DetailLevel: "detailed"})
+	require.NoError(t, err)
+	var response struct {
+		Tools []tools.ToolMetadata `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Len(t, response.Tools, 1)
+	require.Equal(t, "docs_review", response.Tools[0].Name)
+	require.Equal(t, tools.TypePrompt, response.Tools[0].Type)
+	require.Equal(t, []any{"file"}, response.Tools[0].Parameters["required"])
+
+	result, _, err = server.handlePromptGet(context.Background(), nil, PromptGetInput{Name: "docs_review", Arguments: map[string]string{"file": "main.go"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	var prompt mcp.GetPromptResult
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &prompt))
+	require.Equal(t, "Review main.go", prompt.Messages[0].Content.(*mcp.TextContent).Text)
+
+	result, _, err = server.handlePromptGet(context.Background(), nil, PromptGetInput{Name: "docs_missing"})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"error_type":"prompt_not_found"`)
+}
+
+// TestSamplingPassthrough tests that a downstream sampling request reaches the upstream client
+func TestSamplingPassthrough(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "ask", Description: "Ask the client's LLM"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			result, err := req.Session.CreateMessage(ctx, &mcp.CreateMessageParams{
+				Messages:  []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "What is 6*7?"}}},
+				MaxTokens: 10,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{result.Content}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	// Without a sampling-capable client the request is rejected
+	result, err := server.registry.Execute(context.Background(), "down_ask", nil)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, &mcp.ClientOptions{
+		CreateMessageHandler: func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			return &mcp.CreateMessageResult{Role: "assistant", Model: "test", Content: &mcp.TextContent{Text: "42"}}, nil
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	result, err = server.registry.Execute(context.Background(), "down_ask", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, "42", result.Output.Content[0].(*mcp.TextContent).Text)
+}
+
+// TestToolExecuteProgress tests that tool_execute reports elapsed time while
+// a call runs and forwards the downstream server's own progress
+func TestToolExecuteProgress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "quiet", Description: "Slow job without progress"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			time.Sleep(200 * time.Millisecond)
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "chatty", Description: "Slow job with progress"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			token := req.Params.GetProgressToken()
+			if token == nil {
+				return nil, nil, fmt.Errorf("no progress token")
+			}
+			for i := 1; i <= 2; i++ {
+				if err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{ProgressToken: token, Progress: float64(i), Total: 2, Message: fmt.Sprintf("step %d", i)}); err != nil {
+					return nil, nil, err
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	server.progressInterval = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	received := map[any][]*mcp.ProgressNotificationParams{}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[req.Params.ProgressToken] = append(received[req.Params.ProgressToken], req.Params)
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	call := func(tool, token string) {
+		params := &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": token},
+			Name:      "tool_execute",
+			Arguments: map[string]any{"tool_name": tool, "arguments": map[string]any{}},
+		}
+		result, err := session.CallTool(context.Background(), params)
+		require.NoError(t, err)
+		require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"success":true`)
+	}
+
+	// Elapsed time while the downstream server is silent
+	call("down_quiet", "quiet")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["quiet"]) > 0
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	require.Contains(t, received["quiet"][0].Message, "down_quiet running for")
+	mu.Unlock()
+
+	// The downstream server's own progress, under the client's token
+	call("down_chatty", "chatty")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["chatty"]) >= 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, "step 1", received["chatty"][0].Message)
+	require.Equal(t, float64(2), received["chatty"][1].Progress)
+	require.Equal(t, float64(2), received["chatty"][1].Total)
+}
+
+// TestCancellationPropagates tests that cancelling a tool call cancels it on the downstream server
+func TestCancellationPropagates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "crawl", Description: "Long-running job"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+			return nil, nil, ctx.Err()
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	result, _, err := server.handleToolExecute(ctx, nil, ToolExecuteInput{ToolName: "down_crawl"})
+	require.NoError(t, err)
+	require.Contains(t, result.Content[0].(*mcp.TextContent).Text, `"error_type":"cancelled"`)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Downstream tool should be cancelled when the caller gives up")
+	}
+}
+
+// TestCatalogChangeNotifiesClients tests that clients get tools/list_changed when a downstream catalog changes
+func TestCatalogChangeNotifiesClients(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "first", Description: "First tool"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	changed := make(chan struct{}, 10)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, &mcp.ClientOptions{
+		ToolListChangedHandler: func(ctx context.Context, req *mcp.ToolListChangedRequest) {
+			changed <- struct{}{}
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	mcp.AddTool(downstream, &mcp.Tool{Name: "second", Description: "Second tool"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Client should be notified when the catalog changes")
+	}
+
+	listed, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	for _, tool := range listed.Tools {
+		if tool.Name == "tool_search" {
+			require.Contains(t, tool.Description, "Catalog: 2 searchable items from 1 servers.")
+		}
+	}
+}
+
+// TestRootsForwarding tests that client roots are forwarded to downstream servers and kept in sync
+func TestRootsForwarding(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	changed := make(chan struct{}, 10)
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, &mcp.ServerOptions{
+		RootsListChangedHandler: func(ctx context.Context, req *mcp.RootsListChangedRequest) {
+			changed <- struct{}{}
+		},
+	})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "roots", Description: "List the client's roots"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			result, err := req.Session.ListRoots(ctx, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			uris := make([]string, len(result.Roots))
+			for i, root := range result.Roots {
+				uris[i] = root.URI
+			}
+			slices.Sort(uris)
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(uris, ",")}}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	waitForRoots := func() {
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Downstream server should be notified of new roots")
+		}
+	}
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	client.AddRoots(&mcp.Root{URI: "file:///workspace", Name: "workspace"})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	waitForRoots()
+	result, err := server.registry.Execute(context.Background(), "down_roots", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, "file:///workspace", result.Output.Content[0].(*mcp.TextContent).Text)
+
+	// Changes on the client reach the downstream server
+	client.AddRoots(&mcp.Root{URI: "file:///docs", Name: "docs"})
+	waitForRoots()
+	result, err = server.registry.Execute(context.Background(), "down_roots", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, "file:///docs,file:///workspace", result.Output.Content[0].(*mcp.TextContent).Text)
+}
+
+// TestServerLogForwarding tests that external server logs at the configured level reach the client
+func TestServerLogForwarding(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "work", Description: "Log while working"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			_ = req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "info", Logger: "worker", Data: "starting"})
+			_ = req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "error", Logger: "worker", Data: "disk full"})
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "logLevel": "error"}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	messages := make(chan *mcp.LoggingMessageParams, 10)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, &mcp.ClientOptions{
+		LoggingMessageHandler: func(ctx context.Context, req *mcp.LoggingMessageRequest) {
+			messages <- req.Params
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+	require.NoError(t, session.SetLoggingLevel(context.Background(), &mcp.SetLoggingLevelParams{Level: "debug"}))
+
+	result, err := server.registry.Execute(context.Background(), "down_work", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	// The info message is below the server's logLevel and never sent
+	select {
+	case msg := <-messages:
+		require.Equal(t, mcp.LoggingLevel("error"), msg.Level)
+		require.Equal(t, "down/worker", msg.Logger)
+		require.Equal(t, "disk full", msg.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Client should receive the external server's log message")
+	}
+}
+
+// listedToolNames returns the names of the tools an upstream client sees
+func listedToolNames(t *testing.T, session *mcp.ClientSession) []string {
+	t.Helper()
+	listed, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	names := make([]string, len(listed.Tools))
+	for i, tool := range listed.Tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// TestMetaToolNames tests that meta-tools are listed, described and completed under their configured names
+func TestMetaToolNames(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	connect := func(t *testing.T, settings string) map[string]string {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"settings": `+settings+`}`), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		_, err = server.server.Connect(context.Background(), serverTransport, nil)
+		require.NoError(t, err)
+		client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), clientTransport, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { session.Close() })
+
+		listed, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, listed.Tools, len(metaTools), "No meta-tool replaces another")
+		descriptions := make(map[string]string)
+		for _, tool := range listed.Tools {
+			descriptions[tool.Name] = tool.Description
+		}
+
+		completed, err := session.Complete(context.Background(), &mcp.CompleteParams{
+			Ref:      &mcp.CompleteReference{Type: "ref/prompt", Name: server.metaToolName("tool_search")},
+			Argument: mcp.CompleteParamsArgument{Name: "detail_level", Value: "full"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"full_schema"}, completed.Completion.Values)
+		return descriptions
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		descriptions := connect(t, `{"searchProvider": "tfidf", "metaToolPrefix": "onemcp_", "metaToolNames": {"tool_search": "find_tools", "no_such_tool": "x"}}`)
+		require.Contains(t, descriptions, "find_tools")
+		require.Contains(t, descriptions, "onemcp_tool_execute_parallel")
+		require.NotContains(t, descriptions, "tool_search")
+		require.Contains(t, descriptions["onemcp_tool_execute"], "Use find_tools first")
+		require.Contains(t, descriptions["onemcp_tool_execute_parallel"], "instead of repeated onemcp_tool_execute calls")
+		require.Contains(t, descriptions["onemcp_vector_reindex"], "report indexing stats", "Prose is not renamed")
+		require.Contains(t, descriptions["onemcp_health"], "Run active health checks")
+	})
+
+	t.Run("clash with a built-in name", func(t *testing.T) {
+		descriptions := connect(t, `{"searchProvider": "tfidf", "metaToolNames": {"tool_search": "health"}}`)
+		require.Contains(t, descriptions["health"], "Run active health checks")
+		require.Contains(t, descriptions["tool_search"], "Search and discover available tools")
+	})
+
+	t.Run("clash with a prefixed name", func(t *testing.T) {
+		descriptions := connect(t, `{"searchProvider": "tfidf", "metaToolPrefix": "onemcp_", "metaToolNames": {"tool_schema": "onemcp_tool_execute"}}`)
+		require.Contains(t, descriptions["onemcp_tool_execute"], "Execute a single tool")
+		require.Contains(t, descriptions["onemcp_tool_schema"], "Return the complete input schema", "A rejected rename falls back to the prefixed name")
+	})
+}
+
+// TestDirectToolModes tests that passthrough and hybrid modes list downstream tools directly
+func TestDirectToolModes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo the text"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+			Text string `json:"text"`
+		}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: input.Text}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	url := serveDownstream(t, downstream)
+
+	connect := func(t *testing.T, settings string) *mcp.ClientSession {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"settings": ` + settings + `, "mcpServers": {"down": {"url": "` + url + `", "enabled": true}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		_, err = server.server.Connect(context.Background(), serverTransport, nil)
+		require.NoError(t, err)
+		client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), clientTransport, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { session.Close() })
+		return session
+	}
+
+	t.Run("search", func(t *testing.T) {
+		session := connect(t, `{}`)
+		require.NotContains(t, listedToolNames(t, session), "down_echo")
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		session := connect(t, `{"mode": "passthrough"}`)
+		names := listedToolNames(t, session)
+		require.Contains(t, names, "down_echo")
+		require.Contains(t, names, "down_noop")
+		require.Contains(t, names, "tool_search")
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "down_echo",
+			Arguments: map[string]any{"text": "hello"},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Equal(t, "hello", result.Content[0].(*mcp.TextContent).Text)
+	})
+
+	t.Run("hybrid", func(t *testing.T) {
+		session := connect(t, `{"mode": "hybrid", "hybridToolCount": 1}`)
+		require.NotContains(t, listedToolNames(t, session), "down_echo")
+
+		_, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "tool_execute",
+			Arguments: map[string]any{"tool_name": "down_echo", "arguments": map[string]any{"text": "hi"}},
+		})
+		require.NoError(t, err)
+		names := listedToolNames(t, session)
+		require.Contains(t, names, "down_echo")
+		require.NotContains(t, names, "down_noop")
+	})
+}
+
+// TestCompletion tests local meta-tool completions and proxying of prompt completions
+func TestCompletion(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, &mcp.ServerOptions{
+		CompletionHandler: func(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+			if req.Params.Ref.Name != "review" || req.Params.Argument.Name != "file" {
+				return &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}}}, nil
+			}
+			return &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{
+				Values: []string{req.Params.Argument.Value + "/main.go", req.Params.Argument.Value + "/util.go"},
+			}}, nil
+		},
+	})
+	downstream.AddPrompt(&mcp.Prompt{
+		Name:      "review",
+		Arguments: []*mcp.PromptArgument{{Name: "file", Required: true}},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{}, nil
+	})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "category": "dev"}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	complete := func(ref *mcp.CompleteReference, name, value string) []string {
+		result, err := session.Complete(context.Background(), &mcp.CompleteParams{
+			Ref:      ref,
+			Argument: mcp.CompleteParamsArgument{Name: name, Value: value},
+		})
+		require.NoError(t, err)
+		return result.Completion.Values
+	}
+
+	require.Equal(t, []string{"down_echo"}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "tool_execute"}, "tool_name", "DOWN"))
+	require.Equal(t, []string{"dev"}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "tool_search"}, "category", "d"))
+	require.Equal(t, []string{"down_echo"}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "tool_schema"}, "tool_name", "down_"))
+	require.Equal(t, []string{"summary"}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "tool_search"}, "detail_level", "su"))
+	require.Equal(t, []string{"src/main.go", "src/util.go"}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "down_review"}, "file", "src"))
+	require.Empty(t, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "unknown"}, "file", "src"))
+}
+
+// TestListPagination tests that paginated downstream lists are followed and upstream lists are paginated
+func TestListPagination(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, &mcp.ServerOptions{PageSize: 2})
+	for i := range 5 {
+		mcp.AddTool(downstream, &mcp.Tool{Name: fmt.Sprintf("tool%d", i), Description: "Paged tool"},
+			func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+	}
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"mode": "passthrough", "pageSize": 3}, "mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	// Every page of the downstream list was registered
+	require.Len(t, server.registry.ListAll(), 5)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	page, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, page.Tools, 3)
+	require.NotEmpty(t, page.NextCursor)
+
+	var all []string
+	for tool, err := range session.Tools(context.Background(), nil) {
+		require.NoError(t, err)
+		all = append(all, tool.Name)
+	}
+	for i := range 5 {
+		require.Contains(t, all, fmt.Sprintf("down_tool%d", i))
+	}
+	require.Contains(t, all, "tool_search")
+}
+
+// TestStructuredContent tests that downstream output schemas and structured results are preserved
+func TestStructuredContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	type weather struct {
+		City        string  `json:"city"`
+		Temperature float64 `json:"temperature"`
+	}
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "weather", Description: "Current weather"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+			City string `json:"city"`
+		}) (*mcp.CallToolResult, weather, error) {
+			return nil, weather{City: input.City, Temperature: 21.5}, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"mode": "passthrough"}, "mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	tool, err := server.registry.Get("down_weather")
+	require.NoError(t, err)
+	require.NotNil(t, tool.OutputSchema)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	expected := map[string]any{"city": "Paris", "temperature": 21.5}
+
+	// tool_execute carries the downstream structured content
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "tool_execute",
+		Arguments: map[string]any{"tool_name": "down_weather", "arguments": map[string]any{"city": "Paris"}},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Equal(t, expected, result.StructuredContent)
+
+	// Directly listed tools keep their output schema
+	for listed, err := range session.Tools(context.Background(), nil) {
+		require.NoError(t, err)
+		if listed.Name == "down_weather" {
+			require.NotNil(t, listed.OutputSchema)
+		}
+	}
+	result, err = session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "down_weather",
+		Arguments: map[string]any{"city": "Paris"},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Equal(t, expected, result.StructuredContent)
+	require.JSONEq(t, `{"city":"Paris","temperature":21.5}`, result.Content[0].(*mcp.TextContent).Text)
+}
+
+// TestNonTextContent tests that images and other non-text blocks reach the client unchanged
+func TestNonTextContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	png := []byte("\x89PNG fake image")
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "screenshot", Description: "Take a screenshot"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{
+				&mcp.TextContent{Text: "Captured the page"},
+				&mcp.ImageContent{Data: png, MIMEType: "image/png"},
+			}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"mode": "passthrough"}, "mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	// tool_execute returns the JSON result followed by the image
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "tool_execute",
+		Arguments: map[string]any{"tool_name": "down_screenshot", "arguments": map[string]any{}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+
+	var response map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	output := response["result"].(map[string]any)
+	require.Equal(t, []any{
+		map[string]any{"type": "text", "text": "Captured the page"},
+		map[string]any{"type": "image", "mime_type": "image/png"},
+	}, output["content"])
+
+	image, ok := result.Content[1].(*mcp.ImageContent)
+	require.True(t, ok, "second block should be the image")
+	require.Equal(t, png, image.Data)
+	require.Equal(t, "image/png", image.MIMEType)
+
+	// Directly listed tools return the image too
+	result, err = session.CallTool(context.Background(), &mcp.CallToolParams{Name: "down_screenshot"})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	image, ok = result.Content[1].(*mcp.ImageContent)
+	require.True(t, ok, "second block should be the image")
+	require.Equal(t, png, image.Data)
+}
+
+// TestToolErrorContent tests that an error reported by a downstream tool keeps
+// all of its content and its _meta
+func TestToolErrorContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "open", Description: "Open a file"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Meta:    mcp.Meta{"trace": "abc"},
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "permission denied"},
+					&mcp.TextContent{Text: "path: /etc/shadow"},
+				},
+			}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"mode": "passthrough"}, "mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	// tool_execute reports the error with every content block
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "tool_execute",
+		Arguments: map[string]any{"tool_name": "down_open", "arguments": map[string]any{}},
+	})
+	require.NoError(t, err)
+
+	var response map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Equal(t, false, response["success"])
+	require.Equal(t, "tool_error", response["error_type"])
+	require.Equal(t, "tool execution error: permission denied\npath: /etc/shadow", response["error"])
+	output := response["result"].(map[string]any)
+	require.Len(t, output["content"], 2)
+	require.Equal(t, map[string]any{"trace": "abc"}, output["meta"])
+
+	// Directly listed tools return the result as the server sent it
+	result, err = session.CallTool(context.Background(), &mcp.CallToolParams{Name: "down_open"})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	require.Equal(t, "path: /etc/shadow", result.Content[1].(*mcp.TextContent).Text)
+	require.Equal(t, mcp.Meta{"trace": "abc"}, result.Meta)
+}
+
+// TestHTTPSessions tests that concurrent HTTP clients get their own sessions and rate limits
+func TestHTTPSessions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "ok"}}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"sessionRateLimit": 1}, "mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close) // After the sessions, which hold open event streams
+
+	connect := func() *mcp.ClientSession {
+		client := mcp.NewClient(&mcp.Implementation{Name: "upstream", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{Endpoint: httpServer.URL}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { session.Close() })
+		return session
+	}
+	execute := func(session *mcp.ClientSession) *mcp.CallToolResult {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "tool_execute",
+			Arguments: map[string]any{"tool_name": "down_noop", "arguments": map[string]any{}},
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	first, second := connect(), connect()
+	require.NotEqual(t, first.ID(), second.ID())
+
+	require.False(t, execute(first).IsError)
+	limited := execute(first)
+	require.True(t, limited.IsError)
+	require.Contains(t, limited.Content[0].(*mcp.TextContent).Text, "rate_limited")
+
+	// The second session has its own budget
+	require.False(t, execute(second).IsError)
+}
+
+// TestKeepalive tests that servers failing pings are reported unhealthy by server_status
+func TestKeepalive(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	status := func() map[string]any {
+		result, _, err := server.handleServerStatus(context.Background(), nil, ServerStatusInput{})
+		require.NoError(t, err)
+		var response struct {
+			Servers []map[string]any `json:"servers"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		require.Len(t, response.Servers, 1)
+		return response.Servers[0]
+	}
+
+	server.pingServer(context.Background(), "down", time.Second)
+	healthy := status()
+	require.Equal(t, "down", healthy["name"])
+	require.Equal(t, true, healthy["healthy"])
+	require.Equal(t, float64(1), healthy["tools"])
+	require.NotEmpty(t, healthy["last_ping"])
+
+	// The connection dies silently
+	httpServer.CloseClientConnections()
+	httpServer.Close()
+
+	server.pingServer(context.Background(), "down", time.Second)
+	unhealthy := status()
+	require.Equal(t, false, unhealthy["healthy"])
+	require.Equal(t, float64(1), unhealthy["consecutive_failures"])
+	require.Contains(t, unhealthy["last_error"], "ping failed")
+}
+
+// TestServerCapabilities tests that server_capabilities reports what external
+// servers declared, including features the aggregator doesn't proxy
+func TestServerCapabilities(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "2.3.4"}, &mcp.ServerOptions{
+		Instructions: "Call noop before anything else.",
+		HasResources: true,
+		SubscribeHandler: func(ctx context.Context, req *mcp.SubscribeRequest) error {
+			return nil
+		},
+		UnsubscribeHandler: func(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+			return nil
+		},
+	})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	url := serveDownstream(t, downstream)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + url + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	result, _, err := server.handleServerCapabilities(context.Background(), nil, ServerCapabilitiesInput{})
+	require.NoError(t, err)
+
+	var response struct {
+		Servers []struct {
+			Name            string                  `json:"name"`
+			ServerName      string                  `json:"server_name"`
+			ServerVersion   string                  `json:"server_version"`
+			ProtocolVersion string                  `json:"protocol_version"`
+			Capabilities    *mcp.ServerCapabilities `json:"capabilities"`
+			Instructions    string                  `json:"instructions"`
+			Unproxied       []string                `json:"unproxied"`
+		} `json:"servers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Len(t, response.Servers, 1)
+
+	down := response.Servers[0]
+	require.Equal(t, "down", down.Name)
+	require.Equal(t, "downstream", down.ServerName)
+	require.Equal(t, "2.3.4", down.ServerVersion)
+	require.NotEmpty(t, down.ProtocolVersion)
+	require.NotNil(t, down.Capabilities.Tools)
+	require.NotNil(t, down.Capabilities.Resources)
+	require.Nil(t, down.Capabilities.Prompts)
+	require.Equal(t, "Call noop before anything else.", down.Instructions)
+	require.Equal(t, []string{"resources.subscribe"}, down.Unproxied)
+}
+
+// TestServerRefresh tests that server_refresh re-lists a server's tools and
+// reports what changed, even without a tools/list_changed notification
+func TestServerRefresh(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+	mcp.AddTool(downstream, &mcp.Tool{Name: "keep", Description: "Stay the same"}, noop)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "edit", Description: "Get a new description"}, noop)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "drop", Description: "Go away"}, noop)
+
+	// Rewrite tools/list once updated, so the listing changes without a notification
+	var updated atomic.Bool
+	downstream.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			list, ok := result.(*mcp.ListToolsResult)
+			if err != nil || !ok || !updated.Load() {
+				return result, err
+			}
+			var tools []*mcp.Tool
+			for _, tool := range list.Tools {
+				switch tool.Name {
+				case "drop":
+					continue
+				case "edit":
+					edited := *tool
+					edited.Description = "Edited description"
+					tool = &edited
+				}
+				tools = append(tools, tool)
+			}
+			tools = append(tools, &mcp.Tool{Name: "fresh", Description: "Newly added", InputSchema: map[string]any{"type": "object"}})
+			return &mcp.ListToolsResult{Tools: tools}, nil
+		}
+	})
+	url := serveDownstream(t, downstream)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + url + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	type refreshResponse struct {
+		Servers []struct {
+			Name    string   `json:"name"`
+			Added   []string `json:"added"`
+			Removed []string `json:"removed"`
+			Changed []string `json:"changed"`
+			Error   string   `json:"error"`
+		} `json:"servers"`
+	}
+	refresh := func(input ServerRefreshInput) refreshResponse {
+		result, _, err := server.handleServerRefresh(context.Background(), nil, input)
+		require.NoError(t, err)
+		var response refreshResponse
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response
+	}
+
+	// Nothing changed yet
+	response := refresh(ServerRefreshInput{})
+	require.Len(t, response.Servers, 1)
+	require.Equal(t, "down", response.Servers[0].Name)
+	require.Empty(t, response.Servers[0].Added)
+	require.Empty(t, response.Servers[0].Removed)
+	require.Empty(t, response.Servers[0].Changed)
+
+	updated.Store(true)
+	response = refresh(ServerRefreshInput{Server: "down"})
+	require.Len(t, response.Servers, 1)
+	require.Empty(t, response.Servers[0].Error)
+	require.Equal(t, []string{"fresh"}, response.Servers[0].Added)
+	require.Equal(t, []string{"drop"}, response.Servers[0].Removed)
+	require.Equal(t, []string{"edit"}, response.Servers[0].Changed)
+
+	_, err = server.registry.Get("down_fresh")
+	require.NoError(t, err)
+	_, err = server.registry.Get("down_drop")
+	require.Error(t, err)
+	edited, err := server.registry.Get("down_edit")
+	require.NoError(t, err)
+	require.Equal(t, "Edited description", edited.Description)
+
+	_, _, err = server.handleServerRefresh(context.Background(), nil, ServerRefreshInput{Server: "missing"})
+	require.Error(t, err)
+}
+
+// TestInstructionsAggregation tests that external servers' instructions are
+// merged into the aggregator's initialize result
+func TestInstructionsAggregation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	withInstructions := func(instructions string) string {
+		downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, &mcp.ServerOptions{Instructions: instructions})
+		mcp.AddTool(downstream, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+			func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+		return serveDownstream(t, downstream)
+	}
+	browserURL := withInstructions("Take a snapshot\n\nbefore   clicking elements.")
+	githubURL := withInstructions("Always pass the repository as owner/name. Prefer search over listing.")
+	plainURL := withInstructions("")
+
+	instructions := func(settings string) string {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"settings": {` + settings + `}, "mcpServers": {
+			"browser": {"url": "` + browserURL + `", "enabled": true, "pingInterval": -1},
+			"github": {"url": "` + githubURL + `", "enabled": true, "pingInterval": -1},
+			"plain": {"url": "` + plainURL + `", "enabled": true, "pingInterval": -1}
+		}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		defer server.Close()
+
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		serverSession, err := server.server.Connect(context.Background(), serverTransport, nil)
+		require.NoError(t, err)
+		defer serverSession.Close()
+
+		client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), clientTransport, nil)
+		require.NoError(t, err)
+		defer session.Close()
+
+		return session.InitializeResult().Instructions
+	}
+
+	merged := instructions("")
+	require.Contains(t, merged, "## browser\nTake a snapshot before clicking elements.")
+	require.Contains(t, merged, "## github\nAlways pass the repository as owner/name.")
+	require.NotContains(t, merged, "## plain")
+	require.Less(t, strings.Index(merged, "## browser"), strings.Index(merged, "## github"))
+
+	condensed := instructions(`"instructionsMaxChars": 20`)
+	require.Contains(t, condensed, "## github\nAlways pass the…")
+
+	require.Empty(t, instructions(`"forwardInstructions": false`))
+}
+
+// TestCondenseInstructions tests that instructions are collapsed to one line and cut at the limit
+func TestCondenseInstructions(t *testing.T) {
+	require.Equal(t, "Use it well.", condenseInstructions("  Use it\n\twell.  ", 100))
+	require.Equal(t, "Use it…", condenseInstructions("Use it well.", 8))
+	require.Equal(t, "Useitwell…", condenseInstructions("Useitwell.", 9))
+	require.Empty(t, condenseInstructions("", 100))
+}
+
+// TestToolAnnotations tests that tool annotations reach search results and
+// directly listed tools, and that blockDestructive skips destructive tools
+func TestToolAnnotations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+	notDestructive := false
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "read_file", Description: "Read a file",
+		Annotations: &mcp.ToolAnnotations{Title: "Read File", ReadOnlyHint: true}}, noop)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "append_file", Description: "Append to a file",
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: &notDestructive}}, noop)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "delete_file", Description: "Delete a file",
+		Annotations: &mcp.ToolAnnotations{IdempotentHint: true}}, noop)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "touch_file", Description: "Touch a file"}, noop)
+	url := serveDownstream(t, downstream)
+
+	newServer := func(serverSettings string) *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"settings": {"mode": "passthrough"}, "mcpServers": {"fs": {"url": "` + url + `", "enabled": true, "pingInterval": -1` + serverSettings + `}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	server := newServer("")
+	readFile, err := server.registry.Get("fs_read_file")
+	require.NoError(t, err)
+	require.Equal(t, &tools.ToolAnnotations{Title: "Read File", ReadOnlyHint: true}, readFile.Annotations)
+	touchFile, err := server.registry.Get("fs_touch_file")
+	require.NoError(t, err)
+	require.Nil(t, touchFile.Annotations)
+
+	// Annotations are listed with directly listed tools
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	listed := make(map[string]*mcp.Tool)
+	for tool, err := range session.Tools(context.Background(), nil) {
+		require.NoError(t, err)
+		listed[tool.Name] = tool
+	}
+	require.Equal(t, "Read File", listed["fs_read_file"].Annotations.Title)
+	require.True(t, listed["fs_delete_file"].Annotations.IdempotentHint)
+
+	blocking := newServer(`, "blockDestructive": true`)
+	var names []string
+	for _, tool := range blocking.registry.ListAll() {
+		names = append(names, tool.Name)
+	}
+	require.ElementsMatch(t, []string{"fs_read_file", "fs_append_file", "fs_touch_file"}, names)
+}
+
+// TestReconnect tests that a dropped external server connection is
+// re-established and the server's tools are re-indexed
+func TestReconnect(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := func(tool string) http.Handler {
+		server := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: tool, Description: "Do nothing"},
+			func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+		return mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+			return server
+		}, nil)
+	}
+
+	// Requests go to whichever downstream instance is current, like a server restarted behind the same URL
+	var current atomic.Pointer[http.Handler]
+	first := downstream("before")
+	current.Store(&first)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*current.Load()).ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	_, err = server.registry.Get("down_before")
+	require.NoError(t, err)
+
+	// The downstream restarts and forgets the session
+	second := downstream("after")
+	current.Store(&second)
+	httpServer.CloseClientConnections()
+
+	require.Eventually(t, func() bool {
+		server.healthMu.RLock()
+		defer server.healthMu.RUnlock()
+		return server.health["down"].Reconnecting
+	}, 10*time.Second, 20*time.Millisecond, "Dropped server should be marked reconnecting")
+
+	require.Eventually(t, func() bool {
+		_, err := server.registry.Get("down_after")
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond, "Tools should be re-listed after reconnecting")
+
+	_, err = server.registry.Get("down_before")
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool {
+		server.healthMu.RLock()
+		defer server.healthMu.RUnlock()
+		health := server.health["down"]
+		return health.Healthy && !health.Reconnecting
+	}, 5*time.Second, 20*time.Millisecond)
+
+	result, err := server.registry.Execute(context.Background(), "down_after", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+}
+
+// TestLazyServer tests that a lazy server is registered from its tool snapshot
+// and connected on its first tool call
+func TestLazyServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var sessions atomic.Int32
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, &mcp.ServerOptions{
+		InitializedHandler: func(ctx context.Context, req *mcp.InitializedRequest) {
+			sessions.Add(1)
+		},
+	})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo a message"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "hi"}}}, nil, nil
+		})
+	url := serveDownstream(t, downstream)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{"settings": {"cacheDir": "cache"}, "mcpServers": {"down": {"url": "` + url + `", "enabled": true, "lazy": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	// Without a snapshot the server is connected at startup and its tools are snapshotted
+	first, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sessions.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.FileExists(t, filepath.Join(dir, "cache", "tools", "down.json"))
+	first.Close()
+
+	// With a snapshot the tools are registered without connecting
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	_, err = server.registry.Get("down_echo")
+	require.NoError(t, err)
+	_, connected := server.externalClient("down")
+	require.False(t, connected)
+	require.True(t, server.health["down"].Idle)
+	require.Equal(t, int32(1), sessions.Load())
+
+	// The first call connects
+	for range 2 {
+		result, err := server.registry.Execute(context.Background(), "down_echo", nil)
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		require.Equal(t, "hi", result.Output.Content[0].(*mcp.TextContent).Text)
+	}
+	require.Eventually(t, func() bool { return sessions.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	_, connected = server.externalClient("down")
+	require.True(t, connected)
+	require.False(t, server.health["down"].Idle)
+}
+
+// TestServerLocks tests that connecting one server doesn't wait for another server's connect
+func TestServerLocks(t *testing.T) {
+	var locks serverLocks
+	unlockSlow := locks.lock("slow")
+
+	unlocked := make(chan struct{})
+	go func() {
+		locks.lock("fast")()
+		close(unlocked)
+	}()
+	select {
+	case <-unlocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Locking another server waited for the slow one")
+	}
+
+	relocked := make(chan struct{})
+	go func() {
+		locks.lock("slow")()
+		close(relocked)
+	}()
+	select {
+	case <-relocked:
+		t.Fatal("The same server was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockSlow()
+	<-relocked
+}
+
+// TestToolSnapshotFallback tests that the tools of a server that is down at
+// startup are served from its snapshot and that a call connects it once it's back
+func TestToolSnapshotFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo a message"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+			Message string `json:"message"`
+		}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: input.Message}}}, nil, nil
+		})
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil)
+
+	var available atomic.Bool
+	available.Store(true)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{"settings": {"cacheDir": "cache"}, "mcpServers": {"flaky": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	// Every connected server's listing is snapshotted with its hash
+	first, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	first.Close()
+
+	snapshotPath := filepath.Join(dir, "cache", "tools", "flaky.json")
+	data, err := os.ReadFile(snapshotPath)
+	require.NoError(t, err)
+	var snapshot toolSnapshot
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	require.Len(t, snapshot.Tools, 1)
+	hash, err := toolsHash(snapshot.Tools)
+	require.NoError(t, err)
+	require.Equal(t, hash, snapshot.Hash)
+
+	// Down at startup: the tools and their schemas come from the snapshot
+	available.Store(false)
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	tool, err := server.registry.Get("flaky_echo")
+	require.NoError(t, err)
+	require.Contains(t, fmt.Sprint(tool.InputSchema), "message")
+	_, connected := server.externalClient("flaky")
+	require.False(t, connected)
+	require.True(t, server.health["flaky"].Cached)
+	require.False(t, server.health["flaky"].Healthy)
+
+	result, err := server.registry.Execute(context.Background(), "flaky_echo", map[string]any{"message": "hi"})
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	_, err = server.registry.Get("flaky_echo")
+	require.NoError(t, err, "snapshot tools are kept after a failed attempt")
+
+	// Back up: the next call connects
+	available.Store(true)
+	result, err = server.registry.Execute(context.Background(), "flaky_echo", map[string]any{"message": "hi"})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, "hi", result.Output.Content[0].(*mcp.TextContent).Text)
+	_, connected = server.externalClient("flaky")
+	require.True(t, connected)
+
+	// A corrupted snapshot is ignored
+	require.NoError(t, os.WriteFile(snapshotPath, []byte(`{"hash": "0", "tools": []}`), 0644))
+	_, err = server.loadToolSnapshot("flaky")
+	require.Error(t, err)
+}
+
+// TestConcurrentStartup tests that external servers are connected in parallel
+// and that startup gives up on servers still connecting at the deadline
+func TestConcurrentStartup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// slowServer takes a while to answer its first request, like a server being installed
+	slowServer := func(delay time.Duration) string {
+		downstream := noopServer()
+		handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+			return downstream
+		}, nil)
+
+		var once sync.Once
+		stop := make(chan struct{})
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() {
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+				case <-stop:
+				}
+			})
+			handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(httpServer.Close)
+		t.Cleanup(func() { close(stop) }) // Runs first, releasing requests still waiting
+		return httpServer.URL
+	}
+
+	servers := map[string]string{
+		"slow1": slowServer(700 * time.Millisecond),
+		"slow2": slowServer(700 * time.Millisecond),
+		"slow3": slowServer(700 * time.Millisecond),
+		"hung":  slowServer(time.Hour),
+	}
+	var entries []string
+	for name, url := range servers {
+		entries = append(entries, `"`+name+`": {"url": "`+url+`", "enabled": true, "pingInterval": -1, "reconnect": false}`)
+	}
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"startupTimeout": 2}, "mcpServers": {` + strings.Join(entries, ",") + `}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	start := time.Now()
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	elapsed := time.Since(start)
+
+	// Sequentially the slow servers alone would take 2.1s and hit the deadline
+	require.Less(t, elapsed, 4*time.Second)
+	clients := server.connectedClients()
+	require.Len(t, clients, 3)
+	require.NotContains(t, clients, "hung")
+	for _, name := range []string{"slow1", "slow2", "slow3"} {
+		_, err := server.registry.Get(name + "_noop")
+		require.NoError(t, err)
+	}
+}
+
+// TestRemoteServerHeaders tests that configured headers and bearer tokens are
+// sent to remote servers, with environment variables expanded
+func TestRemoteServerHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	t.Setenv("ONEMCP_TEST_TOKEN", "secret")
+
+	downstream := noopServer()
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil)
+
+	// The gateway rejects requests without the expected credentials
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Tenant") != "acme" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	newServer := func(auth string) *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"mcpServers": {"gw": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1` + auth + `}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	unauthenticated := newServer("")
+	_, connected := unauthenticated.externalClient("gw")
+	require.False(t, connected)
+
+	authenticated := newServer(`, "bearerToken": "${ONEMCP_TEST_TOKEN}", "headers": {"X-Tenant": "acme"}`)
+	result, err := authenticated.registry.Execute(context.Background(), "gw_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+}
+
+// TestRemoteServerOAuth tests that a remote server requiring OAuth is authorized, and its token stored privately and refreshed
+func TestRemoteServerOAuth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil)
+
+	// One server is both the MCP server and its authorization server
+	var mu sync.Mutex
+	issued := map[string]bool{}
+	var deviceAuthorizations, refreshes int
+	issue := func(w http.ResponseWriter) {
+		mu.Lock()
+		token := fmt.Sprintf("token-%d", len(issued)+1)
+		issued[token] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600, "refresh_token": "refresh-%s"}`, token, token)
+	}
+
+	var httpServer *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"resource": "%s/mcp", "authorization_servers": [%q]}`, httpServer.URL, httpServer.URL)
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer": %[1]q, "authorization_endpoint": "%[1]s/authorize", "token_endpoint": "%[1]s/token",
+			"registration_endpoint": "%[1]s/register", "device_authorization_endpoint": "%[1]s/device"}`, httpServer.URL)
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"client_id": "registered-client"}`)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deviceAuthorizations++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"device_code": "device", "user_code": "ABCD", "verification_uri": "%s/verify", "interval": 1, "expires_in": 60}`, httpServer.URL)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("client_id") != "registered-client" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			issue(w)
+		case "refresh_token":
+			mu.Lock()
+			refreshes++
+			mu.Unlock()
+			issue(w)
+		default:
+			http.Error(w, `{"error": "unsupported_grant_type"}`, http.StatusBadRequest)
+		}
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		valid := issued[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		mu.Unlock()
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+httpServer.URL+`/.well-known/oauth-protected-resource"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	httpServer = httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+
+	cacheDir := t.TempDir()
+	newServer := func() *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"settings": {"cacheDir": "` + cacheDir + `"}, "mcpServers": {"remote": {"url": "` + httpServer.URL +
+			`/mcp", "enabled": true, "pingInterval": -1, "oauth": {"flow": "device"}}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	// The first connection runs the device flow and caches the token
+	server := newServer()
+	result, err := server.registry.Execute(context.Background(), "remote_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	tokenFile := filepath.Join(cacheDir, "oauth", "remote.json")
+	info, err := os.Stat(tokenFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// An expired cached token is refreshed without authorizing again
+	var cached map[string]any
+	data, err := os.ReadFile(tokenFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &cached))
+	cached["expiry"] = time.Now().Add(-time.Minute)
+	data, err = json.Marshal(cached)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tokenFile, data, 0600))
+
+	server = newServer()
+	result, err = server.registry.Execute(context.Background(), "remote_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, deviceAuthorizations)
+	require.Equal(t, 1, refreshes)
+}
+
+// TestRemoteServerTLS tests that the tls settings decide whether a server with a private CA is trusted
+func TestRemoteServerTLS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	httpServer := httptest.NewTLSServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	t.Cleanup(httpServer.Close)
+
+	// The server's certificate is signed by a CA only known from the bundle
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: httpServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0644))
+
+	newServer := func(tlsConfig string) *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"mcpServers": {"internal": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1` + tlsConfig + `}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	for _, test := range []struct {
+		name      string
+		tls       string
+		connected bool
+	}{
+		{name: "system roots", tls: "", connected: false},
+		{name: "CA bundle", tls: `, "tls": {"caFile": "` + caFile + `"}`, connected: true},
+		{name: "skip verify", tls: `, "tls": {"skipVerify": true}`, connected: true},
+		{name: "missing key", tls: `, "tls": {"caFile": "` + caFile + `", "certFile": "` + caFile + `"}`, connected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(test.tls)
+			_, connected := server.externalClient("internal")
+			require.Equal(t, test.connected, connected)
+		})
+	}
+}
+
+// TestRemoteServerProxy tests that a remote server is reached through the configured proxy
+func TestRemoteServerProxy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	t.Cleanup(httpServer.Close)
+	target, err := url.Parse(httpServer.URL)
+	require.NoError(t, err)
+
+	// The server's host only resolves through the proxy
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(&httputil.ReverseProxy{Rewrite: func(r *httputil.ProxyRequest) {
+		proxied.Add(1)
+		r.SetURL(target)
+	}})
+	t.Cleanup(proxy.Close)
+
+	newServer := func(proxyConfig string) *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"mcpServers": {"remote": {"url": "http://mcp.invalid/mcp", "enabled": true, "pingInterval": -1` + proxyConfig + `}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	unsupported := newServer(`, "proxy": "ftp://proxy.invalid"`)
+	_, connected := unsupported.externalClient("remote")
+	require.False(t, connected)
+
+	server := newServer(`, "proxy": "` + proxy.URL + `"`)
+	result, err := server.registry.Execute(context.Background(), "remote_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Positive(t, proxied.Load())
+}
+
+// TestSSEFallback tests that a server only speaking the legacy HTTP+SSE transport is still connected
+func TestSSEFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	// A server that only speaks the legacy HTTP+SSE transport
+	httpServer := httptest.NewServer(mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	t.Cleanup(httpServer.Close)
+
+	newServer := func(transport string) *AggregatorServer {
+		configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+		configContent := `{"mcpServers": {"legacy": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1` + transport + `}}}`
+		require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+		server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Close() })
+		return server
+	}
+
+	for _, test := range []struct {
+		name      string
+		transport string
+		connected bool
+	}{
+		{name: "fallback", transport: "", connected: true},
+		{name: "forced sse", transport: `, "transport": "sse"`, connected: true},
+		{name: "forced streamable", transport: `, "transport": "streamable-http"`, connected: false},
+		{name: "unknown", transport: `, "transport": "websocket"`, connected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(test.transport)
+			_, connected := server.externalClient("legacy")
+			require.Equal(t, test.connected, connected)
+			if !test.connected {
+				return
+			}
+
+			result, err := server.registry.Execute(context.Background(), "legacy_noop", nil)
+			require.NoError(t, err)
+			require.True(t, result.Success, result.Error)
+		})
+	}
+}
+
+// serveWebSocket serves an MCP server over WebSocket: a minimal RFC 6455
+// server bridging text frames to an in-memory transport. It records the
+// Authorization header of each handshake.
+func serveWebSocket(t *testing.T, server *mcp.Server, authorization *atomic.Value) *httptest.Server {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Protocol") != "mcp" {
+			http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+			return
+		}
+		authorization.Store(r.Header.Get("Authorization"))
+		accept := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Protocol: mcp\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+		rw.Flush()
+
+		ctx := context.Background()
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+			return
+		}
+		bridge, err := clientTransport.Connect(ctx)
+		if err != nil {
+			return
+		}
+		defer bridge.Close()
+
+		// Server messages go out as unmasked text frames
+		go func() {
+			for {
+				msg, err := bridge.Read(ctx)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				data, _ := jsonrpc.EncodeMessage(msg)
+				frame := []byte{0x81}
+				if len(data) < 126 {
+					frame = append(frame, byte(len(data)))
+				} else {
+					frame = append(frame, 126, byte(len(data)>>8), byte(len(data)))
+				}
+				conn.Write(append(frame, data...))
+			}
+		}()
+
+		// Client frames are masked
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(rw, header); err != nil {
+				return
+			}
+			length := int(header[1] & 0x7F)
+			if length == 126 {
+				extended := make([]byte, 2)
+				if _, err := io.ReadFull(rw, extended); err != nil {
+					return
+				}
+				length = int(extended[0])<<8 | int(extended[1])
+			}
+			mask := make([]byte, 4)
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(rw, mask); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(rw, payload); err != nil {
+				return
+			}
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+			if header[0]&0x0F == 0x8 {
+				return // Close frame
+			}
+			msg, err := jsonrpc.DecodeMessage(payload)
+			if err != nil || bridge.Write(ctx, msg) != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// TestWebSocketTransport tests connecting to servers over WebSocket
+func TestWebSocketTransport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "echo", Description: "Echo the input"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: input["text"].(string)}}}, nil, nil
+		})
+	var authorization atomic.Value
+	httpServer := serveWebSocket(t, downstream, &authorization)
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	for _, test := range []struct {
+		name   string
+		config string
+	}{
+		{name: "ws scheme", config: `"url": "` + wsURL + `"`},
+		{name: "transport", config: `"url": "` + httpServer.URL + `", "transport": "websocket"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+			configContent := `{"mcpServers": {"ws": {` + test.config + `, "bearerToken": "secret", "enabled": true, "pingInterval": -1}}}`
+			require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+			server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+			require.NoError(t, err)
+			t.Cleanup(func() { server.Close() })
+
+			// Large enough to need an extended payload length
+			text := strings.Repeat("x", 1000)
+			result, err := server.registry.Execute(context.Background(), "ws_echo", map[string]any{"text": text})
+			require.NoError(t, err)
+			require.True(t, result.Success, result.Error)
+			require.Contains(t, result.Output.Content[0].(*mcp.TextContent).Text, text)
+			require.Equal(t, "Bearer secret", authorization.Load())
+		})
+	}
+}
+
+// TestStdioHelperServer is the server the fake docker, npx and uvx CLIs of
+// the tests below run: a downstream MCP server on stdio. It does nothing in
+// normal test runs.
+func TestStdioHelperServer(t *testing.T) {
+	if os.Getenv("ONEMCP_TEST_STDIO_SERVER") != "1" {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "helper server starting")
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "container", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "greeting", Description: "Return the GREETING variable"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: os.Getenv("GREETING")}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "cwd", Description: "Return the working directory"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			dir, err := os.Getwd()
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: dir}}}, nil, err
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "args", Description: "Return the arguments after the test flags, one per line"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(flag.Args(), "\n")}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "pid", Description: "Return the process ID"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strconv.Itoa(os.Getpid())}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "port", Description: "Return the ONEMCP_PORT variable"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: os.Getenv("ONEMCP_PORT")}}}, nil, nil
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "crash", Description: "Exit mid-call, like a crashing server"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			fmt.Fprintln(os.Stderr, "fatal: browser process crashed")
+			os.Exit(1)
+			return nil, nil, nil
+		})
+	downstream.Run(context.Background(), &mcp.StdioTransport{})
+	os.Exit(0)
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes and reads
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestStdioServerCwdAndStderr tests that stdio servers run in their cwd and their stderr is logged
+func TestStdioServerCwdAndStderr(t *testing.T) {
+	var logs lockedBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+
+	configDir := t.TempDir()
+	workDir := filepath.Join(configDir, "work")
+	require.NoError(t, os.Mkdir(workDir, 0755))
+
+	configPath := filepath.Join(configDir, ".onemcp.json")
+	configContent := `{"mcpServers": {"helper": {"command": "` + os.Args[0] + `", "args": ["-test.run", "^TestStdioHelperServer$"],
+		"cwd": "work", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	result, err := server.registry.Execute(context.Background(), "helper_cwd", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	resolved, err := filepath.EvalSymlinks(workDir)
+	require.NoError(t, err)
+	require.Contains(t, result.Output.Content[0].(*mcp.TextContent).Text, resolved)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), `msg="External MCP server stderr" name=helper line="helper server starting"`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestCommandTemplates tests that command templates are expanded, survive a restart, and unknown ones are rejected
+func TestCommandTemplates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+
+	configDir := t.TempDir()
+	configPath := filepath.Join(configDir, ".onemcp.json")
+	configContent := `{"mcpServers": {
+		"helper": {"command": "` + os.Args[0] + `", "args": ["-test.run", "^TestStdioHelperServer$", "{{configDir}}/data", "{{ home }}", "{{port:auto}}"], "enabled": true, "pingInterval": -1},
+		"bad": {"command": "` + os.Args[0] + `", "args": ["{{workspace}}"], "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	result, err := server.registry.Execute(context.Background(), "helper_args", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	args := strings.Split(result.Output.Content[0].(*mcp.TextContent).Text, "\n")
+	require.Len(t, args, 3)
+	require.Equal(t, filepath.Join(configDir, "data"), args[0])
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	require.Equal(t, home, args[1])
+	port, err := strconv.Atoi(args[2])
+	require.NoError(t, err)
+	require.Positive(t, port)
+
+	// The allocated port is passed to the server in its environment too
+	result, err = server.registry.Execute(context.Background(), "helper_port", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, args[2], result.Output.Content[0].(*mcp.TextContent).Text)
+
+	// Unknown placeholders keep the server from starting
+	_, ok := server.externalClient("bad")
+	require.False(t, ok)
+}
+
+// TestStdioServerCrash tests that a crashed stdio server is restarted with
+// its stderr logged, until it uses up its restart budget
+func TestStdioServerCrash(t *testing.T) {
+	var logs lockedBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"helper": {"command": "` + os.Args[0] + `", "args": ["-test.run", "^TestStdioHelperServer$"],
+		"enabled": true, "pingInterval": -1, "maxRestarts": 1}}, "settings": {"searchProvider": "tfidf"}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	execute := func(tool string) *tools.ExecutionResult {
+		result, err := server.registry.Execute(context.Background(), tool, nil)
+		require.NoError(t, err)
+		return result
+	}
+	before := execute("helper_pid")
+	require.True(t, before.Success, before.Error)
+
+	result := execute("helper_crash")
+	require.False(t, result.Success)
+	require.Equal(t, "server_unavailable", result.ErrorType, result.Error)
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), `msg="External MCP server process exited" name=helper`) &&
+			strings.Contains(logs.String(), "fatal: browser process crashed")
+	}, 5*time.Second, 10*time.Millisecond, "The crash should be logged with the server's last stderr lines")
+
+	// Restarted, with its tools registered again
+	require.Eventually(t, func() bool {
+		return execute("helper_pid").Success
+	}, 10*time.Second, 50*time.Millisecond)
+	after := execute("helper_pid")
+	require.NotEqual(t, before.Output.Content[0].(*mcp.TextContent).Text, after.Output.Content[0].(*mcp.TextContent).Text)
+
+	// The second crash uses up the budget of one restart
+	execute("helper_crash")
+	require.Eventually(t, func() bool {
+		server.healthMu.RLock()
+		defer server.healthMu.RUnlock()
+		health := server.health["helper"]
+		return !health.Reconnecting && strings.HasPrefix(health.LastError, "crashed too often")
+	}, 10*time.Second, 20*time.Millisecond)
+
+	result = execute("helper_pid")
+	require.Equal(t, "server_unavailable", result.ErrorType)
+	require.Contains(t, result.Error, "no longer restarted")
+}
+
+// TestServerRestart tests that server_restart starts a new process for a stdio
+// server and registers its tools again
+func TestServerRestart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"helper": {"command": "` + os.Args[0] + `", "args": ["-test.run", "^TestStdioHelperServer$"], "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	pid := func() string {
+		result, err := server.registry.Execute(context.Background(), "helper_pid", nil)
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		return result.Output.Content[0].(*mcp.TextContent).Text
+	}
+	before := pid()
+	client, ok := server.externalClient("helper")
+	require.True(t, ok)
+
+	result, _, err := server.handleServerRestart(context.Background(), nil, ServerRestartInput{Server: "helper"})
+	require.NoError(t, err)
+	var response struct {
+		Server    string `json:"server"`
+		Restarted bool   `json:"restarted"`
+		Tools     int    `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+	require.Equal(t, "helper", response.Server)
+	require.True(t, response.Restarted)
+	require.Equal(t, 6, response.Tools)
+
+	restarted, ok := server.externalClient("helper")
+	require.True(t, ok)
+	require.NotSame(t, client, restarted)
+	require.NotEqual(t, before, pid())
+
+	_, _, err = server.handleServerRestart(context.Background(), nil, ServerRestartInput{Server: "missing"})
+	require.Error(t, err)
+}
+
+// TestDockerServer tests that docker servers are run in a named container that is removed on close
+func TestDockerServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A fake docker CLI logs its arguments and runs the helper server for "docker run"
+	binDir := t.TempDir()
+	argsLog := filepath.Join(binDir, "args.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + argsLog + "\n" +
+		"if [ \"$1\" = run ]; then exec " + os.Args[0] + " -test.run '^TestStdioHelperServer$'; fi\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+	t.Setenv("ONEMCP_TEST_NAME", "docker")
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"box": {"docker": {"image": "example/mcp:1.0", "args": ["--verbose"], "volumes": ["/tmp:/data:ro"],
+		"env": {"GREETING": "hello ${ONEMCP_TEST_NAME}"}}, "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+
+	result, err := server.registry.Execute(context.Background(), "box_greeting", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Contains(t, result.Output.Content[0].(*mcp.TextContent).Text, "hello docker")
+
+	require.NoError(t, server.Close())
+
+	data, err := os.ReadFile(argsLog)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	// The value of GREETING stays out of the arguments
+	run := strings.Fields(lines[0])
+	require.Equal(t, []string{"run", "-i", "--rm", "--name"}, run[:4])
+	container := run[4]
+	require.True(t, strings.HasPrefix(container, "onemcp-box-"), container)
+	require.Equal(t, []string{"--pull", "missing", "--volume", "/tmp:/data:ro", "--env", "GREETING", "example/mcp:1.0", "--verbose"}, run[5:])
+	require.Equal(t, "rm --force "+container, lines[1])
+}
+
+// TestRunnerServer tests that runner servers are started with a pinned package and cache, and invalid ones skipped
+func TestRunnerServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A fake npx logs its arguments and cache directory and runs the helper server.
+	// PATH holds nothing else, so uvx is missing.
+	binDir := t.TempDir()
+	argsLog := filepath.Join(binDir, "args.log")
+	script := "#!/bin/sh\necho \"$npm_config_cache $@\" >> " + argsLog + "\nexec " + os.Args[0] + " -test.run '^TestStdioHelperServer$'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "npx"), []byte(script), 0755))
+	t.Setenv("PATH", binDir)
+	t.Setenv("ONEMCP_TEST_STDIO_SERVER", "1")
+
+	cacheDir := t.TempDir()
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"settings": {"cacheDir": "` + cacheDir + `"}, "mcpServers": {
+		"pinned": {"runner": "npx", "package": "@example/server", "version": "1.2.3", "args": ["/tmp"], "enabled": true, "pingInterval": -1},
+		"python": {"runner": "uvx", "package": "example-server", "enabled": true, "pingInterval": -1},
+		"both": {"runner": "npx", "package": "@example/server", "command": "node", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	result, err := server.registry.Execute(context.Background(), "pinned_greeting", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	data, err := os.ReadFile(argsLog)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(cacheDir, "runners", "npx")+" --yes @example/server@1.2.3 /tmp", strings.TrimSpace(string(data)))
+
+	// uvx isn't installed, and runner and command are exclusive
+	for _, name := range []string{"python", "both"} {
+		_, connected := server.externalClient(name)
+		require.False(t, connected, name)
+	}
+}
+
+// TestCallTimeout tests that hung external calls time out and are cancelled downstream
+func TestCallTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cancelled := make(chan struct{})
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "hang", Description: "Never return"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, nil, ctx.Err()
+		})
+	mcp.AddTool(downstream, &mcp.Tool{Name: "noop", Description: "Do nothing"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		return downstream
+	}, nil))
+	t.Cleanup(httpServer.Close)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"slow": {"url": "` + httpServer.URL + `", "enabled": true, "pingInterval": -1, "callTimeout": 1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	result, err := server.registry.Execute(context.Background(), "slow_hang", nil)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	require.Equal(t, "timeout", result.ErrorType)
+	require.Equal(t, "tool hang on slow timed out after 1s", result.Error)
+
+	// The server is told to stop working on the call
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out call wasn't cancelled on the server")
+	}
+
+	result, err = server.registry.Execute(context.Background(), "slow_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+}
+
+// TestCallCeiling tests that the watchdog cancels a hung call, marks its
+// server suspect and, with restartOnHang, reconnects to it
+func TestCallCeiling(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+
+	cancelled := make(chan struct{}, 1)
+	downstream := noopServer()
+	mcp.AddTool(downstream, &mcp.Tool{Name: "hang", Description: "Never return"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			<-ctx.Done()
+			cancelled <- struct{}{}
+			return nil, nil, ctx.Err()
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"stuck": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1, "restartOnHang": true}},
+		"settings": {"searchProvider": "tfidf", "callCeiling": 1}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	health := func() serverHealth {
+		server.healthMu.RLock()
+		defer server.healthMu.RUnlock()
+		return *server.health["stuck"]
+	}
+
+	result, err := server.registry.Execute(context.Background(), "stuck_hang", nil)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	require.Equal(t, "timeout", result.ErrorType)
+	require.Equal(t, "tool hang on stuck timed out after 1s", result.Error)
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hung call wasn't cancelled on the server")
+	}
+	require.Eventually(t, func() bool { return health().Suspect }, 5*time.Second, 10*time.Millisecond)
+
+	// Reconnecting clears the suspicion
+	require.Eventually(t, func() bool {
+		current := health()
+		return !current.Suspect && current.Healthy
+	}, 10*time.Second, 20*time.Millisecond)
+	result, err = server.registry.Execute(context.Background(), "stuck_noop", nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+}
+
+// TestServerMetrics tests that tool call latency and errors are reported per
+// server by stats and the Prometheus endpoint
+func TestServerMetrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	mcp.AddTool(downstream, &mcp.Tool{Name: "fail", Description: "Always fail"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "disk full"}}}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	for _, tool := range []string{"down_noop", "down_noop", "down_fail"} {
+		_, err := server.registry.Execute(context.Background(), tool, nil)
+		require.NoError(t, err)
+	}
+
+	result, _, err := server.handleStats(context.Background(), nil, StatsInput{})
+	require.NoError(t, err)
+	var stats struct {
+		TotalCalls  int64 `json:"total_calls"`
+		TotalErrors int64 `json:"total_errors"`
+		Tools       []tools.ToolStats
+		Servers     []struct {
+			Name           string `json:"name"`
+			Calls          int64  `json:"calls"`
+			Errors         int64  `json:"errors"`
+			LastError      string `json:"last_error"`
+			LatencyBuckets []struct {
+				LeMs  int64 `json:"le_ms"`
+				Count int64 `json:"count"`
+			} `json:"latency_buckets"`
+		} `json:"servers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &stats))
+	require.Equal(t, int64(3), stats.TotalCalls)
+	require.Equal(t, int64(1), stats.TotalErrors)
+	require.Len(t, stats.Tools, 2)
+	require.Equal(t, "down_noop", stats.Tools[0].ToolName)
+	require.Equal(t, int64(2), stats.Tools[0].Calls)
+	require.Len(t, stats.Servers, 1)
+	down := stats.Servers[0]
+	require.Equal(t, "down", down.Name)
+	require.Equal(t, int64(3), down.Calls)
+	require.Equal(t, int64(1), down.Errors)
+	require.Equal(t, "fail reported an error: disk full", down.LastError)
+	last := down.LatencyBuckets[len(down.LatencyBuckets)-1]
+	require.Zero(t, last.LeMs)
+	require.Equal(t, int64(3), last.Count)
+
+	recorder := httptest.NewRecorder()
+	server.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	metrics := recorder.Body.String()
+	require.Contains(t, metrics, "# TYPE onemcp_server_tool_call_duration_seconds histogram\n")
+	require.Contains(t, metrics, `onemcp_server_healthy{server="down"} 1`)
+	require.Contains(t, metrics, `onemcp_server_tool_calls_total{server="down"} 3`)
+	require.Contains(t, metrics, `onemcp_server_tool_call_errors_total{server="down"} 1`)
+	require.Contains(t, metrics, `onemcp_server_tool_call_timeouts_total{server="down"} 0`)
+	require.Contains(t, metrics, `onemcp_server_tool_call_duration_seconds_bucket{server="down",le="+Inf"} 3`)
+	require.Contains(t, metrics, `onemcp_server_tool_call_duration_seconds_count{server="down"} 3`)
+	require.Contains(t, metrics, "onemcp_tool_calls_total 3\n")
+	require.Contains(t, metrics, "onemcp_tool_call_errors_total 1\n")
+	require.Contains(t, metrics, "onemcp_search_cache_hits_total 0\n")
+	require.Contains(t, metrics, "# TYPE onemcp_uptime_seconds gauge\n")
+}
+
+// TestSlowCallThreshold tests that tool calls slower than the threshold are
+// counted per server, and that a server can opt out
+func TestSlowCallThreshold(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	downstream := noopServer()
+	mcp.AddTool(downstream, &mcp.Tool{Name: "sleep", Description: "Take a while"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			time.Sleep(50 * time.Millisecond)
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	url := serveDownstream(t, downstream)
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {
+		"watched": {"url": "` + url + `", "enabled": true, "pingInterval": -1},
+		"ignored": {"url": "` + url + `", "enabled": true, "pingInterval": -1, "slowCallThreshold": -1}
+	}, "settings": {"searchProvider": "tfidf", "slowCallThreshold": 20}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	for _, tool := range []string{"watched_sleep", "watched_noop", "ignored_sleep"} {
+		_, err := server.registry.Execute(context.Background(), tool, nil)
+		require.NoError(t, err)
+	}
+
+	slow := map[string]int64{}
+	for _, metrics := range server.serverCallMetrics() {
+		slow[metrics.Name] = metrics.SlowCalls
+	}
+	require.Equal(t, map[string]int64{"watched": 1, "ignored": 0}, slow)
+
+	recorder := httptest.NewRecorder()
+	server.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, recorder.Body.String(), `onemcp_server_slow_tool_calls_total{server="watched"} 1`)
+}
+
+// TestLatencyWindow tests that percentiles cover only the most recent latencies
+func TestLatencyWindow(t *testing.T) {
+	var window latencyWindow
+	require.Equal(t, latencyPercentiles{}, window.percentiles())
+
+	for ms := 1; ms <= latencyWindowSize+100; ms++ {
+		window.record(time.Duration(ms) * time.Millisecond)
+	}
+	// The first 100 fell out of the window, leaving 101-1100
+	require.Equal(t, latencyPercentiles{Count: 1100, P50Ms: 600, P90Ms: 1000, P99Ms: 1090, MaxMs: 1100}, window.percentiles())
+}
+
+// TestPingInterval tests the default, custom and disabled ping intervals
+func TestPingInterval(t *testing.T) {
+	require.Equal(t, defaultPingInterval, pingInterval(0))
+	require.Equal(t, 5*time.Second, pingInterval(5))
+	require.Zero(t, pingInterval(-1))
+}
+
+// TestTracing tests that tool_execute joins the client's trace and passes it on to the downstream server
+func TestTracing(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	var downstreamMeta mcp.Meta
+	downstream := mcp.NewServer(&mcp.Implementation{Name: "downstream", Version: "1.0.0"}, nil)
+	mcp.AddTool(downstream, &mcp.Tool{Name: "traced", Description: "Record the request metadata"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			downstreamMeta = req.Params.Meta
+			return &mcp.CallToolResult{}, nil, nil
+		})
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	// The client's span, sent in _meta
+	clientCtx, clientSpan := provider.Tracer("client").Start(context.Background(), "client")
+	req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Meta: telemetry.InjectMeta(clientCtx, nil)}}
+	clientSpan.End()
+
+	result, _, err := server.handleToolExecute(context.Background(), req, ToolExecuteInput{ToolName: "down_traced"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == clientSpan.SpanContext().TraceID() {
+			spans[span.Name()] = span
+		}
+	}
+	require.Contains(t, spans, "tool_execute")
+	require.Contains(t, spans, "Registry.Execute")
+	require.Contains(t, spans, "tools/call traced")
+	require.Equal(t, clientSpan.SpanContext().SpanID(), spans["tool_execute"].Parent().SpanID())
+	require.Equal(t, spans["tool_execute"].SpanContext().SpanID(), spans["Registry.Execute"].Parent().SpanID())
+	require.Equal(t, spans["Registry.Execute"].SpanContext().SpanID(), spans["tools/call traced"].Parent().SpanID())
+
+	// The downstream server sees the call's span as its parent
+	callSpan := spans["tools/call traced"].SpanContext()
+	require.Equal(t, "00-"+callSpan.TraceID().String()+"-"+callSpan.SpanID().String()+"-01", downstreamMeta["traceparent"])
+}
+
+// TestAuditLog tests that tool calls are audited to file and webhook sinks without their arguments
+func TestAuditLog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	posted := make(chan map[string]any, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer audit-secret", r.Header.Get("Authorization"))
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		posted <- event
+	}))
+	t.Cleanup(webhook.Close)
+	t.Setenv("AUDIT_TOKEN", "audit-secret")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".onemcp.json")
+	configContent := `{
+		"mcpServers": {"down": {"url": "` + serveDownstream(t, noopServer()) + `", "enabled": true, "pingInterval": -1}},
+		"settings": {"searchProvider": "tfidf", "auditSinks": [
+			{"type": "file", "path": "audit/calls.jsonl"},
+			{"type": "webhook", "url": "` + webhook.URL + `", "headers": {"Authorization": "Bearer ${AUDIT_TOKEN}"}},
+			{"type": "stdout"},
+			{"type": "carrier-pigeon"}
+		]}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	require.Equal(t, []string{"file", "webhook", "stdout"}, server.audit.types, "Unknown sinks are skipped")
+	server.audit.dropStdout()
+	require.Equal(t, []string{"file", "webhook"}, server.audit.types)
+
+	ctx := context.WithValue(context.Background(), sessionIDKey{}, "session-1")
+	ctx = context.WithValue(ctx, sessionClientKey{}, "cursor 1.2")
+	_, err = server.registry.Execute(ctx, "down_noop", map[string]any{"path": "/etc/passwd"})
+	require.NoError(t, err)
+	_, err = server.registry.Execute(ctx, "down_missing", nil)
+	require.NoError(t, err)
+	require.NoError(t, server.Close(), "Closing flushes the webhook queue")
+
+	data, err := os.ReadFile(filepath.Join(dir, "audit", "calls.jsonl"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "/etc/passwd", "Arguments are only fingerprinted")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var events []auditEvent
+	for _, line := range lines {
+		var event auditEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	require.Equal(t, "session-1", events[0].Session)
+	require.Equal(t, "cursor 1.2", events[0].Client)
+	require.Equal(t, "down_noop", events[0].Tool)
+	require.NotEmpty(t, events[0].ArgumentsDigest)
+	require.Equal(t, "success", events[0].Outcome)
+	require.Equal(t, "error", events[1].Outcome)
+	require.Equal(t, "tool_not_found", events[1].ErrorType)
+
+	require.Equal(t, "down_noop", (<-posted)["tool"])
+	require.Equal(t, "down_missing", (<-posted)["tool"])
+}
+
+// TestStartupReport tests that stats reports the time taken by each startup phase and server, slowest first
+func TestStartupReport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {
+		"down": {"url": "` + serveDownstream(t, noopServer()) + `", "enabled": true, "pingInterval": -1},
+		"broken": {"url": "http://127.0.0.1:1/mcp", "enabled": true, "pingInterval": -1}
+	}, "settings": {"searchProvider": "tfidf"}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	result, _, err := server.handleStats(context.Background(), nil, StatsInput{})
+	require.NoError(t, err)
+	var stats struct {
+		Startup startupSnapshot `json:"startup"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &stats))
+	require.GreaterOrEqual(t, stats.Startup.TotalMs, stats.Startup.ServersMs)
+	require.Len(t, stats.Startup.Servers, 2)
+	servers := map[string]serverStartup{}
+	for _, server := range stats.Startup.Servers {
+		servers[server.Name] = server
+	}
+	require.Empty(t, servers["down"].Error)
+	require.GreaterOrEqual(t, servers["down"].TotalMs, servers["down"].ConnectMs+servers["down"].ListToolsMs)
+	require.NotEmpty(t, servers["broken"].Error)
+
+	report := startupReport{}
+	report.addServer("fast").TotalMs = 5
+	report.addServer("slow").TotalMs = 900
+	report.addServer("medium").TotalMs = 40
+	var names []string
+	for _, server := range report.snapshot().Servers {
+		names = append(names, server.Name)
+	}
+	require.Equal(t, []string{"slow", "medium", "fast"}, names)
+}
+
+// TestToolSearchDegraded tests that tool_search names the servers that are
+// down, so missing tools aren't mistaken for missing capabilities
+func TestToolSearchDegraded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {
+		"down": {"url": "` + serveDownstream(t, noopServer()) + `", "enabled": true, "pingInterval": -1},
+		"broken": {"url": "http://127.0.0.1:1/mcp", "enabled": true, "pingInterval": -1}
+	}, "settings": {"searchProvider": "tfidf"}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	search := func() []degradedServer {
+		result, _, err := server.handleToolSearch(context.Background(), nil, ToolSearchInput{Query: "noop"})
+		require.NoError(t, err)
+		var response struct {
+			Degraded []degradedServer `json:"degraded"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		return response.Degraded
+	}
+
+	degraded := search()
+	require.Len(t, degraded, 1)
+	require.Equal(t, "broken", degraded[0].Server)
+	require.Contains(t, degraded[0].Reason, "failed to connect at startup")
+
+	server.handleServerDisconnected("down", io.EOF)
+	degraded = search()
+	require.Len(t, degraded, 2)
+	require.Equal(t, degradedServer{Server: "down", Reason: "connection lost, reconnecting: EOF"}, degraded[1])
+}
@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionIDKey is the context key of the client session a request belongs to
+type sessionIDKey struct{}
+
+// sessionID returns the client session of the request in ctx, if known.
+// Sessions over stdio have an empty ID.
+func sessionID(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey{}).(string)
+	return id
+}
+
+// requestIDKey is the context key of the ID of the tool call being handled
+type requestIDKey struct{}
+
+// requestID returns the ID of the tool call in ctx, if any
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random ID for a tool call
+func newRequestID() string {
+	var id [6]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// sessionLogHandler adds the client session and the tool call being handled
+// to log records written with a context, so concurrent HTTP sessions can be
+// told apart and a call followed through the aggregator, registry and client
+type sessionLogHandler struct {
+	slog.Handler
+}
+
+// Handle adds the session and request attributes, if any, and passes the record on
+func (h sessionLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := sessionID(ctx); id != "" {
+		record.AddAttrs(slog.String("session", id))
+	}
+	if id := requestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps session tagging on derived loggers
+func (h sessionLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return sessionLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps session tagging on derived loggers
+func (h sessionLogHandler) WithGroup(name string) slog.Handler {
+	return sessionLogHandler{h.Handler.WithGroup(name)}
+}
+
+// sessionLimiter caps the tool calls each client session may make per minute
+// with a token bucket per session
+type sessionLimiter struct {
+	mu      sync.Mutex
+	perMin  int                     // Calls allowed per minute (also the burst size)
+	buckets map[string]*tokenBucket // Buckets by session ID
+}
+
+// tokenBucket holds the calls a session has left
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newSessionLimiter creates a limiter allowing perMin calls per minute per session
+func newSessionLimiter(perMin int) *sessionLimiter {
+	return &sessionLimiter{
+		perMin:  perMin,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// refillLocked returns a session's bucket topped up to now; the caller holds mu
+func (l *sessionLimiter) refillLocked(session string, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[session]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.perMin), last: now}
+		l.buckets[session] = bucket
+	}
+
+	refill := now.Sub(bucket.last).Minutes() * float64(l.perMin)
+	bucket.tokens = min(float64(l.perMin), bucket.tokens+refill)
+	bucket.last = now
+	return bucket
+}
+
+// allow takes a call from a session's bucket, reporting whether one was left
+func (l *sessionLimiter) allow(session string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket := l.refillLocked(session, now)
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// left returns how many calls a session may make right now
+func (l *sessionLimiter) left(session string, now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.refillLocked(session, now).tokens)
+}
+
+// forget drops a closed session's bucket
+func (l *sessionLimiter) forget(session string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, session)
+}
+
+// sessionMiddleware tags each request's context with its client session and
+// each tool call with a request ID, and enforces the per-session tool call
+// rate limit
+func (s *AggregatorServer) sessionMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		id, client := "", ""
+		if session, ok := req.GetSession().(*mcp.ServerSession); ok {
+			id = session.ID()
+			client = clientName(session)
+		}
+		ctx = context.WithValue(ctx, sessionIDKey{}, id)
+		ctx = context.WithValue(ctx, sessionClientKey{}, client)
+		if method == "tools/call" {
+			ctx = context.WithValue(ctx, requestIDKey{}, newRequestID())
+		}
+
+		if method == "tools/call" && s.limiter != nil && !s.limiter.allow(id, time.Now()) {
+			s.logger.WarnContext(ctx, "Session exceeded its tool call rate limit", "limit_per_minute", s.limiter.perMin)
+			resultJSON, _ := json.Marshal(map[string]any{
+				"error":      "rate limit exceeded: too many tool calls from this session, retry later",
+				"error_type": "rate_limited",
+			})
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: string(resultJSON)},
+				},
+			}, nil
+		}
+
+		return next(ctx, method, req)
+	}
+}
+
+// trackSession releases a client session's state once it disconnects
+func (s *AggregatorServer) trackSession(session *mcp.ServerSession) {
+	id := session.ID()
+	s.logger.Info("Client session started", "session", id)
+
+	go func() {
+		_ = session.Wait()
+		s.logger.Info("Client session ended", "session", id)
+		if s.limiter != nil {
+			s.limiter.forget(id)
+		}
+		s.forgetSessionRoots(id)
+	}()
+}
+
+// SessionInfoInput defines the input for session_info
+type SessionInfoInput struct{}
+
+// handleSessionInfo describes the client session that calls it and what
+// applies to it, to tell apart the clients of an HTTP deployment
+func (s *AggregatorServer) handleSessionInfo(ctx context.Context, req *mcp.CallToolRequest, input SessionInfoInput) (*mcp.CallToolResult, any, error) {
+	id := sessionID(ctx)
+	result := map[string]any{
+		"session_id": id,
+		"transport":  "stdio",
+	}
+	if id != "" {
+		result["transport"] = "http"
+	}
+
+	if req != nil && req.Session != nil {
+		if params := req.Session.InitializeParams(); params != nil {
+			result["protocol_version"] = params.ProtocolVersion
+			result["client_info"] = params.ClientInfo
+			result["client_capabilities"] = params.Capabilities
+		}
+	}
+
+	s.rootsMu.RLock()
+	if roots := s.roots[id]; roots != nil {
+		result["roots"] = roots
+	}
+	s.rootsMu.RUnlock()
+
+	s.macros.mu.Lock()
+	if recording := s.macros.recordings[id]; recording != nil {
+		result["recording_macro"] = recording.name
+	}
+	s.macros.mu.Unlock()
+
+	// What this session may do: the tool exposure mode, its rate limit and
+	// the tools no session may call
+	access := map[string]any{
+		"mode":           s.mode,
+		"disabled_tools": s.registry.Disabled(),
+	}
+	if s.limiter != nil {
+		access["rate_limit_per_minute"] = s.limiter.perMin
+		access["calls_left"] = s.limiter.left(id, time.Now())
+	}
+	result["access"] = access
+	result["settings"] = s.settingValues()
+
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// HTTPHandler serves the aggregator over Streamable HTTP. Every client gets
+// its own session; sessions share the external servers and search index.
+func (s *AggregatorServer) HTTPHandler() http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return s.server
+	}, nil)
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSessionLimiter tests that each session gets its own refilling call budget
+func TestSessionLimiter(t *testing.T) {
+	limiter := newSessionLimiter(2)
+	now := time.Now()
+
+	require.True(t, limiter.allow("a", now))
+	require.True(t, limiter.allow("a", now))
+	require.False(t, limiter.allow("a", now), "burst should be limited to the per-minute rate")
+
+	// Other sessions have their own bucket
+	require.True(t, limiter.allow("b", now))
+
+	// Half a minute refills one call
+	require.True(t, limiter.allow("a", now.Add(30*time.Second)))
+	require.False(t, limiter.allow("a", now.Add(30*time.Second)))
+
+	require.Equal(t, 0, limiter.left("a", now.Add(30*time.Second)))
+	require.Equal(t, 2, limiter.left("a", now.Add(2*time.Minute)))
+
+	limiter.forget("a")
+	require.True(t, limiter.allow("a", now.Add(30*time.Second)))
+}
+
+// TestSessionLogHandler tests that logs are tagged with the session and tool call they belong to
+func TestSessionLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sessionLogHandler{slog.NewTextHandler(&buf, nil)}).With("component", "test")
+
+	logger.InfoContext(context.WithValue(context.Background(), sessionIDKey{}, "abc123"), "tagged")
+	require.Contains(t, buf.String(), "component=test session=abc123")
+
+	buf.Reset()
+	logger.InfoContext(context.WithValue(context.Background(), requestIDKey{}, "f00d"), "call")
+	require.Contains(t, buf.String(), "request_id=f00d")
+
+	buf.Reset()
+	logger.Info("untagged")
+	require.NotContains(t, buf.String(), "session=")
+}
+
+// TestRequestID tests that a tool call's log lines, from the aggregator to the external server's client, share the request ID it returns
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	logger := slog.New(slog.NewTextHandler(lockedWriter{&mu, &buf}, nil))
+
+	downstream := noopServer()
+	mcp.AddTool(downstream, &mcp.Tool{Name: "fail", Description: "Always fail"},
+		func(ctx context.Context, req *mcp.CallToolRequest, input map[string]any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "disk full"}}}, nil, nil
+		})
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	configContent := `{"mcpServers": {"down": {"url": "` + serveDownstream(t, downstream) + `", "enabled": true, "pingInterval": -1}}, "settings": {"searchProvider": "tfidf"}}`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, nil).Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	call := func() string {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "tool_execute", Arguments: map[string]any{"tool_name": "down_fail", "arguments": map[string]any{}}})
+		require.NoError(t, err)
+		var response struct {
+			RequestID string `json:"request_id"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &response))
+		require.Len(t, response.RequestID, 12)
+		return response.RequestID
+	}
+	first, second := call(), call()
+	require.NotEqual(t, first, second, "Every call gets its own ID")
+
+	mu.Lock()
+	defer mu.Unlock()
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "request_id="+first) {
+			lines = append(lines, line)
+		}
+	}
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], `msg="Executing tool"`, "Registry")
+	require.Contains(t, lines[1], `msg="External MCP server tool reported an error"`, "Client")
+	require.Contains(t, lines[2], `msg="Tool reported an error"`, "Registry")
+}
+
+// lockedWriter serializes writes to a buffer shared by goroutines
+type lockedWriter struct {
+	mu     *sync.Mutex
+	writer io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.Write(p)
+}
+
+// TestSessionInfo tests that session_info reports the calling client and what applies to its session
+func TestSessionInfo(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	configPath := filepath.Join(t.TempDir(), ".onemcp.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"settings": {"sessionRateLimit": 5, "mode": "hybrid"}}`), 0644))
+
+	server, err := NewAggregatorServer("test-server", "1.0.0", configPath, logger)
+	require.NoError(t, err)
+	defer server.Close()
+	server.registry.SetDisabled("broken_tool", true)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err = server.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "inspector", Version: "2.1.0"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "session_info", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var info struct {
+		ProtocolVersion string              `json:"protocol_version"`
+		ClientInfo      *mcp.Implementation `json:"client_info"`
+		Access          struct {
+			Mode               string   `json:"mode"`
+			DisabledTools      []string `json:"disabled_tools"`
+			RateLimitPerMinute int      `json:"rate_limit_per_minute"`
+			CallsLeft          int      `json:"calls_left"`
+		} `json:"access"`
+		Settings map[string]any `json:"settings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &info))
+	require.Equal(t, session.InitializeResult().ProtocolVersion, info.ProtocolVersion)
+	require.Equal(t, "inspector", info.ClientInfo.Name)
+	require.Equal(t, "2.1.0", info.ClientInfo.Version)
+	require.Equal(t, "hybrid", info.Access.Mode)
+	require.Equal(t, []string{"broken_tool"}, info.Access.DisabledTools)
+	require.Equal(t, 5, info.Access.RateLimitPerMinute)
+	require.Equal(t, 4, info.Access.CallsLeft, "The session_info call itself counts")
+	require.Contains(t, info.Settings, "searchResultLimit")
+}
@@ -0,0 +1,180 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultDetailLevel is the tool_search detail level used when neither the
+// call nor defaultDetailLevel sets one
+const defaultDetailLevel = "summary"
+
+// detailLevels are the tool_search detail levels, least detailed first
+var detailLevels = []string{"names_only", "summary", "detailed", "full_schema"}
+
+// logLevels maps the logLevel setting to slog levels
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// runtimeSettings are the settings config_get reports and config_set may
+// change while the aggregator runs, by their config file names
+var runtimeSettings = []string{"searchResultLimit", "defaultDetailLevel", "minSearchScore", "logLevel"}
+
+// UseLogLevel lets the logLevel setting, and config_set, change the level of
+// the aggregator's log handler
+func (s *AggregatorServer) UseLogLevel(level *slog.LevelVar) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.logLevel = level
+	if configured, ok := logLevels[s.configuredLogLevel]; ok && level != nil {
+		level.Set(configured)
+	}
+}
+
+// LogRotation returns the size the log file may reach before it is rotated
+// (0 for no limit) and how many rotated files are kept, per the logMaxSizeMB
+// and logMaxFiles settings
+func (s *AggregatorServer) LogRotation() (maxSize int64, maxFiles int) {
+	return s.logMaxSize, s.logMaxFiles
+}
+
+// settingValues returns the current value of every runtime setting
+func (s *AggregatorServer) settingValues() map[string]any {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+
+	values := map[string]any{
+		"searchResultLimit":  s.searchResultLimit,
+		"defaultDetailLevel": s.detailLevel,
+		"minSearchScore":     s.minSearchScore,
+	}
+	if s.logLevel != nil {
+		values["logLevel"] = strings.ToLower(s.logLevel.Level().String())
+	}
+	return values
+}
+
+// applySetting validates and applies a runtime setting, returning the value
+// as it should be persisted
+func (s *AggregatorServer) applySetting(key string, value any) (any, error) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+
+	switch key {
+	case "searchResultLimit":
+		limit, ok := value.(float64)
+		if !ok || limit != float64(int(limit)) || limit < 1 {
+			return nil, fmt.Errorf("searchResultLimit must be a positive integer")
+		}
+		s.searchResultLimit = int(limit)
+		return s.searchResultLimit, nil
+	case "defaultDetailLevel":
+		level, ok := value.(string)
+		if !ok || !slices.Contains(detailLevels, level) {
+			return nil, fmt.Errorf("defaultDetailLevel must be one of %s", strings.Join(detailLevels, ", "))
+		}
+		s.detailLevel = level
+		return level, nil
+	case "minSearchScore":
+		score, ok := value.(float64)
+		if !ok || score < 0 || score > 1 {
+			return nil, fmt.Errorf("minSearchScore must be a number from 0 to 1")
+		}
+		s.minSearchScore = score
+		return score, nil
+	case "logLevel":
+		name, _ := value.(string)
+		level, ok := logLevels[name]
+		if !ok {
+			return nil, fmt.Errorf("logLevel must be one of debug, info, warn, error")
+		}
+		if s.logLevel == nil {
+			return nil, errors.New("the log level of this process can't be changed")
+		}
+		s.logLevel.Set(level)
+		return name, nil
+	}
+	return nil, fmt.Errorf("unknown or read-only setting: %s (settable: %s)", key, strings.Join(runtimeSettings, ", "))
+}
+
+// persistSetting writes a setting into the config file, leaving the rest of
+// the file as it is
+func (s *AggregatorServer) persistSetting(key string, value any) error {
+	return writeConfigSetting(s.configPath, key, value)
+}
+
+// ConfigGetInput defines the input for config_get
+type ConfigGetInput struct{}
+
+func (s *AggregatorServer) handleConfigGet(ctx context.Context, req *mcp.CallToolRequest, input ConfigGetInput) (*mcp.CallToolResult, any, error) {
+	resultJSON, _ := json.Marshal(map[string]any{
+		"settings":    s.settingValues(),
+		"config_path": s.configPath,
+	})
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
+
+// ConfigSetInput defines the input for config_set
+type ConfigSetInput struct {
+	Key     string `json:"key" jsonschema:"Setting to change: 'searchResultLimit', 'defaultDetailLevel', 'minSearchScore' or 'logLevel'"`
+	Value   any    `json:"value" jsonschema:"New value: a positive integer for searchResultLimit, 'names_only'/'summary'/'detailed'/'full_schema' for defaultDetailLevel, 0-1 for minSearchScore, 'debug'/'info'/'warn'/'error' for logLevel"`
+	Persist bool   `json:"persist,omitempty" jsonschema:"Also write the setting to the config file so it survives a restart. Default: false"`
+}
+
+// handleConfigSet changes a runtime setting of the live aggregator and, if
+// asked, in the config file
+func (s *AggregatorServer) handleConfigSet(ctx context.Context, req *mcp.CallToolRequest, input ConfigSetInput) (*mcp.CallToolResult, any, error) {
+	previous := s.settingValues()[input.Key]
+	value, err := s.applySetting(input.Key, input.Value)
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      err.Error(),
+			"error_type": "invalid_setting",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+	s.logger.InfoContext(ctx, "Setting changed", "key", input.Key, "previous", previous, "value", value)
+
+	result := map[string]any{
+		"key":       input.Key,
+		"previous":  previous,
+		"value":     value,
+		"persisted": false,
+	}
+	if input.Persist {
+		if err := s.persistSetting(input.Key, value); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to persist setting", "key", input.Key, "path", s.configPath, "error", err)
+			result["persist_error"] = err.Error()
+		} else {
+			result["persisted"] = true
+		}
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
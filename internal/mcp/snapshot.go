@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/radutopala/onemcp/internal/mcpclient"
+)
+
+// toolSnapshot is a server's tool listing, schemas included, as cached on
+// disk. Hash is the SHA-256 of the tools' JSON: it detects corrupt files and
+// spares rewriting an unchanged listing.
+type toolSnapshot struct {
+	Hash  string           `json:"hash"`
+	Tools []mcpclient.Tool `json:"tools"`
+}
+
+// userCacheDir is os.UserCacheDir; tests point it at a temporary directory
+var userCacheDir = os.UserCacheDir
+
+// defaultCacheDir returns where tool snapshots are kept when cacheDir isn't set
+func defaultCacheDir() string {
+	dir, err := userCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "onemcp")
+}
+
+// toolSnapshotPath returns the file a server's tool listing is snapshotted to
+func (s *AggregatorServer) toolSnapshotPath(name string) string {
+	return filepath.Join(s.cacheDir, "tools", name+".json")
+}
+
+// toolsHash returns the content hash of a tool listing
+func toolsHash(externalTools []mcpclient.Tool) (string, error) {
+	data, err := json.Marshal(externalTools)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// saveToolSnapshot records a server's tool listing so its tools can be
+// registered without connecting: for lazy servers, and for servers that are
+// down or slow to connect at the next start
+func (s *AggregatorServer) saveToolSnapshot(name string, externalTools []mcpclient.Tool) {
+	path := s.toolSnapshotPath(name)
+	hash, err := toolsHash(externalTools)
+	if err == nil {
+		if previous, loadErr := s.loadToolSnapshotFile(path); loadErr == nil && previous.Hash == hash {
+			return // Unchanged
+		}
+	}
+
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(toolSnapshot{Hash: hash, Tools: externalTools})
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		// Written aside and renamed so a crash never leaves half a snapshot
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		s.logger.Warn("Failed to save tool snapshot", "name", name, "path", path, "error", err)
+	}
+}
+
+// loadToolSnapshot reads the tool listing last recorded for a server
+func (s *AggregatorServer) loadToolSnapshot(name string) ([]mcpclient.Tool, error) {
+	snapshot, err := s.loadToolSnapshotFile(s.toolSnapshotPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Tools, nil
+}
+
+// loadToolSnapshotFile reads and verifies a snapshot file
+func (s *AggregatorServer) loadToolSnapshotFile(path string) (*toolSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot toolSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid tool snapshot: %w", err)
+	}
+	hash, err := toolsHash(snapshot.Tools)
+	if err != nil {
+		return nil, err
+	}
+	if hash != snapshot.Hash {
+		return nil, errors.New("tool snapshot doesn't match its hash")
+	}
+	return &snapshot, nil
+}
+
+// registerSnapshotFallback registers the tools of a server that couldn't be
+// connected from its snapshot, so they can still be searched; the first call
+// to one of them retries the connection
+func (s *AggregatorServer) registerSnapshotFallback(name string, config mcpclient.MCPServerConfig, connectErr error) {
+	health := &serverHealth{LastError: connectErr.Error(), Cached: true}
+	if s.registerFromSnapshot(name, config, health) {
+		s.logger.Warn("Serving external tools from snapshot until the server can be connected", "name", name)
+	}
+}
+
+// setSnapshotHealth records a failed connection attempt of a server served from its snapshot
+func (s *AggregatorServer) setSnapshotHealth(name string, connectErr error) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	if health, ok := s.health[name]; ok {
+		health.Healthy = false
+		health.Idle = false
+		health.Cached = true
+		health.LastError = connectErr.Error()
+		health.ConsecutiveFailures++
+	}
+}
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// startupReport records how long each phase of startup took, so a slow
+// startup can be traced to a phase or an external server
+type startupReport struct {
+	mu            sync.Mutex
+	configLoad    time.Duration
+	servers       time.Duration // Connecting every external server, concurrently
+	serverTimings []*serverStartup
+	searchIndex   time.Duration
+	total         time.Duration
+}
+
+// serverStartup is how long one external server took to connect at startup
+type serverStartup struct {
+	Name        string `json:"name"`
+	ConnectMs   int64  `json:"connect_ms"`    // Starting the server and the initialize handshake
+	ListToolsMs int64  `json:"list_tools_ms"` // tools/list
+	TotalMs     int64  `json:"total_ms"`      // Also resources, prompts and waiting for a startup slot
+	Error       string `json:"error,omitempty"`
+}
+
+// startupSnapshot is the startup report as stats shows it
+type startupSnapshot struct {
+	ConfigLoadMs  int64           `json:"config_load_ms"`
+	ServersMs     int64           `json:"servers_ms"`
+	SearchIndexMs int64           `json:"search_index_ms"` // Building the search index over the catalog
+	TotalMs       int64           `json:"total_ms"`
+	Servers       []serverStartup `json:"servers"` // Slowest first
+}
+
+// addServer records the timing of one server, which the caller fills in
+func (r *startupReport) addServer(name string) *serverStartup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timing := &serverStartup{Name: name}
+	r.serverTimings = append(r.serverTimings, timing)
+	return timing
+}
+
+// snapshot returns the report with servers sorted slowest first
+func (r *startupReport) snapshot() startupSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := startupSnapshot{
+		ConfigLoadMs:  r.configLoad.Milliseconds(),
+		ServersMs:     r.servers.Milliseconds(),
+		SearchIndexMs: r.searchIndex.Milliseconds(),
+		TotalMs:       r.total.Milliseconds(),
+		Servers:       make([]serverStartup, 0, len(r.serverTimings)),
+	}
+	for _, timing := range r.serverTimings {
+		snapshot.Servers = append(snapshot.Servers, *timing)
+	}
+	slices.SortStableFunc(snapshot.Servers, func(a, b serverStartup) int {
+		return cmp.Compare(b.TotalMs, a.TotalMs)
+	})
+	return snapshot
+}
+
+// log writes the report, naming the slowest server
+func (r *startupReport) log(logger *slog.Logger) {
+	snapshot := r.snapshot()
+	attrs := []any{
+		"total_ms", snapshot.TotalMs,
+		"config_load_ms", snapshot.ConfigLoadMs,
+		"servers_ms", snapshot.ServersMs,
+		"search_index_ms", snapshot.SearchIndexMs,
+	}
+	if len(snapshot.Servers) > 0 {
+		attrs = append(attrs, "slowest_server", snapshot.Servers[0].Name, "slowest_server_ms", snapshot.Servers[0].TotalMs)
+	}
+	logger.Info("Startup complete", attrs...)
+}
@@ -0,0 +1,14 @@
+//go:build !(windows || plan9)
+
+package mcp
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// dialSyslog connects to a syslog server, or to the local daemon when
+// network and address are empty
+func dialSyslog(network, address, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+}
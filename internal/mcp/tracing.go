@@ -0,0 +1,26 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces meta-tool calls
+var tracer = otel.Tracer("github.com/radutopala/onemcp/internal/mcp")
+
+// startMetaToolSpan starts the server span of a meta-tool call, as a child of
+// the trace the client sent in _meta, if any
+func startMetaToolSpan(ctx context.Context, req *mcp.CallToolRequest, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if req != nil && req.Params != nil {
+		ctx = telemetry.ExtractMeta(ctx, req.Params.Meta)
+	}
+	if id := requestID(ctx); id != "" {
+		attrs = append(attrs, attribute.String("onemcp.request_id", id))
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/tools"
+)
+
+// ToolValidateInput defines the input for tool_validate
+type ToolValidateInput struct {
+	ToolName  string         `json:"tool_name" jsonschema:"Name of the tool, as returned by tool_search (e.g. 'playwright_browser_navigate')"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"Arguments you intend to pass to tool_execute"`
+}
+
+// handleToolValidate checks arguments against a tool's input schema without
+// executing it
+func (s *AggregatorServer) handleToolValidate(ctx context.Context, req *mcp.CallToolRequest, input ToolValidateInput) (*mcp.CallToolResult, any, error) {
+	tool, err := s.registry.Get(input.ToolName)
+	if err != nil {
+		resultJSON, _ := json.Marshal(map[string]any{
+			"error":      err.Error(),
+			"error_type": "tool_not_found",
+		})
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+		}, nil, nil
+	}
+
+	issues := tools.ValidateArguments(tool.InputSchema, input.Arguments)
+	if issues == nil {
+		issues = []tools.ArgumentIssue{} // avoid JSON null
+	}
+	valid := !slices.ContainsFunc(issues, func(issue tools.ArgumentIssue) bool {
+		return issue.Severity == "error"
+	})
+
+	result := map[string]any{
+		"tool_name": tool.Name,
+		"valid":     valid,
+		"issues":    issues,
+	}
+	if tool.Annotations.Destructive() {
+		result["destructive"] = true
+	}
+	resultJSON, _ := json.Marshal(result)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+	}, nil, nil
+}
@@ -1,41 +1,183 @@
 package mcpclient
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer traces calls to external MCP servers
+var tracer = otel.Tracer("github.com/radutopala/onemcp/internal/mcpclient")
+
 // MCPClient represents a client connection to an external MCP server.
 type MCPClient struct {
-	name        string
-	session     *mcp.ClientSession
-	logger      *slog.Logger
-	schemaCache map[string]map[string]any // Cache tool schemas: toolName -> schema
+	name          string
+	client        *mcp.Client
+	sessionMu     sync.RWMutex       // Guards session
+	session       *mcp.ClientSession // Replaced when the connection is re-established
+	down          string             // Why the server can't take calls, empty while connected; guarded by sessionMu
+	newTransport  func() mcp.Transport
+	transportType string
+	cleanup       func()             // Releases what outlives a session (a container), if anything
+	reconnect     bool               // Re-establish dropped connections
+	stderr        *stderrTail        // Last stderr lines of the server process, nil unless the server is one
+	maxRestarts   int                // Restarts of a crashed process allowed per restartWindow, negative for unlimited
+	restarts      []time.Time        // Recent restarts, within restartWindow; used by watch only
+	callTimeout   time.Duration      // Default limit of a tool call, 0 for none
+	callCeiling   time.Duration      // Hard limit of any tool call, enforced by the watchdog; 0 for none
+	restartOnHang bool               // Re-establish the connection after the watchdog cancels a call
+	inflight      inflightCalls      // Running tool calls, for the watchdog
+	slowCall      time.Duration      // Latency above which a tool call is logged as slow, 0 for never
+	metrics       *callMetrics       // Latency and errors of tool calls
+	progress      *progressRouter    // Progress notifications of running tool calls
+	handlers      Handlers           // Notification and connection callbacks
+	closeCtx      context.Context    // Cancelled by Close to stop reconnecting
+	cancelClose   context.CancelFunc // Cancels closeCtx
+	logger        *slog.Logger
+	mu            sync.RWMutex              // Guards schemaCache and rootURIs
+	schemaCache   map[string]map[string]any // Cache tool schemas: toolName -> schema
+	rootURIs      []string                  // URIs of the roots answered to roots/list
+}
+
+// Handlers holds optional callbacks for notifications sent by the external MCP server.
+type Handlers struct {
+	// ToolListChanged is called when the server emits notifications/tools/list_changed.
+	ToolListChanged func(ctx context.Context, serverName string)
+
+	// ResourceListChanged is called when the server emits notifications/resources/list_changed.
+	ResourceListChanged func(ctx context.Context, serverName string)
+
+	// PromptListChanged is called when the server emits notifications/prompts/list_changed.
+	PromptListChanged func(ctx context.Context, serverName string)
+
+	// CreateMessage answers the server's sampling/createMessage requests.
+	// Setting it advertises the sampling capability to the server.
+	CreateMessage func(ctx context.Context, serverName string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+
+	// LoggingMessage is called when the server emits notifications/message.
+	LoggingMessage func(ctx context.Context, serverName string, params *mcp.LoggingMessageParams)
+
+	// Disconnected is called when the connection to the server drops unexpectedly,
+	// before reconnection is attempted.
+	Disconnected func(serverName string, err error)
+
+	// Reconnected is called once a dropped connection is re-established.
+	Reconnected func(ctx context.Context, serverName string)
+
+	// GaveUp is called when a crashed server process is no longer restarted
+	// because it used up its restart budget.
+	GaveUp func(serverName string, err error)
+
+	// Hung is called when the watchdog cancels a tool call that ran past
+	// the call ceiling.
+	Hung func(serverName, toolName string)
 }
 
 // MCPServerConfig represents configuration for an external MCP server.
 // Supports multiple transport types:
-// - Command transport (stdio): Provide "command" field
-// - HTTP transports (Streamable HTTP, SSE): Provide "url" field
+// - Command transport (stdio): Provide "command" field, or "runner" and "package"
+// - Container (stdio over docker run -i): Provide "docker" field
+// - HTTP transports (Streamable HTTP, SSE, WebSocket): Provide "url" field
 type MCPServerConfig struct {
-	Command  string            `json:"command,omitempty"`  // Command to execute (for stdio transport)
-	Args     []string          `json:"args,omitempty"`     // Command arguments
-	URL      string            `json:"url,omitempty"`      // HTTP URL (for Streamable HTTP or SSE transport)
-	Env      map[string]string `json:"env,omitempty"`      // Environment variables (stdio only)
-	Category string            `json:"category,omitempty"` // Category for grouping tools
-	Enabled  bool              `json:"enabled"`            // Whether to load this server
+	Command      string            `json:"command,omitempty"`      // Command to execute (for stdio transport)
+	Docker       *DockerConfig     `json:"docker,omitempty"`       // Container to run (stdio transport over docker run -i)
+	Args         []string          `json:"args,omitempty"`         // Command arguments
+	Runner       string            `json:"runner,omitempty"`       // "npx" or "uvx": run Package with it instead of Command
+	Package      string            `json:"package,omitempty"`      // Package run by Runner, e.g. "@modelcontextprotocol/server-memory"
+	Version      string            `json:"version,omitempty"`      // Version Package is pinned to (default: latest)
+	RunnerCache  string            `json:"runnerCache,omitempty"`  // Directory the runner's package cache is kept in
+	URL          string            `json:"url,omitempty"`          // HTTP URL (for Streamable HTTP or SSE transport) or ws(s) URL (for WebSocket)
+	Transport    string            `json:"transport,omitempty"`    // "streamable-http", "sse" or "websocket" (default: by URL scheme; SSE if Streamable HTTP is rejected)
+	Env          map[string]string `json:"env,omitempty"`          // Environment variables (stdio only)
+	Cwd          string            `json:"cwd,omitempty"`          // Working directory, relative to the config file (stdio only)
+	ConfigDir    string            `json:"-"`                      // Directory of the config file, for {{configDir}} (default: working directory)
+	Headers      map[string]string `json:"headers,omitempty"`      // HTTP headers sent with every request (HTTP only)
+	BearerToken  string            `json:"bearerToken,omitempty"`  // Sent as "Authorization: Bearer <token>" (HTTP only)
+	OAuth        *OAuthConfig      `json:"oauth,omitempty"`        // Authorize with the server's OAuth authorization server (HTTP only)
+	TLS          *TLSConfig        `json:"tls,omitempty"`          // CA bundle, client certificate and verification settings (HTTP only)
+	Proxy        string            `json:"proxy,omitempty"`        // Proxy URL: http, https or socks5 (HTTP only, default: HTTPS_PROXY/NO_PROXY)
+	Category     string            `json:"category,omitempty"`     // Category for grouping tools
+	Enabled      bool              `json:"enabled"`                // Whether to load this server
+	LogLevel     string            `json:"logLevel,omitempty"`     // Minimum level of server log messages to forward, or "off"
+	PingInterval int               `json:"pingInterval,omitempty"` // Seconds between keepalive pings (default: 30, negative disables)
+	CallTimeout  int               `json:"callTimeout,omitempty"`  // Seconds a tool call may take (default: 0, no limit)
+	Reconnect    *bool             `json:"reconnect,omitempty"`    // Re-establish dropped connections with backoff (default: true)
+	MaxRestarts  int               `json:"maxRestarts,omitempty"`  // Restarts of a crashed process per 10 minutes before giving up (default: 5, negative: unlimited; stdio only)
+	Lazy         bool              `json:"lazy,omitempty"`         // Connect on first tool call, listing tools from a snapshot
+
+	BlockDestructive  bool `json:"blockDestructive,omitempty"`  // Skip tools annotated as destructive
+	SlowCallThreshold int  `json:"slowCallThreshold,omitempty"` // Milliseconds before a tool call is logged and counted as slow (default: settings.slowCallThreshold, negative disables)
+	CallCeiling       int  `json:"callCeiling,omitempty"`       // Seconds before the watchdog cancels any tool call as hung (default: settings.callCeiling, negative disables)
+	RestartOnHang     bool `json:"restartOnHang,omitempty"`     // Re-establish the connection after a call is cancelled as hung
+
+	Examples     []string            `json:"examples,omitempty"`     // Usage examples attached to every tool of this server
+	ToolExamples map[string][]string `json:"toolExamples,omitempty"` // Usage examples per tool (unprefixed tool name)
 }
 
 // Tool represents an MCP tool from an external server.
 type Tool struct {
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	InputSchema map[string]any `json:"inputSchema"`
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	InputSchema  map[string]any       `json:"inputSchema"`
+	OutputSchema map[string]any       `json:"outputSchema,omitempty"` // Schema of the tool's structured content, if declared
+	Annotations  *mcp.ToolAnnotations `json:"annotations,omitempty"`  // Behavior hints, if declared
+}
+
+// Resource represents a resource exposed by an external MCP server.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ResourceTemplate represents a parameterized resource (RFC 6570 URI template)
+// exposed by an external MCP server.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// Prompt represents a prompt template exposed by an external MCP server.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes an argument a prompt template accepts.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// ServerInfo describes what an external server declared when the connection was initialized.
+type ServerInfo struct {
+	Name            string                  `json:"server_name"`
+	Version         string                  `json:"server_version"`
+	ProtocolVersion string                  `json:"protocol_version"`
+	Capabilities    *mcp.ServerCapabilities `json:"capabilities"`
+	Instructions    string                  `json:"instructions,omitempty"`
 }
 
 // NewMCPClient creates a new MCP client connected to an external server.
@@ -44,63 +186,232 @@ type Tool struct {
 // - Streamable HTTP transport: When config.URL is provided (recommended for HTTP)
 // - SSE transport: Fallback for older servers (deprecated)
 func NewMCPClient(ctx context.Context, name string, config MCPServerConfig, logger *slog.Logger) (*MCPClient, error) {
+	return NewMCPClientWithHandlers(ctx, name, config, Handlers{}, logger)
+}
+
+// NewMCPClientWithHandlers creates a new MCP client and wires the given notification handlers.
+func NewMCPClientWithHandlers(ctx context.Context, name string, config MCPServerConfig, handlers Handlers, logger *slog.Logger) (*MCPClient, error) {
+	clientOptions := &mcp.ClientOptions{}
+	if handlers.ToolListChanged != nil {
+		clientOptions.ToolListChangedHandler = func(ctx context.Context, req *mcp.ToolListChangedRequest) {
+			logger.Info("Received tools/list_changed from external MCP server", "name", name)
+			handlers.ToolListChanged(ctx, name)
+		}
+	}
+	if handlers.ResourceListChanged != nil {
+		clientOptions.ResourceListChangedHandler = func(ctx context.Context, req *mcp.ResourceListChangedRequest) {
+			logger.Info("Received resources/list_changed from external MCP server", "name", name)
+			handlers.ResourceListChanged(ctx, name)
+		}
+	}
+	if handlers.PromptListChanged != nil {
+		clientOptions.PromptListChangedHandler = func(ctx context.Context, req *mcp.PromptListChangedRequest) {
+			logger.Info("Received prompts/list_changed from external MCP server", "name", name)
+			handlers.PromptListChanged(ctx, name)
+		}
+	}
+	if handlers.CreateMessage != nil {
+		clientOptions.CreateMessageHandler = func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			logger.Info("Received sampling/createMessage from external MCP server", "name", name)
+			return handlers.CreateMessage(ctx, name, req.Params)
+		}
+	}
+
+	progress := newProgressRouter()
+	clientOptions.ProgressNotificationHandler = func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+		progress.deliver(req.Params)
+	}
+	if handlers.LoggingMessage != nil {
+		clientOptions.LoggingMessageHandler = func(ctx context.Context, req *mcp.LoggingMessageRequest) {
+			handlers.LoggingMessage(ctx, name, req.Params)
+		}
+	}
+
 	// Create MCP client
 	client := mcp.NewClient(
 		&mcp.Implementation{
 			Name:    "one-mcp-aggregator",
 			Version: "0.2.0",
 		},
-		nil,
+		clientOptions,
 	)
 
-	var transport mcp.Transport
+	// Fill in {{configDir}}, {{home}}, {{tempDir}} and {{port:auto}}
+	config, err := expandTemplates(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// A runner is shorthand for its command
+	if config.Runner != "" {
+		expanded, err := expandRunner(config)
+		if err != nil {
+			return nil, err
+		}
+		if config.Version == "" && !packageHasVersion(config.Package) {
+			logger.Info("Runner package isn't pinned to a version, the latest is used", "name", name, "package", config.Package)
+		}
+		config = expanded
+	}
+
+	// Transports are created per connection: a command can only be started once
+	var newTransport func() mcp.Transport
 	var transportType string
+	var cleanup func()
+	var stderr *stderrTail // Set for server processes
+
+	// Servers that reject Streamable HTTP are retried over legacy SSE
+	var newFallback func() mcp.Transport
+	var detector *legacyDetector
 
 	// Determine transport type based on configuration
 	if config.URL != "" {
-		// HTTP-based transport (Streamable HTTP - modern standard)
-		transport = &mcp.StreamableClientTransport{
-			Endpoint:   config.URL,
-			MaxRetries: 5, // Default retry count
+		httpClient, err := newHTTPClient(name, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		newSSE := func() mcp.Transport {
+			return &mcp.SSEClientTransport{
+				Endpoint:   config.URL,
+				HTTPClient: httpClient,
+			}
+		}
+
+		transport := config.Transport
+		if transport == "" && isWebSocketURL(config.URL) {
+			transport = transportWebSocket
 		}
-		transportType = "streamable-http"
-		logger.Info("Using Streamable HTTP transport", "name", name, "endpoint", config.URL)
+
+		switch transport {
+		case transportWebSocket:
+			newTransport = func() mcp.Transport {
+				return &webSocketTransport{
+					url:        config.URL,
+					httpClient: httpClient,
+				}
+			}
+			transportType = transportWebSocket
+			logger.Info("Using WebSocket transport", "name", name, "endpoint", config.URL)
+		case transportSSE:
+			// Legacy HTTP+SSE transport (MCP spec 2024-11-05)
+			newTransport = newSSE
+			transportType = transportSSE
+			logger.Info("Using SSE transport", "name", name, "endpoint", config.URL)
+		case "", transportStreamable:
+			// HTTP-based transport (Streamable HTTP - modern standard)
+			streamableClient := httpClient
+			if transport == "" {
+				detector, streamableClient = newLegacyDetector(httpClient)
+				newFallback = newSSE
+			}
+			newTransport = func() mcp.Transport {
+				return &mcp.StreamableClientTransport{
+					Endpoint:   config.URL,
+					HTTPClient: streamableClient,
+					MaxRetries: 5, // Default retry count
+				}
+			}
+			transportType = transportStreamable
+			logger.Info("Using Streamable HTTP transport", "name", name, "endpoint", config.URL)
+		default:
+			return nil, fmt.Errorf("unknown transport %q: must be %q, %q or %q", config.Transport, transportStreamable, transportSSE, transportWebSocket)
+		}
+	} else if config.Docker != nil {
+		// Command transport (stdio) to a container
+		runner, err := newDockerRunner(name, config.Docker, logger)
+		if err != nil {
+			return nil, err
+		}
+		stderr = &stderrTail{}
+		newTransport = func() mcp.Transport {
+			cmd := runner.command()
+			stderr.reset()
+			cmd.Stderr = &stderrLogger{name: name, logger: logger, tail: stderr}
+			cmd.WaitDelay = stderrWaitDelay
+			return &mcp.CommandTransport{
+				Command: cmd,
+			}
+		}
+		transportType = "docker"
+		cleanup = runner.cleanup
+		logger.Info("Using Docker transport", "name", name, "image", config.Docker.Image)
 	} else if config.Command != "" {
 		// Command transport (stdio)
-		cmd := exec.Command(config.Command, config.Args...)
+		stderr = &stderrTail{}
+		newTransport = func() mcp.Transport {
+			cmd := exec.Command(config.Command, config.Args...)
+			cmd.Dir = config.Cwd
+			stderr.reset()
+			cmd.Stderr = &stderrLogger{name: name, logger: logger, tail: stderr}
+			cmd.WaitDelay = stderrWaitDelay
 
-		// Set environment variables
-		if len(config.Env) > 0 {
-			env := os.Environ() // Start with current environment
-			for k, v := range config.Env {
-				env = append(env, fmt.Sprintf("%s=%s", k, v))
+			// Set environment variables
+			if len(config.Env) > 0 {
+				env := os.Environ() // Start with current environment
+				for k, v := range config.Env {
+					env = append(env, fmt.Sprintf("%s=%s", k, v))
+				}
+				cmd.Env = env
 			}
-			cmd.Env = env
-		}
 
-		transport = &mcp.CommandTransport{
-			Command: cmd,
+			return &mcp.CommandTransport{
+				Command: cmd,
+			}
 		}
 		transportType = "stdio"
 		logger.Info("Using stdio transport", "name", name, "command", config.Command)
 	} else {
-		return nil, fmt.Errorf("no transport configured: must provide either 'command' or 'url'")
+		return nil, fmt.Errorf("no transport configured: must provide 'command', 'docker' or 'url'")
+	}
+
+	closeCtx, cancelClose := context.WithCancel(context.Background())
+	c := &MCPClient{
+		name:          name,
+		client:        client,
+		newTransport:  newTransport,
+		transportType: transportType,
+		cleanup:       cleanup,
+		reconnect:     config.Reconnect == nil || *config.Reconnect,
+		stderr:        stderr,
+		maxRestarts:   cmp.Or(config.MaxRestarts, defaultMaxRestarts),
+		callTimeout:   time.Duration(config.CallTimeout) * time.Second,
+		callCeiling:   time.Duration(max(config.CallCeiling, 0)) * time.Second,
+		restartOnHang: config.RestartOnHang,
+		inflight:      inflightCalls{calls: make(map[uint64]*inflightCall)},
+		slowCall:      time.Duration(max(config.SlowCallThreshold, 0)) * time.Millisecond,
+		metrics:       newCallMetrics(),
+		progress:      progress,
+		handlers:      handlers,
+		closeCtx:      closeCtx,
+		cancelClose:   cancelClose,
+		logger:        logger,
+		schemaCache:   make(map[string]map[string]any),
 	}
 
 	// Connect to the server (this also initializes the connection)
-	session, err := client.Connect(ctx, transport, nil)
+	session, err := c.connect(ctx)
+	if err != nil && detector != nil && detector.legacy.Load() {
+		logger.Info("Server rejected Streamable HTTP, falling back to SSE transport", "name", name, "error", err)
+		c.newTransport = newFallback
+		c.transportType = transportSSE
+		session, err = c.connect(ctx)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MCP server (%s): %w", transportType, err)
+		cancelClose()
+		if cleanup != nil {
+			cleanup()
+		}
+		return nil, err
+	}
+	c.session = session
+	go c.watch(session)
+	if c.callCeiling > 0 {
+		go c.watchdog()
 	}
 
-	logger.Info("Connected to external MCP server", "name", name, "transport", transportType)
+	logger.Info("Connected to external MCP server", "name", name, "transport", c.transportType)
 
-	return &MCPClient{
-		name:        name,
-		session:     session,
-		logger:      logger,
-		schemaCache: make(map[string]map[string]any),
-	}, nil
+	return c, nil
 }
 
 // Initialize is now a no-op since connection happens in NewMCPClient
@@ -110,15 +421,44 @@ func (c *MCPClient) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// ServerInfo returns the server's negotiated protocol version, declared capabilities and instructions.
+func (c *MCPClient) ServerInfo() ServerInfo {
+	init := c.currentSession().InitializeResult()
+	if init == nil {
+		return ServerInfo{}
+	}
+
+	info := ServerInfo{
+		ProtocolVersion: init.ProtocolVersion,
+		Capabilities:    init.Capabilities,
+		Instructions:    init.Instructions,
+	}
+	if init.ServerInfo != nil {
+		info.Name = init.ServerInfo.Name
+		info.Version = init.ServerInfo.Version
+	}
+	return info
+}
+
 // ListTools retrieves all tools from the external MCP server.
 func (c *MCPClient) ListTools(ctx context.Context) ([]Tool, error) {
-	result, err := c.session.ListTools(ctx, &mcp.ListToolsParams{})
-	if err != nil {
-		return nil, fmt.Errorf("tools/list failed: %w", err)
+	// Follow nextCursor so servers that paginate tools/list are listed in full
+	var listed []*mcp.Tool
+	for t, err := range c.currentSession().Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("tools/list failed: %w", err)
+		}
+		listed = append(listed, t)
 	}
 
-	tools := make([]Tool, len(result.Tools))
-	for i, t := range result.Tools {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Reset the cache so tools removed by the server don't linger
+	c.schemaCache = make(map[string]map[string]any)
+
+	tools := make([]Tool, len(listed))
+	for i, t := range listed {
 		// Convert InputSchema to map[string]any and cache it
 		schemaMap := make(map[string]any)
 		if t.InputSchema != nil {
@@ -130,10 +470,14 @@ func (c *MCPClient) ListTools(ctx context.Context) ([]Tool, error) {
 			}
 		}
 
+		outputSchema, _ := t.OutputSchema.(map[string]any)
+
 		tools[i] = Tool{
-			Name:        t.Name,
-			Description: t.Description,
-			InputSchema: schemaMap,
+			Name:         t.Name,
+			Description:  t.Description,
+			InputSchema:  schemaMap,
+			OutputSchema: outputSchema,
+			Annotations:  t.Annotations,
 		}
 	}
 
@@ -141,55 +485,294 @@ func (c *MCPClient) ListTools(ctx context.Context) ([]Tool, error) {
 	return tools, nil
 }
 
+// RefreshTools re-lists the server's tools on demand, replacing the cached
+// schemas, e.g. after the server was updated without a tools/list_changed
+// notification.
+func (c *MCPClient) RefreshTools(ctx context.Context) ([]Tool, error) {
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh tools from %s: %w", c.name, err)
+	}
+	return tools, nil
+}
+
 // GetCachedSchema retrieves a cached schema for a tool
 func (c *MCPClient) GetCachedSchema(toolName string) (map[string]any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	schema, ok := c.schemaCache[toolName]
 	return schema, ok
 }
 
-// CallTool executes a tool on the external MCP server.
-func (c *MCPClient) CallTool(ctx context.Context, toolName string, arguments map[string]any) (any, error) {
-	result, err := c.session.CallTool(ctx, &mcp.CallToolParams{
-		Name:      toolName,
+// ListResources retrieves all resources from the external MCP server.
+// Servers that don't advertise the resources capability have none.
+func (c *MCPClient) ListResources(ctx context.Context) ([]Resource, error) {
+	if init := c.currentSession().InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Resources == nil {
+		return nil, nil
+	}
+
+	var resources []Resource
+	for r, err := range c.currentSession().Resources(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("resources/list failed: %w", err)
+		}
+		resources = append(resources, Resource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Title:       r.Title,
+			Description: r.Description,
+			MIMEType:    r.MIMEType,
+			Size:        r.Size,
+		})
+	}
+
+	c.logger.Info("Listed resources from external MCP server", "name", c.name, "count", len(resources))
+	return resources, nil
+}
+
+// ListResourceTemplates retrieves all resource templates from the external MCP server.
+// Servers that don't advertise the resources capability have none.
+func (c *MCPClient) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	if init := c.currentSession().InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Resources == nil {
+		return nil, nil
+	}
+
+	var templates []ResourceTemplate
+	for t, err := range c.currentSession().ResourceTemplates(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("resources/templates/list failed: %w", err)
+		}
+		templates = append(templates, ResourceTemplate{
+			URITemplate: t.URITemplate,
+			Name:        t.Name,
+			Title:       t.Title,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+		})
+	}
+
+	c.logger.Info("Listed resource templates from external MCP server", "name", c.name, "count", len(templates))
+	return templates, nil
+}
+
+// ReadResource reads a resource from the external MCP server.
+func (c *MCPClient) ReadResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	result, err := c.currentSession().ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("resources/read failed: %w", err)
+	}
+	return result, nil
+}
+
+// ListPrompts retrieves all prompts from the external MCP server.
+// Servers that don't advertise the prompts capability have none.
+func (c *MCPClient) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	if init := c.currentSession().InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Prompts == nil {
+		return nil, nil
+	}
+
+	var prompts []Prompt
+	for p, err := range c.currentSession().Prompts(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("prompts/list failed: %w", err)
+		}
+		prompt := Prompt{
+			Name:        p.Name,
+			Title:       p.Title,
+			Description: p.Description,
+		}
+		for _, arg := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	c.logger.Info("Listed prompts from external MCP server", "name", c.name, "count", len(prompts))
+	return prompts, nil
+}
+
+// GetPrompt renders a prompt template on the external MCP server.
+func (c *MCPClient) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*mcp.GetPromptResult, error) {
+	result, err := c.currentSession().GetPrompt(ctx, &mcp.GetPromptParams{
+		Name:      name,
 		Arguments: arguments,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("tools/call failed: %w", err)
+		return nil, fmt.Errorf("prompts/get failed: %w", err)
 	}
+	return result, nil
+}
 
-	// Extract the result from the response
-	// The official SDK returns CallToolResult with Content
-	if result.IsError {
-		// Tool execution failed
-		errorMsg := "unknown error"
-		if len(result.Content) > 0 {
-			if textContent, ok := result.Content[0].(*mcp.TextContent); ok {
-				errorMsg = textContent.Text
-			}
+// Complete asks the server for argument completions of a prompt or resource.
+// Servers that don't advertise the completions capability have none.
+func (c *MCPClient) Complete(ctx context.Context, params *mcp.CompleteParams) (*mcp.CompleteResult, error) {
+	if init := c.currentSession().InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Completions == nil {
+		return &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}}}, nil
+	}
+	result, err := c.currentSession().Complete(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("completion/complete failed: %w", err)
+	}
+	return result, nil
+}
+
+// Ping checks that the server still answers requests.
+func (c *MCPClient) Ping(ctx context.Context) error {
+	if err := c.currentSession().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// SetLogLevel asks the server to send log messages at or above level.
+// Servers that don't advertise the logging capability are left alone.
+func (c *MCPClient) SetLogLevel(ctx context.Context, level mcp.LoggingLevel) error {
+	if init := c.currentSession().InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Logging == nil {
+		return nil
+	}
+	if err := c.currentSession().SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: level}); err != nil {
+		return fmt.Errorf("logging/setLevel failed: %w", err)
+	}
+	return nil
+}
+
+// SetRoots replaces the roots the client answers roots/list with and sends
+// notifications/roots/list_changed to the server when they change.
+func (c *MCPClient) SetRoots(roots []*mcp.Root) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keep := make(map[string]bool, len(roots))
+	uris := make([]string, len(roots))
+	for i, root := range roots {
+		keep[root.URI] = true
+		uris[i] = root.URI
+	}
+
+	var stale []string
+	for _, uri := range c.rootURIs {
+		if !keep[uri] {
+			stale = append(stale, uri)
 		}
-		return nil, fmt.Errorf("tool execution error: %s", errorMsg)
 	}
+	c.client.RemoveRoots(stale...)
+	c.client.AddRoots(roots...)
+	c.rootURIs = uris
+
+	c.logger.Info("Updated roots for external MCP server", "name", c.name, "count", len(roots))
+}
 
-	// Success - extract content
-	resultMap := make(map[string]any)
-	for i, content := range result.Content {
-		if textContent, ok := content.(*mcp.TextContent); ok {
-			if i == 0 && len(result.Content) == 1 {
-				// Single text content - try to return as-is
-				resultMap["content"] = textContent.Text
-			} else {
-				// Multiple contents - store by index
-				resultMap[fmt.Sprintf("content_%d", i)] = textContent.Text
+// CallTool executes a tool on the external MCP server.
+// If ctx is cancelled mid-call, the server is sent notifications/cancelled
+// so it can stop the work instead of finishing it for nobody.
+// The result is returned as the server sent it: content blocks, structured
+// content and _meta. A tool that reports an error (IsError) is not a failed
+// call, so it comes back as a result, not as an error.
+func (c *MCPClient) CallTool(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	return c.CallToolWithTimeout(ctx, toolName, arguments, c.callTimeout)
+}
+
+// CallToolWithTimeout calls a tool, giving up after timeout (0 for no limit)
+// with a *CallTimeoutError. The server is told to cancel the call.
+func (c *MCPClient) CallToolWithTimeout(ctx context.Context, toolName string, arguments map[string]any, timeout time.Duration) (*mcp.CallToolResult, error) {
+	if reason := c.downReason(); reason != "" {
+		err := &ServerDownError{Server: c.name, Reason: reason}
+		c.logger.WarnContext(ctx, "Tool call on unavailable external MCP server", "name", c.name, "tool", toolName, "reason", reason)
+		return nil, err
+	}
+
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	callCtx, untrack := c.track(callCtx, toolName)
+	defer untrack()
+
+	callCtx, span := tracer.Start(callCtx, "tools/call "+toolName, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("onemcp.server", c.name), attribute.String("onemcp.tool", toolName)))
+	var spanErr error
+	defer func() { telemetry.EndSpan(span, spanErr) }()
+
+	params := &mcp.CallToolParams{
+		Name:      toolName,
+		Arguments: arguments,
+	}
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		token, done := c.progress.register(fn)
+		defer done()
+		// Set directly: SetProgressToken loses the token when Meta is nil
+		params.Meta = mcp.Meta{"progressToken": token}
+	}
+	// The server joins the trace if it reads the trace context from _meta
+	params.Meta = telemetry.InjectMeta(callCtx, params.Meta)
+
+	session := c.currentSession()
+	start := time.Now()
+	result, err := session.CallTool(callCtx, params)
+	latency := time.Since(start)
+	if c.slowCall > 0 && latency >= c.slowCall && ctx.Err() == nil {
+		c.logger.WarnContext(ctx, "Slow tool call on external MCP server", "name", c.name, "tool", toolName,
+			"latency_ms", latency.Milliseconds(), "threshold_ms", c.slowCall.Milliseconds())
+		c.metrics.recordSlow()
+	}
+	if err != nil {
+		if ctx.Err() == nil && callCtx.Err() != nil {
+			// Past the call's timeout, or cancelled by the watchdog
+			timeoutErr, hung := context.Cause(callCtx).(*CallTimeoutError)
+			if !hung {
+				timeoutErr = &CallTimeoutError{Server: c.name, Tool: toolName, Limit: timeout}
+			}
+			c.logger.WarnContext(ctx, "Tool call on external MCP server timed out", "name", c.name, "tool", toolName, "timeout", timeoutErr.Limit)
+			if hung && c.restartOnHang && c.reconnect {
+				c.logger.WarnContext(ctx, "Reconnecting to external MCP server after a hung call", "name", c.name)
+				go session.Close() // watch reconnects
 			}
+			spanErr = timeoutErr
+			c.metrics.record(latency, spanErr)
+			return nil, spanErr
+		}
+		spanErr = fmt.Errorf("tools/call failed: %w", err)
+		if errors.Is(err, mcp.ErrConnectionClosed) || errors.Is(err, io.EOF) {
+			// The process exited or the connection dropped mid-call
+			spanErr = &ServerDownError{Server: c.name, Reason: "the connection closed during the call", Err: err}
 		}
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the server
+			c.logger.InfoContext(ctx, "Cancelled tool call on external MCP server", "name", c.name, "tool", toolName, "reason", ctx.Err())
+			return nil, spanErr
+		}
+		c.metrics.record(latency, spanErr)
+		return nil, spanErr
 	}
 
-	return resultMap, nil
+	if result.IsError {
+		c.logger.InfoContext(ctx, "External MCP server tool reported an error", "name", c.name, "tool", toolName)
+		spanErr = toolError(toolName, result)
+		c.metrics.record(latency, spanErr)
+		return result, nil
+	}
+	c.metrics.record(latency, nil)
+	return result, nil
+}
+
+// Metrics returns the latency and error totals of the tool calls made so far
+func (c *MCPClient) Metrics() CallMetrics {
+	return c.metrics.snapshot()
 }
 
 // Close terminates the connection to the external MCP server.
 func (c *MCPClient) Close() error {
-	if err := c.session.Close(); err != nil {
+	c.cancelClose()
+	if c.cleanup != nil {
+		defer c.cleanup()
+	}
+	if err := c.currentSession().Close(); err != nil {
 		c.logger.Warn("External MCP server close error", "name", c.name, "error", err)
 		return err
 	}
@@ -0,0 +1,118 @@
+package mcpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DockerConfig runs a server in a container, talking to it over stdio
+// (docker run -i). The container is removed when the client is closed.
+type DockerConfig struct {
+	Image   string            `json:"image"`             // Image to run
+	Args    []string          `json:"args,omitempty"`    // Arguments passed to the image's entrypoint
+	Volumes []string          `json:"volumes,omitempty"` // Bind mounts, "host:container[:ro]"
+	Env     map[string]string `json:"env,omitempty"`     // Environment variables set in the container; $VAR and ${VAR} are expanded
+	Pull    string            `json:"pull,omitempty"`    // When to pull the image: "missing", "always" or "never" (default: "missing")
+}
+
+// dockerCleanupTimeout bounds removing a container on Close
+const dockerCleanupTimeout = 10 * time.Second
+
+// invalidContainerChars are the characters not allowed in container names
+var invalidContainerChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// dockerRunner starts a server's containers and removes the last one on cleanup
+type dockerRunner struct {
+	name   string
+	config DockerConfig
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	container string // Name of the container started last
+}
+
+// newDockerRunner validates a docker configuration and checks that the docker
+// CLI is available
+func newDockerRunner(name string, config *DockerConfig, logger *slog.Logger) (*dockerRunner, error) {
+	if config.Image == "" {
+		return nil, fmt.Errorf("docker.image is required")
+	}
+	switch config.Pull {
+	case "", "missing", "always", "never":
+	default:
+		return nil, fmt.Errorf("invalid docker.pull %q: must be \"missing\", \"always\" or \"never\"", config.Pull)
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("docker is required to run image %s: %w", config.Image, err)
+	}
+	return &dockerRunner{name: name, config: *config, logger: logger}, nil
+}
+
+// command returns the docker run command for a new container. Each
+// connection gets its own container, named after the server.
+func (d *dockerRunner) command() *exec.Cmd {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	container := "onemcp-" + invalidContainerChars.ReplaceAllString(d.name, "-") + "-" + hex.EncodeToString(suffix)
+
+	pull := d.config.Pull
+	if pull == "" {
+		pull = "missing"
+	}
+	args := []string{"run", "-i", "--rm", "--name", container, "--pull", pull}
+	for _, volume := range d.config.Volumes {
+		args = append(args, "--volume", os.ExpandEnv(volume))
+	}
+
+	// Values are passed through the docker CLI's environment so they don't
+	// show up in the process list
+	keys := make([]string, 0, len(d.config.Env))
+	for key := range d.config.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := os.Environ()
+	for _, key := range keys {
+		args = append(args, "--env", key)
+		env = append(env, key+"="+os.ExpandEnv(d.config.Env[key]))
+	}
+
+	args = append(args, d.config.Image)
+	args = append(args, d.config.Args...)
+
+	d.mu.Lock()
+	d.container = container
+	d.mu.Unlock()
+
+	cmd := exec.Command("docker", args...)
+	cmd.Env = env
+	return cmd
+}
+
+// cleanup force-removes the last container, in case it outlived its docker
+// run process
+func (d *dockerRunner) cleanup() {
+	d.mu.Lock()
+	container := d.container
+	d.mu.Unlock()
+	if container == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerCleanupTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "docker", "rm", "--force", container).CombinedOutput()
+	if err != nil && !strings.Contains(string(output), "No such container") {
+		d.logger.Warn("Failed to remove container", "name", d.name, "container", container, "error", err, "output", strings.TrimSpace(string(output)))
+	}
+}
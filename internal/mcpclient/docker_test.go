@@ -0,0 +1,60 @@
+package mcpclient
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDockerRunnerCommand tests the docker run command built for a container
+func TestDockerRunnerCommand(t *testing.T) {
+	t.Setenv("DATA_DIR", "/srv/data")
+	t.Setenv("API_TOKEN", "secret")
+	runner := &dockerRunner{
+		name: "my server",
+		config: DockerConfig{
+			Image:   "example/mcp:1.0",
+			Args:    []string{"--verbose"},
+			Volumes: []string{"$DATA_DIR:/data:ro"},
+			Env:     map[string]string{"TOKEN": "${API_TOKEN}", "MODE": "fast"},
+		},
+		logger: testLogger(),
+	}
+
+	cmd := runner.command()
+	args := cmd.Args[1:]
+	require.Equal(t, "docker", cmd.Args[0])
+	require.Equal(t, []string{"run", "-i", "--rm", "--name"}, args[:4])
+	require.Regexp(t, regexp.MustCompile(`^onemcp-my-server-[0-9a-f]{8}$`), args[4])
+	require.Equal(t, []string{
+		"--pull", "missing",
+		"--volume", "/srv/data:/data:ro",
+		"--env", "MODE", "--env", "TOKEN",
+		"example/mcp:1.0", "--verbose",
+	}, args[5:])
+	require.Equal(t, args[4], runner.container)
+
+	// Values only go through the environment, so they stay out of the process list
+	require.NotContains(t, args, "secret")
+	require.True(t, slices.Contains(cmd.Env, "TOKEN=secret"))
+	require.True(t, slices.Contains(cmd.Env, "MODE=fast"))
+
+	// Each connection gets a new container
+	require.NotEqual(t, args[4], runner.command().Args[5])
+}
+
+// TestNewDockerRunner_Invalid tests that invalid docker configs and a missing docker CLI are reported
+func TestNewDockerRunner_Invalid(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := newDockerRunner("server", &DockerConfig{}, testLogger())
+	require.ErrorContains(t, err, "docker.image is required")
+
+	_, err = newDockerRunner("server", &DockerConfig{Image: "example/mcp", Pull: "sometimes"}, testLogger())
+	require.ErrorContains(t, err, `invalid docker.pull "sometimes"`)
+
+	_, err = newDockerRunner("server", &DockerConfig{Image: "example/mcp"}, testLogger())
+	require.ErrorContains(t, err, "docker is required to run image example/mcp")
+}
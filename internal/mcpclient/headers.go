@@ -0,0 +1,79 @@
+package mcpclient
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// headerTransport adds fixed headers to every HTTP request sent to a remote server
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+// RoundTrip sets the configured headers on a copy of the request and sends it
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.headers {
+		req.Header[key] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient returns the HTTP client for a remote server: the default client,
+// or one that adds the configured headers and bearer token, uses the configured
+// proxy and TLS settings and authorizes with OAuth. Header values may reference
+// environment variables ($VAR or ${VAR}) so secrets can stay out of the config file.
+// Without a proxy setting, HTTP_PROXY, HTTPS_PROXY and NO_PROXY apply.
+func newHTTPClient(name string, config MCPServerConfig, logger *slog.Logger) (*http.Client, error) {
+	if len(config.Headers) == 0 && config.BearerToken == "" && config.OAuth == nil && config.TLS == nil && config.Proxy == "" {
+		return nil, nil // The SDK uses http.DefaultClient
+	}
+
+	base := http.DefaultTransport
+	if config.TLS != nil || config.Proxy != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if config.TLS != nil {
+			tlsConfig, err := newTLSConfig(config.TLS)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+			}
+			if config.TLS.SkipVerify {
+				logger.Warn("TLS certificate verification is disabled", "name", name)
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+		if config.Proxy != "" {
+			proxy, err := proxyURL(config.Proxy)
+			if err != nil {
+				return nil, err
+			}
+			transport.Proxy = http.ProxyURL(proxy)
+			logger.Info("Using proxy", "name", name, "proxy", proxy.Redacted())
+		}
+		base = transport
+	}
+
+	headers := make(http.Header, len(config.Headers)+1)
+	for key, value := range config.Headers {
+		headers.Set(key, os.ExpandEnv(value))
+	}
+	if config.BearerToken != "" {
+		headers.Set("Authorization", "Bearer "+os.ExpandEnv(config.BearerToken))
+	}
+
+	var transport http.RoundTripper = &headerTransport{base: base, headers: headers}
+	if config.OAuth != nil {
+		transport = &oauthTransport{
+			base:       transport,
+			httpClient: &http.Client{Transport: base},
+			name:       name,
+			resource:   config.URL,
+			config:     *config.OAuth,
+			logger:     logger,
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,116 @@
+package mcpclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// latencyBucketsMs are the upper bounds of the call latency histogram
+var latencyBucketsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// LatencyBucket counts the calls that took at most LeMs milliseconds. The
+// last bucket has no bound and counts every call.
+type LatencyBucket struct {
+	LeMs  int64 `json:"le_ms,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// CallMetrics summarizes the tool calls made to one server. Errors include
+// timeouts and errors reported by the tool itself.
+type CallMetrics struct {
+	Calls          int64           `json:"calls"`
+	Errors         int64           `json:"errors"`
+	Timeouts       int64           `json:"timeouts"`
+	SlowCalls      int64           `json:"slow_calls"` // Calls that took at least slowCallThreshold
+	TotalLatencyMs int64           `json:"total_latency_ms"`
+	AvgLatencyMs   int64           `json:"avg_latency_ms"`
+	MaxLatencyMs   int64           `json:"max_latency_ms"`
+	LatencyBuckets []LatencyBucket `json:"latency_buckets"` // Cumulative, like a Prometheus histogram
+	LastError      string          `json:"last_error,omitempty"`
+	LastErrorAt    time.Time       `json:"last_error_at,omitzero"`
+}
+
+// callMetrics accumulates a client's CallMetrics
+type callMetrics struct {
+	mu      sync.Mutex
+	metrics CallMetrics
+	buckets []int64 // Calls per latency bucket (not cumulative); the last one has no bound
+}
+
+// newCallMetrics creates an empty accumulator
+func newCallMetrics() *callMetrics {
+	return &callMetrics{buckets: make([]int64, len(latencyBucketsMs)+1)}
+}
+
+// record adds one call. callErr is the error reported by the tool (IsError)
+// or the failed call.
+func (m *callMetrics) record(latency time.Duration, callErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ms := latency.Milliseconds()
+	bucket := len(latencyBucketsMs)
+	for i, le := range latencyBucketsMs {
+		if ms <= le {
+			bucket = i
+			break
+		}
+	}
+	m.buckets[bucket]++
+
+	metrics := &m.metrics
+	metrics.Calls++
+	metrics.TotalLatencyMs += ms
+	metrics.MaxLatencyMs = max(metrics.MaxLatencyMs, ms)
+	metrics.AvgLatencyMs = metrics.TotalLatencyMs / metrics.Calls
+
+	if callErr == nil {
+		return
+	}
+	metrics.Errors++
+	var timeout *CallTimeoutError
+	if errors.As(callErr, &timeout) {
+		metrics.Timeouts++
+	}
+	metrics.LastError = callErr.Error()
+	metrics.LastErrorAt = time.Now()
+}
+
+// recordSlow counts a call that exceeded the slow call threshold
+func (m *callMetrics) recordSlow() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.SlowCalls++
+}
+
+// snapshot returns the totals so far
+func (m *callMetrics) snapshot() CallMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := m.metrics
+	snapshot.LatencyBuckets = make([]LatencyBucket, len(m.buckets))
+	var count int64
+	for i, n := range m.buckets {
+		count += n
+		snapshot.LatencyBuckets[i].Count = count
+		if i < len(latencyBucketsMs) {
+			snapshot.LatencyBuckets[i].LeMs = latencyBucketsMs[i]
+		}
+	}
+	return snapshot
+}
+
+// toolError describes a result with IsError set by its first text block
+func toolError(toolName string, result *mcp.CallToolResult) error {
+	for _, content := range result.Content {
+		if text, ok := content.(*mcp.TextContent); ok && text.Text != "" {
+			return fmt.Errorf("%s reported an error: %s", toolName, text.Text)
+		}
+	}
+	return fmt.Errorf("%s reported an error", toolName)
+}
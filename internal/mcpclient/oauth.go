@@ -0,0 +1,624 @@
+package mcpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth flows (OAuthConfig.Flow)
+const (
+	oauthFlowBrowser = "browser" // Authorization code with PKCE, redirected to a loopback listener
+	oauthFlowDevice  = "device"  // Device authorization grant: the user enters a code on another device
+)
+
+// tokenRefreshMargin is how long before expiry an access token is refreshed
+const tokenRefreshMargin = 30 * time.Second
+
+// OAuthConfig configures the MCP authorization flow for a remote server.
+type OAuthConfig struct {
+	ClientID     string   `json:"clientId,omitempty"`     // Pre-registered client ID (default: dynamic client registration)
+	ClientSecret string   `json:"clientSecret,omitempty"` // Secret of a confidential client; $VAR and ${VAR} are expanded
+	Scopes       []string `json:"scopes,omitempty"`       // Scopes to request (default: the scopes the server advertises)
+	Flow         string   `json:"flow,omitempty"`         // "browser" or "device" (default: "browser")
+	RedirectPort int      `json:"redirectPort,omitempty"` // Loopback port of the browser flow's redirect URI (default: random)
+	TokenFile    string   `json:"tokenFile,omitempty"`    // File tokens are cached in (default: <cacheDir>/oauth/<server>.json)
+}
+
+// oauthToken is what the token file holds: the tokens and what is needed to refresh them
+type oauthToken struct {
+	AccessToken   string    `json:"access_token"`
+	RefreshToken  string    `json:"refresh_token,omitempty"`
+	Expiry        time.Time `json:"expiry,omitzero"`
+	TokenEndpoint string    `json:"token_endpoint"`
+	ClientID      string    `json:"client_id"`
+	ClientSecret  string    `json:"client_secret,omitempty"`
+}
+
+// expired reports whether the access token is about to expire
+func (t *oauthToken) expired() bool {
+	return !t.Expiry.IsZero() && time.Now().Add(tokenRefreshMargin).After(t.Expiry)
+}
+
+// tokenResponse is a token endpoint response (RFC 6749 section 5)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// authServerMeta is the part of the authorization server metadata (RFC 8414) the flows use
+type authServerMeta struct {
+	AuthorizationEndpoint       string   `json:"authorization_endpoint"`
+	TokenEndpoint               string   `json:"token_endpoint"`
+	RegistrationEndpoint        string   `json:"registration_endpoint"`
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint"`
+	ScopesSupported             []string `json:"scopes_supported"`
+}
+
+// oauthTransport authorizes requests to an MCP server with an OAuth access
+// token. Tokens are loaded from the token file and refreshed when they
+// expire; when the server answers 401 the authorization flow is run and the
+// request is retried once.
+type oauthTransport struct {
+	base       http.RoundTripper
+	httpClient *http.Client // For OAuth requests: TLS settings apply, the server's headers don't
+	name       string       // Server name, for logs and prompts
+	resource   string       // URL of the MCP server, the protected resource
+	config     OAuthConfig
+	logger     *slog.Logger
+
+	mu    sync.Mutex // Serializes token loading, refreshing and authorization
+	token *oauthToken
+}
+
+// RoundTrip sends the request with the current access token, authorizing and
+// retrying once if the server rejects it
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.currentToken(req.Context())
+	resp, err := t.base.RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The request is only retried if its body can be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	fresh, authErr := t.authorize(req.Context(), token, resp)
+	if authErr != nil {
+		t.logger.Error("OAuth authorization failed", "name", t.name, "error", authErr)
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(withToken(retry, fresh))
+}
+
+// withToken returns a copy of the request carrying the access token, if any
+func withToken(req *http.Request, token *oauthToken) *http.Request {
+	if token == nil {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return req
+}
+
+// currentToken returns the cached token, loading it from the token file and
+// refreshing it if it expired. It returns nil if there is no usable token.
+func (t *oauthTransport) currentToken(ctx context.Context) *oauthToken {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == nil {
+		t.token = t.loadToken()
+	}
+	if t.token != nil && t.token.expired() {
+		if t.token.RefreshToken == "" {
+			t.token = nil
+		} else if err := t.refresh(ctx); err != nil {
+			t.logger.Warn("Failed to refresh OAuth token", "name", t.name, "error", err)
+			t.token = nil
+		}
+	}
+	return t.token
+}
+
+// authorize runs the authorization flow after the server rejected a request
+// sent with the rejected token (nil if none was sent). If another request
+// already replaced that token, the new one is returned instead.
+func (t *oauthTransport) authorize(ctx context.Context, rejected *oauthToken, resp *http.Response) (*oauthToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != nil && t.token != rejected {
+		return t.token, nil
+	}
+	t.token = nil
+
+	meta, scopes, err := t.discover(ctx, resp.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.config.Flow {
+	case "", oauthFlowBrowser:
+		err = t.browserFlow(ctx, meta, scopes)
+	case oauthFlowDevice:
+		err = t.deviceFlow(ctx, meta, scopes)
+	default:
+		err = fmt.Errorf("unknown OAuth flow %q", t.config.Flow)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.saveToken()
+	t.logger.Info("Authorized with external MCP server", "name", t.name)
+	return t.token, nil
+}
+
+// discover finds the authorization server of the MCP server through its
+// protected resource metadata (RFC 9728), falling back to the server's own
+// origin, and fetches the authorization server's metadata
+func (t *oauthTransport) discover(ctx context.Context, header http.Header) (*authServerMeta, []string, error) {
+	resourceURL, err := url.Parse(t.resource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+	origin := resourceURL.Scheme + "://" + resourceURL.Host
+
+	metadataURL := resourceMetadataURL(header)
+	if metadataURL == "" {
+		metadataURL = origin + "/.well-known/oauth-protected-resource"
+	}
+
+	issuer := origin
+	var scopes []string
+	var resourceMeta struct {
+		AuthorizationServers []string `json:"authorization_servers"`
+		ScopesSupported      []string `json:"scopes_supported"`
+	}
+	if err := t.getJSON(ctx, metadataURL, &resourceMeta); err != nil {
+		t.logger.Debug("No protected resource metadata, using the server origin as authorization server", "name", t.name, "error", err)
+	} else if len(resourceMeta.AuthorizationServers) > 0 {
+		issuer = resourceMeta.AuthorizationServers[0]
+		scopes = resourceMeta.ScopesSupported
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid authorization server %q: %w", issuer, err)
+	}
+	issuerPath := strings.TrimSuffix(issuerURL.Path, "/")
+	issuerOrigin := issuerURL.Scheme + "://" + issuerURL.Host
+
+	var meta authServerMeta
+	err = t.getJSON(ctx, issuerOrigin+"/.well-known/oauth-authorization-server"+issuerPath, &meta)
+	if err != nil {
+		err = t.getJSON(ctx, issuerOrigin+"/.well-known/openid-configuration"+issuerPath, &meta)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch authorization server metadata from %s: %w", issuer, err)
+	}
+	if meta.TokenEndpoint == "" {
+		return nil, nil, fmt.Errorf("authorization server %s has no token endpoint", issuer)
+	}
+
+	if len(t.config.Scopes) > 0 {
+		scopes = t.config.Scopes
+	} else if len(scopes) == 0 {
+		scopes = meta.ScopesSupported
+	}
+	return &meta, scopes, nil
+}
+
+// resourceMetadataURL returns the resource_metadata parameter of a Bearer
+// WWW-Authenticate challenge, or "" if there is none
+func resourceMetadataURL(header http.Header) string {
+	for _, challenge := range header.Values("WWW-Authenticate") {
+		_, params, found := strings.Cut(challenge, "resource_metadata=")
+		if !found {
+			continue
+		}
+		params = strings.TrimPrefix(params, `"`)
+		if end := strings.IndexAny(params, `",`); end >= 0 {
+			params = params[:end]
+		}
+		return params
+	}
+	return ""
+}
+
+// browserFlow runs the authorization code flow with PKCE. The user is sent to
+// the authorization endpoint, which redirects back to a loopback listener.
+func (t *oauthTransport) browserFlow(ctx context.Context, meta *authServerMeta, scopes []string) error {
+	if meta.AuthorizationEndpoint == "" {
+		return errors.New("authorization server has no authorization endpoint")
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", t.config.RedirectPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr())
+
+	clientID, clientSecret, err := t.client(ctx, meta, []string{"authorization_code", "refresh_token"}, redirectURI)
+	if err != nil {
+		return err
+	}
+
+	verifier := randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomString()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {t.resource},
+	}
+	if len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	authURL := meta.AuthorizationEndpoint + "?" + query.Encode()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		params := r.URL.Query()
+		var result callback
+		switch {
+		case params.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s %s", params.Get("error"), params.Get("error_description"))
+		case params.Get("state") != state:
+			result.err = errors.New("authorization response has the wrong state")
+		default:
+			result.code = params.Get("code")
+		}
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "OneMCP is authorized. You can close this window.")
+		}
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	t.prompt(fmt.Sprintf("Open this URL to authorize OneMCP with %s: %s", t.name, authURL))
+	openBrowser(authURL)
+
+	var result callback
+	select {
+	case result = <-callbacks:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	return t.requestToken(ctx, meta.TokenEndpoint, clientID, clientSecret, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+// deviceFlow runs the device authorization grant (RFC 8628): the user enters
+// a code at a verification URL while the token endpoint is polled
+func (t *oauthTransport) deviceFlow(ctx context.Context, meta *authServerMeta, scopes []string) error {
+	if meta.DeviceAuthorizationEndpoint == "" {
+		return errors.New("authorization server has no device authorization endpoint")
+	}
+
+	const deviceGrant = "urn:ietf:params:oauth:grant-type:device_code"
+	clientID, clientSecret, err := t.client(ctx, meta, []string{deviceGrant, "refresh_token"}, "")
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"client_id": {clientID}, "resource": {t.resource}}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+	var device struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := t.postForm(ctx, meta.DeviceAuthorizationEndpoint, form, &device); err != nil {
+		return fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	if device.VerificationURIComplete != "" {
+		t.prompt(fmt.Sprintf("Open %s to authorize OneMCP with %s (code %s)", device.VerificationURIComplete, t.name, device.UserCode))
+	} else {
+		t.prompt(fmt.Sprintf("Open %s and enter code %s to authorize OneMCP with %s", device.VerificationURI, device.UserCode, t.name))
+	}
+
+	interval := time.Duration(max(device.Interval, 1)) * time.Second
+	if device.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(device.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for device authorization: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		err := t.requestToken(ctx, meta.TokenEndpoint, clientID, clientSecret, url.Values{
+			"grant_type":  {deviceGrant},
+			"device_code": {device.DeviceCode},
+		})
+		var tokenErr *tokenError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &tokenErr) && tokenErr.code == "authorization_pending":
+		case errors.As(err, &tokenErr) && tokenErr.code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return err
+		}
+	}
+}
+
+// client returns the configured client credentials, or registers a client
+// dynamically (RFC 7591) if none are configured
+func (t *oauthTransport) client(ctx context.Context, meta *authServerMeta, grantTypes []string, redirectURI string) (clientID, clientSecret string, err error) {
+	if t.config.ClientID != "" {
+		return t.config.ClientID, os.ExpandEnv(t.config.ClientSecret), nil
+	}
+	if meta.RegistrationEndpoint == "" {
+		return "", "", errors.New("no clientId configured and the authorization server doesn't support dynamic client registration")
+	}
+
+	registration := map[string]any{
+		"client_name":                "OneMCP",
+		"grant_types":                grantTypes,
+		"token_endpoint_auth_method": "none",
+	}
+	if redirectURI != "" {
+		registration["redirect_uris"] = []string{redirectURI}
+		registration["response_types"] = []string{"code"}
+	}
+	body, _ := json.Marshal(registration)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := t.do(req, &registered); err != nil {
+		return "", "", fmt.Errorf("client registration failed: %w", err)
+	}
+	if registered.ClientID == "" {
+		return "", "", errors.New("client registration returned no client_id")
+	}
+	t.logger.Info("Registered OAuth client", "name", t.name, "client_id", registered.ClientID)
+	return registered.ClientID, registered.ClientSecret, nil
+}
+
+// refresh exchanges the refresh token for a new access token
+func (t *oauthTransport) refresh(ctx context.Context) error {
+	token := t.token
+	err := t.requestToken(ctx, token.TokenEndpoint, token.ClientID, token.ClientSecret, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+	if t.token.RefreshToken == "" {
+		t.token.RefreshToken = token.RefreshToken // Servers may keep the old refresh token valid
+	}
+	t.saveToken()
+	t.logger.Info("Refreshed OAuth token", "name", t.name)
+	return nil
+}
+
+// tokenError is an error response of the token endpoint
+type tokenError struct {
+	code        string
+	description string
+}
+
+func (e *tokenError) Error() string {
+	if e.description != "" {
+		return e.code + ": " + e.description
+	}
+	return e.code
+}
+
+// requestToken calls the token endpoint and stores the returned token
+func (t *oauthTransport) requestToken(ctx context.Context, endpoint, clientID, clientSecret string, form url.Values) error {
+	form.Set("client_id", clientID)
+	form.Set("resource", t.resource)
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+
+	var response tokenResponse
+	err := t.postForm(ctx, endpoint, form, &response)
+	if response.Error != "" {
+		return &tokenError{code: response.Error, description: response.ErrorDescription}
+	}
+	if err != nil {
+		return fmt.Errorf("token request failed: %w", err)
+	}
+	if response.AccessToken == "" {
+		return errors.New("token response has no access_token")
+	}
+
+	t.token = &oauthToken{
+		AccessToken:   response.AccessToken,
+		RefreshToken:  response.RefreshToken,
+		TokenEndpoint: endpoint,
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+	}
+	if response.ExpiresIn > 0 {
+		t.token.Expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return nil
+}
+
+// postForm posts a form and decodes the JSON response into out. Error
+// responses are decoded too, so OAuth error codes can be read from out.
+func (t *oauthTransport) postForm(ctx context.Context, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return t.do(req, out)
+}
+
+// getJSON fetches a JSON document into out
+func (t *oauthTransport) getJSON(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return t.do(req, out)
+}
+
+// do sends an OAuth request and decodes its JSON response into out. The
+// response is decoded even if the status is an error.
+func (t *oauthTransport) do(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, out)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.URL, decodeErr)
+	}
+	return nil
+}
+
+// loadToken reads the cached token, or returns nil if there is none
+func (t *oauthTransport) loadToken() *oauthToken {
+	data, err := os.ReadFile(t.config.TokenFile)
+	if err != nil {
+		return nil
+	}
+	var token oauthToken
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" {
+		t.logger.Warn("Ignoring invalid OAuth token file", "name", t.name, "path", t.config.TokenFile, "error", err)
+		return nil
+	}
+	return &token
+}
+
+// saveToken writes the current token to the token file, readable only by the user
+func (t *oauthTransport) saveToken() {
+	if t.config.TokenFile == "" {
+		return
+	}
+	data, err := json.Marshal(t.token)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(t.config.TokenFile), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(t.config.TokenFile, data, 0600)
+	}
+	if err != nil {
+		t.logger.Warn("Failed to save OAuth token", "name", t.name, "path", t.config.TokenFile, "error", err)
+	}
+}
+
+// prompt tells the user what to do to authorize. Stdout carries the MCP
+// protocol in stdio mode, so the message goes to stderr and the log.
+func (t *oauthTransport) prompt(message string) {
+	t.logger.Warn("OAuth authorization required", "name", t.name, "action", message)
+	fmt.Fprintln(os.Stderr, message)
+}
+
+// openBrowser tries to open a URL in the user's browser
+func openBrowser(target string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+}
+
+// randomString returns 32 random bytes, base64url encoded (a PKCE verifier or state)
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,121 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLogger returns a logger that only reports errors, to keep test output quiet
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// newTestOAuthTransport returns an OAuth transport caching tokens in a temporary file
+func newTestOAuthTransport(t *testing.T) *oauthTransport {
+	return &oauthTransport{
+		base:       http.DefaultTransport,
+		httpClient: http.DefaultClient,
+		name:       "remote",
+		resource:   "https://mcp.example.com/mcp",
+		config:     OAuthConfig{TokenFile: filepath.Join(t.TempDir(), "oauth", "remote.json")},
+		logger:     testLogger(),
+	}
+}
+
+// writeToken stores a token in the transport's token file
+func writeToken(t *testing.T, transport *oauthTransport, token oauthToken) {
+	data, err := json.Marshal(token)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(transport.config.TokenFile), 0700))
+	require.NoError(t, os.WriteFile(transport.config.TokenFile, data, 0600))
+}
+
+// TestOAuthTransport_CachedToken tests that the token file is loaded once and its token reused
+func TestOAuthTransport_CachedToken(t *testing.T) {
+	transport := newTestOAuthTransport(t)
+	require.Nil(t, transport.currentToken(context.Background()), "No token file yet")
+
+	writeToken(t, transport, oauthToken{AccessToken: "cached", Expiry: time.Now().Add(time.Hour)})
+	token := transport.currentToken(context.Background())
+	require.NotNil(t, token)
+	require.Equal(t, "cached", token.AccessToken)
+
+	// Later calls use the token in memory
+	require.NoError(t, os.Remove(transport.config.TokenFile))
+	require.Same(t, token, transport.currentToken(context.Background()))
+
+	req := withToken(httptest.NewRequest(http.MethodGet, "/mcp", nil), token)
+	require.Equal(t, "Bearer cached", req.Header.Get("Authorization"))
+}
+
+// TestOAuthTransport_RefreshesExpiredToken tests that an expiring token is refreshed and the new one saved privately
+func TestOAuthTransport_RefreshesExpiredToken(t *testing.T) {
+	var form map[string][]string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "fresh", "expires_in": 3600})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	transport := newTestOAuthTransport(t)
+	writeToken(t, transport, oauthToken{
+		AccessToken:   "stale",
+		RefreshToken:  "refresh-me",
+		Expiry:        time.Now().Add(10 * time.Second), // Within the refresh margin
+		TokenEndpoint: tokenServer.URL,
+		ClientID:      "client",
+	})
+
+	token := transport.currentToken(context.Background())
+	require.NotNil(t, token)
+	require.Equal(t, "fresh", token.AccessToken)
+	require.Equal(t, "refresh-me", token.RefreshToken, "The old refresh token is kept when none is returned")
+	require.Equal(t, []string{"refresh_token"}, form["grant_type"])
+	require.Equal(t, []string{"refresh-me"}, form["refresh_token"])
+	require.Equal(t, []string{"https://mcp.example.com/mcp"}, form["resource"])
+
+	info, err := os.Stat(transport.config.TokenFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(transport.config.TokenFile)
+	require.NoError(t, err)
+	var saved oauthToken
+	require.NoError(t, json.Unmarshal(data, &saved))
+	require.Equal(t, "fresh", saved.AccessToken)
+}
+
+// TestOAuthTransport_UnusableToken tests that expired tokens without a refresh token and invalid token files are dropped
+func TestOAuthTransport_UnusableToken(t *testing.T) {
+	transport := newTestOAuthTransport(t)
+	writeToken(t, transport, oauthToken{AccessToken: "expired", Expiry: time.Now().Add(-time.Minute)})
+	require.Nil(t, transport.currentToken(context.Background()))
+
+	transport = newTestOAuthTransport(t)
+	writeToken(t, transport, oauthToken{RefreshToken: "no access token"})
+	require.Nil(t, transport.currentToken(context.Background()))
+
+	// A failed refresh drops the token, so the next 401 starts a new authorization
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+	}))
+	t.Cleanup(tokenServer.Close)
+	transport = newTestOAuthTransport(t)
+	writeToken(t, transport, oauthToken{
+		AccessToken:   "expired",
+		RefreshToken:  "revoked",
+		Expiry:        time.Now().Add(-time.Minute),
+		TokenEndpoint: tokenServer.URL,
+	})
+	require.Nil(t, transport.currentToken(context.Background()))
+}
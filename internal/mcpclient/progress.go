@@ -0,0 +1,57 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ProgressFunc receives the progress notifications a server sends for a tool call
+type ProgressFunc func(params *mcp.ProgressNotificationParams)
+
+// progressKey is the context key of the ProgressFunc of a tool call
+type progressKey struct{}
+
+// WithProgress returns a context whose tool calls ask the server for progress
+// notifications and pass them to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressRouter hands progress notifications to the tool call they belong
+// to, by the progress token sent with the call
+type progressRouter struct {
+	mu    sync.Mutex
+	next  int
+	calls map[string]ProgressFunc // Receivers by progress token
+}
+
+func newProgressRouter() *progressRouter {
+	return &progressRouter{calls: make(map[string]ProgressFunc)}
+}
+
+// register assigns a progress token to a call; done releases it
+func (r *progressRouter) register(fn ProgressFunc) (token string, done func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	token = fmt.Sprintf("onemcp-%d", r.next)
+	r.calls[token] = fn
+	return token, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.calls, token)
+	}
+}
+
+// deliver passes a notification to its call, if that call is still running
+func (r *progressRouter) deliver(params *mcp.ProgressNotificationParams) {
+	r.mu.Lock()
+	fn := r.calls[fmt.Sprint(params.ProgressToken)]
+	r.mu.Unlock()
+	if fn != nil {
+		fn(params)
+	}
+}
@@ -0,0 +1,26 @@
+package mcpclient
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// proxyURL parses a server's proxy setting. http, https and socks5(h) proxies
+// are supported; credentials may be given in the URL and reference
+// environment variables.
+func proxyURL(raw string) (*url.URL, error) {
+	proxy, err := url.Parse(os.ExpandEnv(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return proxy, nil
+}
@@ -0,0 +1,190 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Reconnection backoff: the delay doubles after every failed attempt
+const (
+	initialReconnectDelay = time.Second
+	maxReconnectDelay     = time.Minute
+)
+
+// Restart budget of server processes: a server that crashes more often than
+// this is left down instead of being restarted forever
+const (
+	defaultMaxRestarts = 5
+	restartWindow      = 10 * time.Minute
+)
+
+// ServerDownError is returned for tool calls to a server whose process exited
+// or whose connection dropped, while it is restarted or after giving up.
+// Its Unavailable method lets callers classify it without importing this
+// package.
+type ServerDownError struct {
+	Server string
+	Reason string
+	Err    error // The transport error, if the connection closed mid-call
+}
+
+func (e *ServerDownError) Error() string {
+	return fmt.Sprintf("external MCP server %s is unavailable: %s", e.Server, e.Reason)
+}
+
+// Unavailable reports that the server couldn't take the call
+func (e *ServerDownError) Unavailable() bool {
+	return true
+}
+
+// Unwrap returns the transport error
+func (e *ServerDownError) Unwrap() error {
+	return e.Err
+}
+
+// connect opens a new session to the server over a fresh transport. It gives
+// up when ctx is done, closing a session that completes after that. The
+// session itself doesn't inherit ctx's cancellation: transports keep using the
+// connect context for the session's lifetime.
+func (c *MCPClient) connect(ctx context.Context) (*mcp.ClientSession, error) {
+	type connected struct {
+		session *mcp.ClientSession
+		err     error
+	}
+	done := make(chan connected, 1)
+	go func() {
+		session, err := c.client.Connect(context.WithoutCancel(ctx), c.newTransport(), nil)
+		done <- connected{session, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to connect to MCP server (%s): %w", c.transportType, result.err)
+		}
+		return result.session, nil
+	case <-ctx.Done():
+		go func() {
+			if result := <-done; result.session != nil {
+				result.session.Close()
+			}
+		}()
+		return nil, fmt.Errorf("failed to connect to MCP server (%s): %w", c.transportType, ctx.Err())
+	}
+}
+
+// downReason returns why the server can't take calls, or "" while connected
+func (c *MCPClient) downReason() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.down
+}
+
+// setDown records why the server can't take calls
+func (c *MCPClient) setDown(reason string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.down = reason
+}
+
+// currentSession returns the session requests are sent on
+func (c *MCPClient) currentSession() *mcp.ClientSession {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.session
+}
+
+// watch waits for a session to end and, unless the client was closed,
+// reconnects with exponential backoff until it succeeds or the client is closed.
+// A server process is restarted at most maxRestarts times per restartWindow.
+// Tool calls made in the meantime fail with a *ServerDownError.
+func (c *MCPClient) watch(session *mcp.ClientSession) {
+	err := session.Wait()
+	if c.closeCtx.Err() != nil {
+		return // Closed on purpose
+	}
+
+	lost, recovering := "its connection was lost", "reconnecting"
+	if c.stderr != nil {
+		lost, recovering = "its process exited", "restarting it"
+		c.logger.Error("External MCP server process exited", "name", c.name, "error", err, "stderr", c.stderr.String())
+	}
+
+	if !c.reconnect {
+		c.setDown(lost)
+		c.logger.Error("Lost connection to external MCP server", "name", c.name, "error", err)
+		if c.handlers.Disconnected != nil {
+			c.handlers.Disconnected(c.name, err)
+		}
+		return
+	}
+
+	c.setDown(lost + ", " + recovering)
+	c.logger.Warn("Lost connection to external MCP server, reconnecting", "name", c.name, "error", err)
+	if c.handlers.Disconnected != nil {
+		c.handlers.Disconnected(c.name, err)
+	}
+
+	delay := initialReconnectDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.closeCtx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if !c.spendRestart() {
+			c.setDown(fmt.Sprintf("its process exited more than %d times in %s and is no longer restarted", c.maxRestarts, restartWindow))
+			c.logger.Error("External MCP server keeps crashing, no longer restarting it", "name", c.name,
+				"restarts", len(c.restarts), "window", restartWindow)
+			if c.handlers.GaveUp != nil {
+				c.handlers.GaveUp(c.name, err)
+			}
+			return
+		}
+
+		session, err := c.connect(c.closeCtx)
+		if err != nil {
+			delay = min(2*delay, maxReconnectDelay)
+			c.logger.Warn("Failed to reconnect to external MCP server", "name", c.name, "attempt", attempt, "retry_in", delay, "error", err)
+			continue
+		}
+
+		c.sessionMu.Lock()
+		c.session = session
+		c.down = ""
+		c.sessionMu.Unlock()
+		if c.closeCtx.Err() != nil {
+			session.Close() // Closed while connecting
+			return
+		}
+		go c.watch(session)
+
+		c.logger.Info("Reconnected to external MCP server", "name", c.name, "attempts", attempt)
+		if c.handlers.Reconnected != nil {
+			c.handlers.Reconnected(c.closeCtx, c.name)
+		}
+		return
+	}
+}
+
+// spendRestart takes a restart from the budget of a server process, reporting
+// false once it's used up. Connections to remote servers are always retried.
+func (c *MCPClient) spendRestart() bool {
+	if c.stderr == nil || c.maxRestarts < 0 {
+		return true
+	}
+	now := time.Now()
+	c.restarts = slices.DeleteFunc(c.restarts, func(at time.Time) bool {
+		return now.Sub(at) > restartWindow
+	})
+	if len(c.restarts) >= c.maxRestarts {
+		return false
+	}
+	c.restarts = append(c.restarts, now)
+	return true
+}
@@ -0,0 +1,71 @@
+package mcpclient
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package runners (MCPServerConfig.Runner)
+const (
+	runnerNpx = "npx"
+	runnerUvx = "uvx"
+)
+
+// runnerInstallHints tell users how to get a missing runner
+var runnerInstallHints = map[string]string{
+	runnerNpx: "install Node.js (https://nodejs.org)",
+	runnerUvx: "install uv (https://docs.astral.sh/uv/getting-started/installation/)",
+}
+
+// expandRunner turns a runner config ("runner", "package", "version") into the
+// equivalent command config: npx --yes <package>@<version> or
+// uvx <package>@<version>, with the runner's cache in RunnerCache.
+func expandRunner(config MCPServerConfig) (MCPServerConfig, error) {
+	if config.Command != "" {
+		return config, fmt.Errorf("runner and command can't both be set")
+	}
+	if config.Package == "" {
+		return config, fmt.Errorf("runner %q requires a package", config.Runner)
+	}
+	hint, ok := runnerInstallHints[config.Runner]
+	if !ok {
+		return config, fmt.Errorf("unknown runner %q: must be %q or %q", config.Runner, runnerNpx, runnerUvx)
+	}
+	if _, err := exec.LookPath(config.Runner); err != nil {
+		return config, fmt.Errorf("%s not found: %s", config.Runner, hint)
+	}
+
+	pkg := config.Package
+	if config.Version != "" && !packageHasVersion(pkg) {
+		pkg += "@" + config.Version
+	}
+
+	env := make(map[string]string, len(config.Env)+1)
+	var cacheVar string
+	switch config.Runner {
+	case runnerNpx:
+		config.Args = append([]string{"--yes", pkg}, config.Args...)
+		cacheVar = "npm_config_cache"
+	case runnerUvx:
+		config.Args = append([]string{pkg}, config.Args...)
+		cacheVar = "UV_CACHE_DIR"
+	}
+	if config.RunnerCache != "" {
+		env[cacheVar] = filepath.Join(config.RunnerCache, config.Runner)
+	}
+	for key, value := range config.Env {
+		env[key] = value // Explicit settings win over the defaults
+	}
+
+	config.Command = config.Runner
+	config.Env = env
+	return config, nil
+}
+
+// packageHasVersion reports whether a package spec already pins a version
+// (name@version); the @ of an npm scope doesn't count
+func packageHasVersion(pkg string) bool {
+	return strings.LastIndex(pkg, "@") > 0
+}
@@ -0,0 +1,73 @@
+package mcpclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// installFakeRunners puts do-nothing executables with the given names on PATH, and nothing else
+func installFakeRunners(t *testing.T, names ...string) {
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755))
+	}
+	t.Setenv("PATH", dir)
+}
+
+// TestExpandRunner tests that runner configs become npx and uvx commands with a pinned package and cache
+func TestExpandRunner(t *testing.T) {
+	installFakeRunners(t, runnerNpx, runnerUvx)
+
+	config, err := expandRunner(MCPServerConfig{
+		Runner:      runnerNpx,
+		Package:     "@example/server",
+		Version:     "1.2.3",
+		Args:        []string{"/tmp"},
+		RunnerCache: "/cache",
+		Env:         map[string]string{"npm_config_cache": "/mine", "DEBUG": "1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "npx", config.Command)
+	require.Equal(t, []string{"--yes", "@example/server@1.2.3", "/tmp"}, config.Args)
+	require.Equal(t, map[string]string{"npm_config_cache": "/mine", "DEBUG": "1"}, config.Env, "Explicit settings win")
+
+	config, err = expandRunner(MCPServerConfig{
+		Runner:      runnerUvx,
+		Package:     "example-server@0.9",
+		Version:     "1.0",
+		RunnerCache: "/cache",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "uvx", config.Command)
+	require.Equal(t, []string{"example-server@0.9"}, config.Args, "A version in the package wins")
+	require.Equal(t, map[string]string{"UV_CACHE_DIR": filepath.Join("/cache", "uvx")}, config.Env)
+}
+
+// TestExpandRunner_Invalid tests that invalid runner configs and missing runners are reported
+func TestExpandRunner_Invalid(t *testing.T) {
+	installFakeRunners(t, runnerNpx)
+
+	for _, test := range []struct {
+		config MCPServerConfig
+		err    string
+	}{
+		{MCPServerConfig{Runner: runnerNpx, Package: "server", Command: "node"}, "runner and command can't both be set"},
+		{MCPServerConfig{Runner: runnerNpx}, `runner "npx" requires a package`},
+		{MCPServerConfig{Runner: "pipx", Package: "server"}, `unknown runner "pipx"`},
+		{MCPServerConfig{Runner: runnerUvx, Package: "server"}, "uvx not found: install uv"},
+	} {
+		_, err := expandRunner(test.config)
+		require.ErrorContains(t, err, test.err)
+	}
+}
+
+// TestPackageHasVersion tests telling a pinned version from an npm scope
+func TestPackageHasVersion(t *testing.T) {
+	require.True(t, packageHasVersion("server@1.0"))
+	require.True(t, packageHasVersion("@scope/server@1.0"))
+	require.False(t, packageHasVersion("server"))
+	require.False(t, packageHasVersion("@scope/server"))
+}
@@ -0,0 +1,47 @@
+package mcpclient
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Transport types of remote servers (MCPServerConfig.Transport)
+const (
+	transportStreamable = "streamable-http"
+	transportSSE        = "sse"
+)
+
+// legacyDetector records whether a server rejected the first Streamable HTTP
+// POST, sent before a session exists, with a 4xx status such as 404 or 405.
+// Servers that only implement the legacy HTTP+SSE transport answer that way,
+// so the client falls back to SSE, as the spec's backwards compatibility
+// section describes. 401 and 403 are authorization failures, not a transport
+// mismatch.
+type legacyDetector struct {
+	base   http.RoundTripper
+	legacy atomic.Bool
+}
+
+// RoundTrip sends the request and checks the response for the legacy signature
+func (d *legacyDetector) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := d.base.RoundTrip(req)
+	if err == nil && req.Method == http.MethodPost && req.Header.Get("Mcp-Session-Id") == "" {
+		switch status := resp.StatusCode; {
+		case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		case status >= 400 && status < 500:
+			d.legacy.Store(true)
+		}
+	}
+	return resp, err
+}
+
+// newLegacyDetector wraps a server's HTTP client (nil for the default) in a
+// legacyDetector
+func newLegacyDetector(httpClient *http.Client) (*legacyDetector, *http.Client) {
+	base := http.DefaultTransport
+	if httpClient != nil {
+		base = httpClient.Transport
+	}
+	detector := &legacyDetector{base: base}
+	return detector, &http.Client{Transport: detector}
+}
@@ -0,0 +1,90 @@
+package mcpclient
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStderrLine bounds a logged stderr line; longer lines are split
+const maxStderrLine = 4096
+
+// stderrWaitDelay bounds how long a stopped server's stderr is still read:
+// a process it started may keep the pipe open
+const stderrWaitDelay = time.Second
+
+// stderrTailLines is how many of its last stderr lines are logged when a
+// server process exits
+const stderrTailLines = 20
+
+// stderrLogger writes a child process's stderr to the log, one entry per line
+// tagged with the server name, so startup crashes can be diagnosed
+type stderrLogger struct {
+	name   string
+	logger *slog.Logger
+	tail   *stderrTail // Keeps the last lines for the crash log
+
+	mu  sync.Mutex
+	buf []byte // Incomplete last line
+}
+
+// Write logs the complete lines in p and keeps the rest for the next write
+func (w *stderrLogger) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < maxStderrLine {
+				return len(p), nil
+			}
+			i = maxStderrLine
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[min(i+1, len(w.buf)):]
+	}
+}
+
+// log writes one line, skipping blank ones
+func (w *stderrLogger) log(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) > 0 {
+		w.logger.Info("External MCP server stderr", "name", w.name, "line", string(line))
+		w.tail.add(string(line))
+	}
+}
+
+// stderrTail keeps the last stderrTailLines stderr lines of the current
+// server process
+type stderrTail struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// reset forgets the lines of a previous process
+func (t *stderrTail) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = nil
+}
+
+// add keeps a line, dropping the oldest once full
+func (t *stderrTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.lines) == stderrTailLines {
+		t.lines = t.lines[1:]
+	}
+	t.lines = append(t.lines, line)
+}
+
+// String returns the kept lines, oldest first
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
@@ -0,0 +1,100 @@
+package mcpclient
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// templatePattern matches placeholders such as {{configDir}} or {{port:auto}}
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// Environment variables the ports allocated for {{port:auto}} are passed in
+const (
+	portEnv       = "ONEMCP_PORT"  // The first port
+	portEnvPrefix = "ONEMCP_PORT_" // Every port by position, starting at 1
+)
+
+// expandTemplates replaces the placeholders in a server's Command and Args so
+// configs don't hard-code machine-specific paths:
+//   - {{configDir}}: directory of the config file
+//   - {{home}}: the user's home directory
+//   - {{tempDir}}: the temporary directory
+//   - {{port:auto}}: a free local TCP port, a new one per occurrence
+//
+// Allocated ports are also passed to the server in its environment, as
+// ONEMCP_PORT (the first) and ONEMCP_PORT_<n>.
+func expandTemplates(config MCPServerConfig) (MCPServerConfig, error) {
+	var ports []int
+	var expandErr error
+	expand := func(s string) string {
+		return templatePattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := templatePattern.FindStringSubmatch(placeholder)[1]
+			value, err := templateValue(name, config.ConfigDir, &ports)
+			if err != nil && expandErr == nil {
+				expandErr = err
+			}
+			return value
+		})
+	}
+
+	config.Command = expand(config.Command)
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		args[i] = expand(arg)
+	}
+	config.Args = args
+	if expandErr != nil {
+		return config, expandErr
+	}
+
+	if len(ports) > 0 {
+		// Explicit settings win, as with runner defaults
+		env := make(map[string]string, len(config.Env)+len(ports)+1)
+		env[portEnv] = strconv.Itoa(ports[0])
+		for i, port := range ports {
+			env[portEnvPrefix+strconv.Itoa(i+1)] = strconv.Itoa(port)
+		}
+		maps.Copy(env, config.Env)
+		config.Env = env
+	}
+	return config, nil
+}
+
+// templateValue returns the value of one placeholder, allocating a port for
+// port:auto
+func templateValue(name, configDir string, ports *[]int) (string, error) {
+	switch name {
+	case "configDir":
+		if configDir != "" {
+			return configDir, nil
+		}
+		return os.Getwd()
+	case "home":
+		return os.UserHomeDir()
+	case "tempDir":
+		return os.TempDir(), nil
+	case "port:auto":
+		port, err := freePort()
+		if err != nil {
+			return "", fmt.Errorf("failed to allocate a port for {{port:auto}}: %w", err)
+		}
+		*ports = append(*ports, port)
+		return strconv.Itoa(port), nil
+	}
+	return "", fmt.Errorf("unknown placeholder {{%s}}: must be {{configDir}}, {{home}}, {{tempDir}} or {{port:auto}}", name)
+}
+
+// freePort asks the OS for a free local TCP port. The port is released before
+// the server binds it, so another process could take it in between.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,64 @@
+package mcpclient
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestExpandTemplates tests that placeholders in the command and arguments are replaced
+func TestExpandTemplates(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	config, err := expandTemplates(MCPServerConfig{
+		Command:   "{{configDir}}/bin/server",
+		Args:      []string{"--home={{ home }}", "--tmp", "{{tempDir}}", "plain"},
+		ConfigDir: "/etc/onemcp",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "/etc/onemcp/bin/server", config.Command)
+	require.Equal(t, []string{"--home=" + home, "--tmp", os.TempDir(), "plain"}, config.Args)
+	require.Empty(t, config.Env, "No ports were allocated")
+
+	// Without a config directory, {{configDir}} is the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	config, err = expandTemplates(MCPServerConfig{Command: "{{configDir}}"})
+	require.NoError(t, err)
+	require.Equal(t, wd, config.Command)
+}
+
+// TestExpandTemplates_Ports tests that each {{port:auto}} gets its own port, passed in the environment
+func TestExpandTemplates_Ports(t *testing.T) {
+	original := []string{"--port", "{{port:auto}}", "--admin-port", "{{port:auto}}"}
+	config, err := expandTemplates(MCPServerConfig{
+		Command: "server",
+		Args:    original,
+		Env:     map[string]string{"ONEMCP_PORT_2": "9000", "OTHER": "kept"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "{{port:auto}}", original[1], "The config's arguments are not changed in place")
+
+	first, err := strconv.Atoi(config.Args[1])
+	require.NoError(t, err)
+	second, err := strconv.Atoi(config.Args[3])
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	require.Equal(t, map[string]string{
+		"ONEMCP_PORT":   config.Args[1],
+		"ONEMCP_PORT_1": config.Args[1],
+		"ONEMCP_PORT_2": "9000", // Explicit settings win
+		"OTHER":         "kept",
+	}, config.Env)
+}
+
+// TestExpandTemplates_Unknown tests that an unknown placeholder is an error naming the valid ones
+func TestExpandTemplates_Unknown(t *testing.T) {
+	_, err := expandTemplates(MCPServerConfig{Command: "server", Args: []string{"{{workspace}}"}})
+	require.ErrorContains(t, err, "unknown placeholder {{workspace}}")
+	require.ErrorContains(t, err, "{{port:auto}}")
+}
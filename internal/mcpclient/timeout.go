@@ -0,0 +1,30 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CallTimeoutError is returned when a tool call exceeds its per-call timeout.
+// Its Timeout method lets callers classify it like a net.Error without
+// importing this package.
+type CallTimeoutError struct {
+	Server string
+	Tool   string
+	Limit  time.Duration
+}
+
+func (e *CallTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s on %s timed out after %s", e.Tool, e.Server, e.Limit)
+}
+
+// Timeout reports that the error is a timeout
+func (e *CallTimeoutError) Timeout() bool {
+	return true
+}
+
+// Unwrap makes the error match context.DeadlineExceeded
+func (e *CallTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,92 @@
+package mcpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files and returns their paths
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "onemcp test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+// TestNewTLSConfig tests that the CA bundle is trusted and the client certificate loaded
+func TestNewTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	config, err := newTLSConfig(&TLSConfig{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	require.Nil(t, config.RootCAs, "Without a CA bundle, the system roots apply")
+	require.Empty(t, config.Certificates)
+	require.False(t, config.InsecureSkipVerify)
+
+	config, err = newTLSConfig(&TLSConfig{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, SkipVerify: true})
+	require.NoError(t, err)
+	require.NotNil(t, config.RootCAs)
+	require.Len(t, config.Certificates, 1)
+	require.True(t, config.InsecureSkipVerify)
+
+	data, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: config.RootCAs})
+	require.NoError(t, err, "The CA bundle should be trusted")
+}
+
+// TestNewTLSConfig_Invalid tests that unreadable or incomplete TLS settings are reported
+func TestNewTLSConfig_Invalid(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0600))
+
+	for _, test := range []struct {
+		config TLSConfig
+		err    string
+	}{
+		{TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, "failed to read CA bundle"},
+		{TLSConfig{CAFile: empty}, "no certificates found in CA bundle"},
+		{TLSConfig{CertFile: certFile}, "certFile and keyFile must be set together"},
+		{TLSConfig{KeyFile: keyFile}, "certFile and keyFile must be set together"},
+		{TLSConfig{CertFile: certFile, KeyFile: empty}, "failed to load client certificate"},
+	} {
+		_, err := newTLSConfig(&test.config)
+		require.ErrorContains(t, err, test.err)
+	}
+}
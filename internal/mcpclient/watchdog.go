@@ -0,0 +1,86 @@
+package mcpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxWatchdogInterval bounds how often the watchdog looks for hung calls
+const maxWatchdogInterval = 5 * time.Second
+
+// inflightCall is a tool call waiting for its result
+type inflightCall struct {
+	tool   string
+	start  time.Time
+	cancel context.CancelCauseFunc
+}
+
+// inflightCalls tracks the running tool calls of a client for the watchdog
+type inflightCalls struct {
+	mu    sync.Mutex
+	next  uint64
+	calls map[uint64]*inflightCall
+}
+
+// track registers a call, returning its context, which the watchdog cancels
+// once the call runs past callCeiling, and a function to call when it ends
+func (c *MCPClient) track(ctx context.Context, toolName string) (context.Context, func()) {
+	if c.callCeiling <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	c.inflight.mu.Lock()
+	id := c.inflight.next
+	c.inflight.next++
+	c.inflight.calls[id] = &inflightCall{tool: toolName, start: time.Now(), cancel: cancel}
+	c.inflight.mu.Unlock()
+
+	return ctx, func() {
+		c.inflight.mu.Lock()
+		delete(c.inflight.calls, id)
+		c.inflight.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// watchdog cancels tool calls that run past callCeiling until the client is
+// closed, so a hung server can't pile up calls that never return. The server
+// is reported through the Hung handler; with restartOnHang, the cancelled
+// call re-establishes the connection once it returns.
+func (c *MCPClient) watchdog() {
+	ticker := time.NewTicker(min(c.callCeiling/4, maxWatchdogInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeCtx.Done():
+			return
+		case now := <-ticker.C:
+			for _, call := range c.hungCalls(now) {
+				c.logger.Error("Tool call on external MCP server hung, cancelling it", "name", c.name, "tool", call.tool,
+					"running", now.Sub(call.start).Round(time.Second), "ceiling", c.callCeiling)
+				call.cancel(&CallTimeoutError{Server: c.name, Tool: call.tool, Limit: c.callCeiling})
+				if c.handlers.Hung != nil {
+					c.handlers.Hung(c.name, call.tool)
+				}
+			}
+		}
+	}
+}
+
+// hungCalls removes and returns the calls running past callCeiling
+func (c *MCPClient) hungCalls(now time.Time) []*inflightCall {
+	c.inflight.mu.Lock()
+	defer c.inflight.mu.Unlock()
+
+	var hung []*inflightCall
+	for id, call := range c.inflight.calls {
+		if now.Sub(call.start) >= c.callCeiling {
+			hung = append(hung, call)
+			delete(c.inflight.calls, id)
+		}
+	}
+	return hung
+}
@@ -0,0 +1,248 @@
+package mcpclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const transportWebSocket = "websocket"
+
+// webSocketGUID is appended to the handshake key to compute the accept key (RFC 6455 section 1.3)
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage bounds the size of a message read from a server
+const maxWebSocketMessage = 64 << 20
+
+// WebSocket frame opcodes (RFC 6455 section 5.2)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// isWebSocketURL reports whether a server URL uses the ws or wss scheme
+func isWebSocketURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// webSocketTransport connects to an MCP server over WebSocket with the "mcp"
+// subprotocol, one JSON-RPC message per text frame. The handshake is sent
+// with the server's HTTP client, so headers, OAuth, TLS and proxy settings apply.
+type webSocketTransport struct {
+	url        string
+	httpClient *http.Client // nil for http.DefaultClient
+}
+
+// Connect performs the opening handshake and returns the upgraded connection
+func (t *webSocketTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebSocket URL: %w", err)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Protocol", "mcp")
+
+	httpClient := t.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("WebSocket handshake failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebSocket handshake failed: %s", resp.Status)
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.New("WebSocket handshake failed: connection can't be upgraded")
+	}
+
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		rwc.Close()
+		return nil, errors.New("WebSocket handshake failed: invalid Sec-WebSocket-Accept")
+	}
+
+	return &webSocketConn{rwc: rwc, reader: bufio.NewReader(rwc)}, nil
+}
+
+// webSocketConn is the client side of a WebSocket connection carrying MCP messages
+type webSocketConn struct {
+	rwc    io.ReadWriteCloser
+	reader *bufio.Reader // Only used by Read, which the SDK calls from one goroutine
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Read returns the next JSON-RPC message, answering pings on the way. A close
+// frame from the server ends the connection with io.EOF.
+func (c *webSocketConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+			if len(message) > maxWebSocketMessage {
+				return nil, fmt.Errorf("WebSocket message exceeds %d bytes", maxWebSocketMessage)
+			}
+		default:
+			return nil, fmt.Errorf("unknown WebSocket opcode %#x", opcode)
+		}
+
+		if fin {
+			return jsonrpc.DecodeMessage(message)
+		}
+	}
+}
+
+// readFrame reads one frame, unmasking its payload if the server masked it
+func (c *webSocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("WebSocket frame exceeds %d bytes", maxWebSocketMessage)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// Write sends a JSON-RPC message as a text frame
+func (c *webSocketConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+// writeFrame sends one unfragmented frame. Clients must mask every frame.
+func (c *webSocketConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|opcode)
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// Close sends a normal closure frame and closes the connection
+func (c *webSocketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, 1000))
+		err = c.rwc.Close()
+	})
+	return err
+}
+
+// SessionID returns "": WebSocket connections have no MCP session ID
+func (c *webSocketConn) SessionID() string {
+	return ""
+}
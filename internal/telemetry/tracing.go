@@ -0,0 +1,133 @@
+// Package telemetry sets up OpenTelemetry tracing and carries trace context
+// across MCP calls.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace exporters, as named by OTEL_TRACES_EXPORTER
+const (
+	exporterOTLP    = "otlp"
+	exporterConsole = "console"
+	exporterNone    = "none"
+)
+
+// SetupTracing installs the global tracer provider and propagator, configured
+// by the standard OTEL_* environment variables. Tracing is off unless
+// OTEL_TRACES_EXPORTER is "otlp" or "console", or an OTLP endpoint is set
+// (OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT), so
+// spans cost nothing by default. The console exporter writes to stderr, as
+// stdout carries the MCP protocol in stdio mode. The returned function
+// flushes pending spans and must be called before exiting.
+func SetupTracing(ctx context.Context, serviceName, serviceVersion string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch tracesExporter() {
+	case exporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case exporterConsole:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case exporterNone:
+		return func(context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (use otlp, console or none)", os.Getenv("OTEL_TRACES_EXPORTER"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", serviceVersion),
+		),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracesExporter returns the configured trace exporter
+func tracesExporter() string {
+	if exporter := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))); exporter != "" {
+		return exporter
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		return exporterOTLP
+	}
+	return exporterNone
+}
+
+// metaCarrier reads and writes trace context in a request's _meta, where MCP
+// clients and servers exchange it whatever the transport
+type metaCarrier mcp.Meta
+
+func (c metaCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c metaCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c metaCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectMeta adds the trace context of ctx (traceparent, tracestate and
+// baggage) to a request's _meta, creating it if needed
+func InjectMeta(ctx context.Context, meta mcp.Meta) mcp.Meta {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return meta
+	}
+	if meta == nil {
+		meta = mcp.Meta{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, metaCarrier(meta))
+	return meta
+}
+
+// ExtractMeta returns ctx carrying the trace context a client sent in a
+// request's _meta, so the server's spans join the client's trace
+func ExtractMeta(ctx context.Context, meta map[string]any) context.Context {
+	if len(meta) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, metaCarrier(meta))
+}
+
+// EndSpan records err, if any, as the span's error status and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
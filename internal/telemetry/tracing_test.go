@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestMetaPropagation tests that trace context survives a round trip through _meta
+func TestMetaPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	require.Nil(t, InjectMeta(context.Background(), nil), "Without a span, _meta is left alone")
+	require.Equal(t, context.Background(), ExtractMeta(context.Background(), nil))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "call")
+	defer span.End()
+	meta := InjectMeta(ctx, mcp.Meta{"progressToken": "p1"})
+	require.Equal(t, "p1", meta["progressToken"], "Existing metadata is kept")
+	require.Contains(t, meta, "traceparent")
+
+	extracted := trace.SpanContextFromContext(ExtractMeta(context.Background(), meta))
+	require.True(t, extracted.IsRemote())
+	require.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	require.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+}
+
+// TestEndSpan tests that errors mark the span as failed
+func TestEndSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	_, ok := provider.Tracer("test").Start(context.Background(), "ok")
+	EndSpan(ok, nil)
+	_, failed := provider.Tracer("test").Start(context.Background(), "failed")
+	EndSpan(failed, errors.New("disk full"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "disk full", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1, "The error is recorded as an event")
+}
+
+// TestSetupTracing tests choosing the exporter from the environment
+func TestSetupTracing(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	require.Equal(t, exporterNone, tracesExporter(), "Tracing is off by default")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	require.Equal(t, exporterOTLP, tracesExporter())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", " Console ")
+	require.Equal(t, exporterConsole, tracesExporter())
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	shutdown, err := SetupTracing(context.Background(), "onemcp", "1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	_, err = SetupTracing(context.Background(), "onemcp", "1.0.0")
+	require.ErrorContains(t, err, `unsupported OTEL_TRACES_EXPORTER "zipkin"`)
+}
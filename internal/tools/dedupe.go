@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CollapsedTool is a ranked search result with its near-duplicates folded in.
+type CollapsedTool struct {
+	*Tool
+	Alternatives []string // Names of near-duplicate tools from other sources
+}
+
+// CollapseNearDuplicates folds tools from different sources whose names and
+// descriptions are nearly identical into the highest-ranked one. Similarity is
+// the Jaccard index of their word sets; pairs at or above threshold collapse.
+// Tools are compared whatever their category, since categories default to the
+// server name. enabled decides per category whether collapsing applies (nil
+// means always); both tools' categories must allow it.
+func CollapseNearDuplicates(ranked []*Tool, threshold float64, enabled func(category string) bool) []CollapsedTool {
+	results := make([]CollapsedTool, 0, len(ranked))
+	words := make([]map[string]bool, 0, len(ranked))
+
+	for _, tool := range ranked {
+		toolWords := wordSet(baseName(tool) + " " + tool.Description)
+
+		merged := false
+		if enabled == nil || enabled(tool.Category) {
+			for i := range results {
+				primary := &results[i]
+				if primary.SourceName == tool.SourceName || primary.Type != tool.Type {
+					continue
+				}
+				if enabled != nil && !enabled(primary.Category) {
+					continue
+				}
+				if jaccard(words[i], toolWords) >= threshold {
+					primary.Alternatives = append(primary.Alternatives, tool.Name)
+					merged = true
+					break
+				}
+			}
+		}
+
+		if !merged {
+			results = append(results, CollapsedTool{Tool: tool})
+			words = append(words, toolWords)
+		}
+	}
+
+	return results
+}
+
+// baseName returns the tool name without its source prefix
+func baseName(tool *Tool) string {
+	if tool.SourceName == "" {
+		return tool.Name
+	}
+	return strings.TrimPrefix(tool.Name, tool.SourceName+"_")
+}
+
+// wordSet lowercases text and returns its set of letter/digit words
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		set[word] = true
+	}
+	return set
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for word := range a {
+		if b[word] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
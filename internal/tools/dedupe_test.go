@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dedupeTestTools returns ranked tools where fs2_read_file nearly duplicates fs1_read_file
+func dedupeTestTools() []*Tool {
+	return []*Tool{
+		{Name: "fs1_read_file", Category: "filesystem", Description: "Read the contents of a file", Source: SourceExternal, SourceName: "fs1"},
+		{Name: "fs1_write_file", Category: "filesystem", Description: "Write contents to a file", Source: SourceExternal, SourceName: "fs1"},
+		{Name: "fs2_read_file", Category: "filesystem", Description: "Read the contents of a file.", Source: SourceExternal, SourceName: "fs2"},
+		{Name: "browser_read_page", Category: "browser", Description: "Read the contents of a page", Source: SourceExternal, SourceName: "browser"},
+	}
+}
+
+// TestCollapseNearDuplicates tests that a near-duplicate from another source folds into the higher-ranked tool
+func TestCollapseNearDuplicates(t *testing.T) {
+	results := CollapseNearDuplicates(dedupeTestTools(), 0.8, nil)
+
+	require.Len(t, results, 3)
+	require.Equal(t, "fs1_read_file", results[0].Name)
+	require.Equal(t, []string{"fs2_read_file"}, results[0].Alternatives)
+	require.Equal(t, "fs1_write_file", results[1].Name)
+	require.Empty(t, results[1].Alternatives)
+	require.Equal(t, "browser_read_page", results[2].Name)
+}
+
+// TestCollapseNearDuplicates_DisabledCategory tests that a category can opt out of collapsing
+func TestCollapseNearDuplicates_DisabledCategory(t *testing.T) {
+	results := CollapseNearDuplicates(dedupeTestTools(), 0.8, func(category string) bool {
+		return category != "filesystem"
+	})
+
+	require.Len(t, results, 4)
+}
+
+// TestCollapseNearDuplicates_SameSourceNotCollapsed tests that tools of the same source are never folded
+func TestCollapseNearDuplicates_SameSourceNotCollapsed(t *testing.T) {
+	ranked := []*Tool{
+		{Name: "fs_read_file", Category: "filesystem", Description: "Read a file", SourceName: "fs"},
+		{Name: "fs_read_file_v2", Category: "filesystem", Description: "Read a file", SourceName: "fs"},
+	}
+
+	results := CollapseNearDuplicates(ranked, 0.5, nil)
+	require.Len(t, results, 2)
+}
+
+// TestCollapseNearDuplicates_DefaultCategories tests that tools collapse across servers
+// whose categories default to their server names
+func TestCollapseNearDuplicates_DefaultCategories(t *testing.T) {
+	ranked := []*Tool{
+		{Name: "home_read_file", Category: "home", Description: "Read the contents of a file", SourceName: "home"},
+		{Name: "work_read_file", Category: "work", Description: "Read the contents of a file", SourceName: "work"},
+	}
+
+	results := CollapseNearDuplicates(ranked, 0.8, nil)
+	require.Len(t, results, 1)
+	require.Equal(t, []string{"work_read_file"}, results[0].Alternatives)
+
+	// Either tool's category can opt out
+	results = CollapseNearDuplicates(ranked, 0.8, func(category string) bool {
+		return category != "home"
+	})
+	require.Len(t, results, 2)
+}
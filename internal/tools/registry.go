@@ -2,21 +2,53 @@ package tools
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/radutopala/onemcp/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer traces tool executions
+var tracer = otel.Tracer("github.com/radutopala/onemcp/internal/tools")
+
 // ExternalToolExecutor defines the interface for executing external tools.
+// A tool that ran but reported an error is returned as a result with IsError
+// set, not as an error.
 type ExternalToolExecutor interface {
-	CallTool(ctx context.Context, toolName string, arguments map[string]any) (any, error)
+	CallTool(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error)
 }
 
+// historySize is how many executions the registry's history keeps
+const historySize = 500
+
+// recordedArgumentsSize is how many distinct successful argument sets are
+// kept per tool as usage examples
+const recordedArgumentsSize = 3
+
 // Registry manages all available tools and their execution.
 type Registry struct {
+	mu                sync.RWMutex
 	tools             map[string]*Tool
 	externalExecutors map[string]ExternalToolExecutor // Map of source name -> executor
+	calls             map[string]*ToolStats           // Executions per tool name
+	history           []ExecutionRecord               // Most recent executions, oldest first
+	recorded          map[string][]map[string]any     // Arguments of recent successful calls per tool name, newest first
+	disabled          map[string]bool                 // Tools that may not be executed, by name
+	observer          func(ctx context.Context, record ExecutionRecord)
 	logger            *slog.Logger
 }
 
@@ -25,12 +57,17 @@ func NewRegistry(logger *slog.Logger) *Registry {
 	return &Registry{
 		tools:             make(map[string]*Tool),
 		externalExecutors: make(map[string]ExternalToolExecutor),
+		calls:             make(map[string]*ToolStats),
+		recorded:          make(map[string][]map[string]any),
+		disabled:          make(map[string]bool),
 		logger:            logger,
 	}
 }
 
 // RegisterExternalExecutor registers an executor for external tools from a specific source.
 func (r *Registry) RegisterExternalExecutor(sourceName string, executor ExternalToolExecutor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.externalExecutors[sourceName] = executor
 	r.logger.Info("Registered external tool executor", "source", sourceName)
 }
@@ -46,6 +83,8 @@ func (r *Registry) RegisterExternalTool(sourceName, category string, toolName, d
 		Description: description,
 		Source:      SourceExternal,
 		SourceName:  sourceName,
+		Alias:       toolName,
+		Type:        TypeTool,
 		InputSchema: inputSchema,
 		Handler:     nil, // External tools don't have handlers
 	}
@@ -58,10 +97,16 @@ func (r *Registry) Register(tool *Tool) error {
 	if tool.Name == "" {
 		return fmt.Errorf("tool name cannot be empty")
 	}
+	if tool.Type == "" {
+		tool.Type = TypeTool
+	}
 	// Only internal tools require a handler; external tools are executed remotely
 	if tool.Source == SourceInternal && tool.Handler == nil {
 		return fmt.Errorf("tool handler cannot be nil for internal tools")
 	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, exists := r.tools[tool.Name]; exists {
 		return fmt.Errorf("tool %s already registered", tool.Name)
 	}
@@ -71,8 +116,144 @@ func (r *Registry) Register(tool *Tool) error {
 	return nil
 }
 
+// update replaces a registered tool with a changed copy. Tools returned by
+// Get and List may be read concurrently, so they are never changed in place.
+func (r *Registry) update(name string, change func(tool *Tool)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tool, exists := r.tools[name]
+	if !exists {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	updated := *tool
+	change(&updated)
+	r.tools[name] = &updated
+	return nil
+}
+
+// SetExamples attaches usage examples to a registered tool.
+func (r *Registry) SetExamples(name string, examples []string) error {
+	return r.update(name, func(tool *Tool) {
+		tool.Examples = examples
+	})
+}
+
+// SetOutputSchema attaches the schema of a registered tool's structured content.
+func (r *Registry) SetOutputSchema(name string, schema any) error {
+	return r.update(name, func(tool *Tool) {
+		tool.OutputSchema = schema
+	})
+}
+
+// SetAnnotations attaches the behavior hints declared for a registered tool.
+func (r *Registry) SetAnnotations(name string, annotations *ToolAnnotations) error {
+	return r.update(name, func(tool *Tool) {
+		tool.Annotations = annotations
+	})
+}
+
+// UnregisterSource removes all tools registered from the given external source.
+// Returns the number of tools removed. The source's executor is left in place.
+func (r *Registry) UnregisterSource(sourceName string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for name, tool := range r.tools {
+		if tool.Source == SourceExternal && tool.SourceName == sourceName {
+			delete(r.tools, name)
+			removed++
+		}
+	}
+
+	r.logger.Info("Unregistered tools from source", "source", sourceName, "count", removed)
+	return removed
+}
+
+// ReplaceSource swaps the tools registered from an external source for the
+// given ones in a single update: new tools are added, changed ones replaced
+// and the ones no longer listed removed. Tools listed both before and after
+// stay registered throughout, so concurrent executions never miss them.
+// Tools whose name belongs to another source are skipped.
+func (r *Registry) ReplaceSource(sourceName string, replacement []*Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listed := make(map[string]bool, len(replacement))
+	for _, tool := range replacement {
+		if existing, exists := r.tools[tool.Name]; exists && (existing.Source != SourceExternal || existing.SourceName != sourceName) {
+			r.logger.Warn("Skipping tool already registered by another source", "name", tool.Name, "source", sourceName)
+			continue
+		}
+		if tool.Type == "" {
+			tool.Type = TypeTool
+		}
+		r.tools[tool.Name] = tool
+		listed[tool.Name] = true
+	}
+
+	removed := 0
+	for name, tool := range r.tools {
+		if tool.Source == SourceExternal && tool.SourceName == sourceName && !listed[name] {
+			delete(r.tools, name)
+			removed++
+		}
+	}
+
+	r.logger.Info("Replaced tools from source", "source", sourceName, "count", len(listed), "removed", removed)
+}
+
+// Unregister removes a tool by name, reporting whether it was registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; !exists {
+		return false
+	}
+	delete(r.tools, name)
+	r.logger.Info("Unregistered tool", "name", name)
+	return true
+}
+
+// SetDisabled disables or re-enables a tool by name. Disabled tools stay
+// registered but fail to execute; the name needn't be registered yet, so
+// tools of servers that connect later can be disabled too.
+func (r *Registry) SetDisabled(name string, disabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if disabled {
+		r.disabled[name] = true
+	} else {
+		delete(r.disabled, name)
+	}
+	r.logger.Info("Set tool disabled", "name", name, "disabled", disabled)
+}
+
+// IsDisabled reports whether a tool is disabled.
+func (r *Registry) IsDisabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.disabled[name]
+}
+
+// Disabled returns the names of the disabled tools, sorted.
+func (r *Registry) Disabled() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.disabled))
+	for name := range r.disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Get retrieves a tool by name.
 func (r *Registry) Get(name string) (*Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tool, exists := r.tools[name]
 	if !exists {
 		return nil, fmt.Errorf("tool not found: %s", name)
@@ -80,9 +261,58 @@ func (r *Registry) Get(name string) (*Tool, error) {
 	return tool, nil
 }
 
-// Execute runs a tool with the given parameters.
+// Execute runs a tool with the given parameters and records it in the history.
 func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[string]any) (*ExecutionResult, error) {
+	ctx, span := tracer.Start(ctx, "Registry.Execute", trace.WithAttributes(attribute.String("onemcp.tool", toolName)))
 	start := time.Now()
+	result, err := r.execute(ctx, toolName, parameters, start)
+	if err == nil && result != nil && !result.Success {
+		span.SetAttributes(attribute.String("onemcp.error_type", result.ErrorType))
+		telemetry.EndSpan(span, errors.New(result.Error))
+	} else {
+		telemetry.EndSpan(span, err)
+	}
+	if result != nil {
+		digest := argumentsDigest(parameters)
+		if result.Success && len(parameters) > 0 {
+			r.recordArguments(toolName, parameters)
+		}
+		if result.ErrorType != "tool_not_found" && result.ErrorType != "not_executable" && result.ErrorType != "tool_disabled" {
+			r.recordCall(toolName, result)
+		}
+		record := ExecutionRecord{
+			ToolName:        toolName,
+			ArgumentsDigest: digest,
+			Success:         result.Success,
+			ErrorType:       result.ErrorType,
+			ExecutionTimeMs: result.ExecutionTimeMs,
+			Timestamp:       start,
+		}
+		r.recordExecution(record)
+		if observe := r.executionObserver(); observe != nil {
+			observe(ctx, record)
+		}
+	}
+	return result, err
+}
+
+// SetExecutionObserver sets a function called after every execution with its
+// record, in the context of the call. It must not block.
+func (r *Registry) SetExecutionObserver(observe func(ctx context.Context, record ExecutionRecord)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = observe
+}
+
+// executionObserver returns the execution observer, if any
+func (r *Registry) executionObserver() func(ctx context.Context, record ExecutionRecord) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.observer
+}
+
+// execute runs a tool, timing it from start
+func (r *Registry) execute(ctx context.Context, toolName string, parameters map[string]any, start time.Time) (*ExecutionResult, error) {
 
 	tool, err := r.Get(toolName)
 	if err != nil {
@@ -95,9 +325,30 @@ func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[
 		}, nil
 	}
 
+	if r.IsDisabled(toolName) {
+		return &ExecutionResult{
+			Success:         false,
+			ToolName:        toolName,
+			Error:           fmt.Sprintf("tool %s is disabled; run tool_enable to restore it", toolName),
+			ErrorType:       "tool_disabled",
+			ExecutionTimeMs: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	if tool.Type != TypeTool {
+		return &ExecutionResult{
+			Success:         false,
+			ToolName:        toolName,
+			Error:           fmt.Sprintf("%s is a %s, not an executable tool", toolName, tool.Type),
+			ErrorType:       "not_executable",
+			ExecutionTimeMs: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
 	r.logger.InfoContext(ctx, "Executing tool", "name", toolName, "source", tool.Source, "parameters", parameters)
 
 	var result map[string]any
+	var output *mcp.CallToolResult
 	var execErr error
 
 	// Route execution based on source
@@ -106,7 +357,9 @@ func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[
 		result, execErr = tool.Handler(ctx, parameters)
 	} else if tool.Source == SourceExternal {
 		// Execute external tool via MCP client
+		r.mu.RLock()
 		executor, ok := r.externalExecutors[tool.SourceName]
+		r.mu.RUnlock()
 		if !ok {
 			return &ExecutionResult{
 				Success:         false,
@@ -127,21 +380,7 @@ func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[
 		// toolName format: "servername_originaltoolname"
 		originalToolName := strings.TrimPrefix(toolName, tool.SourceName+"_")
 
-		externalResult, err := executor.CallTool(ctx, originalToolName, paramsInterface)
-		if err != nil {
-			execErr = err
-		} else {
-			// Convert result to map[string]any
-			if resultMap, ok := externalResult.(map[string]any); ok {
-				result = make(map[string]any)
-				for k, v := range resultMap {
-					result[k] = v
-				}
-			} else {
-				// Wrap non-map results
-				result = map[string]any{"result": externalResult}
-			}
-		}
+		output, execErr = executor.CallTool(ctx, originalToolName, paramsInterface)
 	} else {
 		execErr = fmt.Errorf("unknown tool source: %s", tool.Source)
 	}
@@ -149,6 +388,44 @@ func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[
 	executionTime := time.Since(start).Milliseconds()
 
 	if execErr != nil {
+		// The caller gave up; the external call was cancelled on the server too
+		if ctx.Err() != nil {
+			r.logger.WarnContext(ctx, "Tool execution cancelled", "name", toolName, "source", tool.Source, "reason", ctx.Err())
+			return &ExecutionResult{
+				Success:         false,
+				ToolName:        toolName,
+				Error:           execErr.Error(),
+				ErrorType:       "cancelled",
+				ExecutionTimeMs: executionTime,
+			}, nil
+		}
+
+		// The call ran out of its own time limit (e.g. an external server's callTimeout)
+		var timeout interface{ Timeout() bool }
+		if errors.As(execErr, &timeout) && timeout.Timeout() {
+			r.logger.WarnContext(ctx, "Tool execution timed out", "name", toolName, "source", tool.Source, "error", execErr)
+			return &ExecutionResult{
+				Success:         false,
+				ToolName:        toolName,
+				Error:           execErr.Error(),
+				ErrorType:       "timeout",
+				ExecutionTimeMs: executionTime,
+			}, nil
+		}
+
+		// The external server is down: its process exited or its connection dropped
+		var unavailable interface{ Unavailable() bool }
+		if errors.As(execErr, &unavailable) && unavailable.Unavailable() {
+			r.logger.WarnContext(ctx, "Tool execution failed, server unavailable", "name", toolName, "source", tool.Source, "error", execErr)
+			return &ExecutionResult{
+				Success:         false,
+				ToolName:        toolName,
+				Error:           execErr.Error(),
+				ErrorType:       "server_unavailable",
+				ExecutionTimeMs: executionTime,
+			}, nil
+		}
+
 		r.logger.ErrorContext(ctx, "Tool execution failed", "name", toolName, "source", tool.Source, "error", execErr)
 		return &ExecutionResult{
 			Success:         false,
@@ -159,16 +436,44 @@ func (r *Registry) Execute(ctx context.Context, toolName string, parameters map[
 		}, nil
 	}
 
+	// The tool ran but reported an error; its content is kept for the details
+	if output != nil && output.IsError {
+		r.logger.WarnContext(ctx, "Tool reported an error", "name", toolName, "source", tool.Source)
+		return &ExecutionResult{
+			Success:         false,
+			ToolName:        toolName,
+			Output:          output,
+			Error:           toolErrorMessage(output),
+			ErrorType:       "tool_error",
+			ExecutionTimeMs: executionTime,
+		}, nil
+	}
+
 	r.logger.InfoContext(ctx, "Tool execution successful", "name", toolName, "source", tool.Source, "execution_time_ms", executionTime)
 
 	return &ExecutionResult{
 		Success:         true,
 		ToolName:        toolName,
 		Result:          result,
+		Output:          output,
 		ExecutionTimeMs: executionTime,
 	}, nil
 }
 
+// toolErrorMessage joins the text content of a result with IsError set
+func toolErrorMessage(output *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range output.Content {
+		if text, ok := content.(*mcp.TextContent); ok && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return "tool execution error: unknown error"
+	}
+	return "tool execution error: " + strings.Join(texts, "\n")
+}
+
 // ExecuteBatch runs multiple tools in sequence.
 func (r *Registry) ExecuteBatch(ctx context.Context, request *BatchExecutionRequest) (*BatchExecutionResult, error) {
 	start := time.Now()
@@ -190,6 +495,10 @@ func (r *Registry) ExecuteBatch(ctx context.Context, request *BatchExecutionRequ
 			successCount++
 		} else {
 			failedCount++
+			if ctx.Err() != nil {
+				r.logger.WarnContext(ctx, "Stopping batch execution, request cancelled", "tool", toolExec.ToolName)
+				break
+			}
 			if !request.ContinueOnError {
 				r.logger.WarnContext(ctx, "Stopping batch execution due to error", "tool", toolExec.ToolName)
 				break
@@ -207,11 +516,242 @@ func (r *Registry) ExecuteBatch(ctx context.Context, request *BatchExecutionRequ
 	}, nil
 }
 
+// ExecuteParallel executes independent tools concurrently, at most
+// maxParallel at a time, and returns their results in request order. Every
+// call runs regardless of the others failing; calls still waiting when the
+// request is cancelled are reported as cancelled.
+func (r *Registry) ExecuteParallel(ctx context.Context, calls []ToolExecution, maxParallel int) *BatchExecutionResult {
+	start := time.Now()
+
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+	sem := make(chan struct{}, maxParallel)
+	results := make([]ExecutionResult, len(calls))
+	var wg sync.WaitGroup
+
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = ExecutionResult{
+					Success:   false,
+					ToolName:  call.ToolName,
+					Error:     ctx.Err().Error(),
+					ErrorType: "cancelled",
+				}
+				return
+			}
+
+			result, _ := r.Execute(ctx, call.ToolName, call.Arguments)
+			results[i] = *result
+		}()
+	}
+	wg.Wait()
+
+	batch := &BatchExecutionResult{
+		Results:              results,
+		TotalExecutionTimeMs: time.Since(start).Milliseconds(),
+	}
+	for _, result := range results {
+		if result.Success {
+			batch.SuccessfulCount++
+		} else {
+			batch.FailedCount++
+		}
+	}
+	return batch
+}
+
 // ListAll returns all registered tools.
 func (r *Registry) ListAll() []*Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tools := make([]*Tool, 0, len(r.tools))
 	for _, tool := range r.tools {
 		tools = append(tools, tool)
 	}
 	return tools
 }
+
+// recordExecution appends an execution to the history, dropping the oldest
+// once it holds historySize
+func (r *Registry) recordExecution(record ExecutionRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.history) >= historySize {
+		r.history = slices.Delete(r.history, 0, len(r.history)-historySize+1)
+	}
+	r.history = append(r.history, record)
+}
+
+// recordCall adds an execution of a registered tool to its stats
+func (r *Registry) recordCall(name string, result *ExecutionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.calls[name]
+	if !ok {
+		stats = &ToolStats{ToolName: name}
+		r.calls[name] = stats
+	}
+	stats.Calls++
+	if !result.Success {
+		stats.Errors++
+	}
+	stats.TotalExecutionTimeMs += result.ExecutionTimeMs
+	stats.MaxExecutionTimeMs = max(stats.MaxExecutionTimeMs, result.ExecutionTimeMs)
+	stats.AvgExecutionTimeMs = stats.TotalExecutionTimeMs / stats.Calls
+}
+
+// ToolStats returns the stats of every executed tool, most executed first.
+// Ties are broken by name.
+func (r *Registry) ToolStats() []ToolStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make([]ToolStats, 0, len(r.calls))
+	for _, tool := range r.calls {
+		stats = append(stats, *tool)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Calls != stats[j].Calls {
+			return stats[i].Calls > stats[j].Calls
+		}
+		return stats[i].ToolName < stats[j].ToolName
+	})
+	return stats
+}
+
+// recordArguments keeps a redacted copy of the arguments of a successful call
+// as a usage example, replacing an earlier call with the same arguments
+func (r *Registry) recordArguments(name string, arguments map[string]any) {
+	arguments = redactArguments(arguments).(map[string]any)
+	digest := argumentsDigest(arguments)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded := []map[string]any{arguments}
+	for _, previous := range r.recorded[name] {
+		if len(recorded) < recordedArgumentsSize && argumentsDigest(previous) != digest {
+			recorded = append(recorded, previous)
+		}
+	}
+	r.recorded[name] = recorded
+}
+
+// RecordedArguments returns the arguments of a tool's most recent distinct
+// successful calls, newest first.
+func (r *Registry) RecordedArguments(name string) []map[string]any {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.recorded[name])
+}
+
+// History returns the most recent executions, newest first.
+func (r *Registry) History() []ExecutionRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	history := slices.Clone(r.history)
+	slices.Reverse(history)
+	return history
+}
+
+// redactedValue replaces the values of secret-looking arguments in usage examples
+const redactedValue = "[REDACTED]"
+
+// secretWords are the words of argument names whose values are never recorded
+var secretWords = map[string]bool{
+	"token": true, "key": true, "apikey": true, "secret": true, "password": true, "passwd": true, "authorization": true,
+}
+
+// isSecretArgument reports whether an argument name looks like it holds a
+// secret, such as api_key, accessToken or Authorization. Names are split
+// into words, so max_tokens or keywords are kept.
+func isSecretArgument(name string) bool {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, c := range runes {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			words, word = append(words, string(word)), nil
+			continue
+		case unicode.IsUpper(c) && i > 0 && unicode.IsLower(runes[i-1]):
+			words, word = append(words, string(word)), nil
+		}
+		word = append(word, unicode.ToLower(c))
+	}
+	words = append(words, string(word))
+	return slices.ContainsFunc(words, func(word string) bool { return secretWords[word] })
+}
+
+// redactArguments deep-copies tool arguments, so later changes by the caller
+// don't leak into recorded examples, and replaces secret-looking values
+func redactArguments(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for name, v := range value {
+			if isSecretArgument(name) {
+				copied[name] = redactedValue
+			} else {
+				copied[name] = redactArguments(v)
+			}
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, v := range value {
+			copied[i] = redactArguments(v)
+		}
+		return copied
+	}
+	return value
+}
+
+// argumentsDigest fingerprints tool arguments. Map keys are marshalled in
+// sorted order, so equal arguments always get the same digest.
+func argumentsDigest(arguments map[string]any) string {
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		data = fmt.Appendf(nil, "%v", arguments)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// MostUsed returns up to n registered tools that have been executed, most
+// executed first. Ties are broken by name.
+func (r *Registry) MostUsed(n int) []*Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var used []*Tool
+	for name, stats := range r.calls {
+		if tool, exists := r.tools[name]; exists && stats.Calls > 0 {
+			used = append(used, tool)
+		}
+	}
+	sort.Slice(used, func(i, j int) bool {
+		ci, cj := r.calls[used[i].Name].Calls, r.calls[used[j].Name].Calls
+		if ci != cj {
+			return ci > cj
+		}
+		return used[i].Name < used[j].Name
+	})
+
+	if len(used) > n {
+		used = used[:n]
+	}
+	return used
+}
@@ -2,24 +2,28 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
 // MockExternalExecutor implements ExternalToolExecutor for testing
 type MockExternalExecutor struct {
-	callToolFunc func(ctx context.Context, toolName string, arguments map[string]any) (any, error)
+	callToolFunc func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error)
 }
 
-func (m *MockExternalExecutor) CallTool(ctx context.Context, toolName string, arguments map[string]any) (any, error) {
+func (m *MockExternalExecutor) CallTool(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
 	if m.callToolFunc != nil {
 		return m.callToolFunc(ctx, toolName, arguments)
 	}
-	return map[string]any{"result": "mock_result"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "mock_result"}}}, nil
 }
 
 // RegistryTestSuite is the test suite for Registry
@@ -39,6 +43,21 @@ func (s *RegistryTestSuite) SetupTest() {
 	s.ctx = context.Background()
 }
 
+// registerTestTool registers an internal tool that succeeds unless called with "fail": true
+func (s *RegistryTestSuite) registerTestTool(name string) {
+	require.NoError(s.T(), s.registry.Register(&Tool{
+		Name:     name,
+		Category: "test",
+		Source:   SourceInternal,
+		Handler: func(ctx context.Context, params map[string]any) (map[string]any, error) {
+			if params["fail"] == true {
+				return nil, fmt.Errorf("failed")
+			}
+			return map[string]any{"result": "success"}, nil
+		},
+	}))
+}
+
 // TestNewRegistry tests registry creation
 func (s *RegistryTestSuite) TestNewRegistry() {
 	require.NotNil(s.T(), s.registry)
@@ -67,6 +86,7 @@ func (s *RegistryTestSuite) TestRegister() {
 	registered, err := s.registry.Get("test_tool")
 	require.NoError(s.T(), err)
 	require.Equal(s.T(), "test_tool", registered.Name)
+	require.Equal(s.T(), TypeTool, registered.Type, "Type should default to tool")
 }
 
 // TestRegister_EmptyName tests registration with empty name
@@ -173,6 +193,153 @@ func (s *RegistryTestSuite) TestRegisterExternalTool() {
 	require.Equal(s.T(), "test_server", tool.SourceName)
 }
 
+// TestSetExamples tests attaching usage examples to a tool
+func (s *RegistryTestSuite) TestSetExamples() {
+	s.registry.RegisterExternalTool("server", "test", "my_tool", "Test tool", map[string]any{"type": "object"})
+
+	err := s.registry.SetExamples("server_my_tool", []string{"do the thing"})
+	require.NoError(s.T(), err)
+
+	tool, err := s.registry.Get("server_my_tool")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"do the thing"}, tool.Examples)
+
+	err = s.registry.SetExamples("missing", []string{"x"})
+	require.Error(s.T(), err)
+}
+
+// TestSetters_CopyOnWrite tests that changing a tool leaves tools already handed out untouched, so readers don't race with writers
+func (s *RegistryTestSuite) TestSetters_CopyOnWrite() {
+	s.registry.RegisterExternalTool("server", "test", "my_tool", "Test tool", map[string]any{"type": "object"})
+	before, err := s.registry.Get("server_my_tool")
+	require.NoError(s.T(), err)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 100 {
+			for _, tool := range s.registry.ListAll() {
+				_ = tool.Examples
+				_ = tool.Annotations.Destructive()
+			}
+		}
+	})
+	for range 100 {
+		require.NoError(s.T(), s.registry.SetExamples("server_my_tool", []string{"do the thing"}))
+		require.NoError(s.T(), s.registry.SetAnnotations("server_my_tool", &ToolAnnotations{ReadOnlyHint: true}))
+		require.NoError(s.T(), s.registry.SetOutputSchema("server_my_tool", map[string]any{"type": "object"}))
+	}
+	wg.Wait()
+
+	require.Nil(s.T(), before.Examples)
+	require.Nil(s.T(), before.Annotations)
+	require.Nil(s.T(), before.OutputSchema)
+
+	after, err := s.registry.Get("server_my_tool")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"do the thing"}, after.Examples)
+	require.True(s.T(), after.Annotations.ReadOnlyHint)
+	require.NotNil(s.T(), after.OutputSchema)
+}
+
+// TestUnregisterSource tests removing all tools of an external source
+func (s *RegistryTestSuite) TestUnregisterSource() {
+	s.registry.RegisterExternalTool("server_a", "test", "tool_one", "Tool one", map[string]any{"type": "object"})
+	s.registry.RegisterExternalTool("server_a", "test", "tool_two", "Tool two", map[string]any{"type": "object"})
+	s.registry.RegisterExternalTool("server_b", "test", "tool_one", "Tool one", map[string]any{"type": "object"})
+
+	removed := s.registry.UnregisterSource("server_a")
+	require.Equal(s.T(), 2, removed)
+
+	_, err := s.registry.Get("server_a_tool_one")
+	require.Error(s.T(), err)
+	_, err = s.registry.Get("server_b_tool_one")
+	require.NoError(s.T(), err)
+
+	// Re-registering after removal should succeed
+	err = s.registry.RegisterExternalTool("server_a", "test", "tool_one", "Tool one", map[string]any{"type": "object"})
+	require.NoError(s.T(), err)
+}
+
+// TestReplaceSource tests that replacing a source's tools adds, replaces and
+// removes them without ever dropping the ones listed before and after
+func (s *RegistryTestSuite) TestReplaceSource() {
+	s.registry.RegisterExternalTool("server_a", "test", "kept", "Kept", map[string]any{"type": "object"})
+	s.registry.RegisterExternalTool("server_a", "test", "changed", "Before", map[string]any{"type": "object"})
+	s.registry.RegisterExternalTool("server_a", "test", "gone", "Gone", map[string]any{"type": "object"})
+	s.registry.RegisterExternalTool("server_b", "test", "tool", "Other", map[string]any{"type": "object"})
+
+	entry := func(name, description string) *Tool {
+		return &Tool{Name: "server_a_" + name, Description: description, Source: SourceExternal, SourceName: "server_a", Alias: name}
+	}
+
+	stop := make(chan struct{})
+	missed := make(chan error, 1)
+	go func() {
+		defer close(missed)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := s.registry.Get("server_a_kept"); err != nil {
+				missed <- err
+				return
+			}
+		}
+	}()
+	for range 100 {
+		s.registry.ReplaceSource("server_a", []*Tool{entry("kept", "Kept"), entry("changed", "After"), entry("added", "Added")})
+	}
+	close(stop)
+	require.NoError(s.T(), <-missed)
+
+	changed, err := s.registry.Get("server_a_changed")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "After", changed.Description)
+	added, err := s.registry.Get("server_a_added")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), TypeTool, added.Type)
+	_, err = s.registry.Get("server_a_gone")
+	require.Error(s.T(), err)
+	_, err = s.registry.Get("server_b_tool")
+	require.NoError(s.T(), err, "Other sources' tools should be left alone")
+
+	// A name taken by another source is not overwritten
+	s.registry.ReplaceSource("server_a", []*Tool{{Name: "server_b_tool", Source: SourceExternal, SourceName: "server_a"}})
+	other, err := s.registry.Get("server_b_tool")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "server_b", other.SourceName)
+}
+
+// TestSetDisabled tests that disabled tools fail to execute until re-enabled
+func (s *RegistryTestSuite) TestSetDisabled() {
+	tool := &Tool{
+		Name:   "flaky",
+		Source: SourceInternal,
+		Handler: func(ctx context.Context, params map[string]any) (map[string]any, error) {
+			return map[string]any{"ok": true}, nil
+		},
+	}
+	require.NoError(s.T(), s.registry.Register(tool))
+
+	s.registry.SetDisabled("flaky", true)
+	s.registry.SetDisabled("not_yet_registered", true)
+	require.Equal(s.T(), []string{"flaky", "not_yet_registered"}, s.registry.Disabled())
+
+	result, err := s.registry.Execute(context.Background(), "flaky", nil)
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.Success)
+	require.Equal(s.T(), "tool_disabled", result.ErrorType)
+	require.Empty(s.T(), s.registry.ToolStats(), "Blocked calls shouldn't count as calls")
+
+	s.registry.SetDisabled("flaky", false)
+	require.False(s.T(), s.registry.IsDisabled("flaky"))
+	result, err = s.registry.Execute(context.Background(), "flaky", nil)
+	require.NoError(s.T(), err)
+	require.True(s.T(), result.Success)
+}
+
 // TestSearch tests tool search
 // TestExecute_Internal tests internal tool execution
 func (s *RegistryTestSuite) TestExecute_Internal() {
@@ -207,8 +374,11 @@ func (s *RegistryTestSuite) TestExecute_NotFound() {
 func (s *RegistryTestSuite) TestExecute_External() {
 	// Register executor
 	executor := &MockExternalExecutor{
-		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (any, error) {
-			return map[string]any{"external_result": "ok", "tool": toolName}, nil
+		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{
+				Content:           []mcp.Content{&mcp.TextContent{Text: "ok"}},
+				StructuredContent: map[string]any{"tool": toolName},
+			}, nil
 		},
 	}
 	s.registry.RegisterExternalExecutor("external_server", executor)
@@ -225,8 +395,32 @@ func (s *RegistryTestSuite) TestExecute_External() {
 	result, err := s.registry.Execute(s.ctx, "external_server_remote_tool", map[string]any{"param": "value"})
 	require.NoError(s.T(), err)
 	require.True(s.T(), result.Success)
-	require.Equal(s.T(), "ok", result.Result["external_result"])
-	require.Equal(s.T(), "remote_tool", result.Result["tool"]) // Should strip prefix
+	require.Equal(s.T(), "ok", result.Output.Content[0].(*mcp.TextContent).Text)
+	require.Equal(s.T(), map[string]any{"tool": "remote_tool"}, result.Output.StructuredContent) // Should strip prefix
+}
+
+// TestExecute_ToolError tests that an error reported by an external tool keeps its content
+func (s *RegistryTestSuite) TestExecute_ToolError() {
+	s.registry.RegisterExternalExecutor("server", &MockExternalExecutor{
+		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "file not found"},
+					&mcp.TextContent{Text: "searched /tmp"},
+				},
+			}, nil
+		},
+	})
+	require.NoError(s.T(), s.registry.RegisterExternalTool("server", "test", "read", "Read a file", nil))
+
+	result, err := s.registry.Execute(s.ctx, "server_read", nil)
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.Success)
+	require.Equal(s.T(), "tool_error", result.ErrorType)
+	require.Equal(s.T(), "tool execution error: file not found\nsearched /tmp", result.Error)
+	require.True(s.T(), result.Output.IsError)
+	require.Len(s.T(), result.Output.Content, 2)
 }
 
 // TestExecute_ExternalExecutorNotFound tests external tool with missing executor
@@ -246,6 +440,22 @@ func (s *RegistryTestSuite) TestExecute_ExternalExecutorNotFound() {
 	require.Equal(s.T(), "executor_not_found", result.ErrorType)
 }
 
+// TestExecute_NotExecutable tests that prompts and resources can't be executed
+func (s *RegistryTestSuite) TestExecute_NotExecutable() {
+	s.registry.Register(&Tool{
+		Name:       "server_summarize",
+		Category:   "prompts",
+		Source:     SourceExternal,
+		SourceName: "server",
+		Type:       TypePrompt,
+	})
+
+	result, err := s.registry.Execute(s.ctx, "server_summarize", map[string]any{})
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.Success)
+	require.Equal(s.T(), "not_executable", result.ErrorType)
+}
+
 // TestExecuteBatch tests batch execution
 func (s *RegistryTestSuite) TestExecuteBatch() {
 	// Register tools
@@ -315,6 +525,168 @@ func (s *RegistryTestSuite) TestExecuteBatch_StopOnError() {
 	require.Equal(s.T(), 1, result.FailedCount)
 }
 
+// TestExecuteBatch_Cancelled tests that a cancelled request is reported as such and ends the batch
+func (s *RegistryTestSuite) TestExecuteBatch_Cancelled() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.registry.RegisterExternalExecutor("server", &MockExternalExecutor{
+		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+			cancel() // The client gives up mid-call
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	require.NoError(s.T(), s.registry.RegisterExternalTool("server", "test", "slow", "Slow tool", nil))
+
+	request := &BatchExecutionRequest{
+		Tools: []ToolExecution{
+			{ToolName: "server_slow", Arguments: map[string]any{}},
+			{ToolName: "server_slow", Arguments: map[string]any{}}, // Should not execute
+		},
+		ContinueOnError: true,
+	}
+
+	result, err := s.registry.ExecuteBatch(ctx, request)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), result.Results, 1)
+	require.Equal(s.T(), "cancelled", result.Results[0].ErrorType)
+}
+
+// TestExecuteParallel tests that parallel execution keeps request order, runs
+// every call and stays within maxParallel
+func (s *RegistryTestSuite) TestExecuteParallel() {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	s.registry.RegisterExternalExecutor("server", &MockExternalExecutor{
+		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprint(arguments["n"])}}}, nil
+		},
+	})
+	require.NoError(s.T(), s.registry.RegisterExternalTool("server", "test", "slow", "Slow tool", nil))
+
+	calls := []ToolExecution{
+		{ToolName: "server_slow", Arguments: map[string]any{"n": 0}},
+		{ToolName: "nonexistent", Arguments: map[string]any{}},
+		{ToolName: "server_slow", Arguments: map[string]any{"n": 2}},
+		{ToolName: "server_slow", Arguments: map[string]any{"n": 3}},
+		{ToolName: "server_slow", Arguments: map[string]any{"n": 4}},
+	}
+
+	result := s.registry.ExecuteParallel(s.ctx, calls, 2)
+	require.Len(s.T(), result.Results, 5)
+	require.Equal(s.T(), 4, result.SuccessfulCount)
+	require.Equal(s.T(), 1, result.FailedCount)
+	require.Equal(s.T(), "tool_not_found", result.Results[1].ErrorType)
+	for _, i := range []int{0, 2, 3, 4} {
+		require.Equal(s.T(), fmt.Sprint(i), result.Results[i].Output.Content[0].(*mcp.TextContent).Text)
+	}
+	require.LessOrEqual(s.T(), peak, 2)
+	require.Equal(s.T(), 2, peak)
+}
+
+// TestHistory tests that executions are recorded newest first, with equal
+// arguments sharing a digest, and that the history is bounded
+func (s *RegistryTestSuite) TestHistory() {
+	s.registerTestTool("tool1")
+
+	_, _ = s.registry.Execute(s.ctx, "tool1", map[string]any{"a": 1, "b": "x"})
+	_, _ = s.registry.Execute(s.ctx, "nonexistent", nil)
+	_, _ = s.registry.Execute(s.ctx, "tool1", map[string]any{"b": "x", "a": 1})
+
+	history := s.registry.History()
+	require.Len(s.T(), history, 3)
+	require.Equal(s.T(), "tool1", history[0].ToolName)
+	require.True(s.T(), history[0].Success)
+	require.Equal(s.T(), "nonexistent", history[1].ToolName)
+	require.False(s.T(), history[1].Success)
+	require.Equal(s.T(), "tool_not_found", history[1].ErrorType)
+	require.Equal(s.T(), history[0].ArgumentsDigest, history[2].ArgumentsDigest)
+	require.NotEqual(s.T(), history[0].ArgumentsDigest, history[1].ArgumentsDigest)
+	require.False(s.T(), history[0].Timestamp.Before(history[2].Timestamp))
+
+	for range historySize {
+		_, _ = s.registry.Execute(s.ctx, "tool1", nil)
+	}
+	require.Len(s.T(), s.registry.History(), historySize)
+}
+
+// TestRecordedArguments tests that the arguments of recent distinct successful calls are kept, newest first
+func (s *RegistryTestSuite) TestRecordedArguments() {
+	s.registerTestTool("tool1")
+
+	for _, arguments := range []map[string]any{
+		{"n": 1}, {"n": 2}, {"fail": true}, {"n": 1}, {"n": 3}, {"n": 4}, nil,
+	} {
+		_, _ = s.registry.Execute(s.ctx, "tool1", arguments)
+	}
+
+	require.Equal(s.T(), []map[string]any{{"n": 4}, {"n": 3}, {"n": 1}}, s.registry.RecordedArguments("tool1"))
+	require.Empty(s.T(), s.registry.RecordedArguments("nonexistent"))
+}
+
+// TestRecordedArguments_CopiedAndRedacted tests that recorded arguments are deep copies with secret-looking values redacted
+func (s *RegistryTestSuite) TestRecordedArguments_CopiedAndRedacted() {
+	s.registerTestTool("tool1")
+
+	arguments := map[string]any{
+		"query":      "open issues",
+		"max_tokens": 100,
+		"keywords":   []any{"bug"},
+		"api_key":    "sk-123",
+		"options": map[string]any{
+			"accessToken": "abc",
+			"headers":     []any{map[string]any{"Authorization": "Bearer abc"}},
+		},
+		"password": "hunter2",
+	}
+	_, _ = s.registry.Execute(s.ctx, "tool1", arguments)
+
+	arguments["query"] = "changed"
+	arguments["keywords"].([]any)[0] = "changed"
+	arguments["options"].(map[string]any)["region"] = "changed"
+
+	require.Equal(s.T(), []map[string]any{{
+		"query":      "open issues",
+		"max_tokens": 100,
+		"keywords":   []any{"bug"},
+		"api_key":    redactedValue,
+		"options": map[string]any{
+			"accessToken": redactedValue,
+			"headers":     []any{map[string]any{"Authorization": redactedValue}},
+		},
+		"password": redactedValue,
+	}}, s.registry.RecordedArguments("tool1"))
+}
+
+// timeoutError is an error whose Timeout method reports a timeout, like net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "call timed out" }
+func (timeoutError) Timeout() bool { return true }
+
+// TestExecute_Timeout tests that an executor's timeout error is reported as such
+func (s *RegistryTestSuite) TestExecute_Timeout() {
+	s.registry.RegisterExternalExecutor("server", &MockExternalExecutor{
+		callToolFunc: func(ctx context.Context, toolName string, arguments map[string]any) (*mcp.CallToolResult, error) {
+			return nil, fmt.Errorf("calling %s: %w", toolName, timeoutError{})
+		},
+	})
+	require.NoError(s.T(), s.registry.RegisterExternalTool("server", "test", "slow", "Slow tool", nil))
+
+	result, err := s.registry.Execute(s.ctx, "server_slow", nil)
+	require.NoError(s.T(), err)
+	require.False(s.T(), result.Success)
+	require.Equal(s.T(), "timeout", result.ErrorType)
+	require.Equal(s.T(), "calling slow: call timed out", result.Error)
+}
+
 // TestListAll tests listing all tools
 func (s *RegistryTestSuite) TestListAll() {
 	// Register some tools
@@ -332,6 +704,54 @@ func (s *RegistryTestSuite) TestListAll() {
 	require.Len(s.T(), tools, 3)
 }
 
+// TestMostUsed tests that the most executed tools come first, ties broken by name
+func (s *RegistryTestSuite) TestMostUsed() {
+	for _, name := range []string{"tool_a", "tool_b", "tool_c"} {
+		s.registerTestTool(name)
+	}
+
+	require.Empty(s.T(), s.registry.MostUsed(2))
+
+	for _, name := range []string{"tool_c", "tool_b", "tool_c", "tool_a", "missing"} {
+		_, err := s.registry.Execute(context.Background(), name, nil)
+		require.NoError(s.T(), err)
+	}
+
+	used := s.registry.MostUsed(2)
+	require.Len(s.T(), used, 2)
+	require.Equal(s.T(), "tool_c", used[0].Name)
+	require.Equal(s.T(), "tool_a", used[1].Name) // Tied with tool_b, first by name
+}
+
+// TestToolStats tests that calls and errors are counted per registered tool
+func (s *RegistryTestSuite) TestToolStats() {
+	s.registerTestTool("flaky")
+
+	for _, params := range []map[string]any{nil, {"fail": true}, nil} {
+		_, err := s.registry.Execute(context.Background(), "flaky", params)
+		require.NoError(s.T(), err)
+	}
+	_, err := s.registry.Execute(context.Background(), "missing", nil)
+	require.NoError(s.T(), err)
+
+	stats := s.registry.ToolStats()
+	require.Len(s.T(), stats, 1, "Unknown tools aren't counted")
+	require.Equal(s.T(), "flaky", stats[0].ToolName)
+	require.Equal(s.T(), int64(3), stats[0].Calls)
+	require.Equal(s.T(), int64(1), stats[0].Errors)
+}
+
+// TestDestructive tests that annotations are destructive unless read-only or explicitly not destructive
+func (s *RegistryTestSuite) TestDestructive() {
+	no, yes := false, true
+
+	require.False(s.T(), (*ToolAnnotations)(nil).Destructive())
+	require.True(s.T(), (&ToolAnnotations{}).Destructive()) // destructiveHint defaults to true
+	require.True(s.T(), (&ToolAnnotations{DestructiveHint: &yes}).Destructive())
+	require.False(s.T(), (&ToolAnnotations{DestructiveHint: &no}).Destructive())
+	require.False(s.T(), (&ToolAnnotations{ReadOnlyHint: true, DestructiveHint: &yes}).Destructive())
+}
+
 // TestRegistryTestSuite runs the test suite
 func TestRegistryTestSuite(t *testing.T) {
 	suite.Run(t, new(RegistryTestSuite))
@@ -2,6 +2,9 @@ package tools
 
 import (
 	"context"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // ToolSource indicates where a tool is implemented
@@ -12,28 +15,85 @@ const (
 	SourceExternal ToolSource = "external" // External MCP server tools
 )
 
+// ItemType indicates what kind of capability an indexed item is
+type ItemType string
+
+const (
+	TypeTool     ItemType = "tool"     // Executable tool
+	TypePrompt   ItemType = "prompt"   // Prompt template
+	TypeResource ItemType = "resource" // Readable resource
+)
+
 // ToolHandler represents a function that handles tool execution
 type ToolHandler func(context.Context, map[string]any) (map[string]any, error)
 
 // Tool represents a single executable tool with its metadata and handler.
 type Tool struct {
-	Name        string      // Tool name
-	Category    string      // Category for organizing tools (e.g., "browser", "playwright", etc.)
-	Description string      // Tool description
-	InputSchema any         // Schema for tool parameters (can be map[string]any or struct with jsonschema tags)
-	Handler     ToolHandler // Handler function for internal tools (nil for external)
-	Source      ToolSource  // Where the tool is implemented
-	SourceName  string      // Name of external MCP server (if external)
+	Name         string           // Tool name
+	Category     string           // Category for organizing tools (e.g., "browser", "playwright", etc.)
+	Description  string           // Tool description
+	InputSchema  any              // Schema for tool parameters (can be map[string]any or struct with jsonschema tags)
+	Handler      ToolHandler      // Handler function for internal tools (nil for external)
+	Source       ToolSource       // Where the tool is implemented
+	SourceName   string           // Name of external MCP server (if external)
+	Alias        string           // Name or URI the external server knows the item by, without the server prefix
+	Type         ItemType         // Kind of capability (defaults to TypeTool)
+	Examples     []string         // Usage examples (phrases or example invocations) from config
+	OutputSchema any              // Schema of the tool's structured content (nil if not declared)
+	Annotations  *ToolAnnotations // Behavior hints declared by the tool's server (nil if none)
+}
+
+// ToolAnnotations are the behavior hints an MCP server declares for a tool.
+// Clients should treat them as untrusted hints.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"read_only,omitempty"`
+	DestructiveHint *bool  `json:"destructive,omitempty"` // nil means true, as in MCP
+	IdempotentHint  bool   `json:"idempotent,omitempty"`
+}
+
+// Destructive reports whether the annotations declare that a tool may make
+// destructive updates: it is not read-only and destructiveHint is unset or
+// true. Tools without annotations (nil) are not considered destructive.
+func (a *ToolAnnotations) Destructive() bool {
+	if a == nil || a.ReadOnlyHint {
+		return false
+	}
+	return a.DestructiveHint == nil || *a.DestructiveHint
 }
 
 // ExecutionResult represents the result of a tool execution.
 type ExecutionResult struct {
-	Success         bool           `json:"success"`
-	ToolName        string         `json:"tool_name"`
-	Result          map[string]any `json:"result,omitempty"`
-	Error           string         `json:"error,omitempty"`
-	ErrorType       string         `json:"error_type,omitempty"`
-	ExecutionTimeMs int64          `json:"execution_time_ms"`
+	Success         bool                `json:"success"`
+	ToolName        string              `json:"tool_name"`
+	Result          map[string]any      `json:"result,omitempty"` // Output of an internal tool
+	Output          *mcp.CallToolResult `json:"output,omitempty"` // Result of an external tool, as its server returned it
+	Error           string              `json:"error,omitempty"`
+	ErrorType       string              `json:"error_type,omitempty"`
+	ExecutionTimeMs int64               `json:"execution_time_ms"`
+}
+
+// ExecutionRecord is one past tool execution kept in the registry's history.
+// Arguments are kept only as a digest, so identical calls can be recognized
+// without holding on to their contents.
+type ExecutionRecord struct {
+	ToolName        string    `json:"tool_name"`
+	ArgumentsDigest string    `json:"arguments_digest"`
+	Success         bool      `json:"success"`
+	ErrorType       string    `json:"error_type,omitempty"`
+	ExecutionTimeMs int64     `json:"execution_time_ms"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// ToolStats summarizes the executions of one tool. Errors include errors
+// reported by the tool itself.
+type ToolStats struct {
+	ToolName             string `json:"tool_name"`
+	Calls                int64  `json:"calls"`
+	Errors               int64  `json:"errors"`
+	TotalExecutionTimeMs int64  `json:"total_execution_time_ms"`
+	AvgExecutionTimeMs   int64  `json:"avg_execution_time_ms"`
+	MaxExecutionTimeMs   int64  `json:"max_execution_time_ms"`
 }
 
 // BatchExecutionRequest represents a request to execute multiple tools.
@@ -58,8 +118,16 @@ type BatchExecutionResult struct {
 
 // ToolMetadata represents tool information for search results.
 type ToolMetadata struct {
-	Name        string         `json:"name"`
-	Category    string         `json:"category"`
-	Description string         `json:"description"`
-	Parameters  map[string]any `json:"parameters,omitempty"` // Schema as map
+	Name         string           `json:"name"`
+	Type         ItemType         `json:"type,omitempty"`
+	Category     string           `json:"category"`
+	Server       string           `json:"server,omitempty"` // External server providing the item
+	Alias        string           `json:"alias,omitempty"`  // Name or URI on that server
+	Score        float64          `json:"score,omitempty"`  // Search relevance in [0, 1]
+	Description  string           `json:"description"`
+	Parameters   map[string]any   `json:"parameters,omitempty"`    // Schema as map
+	OutputSchema map[string]any   `json:"output_schema,omitempty"` // Schema of the structured result, if declared
+	Alternatives []string         `json:"alternatives,omitempty"`  // Near-duplicate tools from other servers
+	Examples     []string         `json:"examples,omitempty"`      // Usage examples from config
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`   // Behavior hints declared by the tool's server
 }
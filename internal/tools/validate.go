@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Kinds of argument issues
+const (
+	IssueMissing      = "missing"       // A required property is absent
+	IssueExtra        = "extra"         // A property the schema doesn't declare
+	IssueWrongType    = "wrong_type"    // The value has a type the schema doesn't allow
+	IssueInvalidValue = "invalid_value" // The value is not one of the schema's enum values
+)
+
+// ArgumentIssue is a problem found in tool arguments. Issues with severity
+// "warning" don't make the arguments invalid: an undeclared property a schema
+// allows is usually still a typo.
+type ArgumentIssue struct {
+	Path     string `json:"path"` // Dotted path to the argument, e.g. "options.timeout" or "files[2]"
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+// ValidateArguments checks arguments against a tool's JSON input schema:
+// required and undeclared properties, types and enum values, recursing into
+// nested objects and arrays. Keywords it doesn't know are ignored, so it never
+// rejects arguments for a schema feature it can't check.
+func ValidateArguments(schema any, arguments map[string]any) []ArgumentIssue {
+	schemaMap, _ := schema.(map[string]any)
+	if schemaMap == nil {
+		return nil
+	}
+
+	// Normalize the values to what they'd be after a JSON round trip
+	var normalized any = map[string]any{}
+	if arguments != nil {
+		if data, err := json.Marshal(arguments); err == nil {
+			json.Unmarshal(data, &normalized)
+		}
+	}
+
+	var issues []ArgumentIssue
+	validateValue(schemaMap, normalized, "", &issues)
+	return issues
+}
+
+// validateValue checks one value against its schema, appending issues
+func validateValue(schema map[string]any, value any, path string, issues *[]ArgumentIssue) {
+	addIssue := func(kind, severity, message string) {
+		*issues = append(*issues, ArgumentIssue{Path: path, Kind: kind, Severity: severity, Message: message})
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := jsonType(value)
+		allowed := slices.Contains(types, actual) || (actual == "integer" && slices.Contains(types, "number"))
+		if !allowed {
+			addIssue(IssueWrongType, "error", fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), actual))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(allowed any) bool { return jsonEqual(allowed, value) }) {
+		values := make([]string, len(enum))
+		for i, allowed := range enum {
+			data, _ := json.Marshal(allowed)
+			values[i] = string(data)
+		}
+		addIssue(IssueInvalidValue, "error", "must be one of "+strings.Join(values, ", "))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		validateObject(schema, v, path, issues)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), issues)
+			}
+		}
+	}
+}
+
+// validateObject checks the properties of an object value
+func validateObject(schema map[string]any, object map[string]any, path string, issues *[]ArgumentIssue) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		name, ok := name.(string)
+		if !ok {
+			continue
+		}
+		if _, present := object[name]; !present {
+			*issues = append(*issues, ArgumentIssue{Path: join(name), Kind: IssueMissing, Severity: "error", Message: "required property is missing"})
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			validateValue(property, object[name], join(name), issues)
+			continue
+		}
+		if _, declared := properties[name]; declared || properties == nil {
+			continue // Declared without a schema, or the schema lists no properties at all
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*issues = append(*issues, ArgumentIssue{Path: join(name), Kind: IssueExtra, Severity: "error", Message: "property is not allowed by the schema"})
+				continue
+			}
+		case map[string]any:
+			validateValue(additional, object[name], join(name), issues)
+			continue
+		}
+		*issues = append(*issues, ArgumentIssue{Path: join(name), Kind: IssueExtra, Severity: "warning", Message: "property is not declared by the schema and may be ignored"})
+	}
+}
+
+// schemaTypes returns the types a schema allows, from "type" as a string or an array
+func schemaTypes(value any) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonType returns the JSON schema type of a value decoded from JSON
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares two values decoded from JSON
+func jsonEqual(a, b any) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(da) == string(db)
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// validateTestSchema is a schema using every keyword ValidateArguments checks
+func validateTestSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url":     map[string]any{"type": "string"},
+			"retries": map[string]any{"type": "integer"},
+			"mode":    map[string]any{"type": "string", "enum": []any{"fast", "safe"}},
+			"options": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"timeout": map[string]any{"type": "number"},
+				},
+				"additionalProperties": false,
+			},
+			"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required": []any{"url"},
+	}
+}
+
+// TestValidateArguments tests that each kind of issue is reported with its path and severity
+func TestValidateArguments(t *testing.T) {
+	issues := ValidateArguments(validateTestSchema(), map[string]any{
+		"retries": 1.5,
+		"mode":    "reckless",
+		"options": map[string]any{"timeout": 10, "verbose": true},
+		"tags":    []any{"a", 2},
+		"urll":    "https://example.com",
+	})
+
+	require.Equal(t, []ArgumentIssue{
+		{Path: "url", Kind: IssueMissing, Severity: "error", Message: "required property is missing"},
+		{Path: "mode", Kind: IssueInvalidValue, Severity: "error", Message: `must be one of "fast", "safe"`},
+		{Path: "options.verbose", Kind: IssueExtra, Severity: "error", Message: "property is not allowed by the schema"},
+		{Path: "retries", Kind: IssueWrongType, Severity: "error", Message: "expected integer, got number"},
+		{Path: "tags[1]", Kind: IssueWrongType, Severity: "error", Message: "expected string, got integer"},
+		{Path: "urll", Kind: IssueExtra, Severity: "warning", Message: "property is not declared by the schema and may be ignored"},
+	}, issues)
+}
+
+// TestValidateArguments_Valid tests that valid arguments, or a missing schema, report no issues
+func TestValidateArguments_Valid(t *testing.T) {
+	require.Empty(t, ValidateArguments(validateTestSchema(), map[string]any{
+		"url":     "https://example.com",
+		"retries": 3,
+		"mode":    "safe",
+		"options": map[string]any{"timeout": 2.5},
+		"tags":    []string{"a", "b"},
+	}))
+
+	// Without a schema there is nothing to check
+	require.Empty(t, ValidateArguments(nil, map[string]any{"anything": true}))
+	require.Empty(t, ValidateArguments(map[string]any{"type": "object"}, map[string]any{"anything": true}))
+}